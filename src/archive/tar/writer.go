@@ -7,6 +7,7 @@ package tar
 import (
 	"fmt"
 	"io"
+	"io/fs"
 	"path"
 	"sort"
 	"strings"
@@ -107,6 +108,71 @@ func (tw *Writer) WriteHeader(hdr *Header) error {
 	}
 }
 
+// AddFS adds the files from fs.FS to the archive.
+// It walks the directory tree starting at the root of the filesystem
+// adding each file and directory to the tar archive while maintaining
+// the directory structure. The mode and modification time of each
+// entry are taken from its fs.FileInfo.
+//
+// Symbolic links are added only if fsys has a method
+//
+//	ReadLink(name string) (string, error)
+//
+// that reports the target of a link, as the result of [os.DirFS] does.
+// AddFS returns an error for a symbolic link in any other file system,
+// and for any other file that is neither regular nor a directory.
+func (tw *Writer) AddFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		switch mode := info.Mode(); {
+		case mode.IsRegular(), mode.IsDir():
+		case mode&fs.ModeSymlink != 0:
+			rl, ok := fsys.(interface {
+				ReadLink(name string) (string, error)
+			})
+			if !ok {
+				return fmt.Errorf("archive/tar: cannot add symbolic link %s: file system does not implement ReadLink", name)
+			}
+			if link, err = rl.ReadLink(name); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("archive/tar: cannot add non-regular file %s", name)
+		}
+		h, err := FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		h.Name = name
+		if d.IsDir() {
+			h.Name += "/"
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
 func (tw *Writer) writeUSTARHeader(hdr *Header) error {
 	// Check if we can use USTAR prefix/suffix splitting.
 	var namePrefix string
@@ -8,13 +8,17 @@ import (
 	"bytes"
 	"encoding/hex"
 	"errors"
+	"internal/testenv"
 	"io"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"testing/iotest"
 	"time"
 )
@@ -1333,3 +1337,98 @@ func TestFileWriter(t *testing.T) {
 		}
 	}
 }
+
+func TestWriterAddFS(t *testing.T) {
+	modTime := time.Date(2023, 10, 5, 12, 30, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"file.go":              {Data: []byte("hello"), Mode: 0o644, ModTime: modTime},
+		"subfolder/another.go": {Data: []byte("world"), Mode: 0o755, ModTime: modTime},
+		"subfolder":            {Mode: fs.ModeDir | 0o750, ModTime: modTime},
+	}
+	var buf bytes.Buffer
+	tw := NewWriter(&buf)
+	if err := tw.AddFS(fsys); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Test that we can get the files back from the archive.
+	want := []struct {
+		name     string
+		typeflag byte
+		mode     int64
+		data     string
+	}{
+		{"file.go", TypeReg, 0o644, "hello"},
+		{"subfolder/", TypeDir, 0o750, ""},
+		{"subfolder/another.go", TypeReg, 0o755, "world"},
+	}
+	tr := NewReader(&buf)
+	for _, w := range want {
+		hdr, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name != w.name || hdr.Typeflag != w.typeflag || hdr.Mode != w.mode {
+			t.Errorf("got header %q, type %q, mode %o; want %q, type %q, mode %o",
+				hdr.Name, hdr.Typeflag, hdr.Mode, w.name, w.typeflag, w.mode)
+		}
+		if !hdr.ModTime.Equal(modTime) {
+			t.Errorf("%s: ModTime = %v, want %v", hdr.Name, hdr.ModTime, modTime)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != w.data {
+			t.Errorf("%s: data = %q, want %q", hdr.Name, data, w.data)
+		}
+	}
+	if _, err := tr.Next(); err != io.EOF {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+}
+
+func TestWriterAddFSSymlink(t *testing.T) {
+	testenv.MustHaveSymlink(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("file", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	tw := NewWriter(&buf)
+	if err := tw.AddFS(os.DirFS(dir)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tr := NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			t.Fatal("symbolic link not found in archive")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Name == "link" {
+			if hdr.Typeflag != TypeSymlink || hdr.Linkname != "file" {
+				t.Errorf("link: type %q, Linkname %q; want type %q, Linkname %q", hdr.Typeflag, hdr.Linkname, TypeSymlink, "file")
+			}
+			break
+		}
+	}
+
+	// A symbolic link in a file system without ReadLink is an error.
+	fsys := fstest.MapFS{"link": {Data: []byte("file"), Mode: fs.ModeSymlink | 0o777}}
+	if err := NewWriter(io.Discard).AddFS(fsys); err == nil {
+		t.Error("AddFS of symbolic link without ReadLink succeeded, want error")
+	}
+}
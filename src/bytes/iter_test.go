@@ -0,0 +1,127 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bytes_test
+
+import (
+	. "bytes"
+	"internal/testenv"
+	"testing"
+)
+
+func collect(seq func(func([]byte) bool)) [][]byte {
+	var a [][]byte
+	for s := range seq {
+		a = append(a, s)
+	}
+	return a
+}
+
+func TestSplitSeq(t *testing.T) {
+	for _, tt := range splittests {
+		if tt.n >= 0 {
+			continue // SplitSeq has no limit
+		}
+		a := collect(SplitSeq([]byte(tt.s), []byte(tt.sep)))
+
+		// Appending to the results should not change future results.
+		for _, v := range a {
+			_ = append(v, 'z')
+		}
+
+		if result := sliceOfString(a); !eq(result, tt.a) {
+			t.Errorf("SplitSeq(%q, %q) = %v; want %v", tt.s, tt.sep, result, tt.a)
+		}
+	}
+}
+
+func TestFieldsSeq(t *testing.T) {
+	for _, tt := range fieldstests {
+		a := collect(FieldsSeq([]byte(tt.s)))
+
+		// Appending to the results should not change future results.
+		for _, v := range a {
+			_ = append(v, 'z')
+		}
+
+		if result := sliceOfString(a); !eq(result, tt.a) {
+			t.Errorf("FieldsSeq(%q) = %v; want %v", tt.s, result, tt.a)
+		}
+	}
+}
+
+var linesTests = []struct {
+	s string
+	a []string
+}{
+	{"", nil},
+	{"\n", []string{"\n"}},
+	{"abc", []string{"abc"}},
+	{"abc\n", []string{"abc\n"}},
+	{"abc\ndef", []string{"abc\n", "def"}},
+	{"abc\n\ndef\n", []string{"abc\n", "\n", "def\n"}},
+	{"a\r\nb\r\n", []string{"a\r\n", "b\r\n"}},
+}
+
+func TestLines(t *testing.T) {
+	for _, tt := range linesTests {
+		a := collect(Lines([]byte(tt.s)))
+
+		// Appending to the results should not change future results.
+		for _, v := range a {
+			_ = append(v, 'z')
+		}
+
+		if result := sliceOfString(a); !eq(result, tt.a) {
+			t.Errorf("Lines(%q) = %q; want %q", tt.s, result, tt.a)
+		}
+	}
+}
+
+func TestSeqAllocs(t *testing.T) {
+	testenv.SkipIfOptimizationOff(t)
+
+	s := []byte("the quick\tbrown fox\njumps over\nthe lazy dog\n")
+	sep := []byte(" ")
+	tests := []struct {
+		name string
+		f    func() int
+	}{
+		{"SplitSeq", func() (n int) {
+			for range SplitSeq(s, sep) {
+				n++
+			}
+			return n
+		}},
+		{"SplitSeqEmpty", func() (n int) {
+			for range SplitSeq(s, nil) {
+				n++
+			}
+			return n
+		}},
+		{"FieldsSeq", func() (n int) {
+			for range FieldsSeq(s) {
+				n++
+			}
+			return n
+		}},
+		{"Lines", func() (n int) {
+			for range Lines(s) {
+				n++
+			}
+			return n
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if n := tt.f(); n == 0 {
+				t.Fatalf("%s yielded no values", tt.name)
+			}
+			allocs := testing.AllocsPerRun(100, func() { tt.f() })
+			if allocs != 0 {
+				t.Errorf("ranging over %s allocated %v times; want 0", tt.name, allocs)
+			}
+		})
+	}
+}
@@ -522,6 +522,11 @@ opSwitch:
 				case "getcallerpc", "getcallersp":
 					v.reason = "call to " + fn
 					return true
+				case "deferrangefunc":
+					// Defers added by range-over-func loop bodies
+					// belong to the frame that calls deferrangefunc.
+					v.reason = "defer call in range func"
+					return true
 				case "throw":
 					v.budget -= inlineExtraThrowCost
 					break opSwitch
@@ -12,6 +12,7 @@ import (
 	"sort"
 
 	"cmd/compile/internal/base"
+	"cmd/compile/internal/rangefunc"
 	"cmd/compile/internal/syntax"
 	"cmd/compile/internal/types2"
 	"cmd/internal/src"
@@ -121,6 +122,12 @@ func checkFiles(m posMap, noders []*noder) (*types2.Package, *types2.Info) {
 		base.FatalfAt(src.NoXPos, "conf.Check error: %v", err)
 	}
 
+	// Rewrite range over function to explicit function calls
+	// with the loop bodies converted into new implicit closures.
+	// We do this now, before serialization to unified IR, so that
+	// the implicit closures are handled like any other closure.
+	rangefunc.Rewrite(pkg, info, files)
+
 	return pkg, info
 }
 
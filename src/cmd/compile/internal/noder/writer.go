@@ -1272,6 +1272,9 @@ func (w *writer) stmt1(stmt syntax.Stmt) {
 		w.pos(stmt)
 		w.op(callOps[stmt.Tok])
 		w.expr(stmt.Call)
+		if stmt.Tok == syntax.Defer {
+			w.optExpr(stmt.DeferAt)
+		}
 
 	case *syntax.DeclStmt:
 		for _, decl := range stmt.DeclList {
@@ -1739,6 +1742,15 @@ func (w *writer) expr(expr syntax.Expr) {
 			w.p.fatalf(expr, "unexpected type expression %v", syntax.String(expr))
 		}
 
+		if tv.IsRuntimeHelper() {
+			if pkg := obj.Pkg(); pkg != nil && pkg.Name() == "runtime" {
+				w.Code(exprRuntimeBuiltin)
+				w.String(obj.Name())
+				return
+			}
+			w.p.fatalf(expr, "unexpected runtime helper %v", syntax.String(expr))
+		}
+
 		if tv.Value != nil {
 			w.Code(exprConst)
 			w.pos(expr)
@@ -0,0 +1,799 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package rangefunc rewrites range-over-func loops into code that
+does not use range-over-func. The rewrite happens on the syntax
+tree after type checking and before the tree is serialized to
+unified IR, so the closures it introduces are ordinary closures to
+the rest of the compiler and can be inlined like any other.
+
+# Basic rewrite
+
+The loop
+
+	for x := range f {
+		...
+	}
+
+becomes
+
+	{
+		var #exit1 bool
+		f(func(x T) bool {
+			if #exit1 {
+				runtime.panicrangeexit()
+			}
+			...
+			return true
+		})
+		#exit1 = true
+	}
+
+The loop variables become parameters of the body closure, so each
+iteration has its own copy of them, as with the other for loops in
+Go 1.22. If the range clause assigns to existing variables instead of
+declaring new ones, the closure has fresh parameters #p1, #p2, and
+its body starts by assigning them to the original variables.
+
+The #exit variable records that the loop is done: once the body has
+returned false, or once f has returned, a further call of the body is
+a bug in f and panics.
+
+# Branches and returns
+
+Inside the body closure, "continue" becomes "return true" and "break"
+becomes "#exit1 = true; return false".
+
+A branch to a statement outside the loop body (a labeled break or
+continue of an outer loop, or a goto) and a return statement cannot be
+expressed inside the closure. Instead, the body records the pending
+action in the variable #next, which is shared by all the loops nested
+in the same outermost range-over-func loop, and returns false. After f
+returns, the code that follows the call checks #next and performs the
+action, or, if the target is further out, passes it on by returning
+false from the enclosing loop body.
+
+Return statements use #next = -1 for a bare return and #next = -2
+for a return with results, which are first saved in #r1, #r2, and so
+on. Branches use positive values, one per distinct branch target.
+For example,
+
+	F:
+	for x := range f {
+		for y := range g {
+			if y == 0 {
+				continue F
+			}
+			return x + y
+		}
+	}
+
+becomes
+
+	F: {
+		var #next int
+		var #r1 int
+		var #exit1 bool
+		f(func(x int) bool {
+			if #exit1 {
+				runtime.panicrangeexit()
+			}
+			{
+				var #exit2 bool
+				g(func(y int) bool {
+					if #exit2 {
+						runtime.panicrangeexit()
+					}
+					if y == 0 {
+						#next = 1
+						#exit2 = true
+						return false
+					}
+					{
+						#r1 = x + y
+						#next = -2
+						#exit2 = true
+						return false
+					}
+					return true
+				})
+				#exit2 = true
+				if #next == 1 {
+					#next = 0
+					return true
+				}
+				if #next != 0 {
+					#exit1 = true
+					return false
+				}
+			}
+			return true
+		})
+		#exit1 = true
+		if #next == -2 {
+			#next = 0
+			return #r1
+		}
+	}
+
+# Defers
+
+A defer statement in a loop body must run when the enclosing function
+returns, not when the body closure returns. The outermost loop calls
+runtime.deferrangefunc to obtain a token for the enclosing function's
+frame, and defer statements in the body become calls to
+runtime.deferprocat with that token (recorded as CallStmt.DeferAt):
+
+	{
+		var #defers = runtime.deferrangefunc()
+		...
+		f(func(x T) bool {
+			...
+			defer g() // deferprocat(g, #defers)
+			...
+		})
+		...
+	}
+*/
+package rangefunc
+
+import (
+	"cmd/compile/internal/syntax"
+	"cmd/compile/internal/types2"
+	"fmt"
+	"go/constant"
+	"strconv"
+)
+
+// Rewrite rewrites all the range-over-func loops in the files.
+func Rewrite(pkg *types2.Package, info *types2.Info, files []*syntax.File) {
+	rt := newRuntimeHelpers()
+	for _, file := range files {
+		syntax.Inspect(file, func(n syntax.Node) bool {
+			switch n := n.(type) {
+			case *syntax.FuncDecl:
+				if obj := info.Defs[n.Name]; obj != nil {
+					rewriteFunc(pkg, info, rt, n.Body, obj.Type().(*types2.Signature))
+				}
+				return false
+			case *syntax.FuncLit:
+				rewriteFunc(pkg, info, rt, n.Body, n.GetTypeInfo().Type.(*types2.Signature))
+				return false
+			}
+			return true
+		})
+	}
+}
+
+// A rewriter implements rewriting the range-over-funcs in a single function body.
+type rewriter struct {
+	pkg  *types2.Package
+	info *types2.Info
+	rt   *runtimeHelpers
+	sig  *types2.Signature // signature of the function being rewritten
+	body *syntax.BlockStmt
+
+	// loopOf maps each for, switch, select and labeled statement in
+	// body to the innermost range-over-func loop containing it.
+	loopOf map[syntax.Stmt]*syntax.ForStmt
+
+	stack     []syntax.Node // nodes being visited
+	forStack  []*forLoop    // range-over-func loops being visited
+	rewritten map[*syntax.ForStmt]syntax.Stmt
+	exitCount int // number of #exit variables declared so far
+
+	// Variables and branches shared by the loops nested in the
+	// current outermost range-over-func loop.
+	nextVar    *types2.Var          // #next
+	retVars    []*types2.Var        // #r1, #r2, ...
+	defers     *types2.Var          // #defers
+	branches   []*syntax.BranchStmt // branches[i] is recorded as #next = i+1
+	branchNext map[branchKey]int    // #next values for branches
+}
+
+// A branchKey identifies the destination of a branch statement.
+// A break and a continue of the same loop have different destinations.
+type branchKey struct {
+	target    syntax.Stmt
+	continue_ bool
+}
+
+// A forLoop is a range-over-func loop being rewritten.
+type forLoop struct {
+	nfor   *syntax.ForStmt
+	exit   *types2.Var // #exitN
+	result types2.Type // result type of the body closure
+	checks []int       // #next values to check for after the call
+}
+
+// A runtimeHelpers holds the runtime functions called by rewritten code.
+type runtimeHelpers struct {
+	panicrangeexit *types2.Func
+	deferrangefunc *types2.Func
+}
+
+func newRuntimeHelpers() *runtimeHelpers {
+	pkg := types2.NewPackage("runtime", "runtime")
+	fn := func(name string, results ...*types2.Var) *types2.Func {
+		sig := types2.NewSignatureType(nil, nil, nil, nil, types2.NewTuple(results...), false)
+		return types2.NewFunc(syntax.Pos{}, pkg, name, sig)
+	}
+	anyType := types2.Universe.Lookup("any").Type()
+	return &runtimeHelpers{
+		panicrangeexit: fn("panicrangeexit"),
+		deferrangefunc: fn("deferrangefunc", types2.NewVar(syntax.Pos{}, pkg, "", anyType)),
+	}
+}
+
+// rewriteFunc rewrites the range-over-func loops in body, the body of
+// a function with signature sig, and in the function literals it contains.
+func rewriteFunc(pkg *types2.Package, info *types2.Info, rt *runtimeHelpers, body *syntax.BlockStmt, sig *types2.Signature) {
+	if body == nil {
+		return
+	}
+	r := &rewriter{
+		pkg:  pkg,
+		info: info,
+		rt:   rt,
+		sig:  sig,
+		body: body,
+	}
+	lits, found := r.findLoops()
+	if found {
+		syntax.Inspect(body, r.inspect)
+	}
+	for _, lit := range lits {
+		rewriteFunc(pkg, info, rt, lit.Body, lit.GetTypeInfo().Type.(*types2.Signature))
+	}
+}
+
+// findLoops records in r.loopOf the innermost range-over-func loop
+// containing each potential branch target in the body. It reports
+// whether the body contains any range-over-func loops, and returns
+// the function literals directly contained in the body.
+func (r *rewriter) findLoops() (lits []*syntax.FuncLit, found bool) {
+	var stack []syntax.Node
+	var loops []*syntax.ForStmt
+	syntax.Inspect(r.body, func(n syntax.Node) bool {
+		switch n := n.(type) {
+		case nil:
+			if len(loops) > 0 && stack[len(stack)-1] == loops[len(loops)-1] {
+				loops = loops[:len(loops)-1]
+			}
+			stack = stack[:len(stack)-1]
+			return true
+		case *syntax.FuncLit:
+			lits = append(lits, n)
+			return false
+		case *syntax.ForStmt, *syntax.SwitchStmt, *syntax.SelectStmt, *syntax.LabeledStmt:
+			if len(loops) > 0 {
+				if r.loopOf == nil {
+					r.loopOf = make(map[syntax.Stmt]*syntax.ForStmt)
+				}
+				r.loopOf[n.(syntax.Stmt)] = loops[len(loops)-1]
+			}
+		}
+		stack = append(stack, n)
+		if nfor := rangeFunc(n); nfor != nil {
+			loops = append(loops, nfor)
+			found = true
+		}
+		return true
+	})
+	return lits, found
+}
+
+// rangeFunc returns n as a *syntax.ForStmt if n is a range-over-func loop.
+func rangeFunc(n syntax.Node) *syntax.ForStmt {
+	nfor, ok := n.(*syntax.ForStmt)
+	if !ok {
+		return nil
+	}
+	rclause, ok := nfor.Init.(*syntax.RangeClause)
+	if !ok {
+		return nil
+	}
+	if _, ok := types2.CoreType(rclause.X.GetTypeInfo().Type).(*types2.Signature); !ok {
+		return nil
+	}
+	return nfor
+}
+
+// inspect is the syntax.Inspect callback for the rewrite.
+// Statements are edited after their children have been visited,
+// so that by the time a range-over-func loop is replaced,
+// the loops nested in it have been rewritten already.
+func (r *rewriter) inspect(n syntax.Node) bool {
+	switch n := n.(type) {
+	case *syntax.FuncLit:
+		// Function literals are rewritten separately, by rewriteFunc.
+		return false
+
+	case nil:
+		top := r.stack[len(r.stack)-1]
+		switch top := top.(type) {
+		case *syntax.BlockStmt:
+			r.editStmts(top.List)
+		case *syntax.CaseClause:
+			r.editStmts(top.Body)
+		case *syntax.CommClause:
+			r.editStmts(top.Body)
+		case *syntax.LabeledStmt:
+			top.Stmt = r.editStmt(top.Stmt)
+		}
+		if len(r.forStack) > 0 && top == r.forStack[len(r.forStack)-1].nfor {
+			r.endLoop(r.forStack[len(r.forStack)-1])
+			r.forStack = r.forStack[:len(r.forStack)-1]
+		}
+		r.stack = r.stack[:len(r.stack)-1]
+
+	default:
+		r.stack = append(r.stack, n)
+		if nfor := rangeFunc(n); nfor != nil {
+			r.startLoop(nfor)
+		}
+	}
+	return true
+}
+
+// startLoop starts the rewrite of the range-over-func loop nfor.
+func (r *rewriter) startLoop(nfor *syntax.ForStmt) {
+	rclause := nfor.Init.(*syntax.RangeClause)
+	r.exitCount++
+	loop := &forLoop{
+		nfor:   nfor,
+		exit:   r.newVar(nfor.Pos(), fmt.Sprintf("#exit%d", r.exitCount), types2.Typ[types2.Bool]),
+		result: yieldType(rclause).Results().At(0).Type(),
+	}
+	r.forStack = append(r.forStack, loop)
+}
+
+// yieldType returns the type of the yield function
+// passed to the function ranged over by rclause.
+func yieldType(rclause *syntax.RangeClause) *types2.Signature {
+	ftyp := types2.CoreType(rclause.X.GetTypeInfo().Type).(*types2.Signature)
+	return types2.CoreType(ftyp.Params().At(0).Type()).(*types2.Signature)
+}
+
+func (r *rewriter) editStmts(list []syntax.Stmt) {
+	for i, s := range list {
+		list[i] = r.editStmt(s)
+	}
+}
+
+// editStmt returns the replacement for the statement x.
+func (r *rewriter) editStmt(x syntax.Stmt) syntax.Stmt {
+	if x, ok := x.(*syntax.ForStmt); ok {
+		if s := r.rewritten[x]; s != nil {
+			return s
+		}
+	}
+	if len(r.forStack) > 0 {
+		switch x := x.(type) {
+		case *syntax.BranchStmt:
+			return r.editBranch(x)
+		case *syntax.CallStmt:
+			if x.Tok == syntax.Defer {
+				return r.editDefer(x)
+			}
+		case *syntax.ReturnStmt:
+			return r.editReturn(x)
+		}
+	}
+	return x
+}
+
+// editBranch returns the replacement for the branch statement x
+// in the body of the innermost range-over-func loop.
+func (r *rewriter) editBranch(x *syntax.BranchStmt) syntax.Stmt {
+	if x.Tok == syntax.Fallthrough {
+		return x // never leaves the enclosing switch
+	}
+	loop := r.forStack[len(r.forStack)-1]
+	pos := x.Pos()
+	if x.Target == loop.nfor {
+		if x.Tok == syntax.Continue {
+			return r.returnBool(pos, loop, true)
+		}
+		return r.block(pos, r.exitStmts(pos, loop)...)
+	}
+	if r.levelOf(x.Target) == loop.nfor {
+		return x // the target is in the loop body
+	}
+
+	key := branchKey{x.Target, x.Tok == syntax.Continue}
+	next, ok := r.branchNext[key]
+	if !ok {
+		if r.branchNext == nil {
+			r.branchNext = make(map[branchKey]int)
+		}
+		r.branches = append(r.branches, x)
+		next = len(r.branches)
+		r.branchNext[key] = next
+	}
+	loop.addCheck(next)
+	return r.block(pos, append([]syntax.Stmt{r.setNext(pos, next)}, r.exitStmts(pos, loop)...)...)
+}
+
+// editDefer returns the replacement for the defer statement x
+// in the body of a range-over-func loop.
+func (r *rewriter) editDefer(x *syntax.CallStmt) syntax.Stmt {
+	if r.defers == nil {
+		r.defers = r.newVar(r.forStack[0].nfor.Pos(), "#defers", types2.Universe.Lookup("any").Type())
+	}
+	x.DeferAt = r.useVar(x.Pos(), r.defers)
+	return x
+}
+
+// editReturn returns the replacement for the return statement x
+// in the body of the innermost range-over-func loop.
+func (r *rewriter) editReturn(x *syntax.ReturnStmt) syntax.Stmt {
+	loop := r.forStack[len(r.forStack)-1]
+	pos := x.Pos()
+	var list []syntax.Stmt
+	next := -1
+	if x.Results != nil {
+		next = -2
+		if r.retVars == nil {
+			results := r.sig.Results()
+			for i := 0; i < results.Len(); i++ {
+				v := r.newVar(r.forStack[0].nfor.Pos(), fmt.Sprintf("#r%d", i+1), results.At(i).Type())
+				r.retVars = append(r.retVars, v)
+			}
+		}
+		list = append(list, r.assign(pos, r.useList(pos, r.retVars), x.Results))
+	}
+	loop.addCheck(next)
+	list = append(list, r.setNext(pos, next))
+	list = append(list, r.exitStmts(pos, loop)...)
+	return r.block(pos, list...)
+}
+
+// levelOf returns the range-over-func loop whose body contains the
+// branch target, or nil if the target is outside all loops. A break
+// or continue of a range-over-func loop takes effect in that loop's body.
+func (r *rewriter) levelOf(target syntax.Stmt) *syntax.ForStmt {
+	for _, loop := range r.forStack {
+		if loop.nfor == target {
+			return loop.nfor
+		}
+	}
+	return r.loopOf[target]
+}
+
+// addCheck records that the code following the call of loop's
+// range function must check for #next == next.
+func (loop *forLoop) addCheck(next int) {
+	for _, c := range loop.checks {
+		if c == next {
+			return
+		}
+	}
+	loop.checks = append(loop.checks, next)
+}
+
+// endLoop finishes the rewrite of the innermost range-over-func loop,
+// recording the block that replaces it in r.rewritten.
+func (r *rewriter) endLoop(loop *forLoop) {
+	nfor := loop.nfor
+	start, end := nfor.Pos(), nfor.Body.Rbrace
+	rclause := nfor.Init.(*syntax.RangeClause)
+
+	call := &syntax.CallExpr{
+		Fun:     rclause.X,
+		ArgList: []syntax.Expr{r.bodyFunc(loop, rclause)},
+	}
+	call.SetPos(start)
+	tv := syntax.TypeAndValue{Type: (*types2.Tuple)(nil)}
+	tv.SetIsVoid()
+	call.SetTypeInfo(tv)
+
+	var list []syntax.Stmt
+	list = append(list, r.declStmt(start, loop.exit, nil))
+	list = append(list, r.exprStmt(start, call))
+	list = append(list, r.assign(end, r.useVar(end, loop.exit), r.boolConst(end, types2.Typ[types2.Bool], true)))
+	list = append(list, r.checks(end, loop)...)
+
+	if len(r.forStack) == 1 {
+		// Outermost loop: declare the shared variables.
+		var decls []syntax.Stmt
+		if r.nextVar != nil {
+			decls = append(decls, r.declStmt(start, r.nextVar, nil))
+		}
+		for _, v := range r.retVars {
+			decls = append(decls, r.declStmt(start, v, nil))
+		}
+		if r.defers != nil {
+			decls = append(decls, r.declStmt(start, r.defers, r.callRuntime(start, r.rt.deferrangefunc)))
+		}
+		list = append(decls, list...)
+
+		r.nextVar = nil
+		r.retVars = nil
+		r.defers = nil
+		r.branches = nil
+		r.branchNext = nil
+	}
+
+	block := &syntax.BlockStmt{List: list, Rbrace: end}
+	block.SetPos(start)
+	if r.rewritten == nil {
+		r.rewritten = make(map[*syntax.ForStmt]syntax.Stmt)
+	}
+	r.rewritten[nfor] = block
+}
+
+// bodyFunc returns the closure that replaces the body of loop.
+func (r *rewriter) bodyFunc(loop *forLoop, rclause *syntax.RangeClause) *syntax.FuncLit {
+	nfor := loop.nfor
+	start := nfor.Body.Pos()
+	yield := yieldType(rclause)
+	lhs := unpackListExpr(rclause.Lhs)
+
+	var (
+		params []*types2.Var
+		dsts   []syntax.Expr // assignment form: original variables
+		srcs   []syntax.Expr // assignment form: parameters
+	)
+	for i := 0; i < yield.Params().Len(); i++ {
+		var param *types2.Var
+		if i < len(lhs) && rclause.Def {
+			param = r.info.Defs[lhs[i].(*syntax.Name)].(*types2.Var)
+		} else {
+			param = r.newVar(rclause.Pos(), fmt.Sprintf("#p%d", i+1), yield.Params().At(i).Type())
+			if i < len(lhs) && !isBlank(lhs[i]) {
+				dsts = append(dsts, lhs[i])
+				srcs = append(srcs, r.useVar(rclause.Pos(), param))
+			}
+		}
+		params = append(params, param)
+	}
+	result := types2.NewVar(start, r.pkg, "", loop.result)
+	sig := types2.NewSignatureType(nil, nil, nil, types2.NewTuple(params...), types2.NewTuple(result), yield.Variadic())
+
+	// if #exitN { runtime.panicrangeexit() }
+	list := []syntax.Stmt{
+		r.ifStmt(start, r.useVar(start, loop.exit), r.exprStmt(start, r.callRuntime(start, r.rt.panicrangeexit))),
+	}
+	if len(dsts) > 0 {
+		list = append(list, r.assign(rclause.Pos(), listExpr(dsts), listExpr(srcs)))
+	}
+	list = append(list, nfor.Body.List...)
+	list = append(list, r.returnBool(nfor.Body.Rbrace, loop, true))
+
+	ftyp := &syntax.FuncType{}
+	ftyp.SetPos(start)
+	body := &syntax.BlockStmt{List: list, Rbrace: nfor.Body.Rbrace}
+	body.SetPos(start)
+	lit := &syntax.FuncLit{Type: ftyp, Body: body}
+	lit.SetPos(start)
+	tv := syntax.TypeAndValue{Type: sig}
+	tv.SetIsValue()
+	lit.SetTypeInfo(tv)
+	return lit
+}
+
+// checks returns the statements that follow the call of loop's range
+// function, which carry out the branches and returns recorded in #next.
+func (r *rewriter) checks(pos syntax.Pos, loop *forLoop) []syntax.Stmt {
+	var parent *forLoop
+	var level *syntax.ForStmt
+	if n := len(r.forStack); n > 1 {
+		parent = r.forStack[n-2]
+		level = parent.nfor
+	}
+
+	var list []syntax.Stmt
+	propagate := false
+	for _, next := range loop.checks {
+		var action []syntax.Stmt
+		switch {
+		case next == -1:
+			if parent != nil {
+				parent.addCheck(next)
+				propagate = true
+				continue
+			}
+			action = []syntax.Stmt{r.returnStmt(pos, nil)}
+		case next == -2:
+			if parent != nil {
+				parent.addCheck(next)
+				propagate = true
+				continue
+			}
+			action = []syntax.Stmt{r.returnStmt(pos, r.useList(pos, r.retVars))}
+		default:
+			b := r.branches[next-1]
+			if r.levelOf(b.Target) != level {
+				parent.addCheck(next)
+				propagate = true
+				continue
+			}
+			switch {
+			case parent != nil && b.Target == parent.nfor && b.Tok == syntax.Continue:
+				action = []syntax.Stmt{r.returnBool(pos, parent, true)}
+			case parent != nil && b.Target == parent.nfor:
+				action = r.exitStmts(pos, parent)
+			default:
+				action = []syntax.Stmt{b}
+			}
+		}
+		// if #next == next { #next = 0; action }
+		cond := r.compare(pos, syntax.Eql, r.useVar(pos, r.nextVar), r.intConst(pos, next))
+		list = append(list, r.ifStmt(pos, cond, append([]syntax.Stmt{r.setNext(pos, 0)}, action...)...))
+	}
+	if propagate {
+		// if #next != 0 { #exitP = true; return false }
+		cond := r.compare(pos, syntax.Neq, r.useVar(pos, r.nextVar), r.intConst(pos, 0))
+		list = append(list, r.ifStmt(pos, cond, r.exitStmts(pos, parent)...))
+	}
+	return list
+}
+
+// exitStmts returns the statements that leave the body of loop:
+// "#exitN = true; return false".
+func (r *rewriter) exitStmts(pos syntax.Pos, loop *forLoop) []syntax.Stmt {
+	return []syntax.Stmt{
+		r.assign(pos, r.useVar(pos, loop.exit), r.boolConst(pos, types2.Typ[types2.Bool], true)),
+		r.returnBool(pos, loop, false),
+	}
+}
+
+// setNext returns the statement "#next = next".
+func (r *rewriter) setNext(pos syntax.Pos, next int) syntax.Stmt {
+	if r.nextVar == nil {
+		r.nextVar = r.newVar(r.forStack[0].nfor.Pos(), "#next", types2.Typ[types2.Int])
+	}
+	return r.assign(pos, r.useVar(pos, r.nextVar), r.intConst(pos, next))
+}
+
+// newVar returns a new local variable for use in rewritten code.
+func (r *rewriter) newVar(pos syntax.Pos, name string, typ types2.Type) *types2.Var {
+	return types2.NewVar(pos, r.pkg, name, typ)
+}
+
+// useVar returns a reference to the variable v.
+func (r *rewriter) useVar(pos syntax.Pos, v *types2.Var) *syntax.Name {
+	n := syntax.NewName(pos, v.Name())
+	r.info.Uses[n] = v
+	tv := syntax.TypeAndValue{Type: v.Type()}
+	tv.SetIsValue()
+	tv.SetAddressable()
+	tv.SetAssignable()
+	n.SetTypeInfo(tv)
+	return n
+}
+
+// useList returns a reference to the variables vars, as a list if needed.
+func (r *rewriter) useList(pos syntax.Pos, vars []*types2.Var) syntax.Expr {
+	var list []syntax.Expr
+	for _, v := range vars {
+		list = append(list, r.useVar(pos, v))
+	}
+	return listExpr(list)
+}
+
+// declStmt returns the statement "var v = init", or "var v" if init is nil.
+func (r *rewriter) declStmt(pos syntax.Pos, v *types2.Var, init syntax.Expr) syntax.Stmt {
+	n := syntax.NewName(pos, v.Name())
+	r.info.Defs[n] = v
+	d := &syntax.VarDecl{NameList: []*syntax.Name{n}, Values: init}
+	d.SetPos(pos)
+	s := &syntax.DeclStmt{DeclList: []syntax.Decl{d}}
+	s.SetPos(pos)
+	return s
+}
+
+// callRuntime returns a call of the runtime function fn, which takes no arguments.
+func (r *rewriter) callRuntime(pos syntax.Pos, fn *types2.Func) *syntax.CallExpr {
+	name := syntax.NewName(pos, fn.Name())
+	r.info.Uses[name] = fn
+	tv := syntax.TypeAndValue{Type: fn.Type()}
+	tv.SetIsValue()
+	tv.SetIsRuntimeHelper()
+	name.SetTypeInfo(tv)
+
+	call := &syntax.CallExpr{Fun: name}
+	call.SetPos(pos)
+	tv = syntax.TypeAndValue{Type: (*types2.Tuple)(nil)}
+	if results := fn.Type().(*types2.Signature).Results(); results.Len() == 1 {
+		tv.Type = results.At(0).Type()
+		tv.SetIsValue()
+	} else {
+		tv.SetIsVoid()
+	}
+	call.SetTypeInfo(tv)
+	return call
+}
+
+// returnBool returns the statement "return b" for the body closure of loop.
+func (r *rewriter) returnBool(pos syntax.Pos, loop *forLoop, b bool) syntax.Stmt {
+	return r.returnStmt(pos, r.boolConst(pos, loop.result, b))
+}
+
+func (r *rewriter) returnStmt(pos syntax.Pos, results syntax.Expr) *syntax.ReturnStmt {
+	s := &syntax.ReturnStmt{Results: results}
+	s.SetPos(pos)
+	return s
+}
+
+func (r *rewriter) assign(pos syntax.Pos, lhs, rhs syntax.Expr) *syntax.AssignStmt {
+	s := &syntax.AssignStmt{Lhs: lhs, Rhs: rhs}
+	s.SetPos(pos)
+	return s
+}
+
+func (r *rewriter) exprStmt(pos syntax.Pos, x syntax.Expr) *syntax.ExprStmt {
+	s := &syntax.ExprStmt{X: x}
+	s.SetPos(pos)
+	return s
+}
+
+func (r *rewriter) block(pos syntax.Pos, list ...syntax.Stmt) *syntax.BlockStmt {
+	b := &syntax.BlockStmt{List: list, Rbrace: pos}
+	b.SetPos(pos)
+	return b
+}
+
+func (r *rewriter) ifStmt(pos syntax.Pos, cond syntax.Expr, then ...syntax.Stmt) *syntax.IfStmt {
+	s := &syntax.IfStmt{Cond: cond, Then: r.block(pos, then...)}
+	s.SetPos(pos)
+	return s
+}
+
+// compare returns the comparison "x op y".
+func (r *rewriter) compare(pos syntax.Pos, op syntax.Operator, x, y syntax.Expr) *syntax.Operation {
+	e := &syntax.Operation{Op: op, X: x, Y: y}
+	e.SetPos(pos)
+	tv := syntax.TypeAndValue{Type: types2.Typ[types2.UntypedBool]}
+	tv.SetIsValue()
+	e.SetTypeInfo(tv)
+	return e
+}
+
+// intConst returns the constant x of type int.
+func (r *rewriter) intConst(pos syntax.Pos, x int) *syntax.BasicLit {
+	lit := &syntax.BasicLit{Value: strconv.Itoa(x), Kind: syntax.IntLit}
+	lit.SetPos(pos)
+	tv := syntax.TypeAndValue{Type: types2.Typ[types2.Int], Value: constant.MakeInt64(int64(x))}
+	tv.SetIsValue()
+	lit.SetTypeInfo(tv)
+	return lit
+}
+
+// boolConst returns the constant b of the boolean type typ.
+func (r *rewriter) boolConst(pos syntax.Pos, typ types2.Type, b bool) *syntax.Name {
+	n := syntax.NewName(pos, strconv.FormatBool(b))
+	tv := syntax.TypeAndValue{Type: typ, Value: constant.MakeBool(b)}
+	tv.SetIsValue()
+	n.SetTypeInfo(tv)
+	return n
+}
+
+func isBlank(x syntax.Expr) bool {
+	name, ok := x.(*syntax.Name)
+	return ok && name.Value == "_"
+}
+
+// listExpr returns list as a single expression.
+func listExpr(list []syntax.Expr) syntax.Expr {
+	if len(list) == 1 {
+		return list[0]
+	}
+	l := &syntax.ListExpr{ElemList: list}
+	l.SetPos(list[0].Pos())
+	return l
+}
+
+// unpackListExpr returns the expressions in x, which may be a list.
+func unpackListExpr(x syntax.Expr) []syntax.Expr {
+	switch x := x.(type) {
+	case nil:
+		return nil
+	case *syntax.ListExpr:
+		return x.ElemList
+	}
+	return []syntax.Expr{x}
+}
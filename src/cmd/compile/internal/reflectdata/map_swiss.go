@@ -0,0 +1,111 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reflectdata
+
+import (
+	"cmd/compile/internal/base"
+	"cmd/compile/internal/ir"
+	"cmd/compile/internal/types"
+	"cmd/internal/src"
+)
+
+// The Swiss table map implementation, enabled by GOEXPERIMENT=swissmap,
+// uses a variant of the bucket type of the classic implementation for
+// its groups (see MapBucketType), and different map header and
+// iterator types.
+
+// swissMapType returns a type interchangeable with runtime.hmap
+// when the swissmap experiment is enabled.
+// Make sure this stays in sync with runtime/map_swiss.go.
+func swissMapType() *types.Type {
+	// build a struct:
+	// type hmap struct {
+	//    count       int
+	//    flags       uint8
+	//    globalDepth uint8
+	//    globalShift uint8
+	//    seed        uintptr
+	//    dirPtr      unsafe.Pointer
+	//    dirLen      int
+	//    clearSeq    uint64
+	// }
+	// must match runtime/map_swiss.go:hmap.
+	fields := []*types.Field{
+		makefield("count", types.Types[types.TINT]),
+		makefield("flags", types.Types[types.TUINT8]),
+		makefield("globalDepth", types.Types[types.TUINT8]),
+		makefield("globalShift", types.Types[types.TUINT8]),
+		makefield("seed", types.Types[types.TUINTPTR]),     // Used in walk.go for OMAKEMAP.
+		makefield("dirPtr", types.Types[types.TUNSAFEPTR]), // Used in walk.go for OMAKEMAP.
+		makefield("dirLen", types.Types[types.TINT]),
+		makefield("clearSeq", types.Types[types.TUINT64]),
+	}
+
+	n := ir.NewDeclNameAt(src.NoXPos, ir.OTYPE, ir.Pkgs.Runtime.Lookup("hmap"))
+	hmap := types.NewNamed(n)
+	n.SetType(hmap)
+	n.SetTypecheck(1)
+
+	hmap.SetUnderlying(types.NewStruct(fields))
+	types.CalcSize(hmap)
+
+	// The size of hmap should be 48 bytes on 64 bit
+	// and 28 bytes on 32 bit platforms.
+	if size := int64(8 + 5*types.PtrSize); hmap.Size() != size {
+		base.Fatalf("hmap size not correct: got %d, want %d", hmap.Size(), size)
+	}
+	return hmap
+}
+
+// swissMapIterType returns a type interchangeable with runtime.hiter
+// when the swissmap experiment is enabled.
+// Make sure this stays in sync with runtime/map_swiss.go.
+func swissMapIterType() *types.Type {
+	hmap := MapType()
+
+	// build a struct:
+	// type hiter struct {
+	//    key         unsafe.Pointer // *Key
+	//    elem        unsafe.Pointer // *Elem
+	//    t           unsafe.Pointer // *MapType
+	//    h           *hmap
+	//    group       unsafe.Pointer // *bmap
+	//    tab         unsafe.Pointer // *table
+	//    entryOffset uintptr
+	//    entryIdx    uintptr
+	//    dirOffset   uintptr
+	//    dirIdx      uintptr
+	//    clearSeq    uint64
+	//    globalDepth uint8
+	// }
+	// must match runtime/map_swiss.go:hiter.
+	fields := []*types.Field{
+		makefield("key", types.Types[types.TUNSAFEPTR]),  // Used in range.go for TMAP.
+		makefield("elem", types.Types[types.TUNSAFEPTR]), // Used in range.go for TMAP.
+		makefield("t", types.Types[types.TUNSAFEPTR]),
+		makefield("h", types.NewPtr(hmap)),
+		makefield("group", types.Types[types.TUNSAFEPTR]),
+		makefield("tab", types.Types[types.TUNSAFEPTR]),
+		makefield("entryOffset", types.Types[types.TUINTPTR]),
+		makefield("entryIdx", types.Types[types.TUINTPTR]),
+		makefield("dirOffset", types.Types[types.TUINTPTR]),
+		makefield("dirIdx", types.Types[types.TUINTPTR]),
+		makefield("clearSeq", types.Types[types.TUINT64]),
+		makefield("globalDepth", types.Types[types.TUINT8]),
+	}
+
+	// build iterator struct holding the above fields
+	n := ir.NewDeclNameAt(src.NoXPos, ir.OTYPE, ir.Pkgs.Runtime.Lookup("hiter"))
+	hiter := types.NewNamed(n)
+	n.SetType(hiter)
+	n.SetTypecheck(1)
+
+	hiter.SetUnderlying(types.NewStruct(fields))
+	types.CalcSize(hiter)
+	if size := int64(8 + 11*types.PtrSize); hiter.Size() != size {
+		base.Fatalf("hash_iter size not correct %d %d", hiter.Size(), size)
+	}
+	return hiter
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"internal/abi"
+	"internal/buildcfg"
 	"os"
 	"sort"
 	"strings"
@@ -65,6 +66,9 @@ type typeSig struct {
 //	      elems [BUCKETSIZE]elemType
 //	      overflow *bucket
 //	    }
+//
+// With GOEXPERIMENT=swissmap, a bucket is a Swiss table group, which
+// has a uint64 control word in place of tophash and no overflow field.
 const (
 	BUCKETSIZE  = abi.MapBucketCount
 	MAXKEYSIZE  = abi.MapMaxKeyBytes
@@ -106,11 +110,18 @@ func MapBucketType(t *types.Type) *types.Type {
 
 	field := make([]*types.Field, 0, 5)
 
-	// The first field is: uint8 topbits[BUCKETSIZE].
-	arr := types.NewArray(types.Types[types.TUINT8], BUCKETSIZE)
-	field = append(field, makefield("topbits", arr))
+	if buildcfg.Experiment.SwissMap {
+		// The first field of a Swiss table group is its control word,
+		// holding one control byte per slot. It is a uint64 so that
+		// the runtime can load it in one go.
+		field = append(field, makefield("ctrl", types.Types[types.TUINT64]))
+	} else {
+		// The first field is: uint8 topbits[BUCKETSIZE].
+		arr := types.NewArray(types.Types[types.TUINT8], BUCKETSIZE)
+		field = append(field, makefield("topbits", arr))
+	}
 
-	arr = types.NewArray(keytype, BUCKETSIZE)
+	arr := types.NewArray(keytype, BUCKETSIZE)
 	arr.SetNoalg(true)
 	keys := makefield("keys", arr)
 	field = append(field, keys)
@@ -125,13 +136,17 @@ func MapBucketType(t *types.Type) *types.Type {
 	// buckets can be marked as having no pointers.
 	// Arrange for the bucket to have no pointers by changing
 	// the type of the overflow field to uintptr in this case.
-	// See comment on hmap.overflow in runtime/map.go.
-	otyp := types.Types[types.TUNSAFEPTR]
-	if !elemtype.HasPointers() && !keytype.HasPointers() {
-		otyp = types.Types[types.TUINTPTR]
+	// See comment on hmap.overflow in runtime/map_noswiss.go.
+	// Swiss table groups have no overflow field.
+	var overflow *types.Field
+	if !buildcfg.Experiment.SwissMap {
+		otyp := types.Types[types.TUNSAFEPTR]
+		if !elemtype.HasPointers() && !keytype.HasPointers() {
+			otyp = types.Types[types.TUINTPTR]
+		}
+		overflow = makefield("overflow", otyp)
+		field = append(field, overflow)
 	}
-	overflow := makefield("overflow", otyp)
-	field = append(field, overflow)
 
 	// link up fields
 	bucket := types.NewStruct(field[:])
@@ -184,7 +199,7 @@ func MapBucketType(t *types.Type) *types.Type {
 
 	// Double-check that overflow field is final memory in struct,
 	// with no padding at end.
-	if overflow.Offset != bucket.Size()-int64(types.PtrSize) {
+	if overflow != nil && overflow.Offset != bucket.Size()-int64(types.PtrSize) {
 		base.Fatalf("bad offset of overflow in bmap for %v, overflow.Offset=%d, bucket.Size()-int64(types.PtrSize)=%d",
 			t, overflow.Offset, bucket.Size()-int64(types.PtrSize))
 	}
@@ -203,6 +218,10 @@ func MapType() *types.Type {
 	if hmapType != nil {
 		return hmapType
 	}
+	if buildcfg.Experiment.SwissMap {
+		hmapType = swissMapType()
+		return hmapType
+	}
 
 	// build a struct:
 	// type hmap struct {
@@ -255,6 +274,10 @@ func MapIterType() *types.Type {
 	if hiterType != nil {
 		return hiterType
 	}
+	if buildcfg.Experiment.SwissMap {
+		hiterType = swissMapIterType()
+		return hiterType
+	}
 
 	hmap := MapType()
 
@@ -781,6 +781,10 @@ func init() {
 		{name: "PXOR", argLength: 2, reg: fp21, asm: "PXOR", commutative: true, resultInArg0: true}, // exclusive or, applied to X regs (for float negation).
 		{name: "POR", argLength: 2, reg: fp21, asm: "POR", commutative: true, resultInArg0: true},   // inclusive or, applied to X regs (for float min/max).
 
+		// Byte-wise operations on the low 8 bytes of X regs, for matching map group control words.
+		{name: "PCMPEQB", argLength: 2, reg: fp21, asm: "PCMPEQB", commutative: true, resultInArg0: true}, // set each byte to 0xff if the bytes of arg0 and arg1 are equal, else 0
+		{name: "PMOVMSKB", argLength: 1, reg: fpgp, asm: "PMOVMSKB", typ: "UInt32"},                       // bit i of result = high bit of byte i of arg0, for i in [0, 16)
+
 		{name: "LEAQ", argLength: 1, reg: gp11sb, asm: "LEAQ", aux: "SymOff", rematerializeable: true, symEffect: "Addr"}, // arg0 + auxint + offset encoded in aux
 		{name: "LEAL", argLength: 1, reg: gp11sb, asm: "LEAL", aux: "SymOff", rematerializeable: true, symEffect: "Addr"}, // arg0 + auxint + offset encoded in aux
 		{name: "LEAW", argLength: 1, reg: gp11sb, asm: "LEAW", aux: "SymOff", rematerializeable: true, symEffect: "Addr"}, // arg0 + auxint + offset encoded in aux
@@ -102,6 +102,7 @@ func InitConfig() {
 	ir.Syms.CgoCheckPtrWrite = typecheck.LookupRuntimeFunc("cgoCheckPtrWrite")
 	ir.Syms.CheckPtrAlignment = typecheck.LookupRuntimeFunc("checkptrAlignment")
 	ir.Syms.Deferproc = typecheck.LookupRuntimeFunc("deferproc")
+	ir.Syms.Deferprocat = typecheck.LookupRuntimeFunc("deferprocat")
 	ir.Syms.DeferprocStack = typecheck.LookupRuntimeFunc("deferprocStack")
 	ir.Syms.Deferreturn = typecheck.LookupRuntimeFunc("deferreturn")
 	ir.Syms.Duffcopy = typecheck.LookupRuntimeFunc("duffcopy")
@@ -1448,7 +1449,7 @@ func (s *state) stmt(n ir.Node) {
 		s.callResult(n, callNormal)
 		if n.Op() == ir.OCALLFUNC && n.X.Op() == ir.ONAME && n.X.(*ir.Name).Class == ir.PFUNC {
 			if fn := n.X.Sym().Name; base.Flag.CompilingRuntime && fn == "throw" ||
-				n.X.Sym().Pkg == ir.Pkgs.Runtime && (fn == "throwinit" || fn == "gopanic" || fn == "panicwrap" || fn == "block" || fn == "panicmakeslicelen" || fn == "panicmakeslicecap" || fn == "panicunsafeslicelen" || fn == "panicunsafeslicenilptr" || fn == "panicunsafestringlen" || fn == "panicunsafestringnilptr") {
+				n.X.Sym().Pkg == ir.Pkgs.Runtime && (fn == "throwinit" || fn == "gopanic" || fn == "panicwrap" || fn == "block" || fn == "panicmakeslicelen" || fn == "panicmakeslicecap" || fn == "panicunsafeslicelen" || fn == "panicunsafeslicenilptr" || fn == "panicunsafestringlen" || fn == "panicunsafestringnilptr" || fn == "panicrangeexit") {
 				m := s.mem()
 				b := s.endBlock()
 				b.Kind = ssa.BlockExit
@@ -1475,10 +1476,10 @@ func (s *state) stmt(n ir.Node) {
 			s.openDeferRecord(n.Call.(*ir.CallExpr))
 		} else {
 			d := callDefer
-			if n.Esc() == ir.EscNever {
+			if n.Esc() == ir.EscNever && n.DeferAt == nil {
 				d = callDeferStack
 			}
-			s.callResult(n.Call.(*ir.CallExpr), d)
+			s.call(n.Call.(*ir.CallExpr), d, false, n.DeferAt)
 		}
 	case ir.OGO:
 		n := n.(*ir.GoDeferStmt)
@@ -4133,6 +4134,44 @@ func InitTables() {
 		},
 		sys.ARM64, sys.PPC64)
 
+	if buildcfg.Experiment.SwissMap {
+		// Match all the control bytes of a map group at once with SSE2.
+		// The control word and the byte to match are zero-extended
+		// into X registers, so only the low 8 bits of the mask count.
+		// Keep in sync with runtime/map_swiss.go and map_swiss_amd64.go.
+		ctrlMask := func(s *state, v *ssa.Value) *ssa.Value {
+			x := s.newValue1(ssa.OpAMD64MOVQi2f, types.Types[types.TFLOAT64], v)
+			return s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT32], x)
+		}
+		ctrlMatch := func(s *state, ctrl, b *ssa.Value) *ssa.Value {
+			x := s.newValue1(ssa.OpAMD64MOVQi2f, types.Types[types.TFLOAT64], ctrl)
+			y := s.newValue1(ssa.OpAMD64MOVQi2f, types.Types[types.TFLOAT64], b)
+			eq := s.newValue2(ssa.OpAMD64PCMPEQB, types.Types[types.TFLOAT64], x, y)
+			mask := s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT32], eq)
+			return s.newValue1(ssa.OpZeroExt8to64, types.Types[types.TUINT64], s.newValue1(ssa.OpTrunc32to8, types.Types[types.TUINT8], mask))
+		}
+		addF("runtime", "ctrlGroup.matchH2",
+			func(s *state, n *ir.CallExpr, args []*ssa.Value) *ssa.Value {
+				// Broadcast ctrlFull|h2 to all the bytes of a word.
+				b := s.newValue2(ssa.OpOr64, types.Types[types.TUINT64], args[1], s.constInt64(types.Types[types.TUINT64], 0x80))
+				b = s.newValue2(ssa.OpMul64, types.Types[types.TUINT64], b, s.constInt64(types.Types[types.TUINT64], 0x0101010101010101))
+				return ctrlMatch(s, args[0], b)
+			},
+			sys.AMD64)
+		addF("runtime", "ctrlGroup.matchEmpty",
+			func(s *state, n *ir.CallExpr, args []*ssa.Value) *ssa.Value {
+				return ctrlMatch(s, args[0], s.constInt64(types.Types[types.TUINT64], 0))
+			},
+			sys.AMD64)
+		addF("runtime", "ctrlGroup.matchEmptyOrDeleted",
+			func(s *state, n *ir.CallExpr, args []*ssa.Value) *ssa.Value {
+				// Empty and deleted control bytes have the high bit clear.
+				mask := s.newValue1(ssa.OpZeroExt32to64, types.Types[types.TUINT64], ctrlMask(s, args[0]))
+				return s.newValue2(ssa.OpXor64, types.Types[types.TUINT64], mask, s.constInt64(types.Types[types.TUINT64], 0xff))
+			},
+			sys.AMD64)
+	}
+
 	brev_arch := []sys.ArchFamily{sys.AMD64, sys.I386, sys.ARM64, sys.ARM, sys.S390X}
 	if buildcfg.GOPPC64 >= 10 {
 		// Use only on Power10 as the new byte reverse instructions that Power10 provide
@@ -4974,6 +5013,12 @@ func IsIntrinsicCall(n *ir.CallExpr) bool {
 	if n == nil {
 		return false
 	}
+	if n.X.Op() == ir.OMETHEXPR {
+		// Method calls are still method expressions
+		// when inlining happens (see walkExpr1).
+		sel := n.X.(*ir.SelectorExpr)
+		return findIntrinsic(ir.MethodSym(sel.X.Type(), sel.Sel)) != nil
+	}
 	name, ok := n.X.(*ir.Name)
 	if !ok {
 		return false
@@ -5166,16 +5211,18 @@ func (s *state) openDeferExit() {
 }
 
 func (s *state) callResult(n *ir.CallExpr, k callKind) *ssa.Value {
-	return s.call(n, k, false)
+	return s.call(n, k, false, nil)
 }
 
 func (s *state) callAddr(n *ir.CallExpr, k callKind) *ssa.Value {
-	return s.call(n, k, true)
+	return s.call(n, k, true, nil)
 }
 
 // Calls the function n using the specified call type.
 // Returns the address of the return value (or nil if none).
-func (s *state) call(n *ir.CallExpr, k callKind, returnResultAddr bool) *ssa.Value {
+// If deferExtra is non-nil, n must be a deferred call, and deferExtra
+// is passed to runtime.deferprocat along with the closure.
+func (s *state) call(n *ir.CallExpr, k callKind, returnResultAddr bool, deferExtra ir.Expr) *ssa.Value {
 	s.prevCall = nil
 	var callee *ir.Name    // target function (if static)
 	var closure *ssa.Value // ptr to closure to run (if dynamic)
@@ -5192,6 +5239,14 @@ func (s *state) call(n *ir.CallExpr, k callKind, returnResultAddr bool) *ssa.Val
 		s.Fatalf("go/defer call with arguments: %v", n)
 	}
 
+	var dextra *ssa.Value
+	if deferExtra != nil {
+		if k != callDefer {
+			s.Fatalf("extra defer argument for non-heap defer call: %v", n)
+		}
+		dextra = s.expr(deferExtra)
+	}
+
 	switch n.Op() {
 	case ir.OCALLFUNC:
 		if (k == callNormal || k == callTail) && fn.Op() == ir.ONAME && fn.(*ir.Name).Class == ir.PFUNC {
@@ -5284,6 +5339,13 @@ func (s *state) call(n *ir.CallExpr, k callKind, returnResultAddr bool) *ssa.Val
 			callArgs = append(callArgs, closure)
 			stksize += int64(types.PtrSize)
 			argStart += int64(types.PtrSize)
+			if dextra != nil {
+				// Extra frame argument of type any for deferprocat.
+				ACArgs = append(ACArgs, types.Types[types.TINTER])
+				callArgs = append(callArgs, dextra)
+				stksize += 2 * int64(types.PtrSize)
+				argStart += 2 * int64(types.PtrSize)
+			}
 		}
 
 		// Set receiver (for interface calls).
@@ -5319,7 +5381,11 @@ func (s *state) call(n *ir.CallExpr, k callKind, returnResultAddr bool) *ssa.Val
 		// call target
 		switch {
 		case k == callDefer:
-			aux := ssa.StaticAuxCall(ir.Syms.Deferproc, s.f.ABIDefault.ABIAnalyzeTypes(ACArgs, ACResults)) // TODO paramResultInfo for DeferProc
+			sym := ir.Syms.Deferproc
+			if dextra != nil {
+				sym = ir.Syms.Deferprocat
+			}
+			aux := ssa.StaticAuxCall(sym, s.f.ABIDefault.ABIAnalyzeTypes(ACArgs, ACResults)) // TODO paramResultInfo for DeferProc
 			call = s.newValue0A(ssa.OpStaticLECall, aux.LateExpansionResultType(), aux)
 		case k == callGo:
 			aux := ssa.StaticAuxCall(ir.Syms.Newproc, s.f.ABIDefault.ABIAnalyzeTypes(ACArgs, ACResults))
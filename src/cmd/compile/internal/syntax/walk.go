@@ -245,6 +245,9 @@ func (w walker) node(n Node) {
 
 	case *CallStmt:
 		w.node(n.Call)
+		if n.DeferAt != nil {
+			w.node(n.DeferAt)
+		}
 
 	case *ReturnStmt:
 		if n.Results != nil {
@@ -22,10 +22,12 @@ func panicmakeslicelen()
 func panicmakeslicecap()
 func throwinit()
 func panicwrap()
+func panicrangeexit()
 
 func gopanic(interface{})
 func gorecover(*int32) interface{}
 func goschedguarded()
+func deferrangefunc() interface{}
 
 // Note: these declarations are just for wasm port.
 // Other ports call assembly stubs instead.
@@ -154,6 +156,8 @@ func chanrecv1(hchan <-chan any, elem *any)
 func chanrecv2(hchan <-chan any, elem *any) bool
 func chansend1(hchan chan<- any, elem *any)
 func closechan(hchan any)
+func chanlen(hchan any) int
+func chancap(hchan any) int
 
 var writeBarrier struct {
 	enabled bool
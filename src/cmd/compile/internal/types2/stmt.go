@@ -854,6 +854,7 @@ func (check *Checker) rangeStmt(inner stmtContext, s *syntax.ForStmt, rclause *s
 	if x.mode != invalid {
 		// Ranging over a type parameter is permitted if it has a core type.
 		var cause string
+		isFunc := false
 		u := coreType(x.typ)
 		if t, _ := u.(*Chan); t != nil {
 			if sValue != nil {
@@ -872,8 +873,27 @@ func (check *Checker) rangeStmt(inner stmtContext, s *syntax.ForStmt, rclause *s
 				cause = check.sprintf("%s has no core type", x.typ)
 			}
 		}
-		key, val = rangeKeyVal(u)
-		if key == nil || cause != "" {
+		if sig, _ := u.(*Signature); sig != nil {
+			isFunc = true
+			if !check.allowVersion(check.pkg, &x, go1_22) {
+				cause = "requires go1.22 or later"
+			} else {
+				var n int
+				key, val, n, cause = rangeFuncKeyVal(sig)
+				if cause == "" {
+					switch {
+					case n == 0 && sKey != nil:
+						check.softErrorf(sKey, InvalidIterVar, "range over %s permits no iteration variables", &x)
+					case n == 1 && sValue != nil:
+						check.softErrorf(sValue, InvalidIterVar, "range over %s permits only one iteration variable", &x)
+					}
+					// ok to continue
+				}
+			}
+		} else {
+			key, val = rangeKeyVal(u)
+		}
+		if key == nil && !isFunc || cause != "" {
 			if cause == "" {
 				check.softErrorf(&x, InvalidRangeExpr, "cannot range over %s", &x)
 			} else {
@@ -978,3 +998,36 @@ func rangeKeyVal(typ Type) (key, val Type) {
 	}
 	return
 }
+
+// rangeFuncKeyVal returns the key and value types produced by a range
+// clause over a function of type sig, and the number n of iteration
+// variables it permits. A range function must have the form
+// func(yield func(K, V) bool), where yield takes at most two parameters.
+// If sig is not of that form, cause describes the problem.
+func rangeFuncKeyVal(sig *Signature) (key, val Type, n int, cause string) {
+	const want = "func must be func(yield func(...) bool)"
+	if sig.Params().Len() != 1 {
+		return nil, nil, 0, want + ": wrong argument count"
+	}
+	if sig.Results().Len() != 0 {
+		return nil, nil, 0, want + ": unexpected results"
+	}
+	yield, _ := coreType(sig.Params().At(0).Type()).(*Signature)
+	if yield == nil {
+		return nil, nil, 0, want + ": argument is not func"
+	}
+	if yield.Params().Len() > 2 {
+		return nil, nil, 0, want + ": yield func has too many parameters"
+	}
+	if yield.Results().Len() != 1 || !isBoolean(yield.Results().At(0).Type()) {
+		return nil, nil, 0, want + ": yield func does not return bool"
+	}
+	n = yield.Params().Len()
+	if n >= 1 {
+		key = yield.Params().At(0).Type()
+	}
+	if n >= 2 {
+		val = yield.Params().At(1).Type()
+	}
+	return key, val, n, ""
+}
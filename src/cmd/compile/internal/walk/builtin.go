@@ -258,6 +258,16 @@ func walkLenCap(n *ir.UnaryExpr, init *ir.Nodes) ir.Node {
 		_, len := backingArrayPtrLen(cheapExpr(conv.X, init))
 		return len
 	}
+	if isChanLenCap(n) {
+		name := "chanlen"
+		if n.Op() == ir.OCAP {
+			name = "chancap"
+		}
+		// cannot use chanfn - closechan takes any, not chan any,
+		// because it accepts both send-only and recv-only channels.
+		fn := typecheck.LookupRuntime(name, n.X.Type())
+		return mkcall1(fn, n.Type(), init, n.X)
+	}
 
 	n.X = walkExpr(n.X, init)
 
@@ -336,7 +346,7 @@ func walkMakeMap(n *ir.MakeExpr, init *ir.Nodes) ir.Node {
 			b := stackTempAddr(&nif.Body, reflectdata.MapBucketType(t))
 
 			// h.buckets = b
-			bsym := hmapType.Field(5).Sym // hmap.buckets see reflect.go:hmap
+			bsym := hmapType.Field(5).Sym // hmap.buckets (hmap.dirPtr for swissmap) see reflect.go:hmap
 			na := ir.NewAssignStmt(base.Pos, ir.NewSelectorExpr(base.Pos, ir.ODOT, h, bsym), typecheck.ConvNop(b, types.Types[types.TUNSAFEPTR]))
 			nif.Body.Append(na)
 			appendWalkStmt(init, nif)
@@ -355,9 +365,11 @@ func walkMakeMap(n *ir.MakeExpr, init *ir.Nodes) ir.Node {
 			// Only need to initialize h.hash0 since
 			// hmap h has been allocated on the stack already.
 			// h.hash0 = fastrand()
+			// (With GOEXPERIMENT=swissmap, the hash seed is a
+			// uintptr field named seed instead.)
 			rand := mkcall("fastrand", types.Types[types.TUINT32], init)
-			hashsym := hmapType.Field(4).Sym // hmap.hash0 see reflect.go:hmap
-			appendWalkStmt(init, ir.NewAssignStmt(base.Pos, ir.NewSelectorExpr(base.Pos, ir.ODOT, h, hashsym), rand))
+			hashfield := hmapType.Field(4) // hmap.hash0 see reflect.go:hmap
+			appendWalkStmt(init, ir.NewAssignStmt(base.Pos, ir.NewSelectorExpr(base.Pos, ir.ODOT, h, hashfield.Sym), typecheck.Conv(rand, hashfield.Type)))
 			return typecheck.ConvNop(h, t)
 		}
 		// Call runtime.makehmap to allocate an
@@ -863,6 +875,13 @@ func isRuneCount(n ir.Node) bool {
 	return base.Flag.N == 0 && !base.Flag.Cfg.Instrumenting && n.Op() == ir.OLEN && n.(*ir.UnaryExpr).X.Op() == ir.OSTR2RUNES
 }
 
+// isChanLenCap reports whether n is of the form len(c) or cap(c) for a channel c.
+// Note that this does not check for -N or instrumenting because this
+// is a correctness rewrite, not an optimization.
+func isChanLenCap(n ir.Node) bool {
+	return (n.Op() == ir.OLEN || n.Op() == ir.OCAP) && n.(*ir.UnaryExpr).X.Type().IsChan()
+}
+
 // isByteCount reports whether n is of the form len(string([]byte)).
 func isByteCount(n ir.Node) bool {
 	return base.Flag.N == 0 && !base.Flag.Cfg.Instrumenting && n.Op() == ir.OLEN &&
@@ -548,6 +548,17 @@ func walkCall(n *ir.CallExpr, init *ir.Nodes) ir.Node {
 		directClosureCall(n)
 	}
 
+	if n.Op() == ir.OCALLFUNC {
+		if fn := ir.StaticCalleeName(n.X); fn != nil && fn.Sym().Pkg == ir.Pkgs.Runtime && fn.Sym().Name == "deferrangefunc" {
+			// The result of runtime.deferrangefunc is shared with
+			// range-over-func loop bodies that may add defers to this
+			// frame, so we cannot use open-coded defers, and we need to
+			// call deferreturn even if there are no explicit defers.
+			ir.CurFunc.SetHasDefer(true)
+			ir.CurFunc.SetOpenCodedDeferDisallowed(true)
+		}
+	}
+
 	if isFuncPCIntrinsic(n) {
 		// For internal/abi.FuncPCABIxxx(fn), if fn is a defined function, rewrite
 		// it to the address of the function of the ABI fn is defined.
@@ -766,6 +766,9 @@ func (o *orderState) stmt(n ir.Node) {
 		t := o.markTemp()
 		o.init(n.Call)
 		o.call(n.Call)
+		if n.DeferAt != nil {
+			n.DeferAt = o.cheapExpr(n.DeferAt).(ir.Expr)
+		}
 		o.out = append(o.out, n)
 		o.popTemp(t)
 
@@ -54,6 +54,7 @@ var bootstrapDirs = []string{
 	"cmd/link/internal/...",
 	"compress/flate",
 	"compress/zlib",
+	"compress/zstd",
 	"container/heap",
 	"debug/dwarf",
 	"debug/elf",
@@ -85,7 +86,6 @@ var bootstrapDirs = []string{
 	"internal/types/errors",
 	"internal/unsafeheader",
 	"internal/xcoff",
-	"internal/zstd",
 	"math/bits",
 	"sort",
 }
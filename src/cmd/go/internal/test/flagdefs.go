@@ -60,6 +60,7 @@ var passAnalyzersToVet = map[string]bool{
 	"methods":          true,
 	"nilfunc":          true,
 	"printf":           true,
+	"randv2":           true,
 	"rangeloops":       true,
 	"shift":            true,
 	"sigchanyzer":      true,
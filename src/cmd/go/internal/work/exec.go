@@ -1255,6 +1255,10 @@ func (b *Builder) vet(ctx context.Context, a *Action) error {
 		// changed here.
 		vetFlags = []string{"-unsafeptr=false"}
 
+		// Turn off -randv2 checks too. The standard library
+		// keeps using math/rand, which remains supported.
+		vetFlags = append(vetFlags, "-randv2=false")
+
 		// Also turn off -unreachable checks during go test.
 		// During testing it is very common to make changes
 		// like hard-coded forced returns or panics that make
@@ -44,6 +44,7 @@ var runtimePkgs = []string{
 	"runtime",
 
 	"runtime/internal/atomic",
+	"runtime/internal/cgroup",
 	"runtime/internal/math",
 	"runtime/internal/sys",
 	"runtime/internal/syscall",
@@ -882,9 +882,11 @@ func (ctxt *Link) linksetup() {
 		}
 
 		// Set runtime.disableMemoryProfiling bool if
-		// runtime.MemProfile is not retained in the binary after
-		// deadcode (and we're not dynamically linking).
-		memProfile := ctxt.loader.Lookup("runtime.MemProfile", abiInternalVer)
+		// runtime.memProfileWithLabels, which is behind
+		// runtime.MemProfile and runtime/pprof, is not retained
+		// in the binary after deadcode (and we're not dynamically
+		// linking).
+		memProfile := ctxt.loader.Lookup("runtime.memProfileWithLabels", abiInternalVer)
 		if memProfile != 0 && !ctxt.loader.AttrReachable(memProfile) && !ctxt.DynlinkingGo() {
 			memProfSym := ctxt.loader.LookupOrCreateSym("runtime.disableMemoryProfiling", 0)
 			sb := ctxt.loader.MakeSymbolUpdater(memProfSym)
@@ -40,6 +40,7 @@ To list the available checks, run "go tool vet help":
 	lostcancel   check cancel func returned by context.WithCancel is called
 	nilfunc      check for useless comparisons between functions and nil
 	printf       check consistency of Printf format strings and arguments
+	randv2       report uses of math/rand that change in math/rand/v2
 	shift        check for shifts that equal or exceed the width of the integer
 	slog         check for incorrect arguments to log/slog functions
 	stdmethods   check signature of methods of well-known interfaces
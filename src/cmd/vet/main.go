@@ -62,6 +62,7 @@ func main() {
 		lostcancel.Analyzer,
 		nilfunc.Analyzer,
 		printf.Analyzer,
+		randv2Analyzer,
 		shift.Analyzer,
 		sigchanyzer.Analyzer,
 		slog.Analyzer,
@@ -0,0 +1,210 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+const randv2Doc = `report uses of math/rand that change in math/rand/v2
+
+The randv2 checker helps migrate code from math/rand to math/rand/v2.
+It reports references to functions and *Rand methods that are
+renamed in math/rand/v2, such as Intn (now IntN) and Int63 (now Int64),
+and references to Seed, Read and NewSource, which have no direct
+equivalent in math/rand/v2.
+
+When a file uses only top-level functions of math/rand that also exist
+in math/rand/v2, possibly under a new name, the checker suggests a fix
+that migrates the whole file: it changes the import to math/rand/v2 and
+renames the functions. Files that use other parts of math/rand, such as
+the Rand and Source types, must be migrated by hand.`
+
+var randv2Analyzer = &analysis.Analyzer{
+	Name: "randv2",
+	Doc:  randv2Doc,
+	Run:  runRandv2,
+}
+
+// randv2Renames maps the names of math/rand functions and *Rand methods
+// to their names in math/rand/v2.
+var randv2Renames = map[string]string{
+	"Int31":  "Int32",
+	"Int31n": "Int32N",
+	"Int63":  "Int64",
+	"Int63n": "Int64N",
+	"Intn":   "IntN",
+}
+
+// randv2Removed maps the names of math/rand functions and *Rand methods
+// that are not in math/rand/v2 to advice about what to use instead.
+// Method names are written with a "Rand." prefix.
+var randv2Removed = map[string]string{
+	"Read":      "use crypto/rand.Read",
+	"Rand.Read": "use crypto/rand.Read",
+	"Seed":      "the top-level functions are always randomly seeded",
+	"Rand.Seed": "seed the Source instead",
+	"NewSource": "use NewPCG or NewChaCha8",
+}
+
+// randv2Unchanged is the set of top-level math/rand functions that
+// have the same name and signature in math/rand/v2.
+var randv2Unchanged = map[string]bool{
+	"ExpFloat64":  true,
+	"Float32":     true,
+	"Float64":     true,
+	"Int":         true,
+	"NormFloat64": true,
+	"Perm":        true,
+	"Shuffle":     true,
+	"Uint32":      true,
+	"Uint64":      true,
+}
+
+func runRandv2(pass *analysis.Pass) (interface{}, error) {
+	if !importsPath(pass.Pkg, "math/rand") {
+		return nil, nil
+	}
+	for _, file := range pass.Files {
+		checkRandv2File(pass, file)
+	}
+	return nil, nil
+}
+
+// checkRandv2File reports the uses of math/rand in file that change in
+// math/rand/v2. The renamed top-level functions get a suggested fix that
+// migrates the whole file, if every other use of the math/rand import in
+// the file is also valid in math/rand/v2.
+func checkRandv2File(pass *analysis.Pass, file *ast.File) {
+	var imp *ast.ImportSpec
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == "math/rand" {
+			imp = spec
+		}
+	}
+	if imp == nil {
+		return
+	}
+
+	var (
+		renames []*ast.SelectorExpr   // renamed top-level functions
+		migrate = true                // whether the import can be changed
+		diags   []analysis.Diagnostic // diagnostics, without fixes
+		fixable []bool                // whether diags[i] is a renamed top-level function
+	)
+	ast.Inspect(file, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		obj := pass.TypesInfo.Uses[sel.Sel]
+		if obj == nil || obj.Pkg() == nil || obj.Pkg().Path() != "math/rand" {
+			return true
+		}
+		qualified := false
+		if id, ok := sel.X.(*ast.Ident); ok {
+			_, qualified = pass.TypesInfo.Uses[id].(*types.PkgName)
+		}
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			// A type, such as Rand or Source, whose API differs
+			// in math/rand/v2, or a field.
+			if qualified {
+				migrate = false
+			}
+			return true
+		}
+
+		name := fn.Name()
+		key := name
+		display := "math/rand." + name
+		if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+			if !isRandPointer(recv.Type()) {
+				return true
+			}
+			key = "Rand." + name
+			display = "math/rand.(*Rand)." + name
+		} else if qualified {
+			switch {
+			case randv2Renames[name] != "":
+				renames = append(renames, sel)
+			case !randv2Unchanged[name]:
+				migrate = false
+			}
+		}
+
+		var msg string
+		if newName, ok := randv2Renames[name]; ok {
+			msg = display + " is named " + newName + " in math/rand/v2"
+		} else if advice, ok := randv2Removed[key]; ok {
+			msg = display + " has no equivalent in math/rand/v2: " + advice
+		} else {
+			return true
+		}
+		diags = append(diags, analysis.Diagnostic{Pos: sel.Sel.Pos(), End: sel.Sel.End(), Message: msg})
+		fixable = append(fixable, qualified && key == name && randv2Renames[name] != "")
+		return true
+	})
+
+	// Dot and blank imports are not changed.
+	if imp.Name != nil && (imp.Name.Name == "." || imp.Name.Name == "_") {
+		migrate = false
+	}
+	var fix []analysis.SuggestedFix
+	if migrate && len(renames) > 0 {
+		// Renaming a single function does not compile until the
+		// import is changed too, so the fix migrates the whole file.
+		edits := []analysis.TextEdit{{
+			Pos:     imp.Path.Pos(),
+			End:     imp.Path.End(),
+			NewText: []byte(strconv.Quote("math/rand/v2")),
+		}}
+		for _, sel := range renames {
+			edits = append(edits, analysis.TextEdit{
+				Pos:     sel.Sel.Pos(),
+				End:     sel.Sel.End(),
+				NewText: []byte(randv2Renames[sel.Sel.Name]),
+			})
+		}
+		fix = []analysis.SuggestedFix{{
+			Message:   "Migrate file to math/rand/v2",
+			TextEdits: edits,
+		}}
+	}
+	for i, d := range diags {
+		if fixable[i] {
+			d.SuggestedFixes = fix
+		}
+		pass.Report(d)
+	}
+}
+
+// isRandPointer reports whether t is *math/rand.Rand.
+func isRandPointer(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "math/rand" && obj.Name() == "Rand"
+}
+
+// importsPath reports whether pkg imports the package with the given path.
+func importsPath(pkg *types.Package, path string) bool {
+	for _, imp := range pkg.Imports() {
+		if imp.Path() == path {
+			return true
+		}
+	}
+	return false
+}
@@ -23,8 +23,8 @@ func (s *ST) SetX(x int, ch chan int) {
 
 	// Bail on any potential side effects to avoid false positives
 	s.l[num()] = s.l[num()]
-	rng := rand.New(rand.NewSource(0))
-	s.l[rng.Intn(len(s.l))] = s.l[rng.Intn(len(s.l))]
+	rng := rand.New(rand.NewSource(0))                // ERROR "math/rand.NewSource has no equivalent in math/rand/v2"
+	s.l[rng.Intn(len(s.l))] = s.l[rng.Intn(len(s.l))] // ERROR "math/rand.\(\*Rand\).Intn is named IntN in math/rand/v2"
 	s.l[<-ch] = s.l[<-ch]
 }
 
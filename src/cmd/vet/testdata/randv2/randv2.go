@@ -0,0 +1,47 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the randv2 checker.
+
+package randv2
+
+import (
+	"math/rand"
+	"time"
+)
+
+func RandFuncs() {
+	rand.Seed(1)          // ERROR "math/rand.Seed has no equivalent in math/rand/v2: the top-level functions are always randomly seeded"
+	_ = rand.Intn(10)     // ERROR "math/rand.Intn is named IntN in math/rand/v2"
+	_ = rand.Int31()      // ERROR "math/rand.Int31 is named Int32 in math/rand/v2"
+	_ = rand.Int31n(10)   // ERROR "math/rand.Int31n is named Int32N in math/rand/v2"
+	_ = rand.Int63()      // ERROR "math/rand.Int63 is named Int64 in math/rand/v2"
+	_ = rand.Int63n(10)   // ERROR "math/rand.Int63n is named Int64N in math/rand/v2"
+	_, _ = rand.Read(nil) // ERROR "math/rand.Read has no equivalent in math/rand/v2: use crypto/rand.Read"
+
+	// Unchanged in math/rand/v2.
+	_ = rand.Float64()
+	_ = rand.Uint32()
+	_ = rand.Perm(3)
+	rand.Shuffle(3, func(i, j int) {})
+	_ = time.Duration(rand.Int())
+
+	f := rand.Intn // ERROR "math/rand.Intn is named IntN in math/rand/v2"
+	_ = f
+}
+
+func RandMethods() {
+	r := rand.New(rand.NewSource(1)) // ERROR "math/rand.NewSource has no equivalent in math/rand/v2: use NewPCG or NewChaCha8"
+	r.Seed(2)                        // ERROR "math/rand.\(\*Rand\).Seed has no equivalent in math/rand/v2: seed the Source instead"
+	_ = r.Intn(10)                   // ERROR "math/rand.\(\*Rand\).Intn is named IntN in math/rand/v2"
+	_ = r.Int63()                    // ERROR "math/rand.\(\*Rand\).Int63 is named Int64 in math/rand/v2"
+	_, _ = r.Read(nil)               // ERROR "math/rand.\(\*Rand\).Read has no equivalent in math/rand/v2: use crypto/rand.Read"
+	_ = r.Float64()
+}
+
+// Methods of Source are not reported.
+func SourceMethods(src rand.Source) {
+	_ = src.Int63()
+	src.Seed(1)
+}
@@ -0,0 +1,23 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file contains tests for the fixes suggested by the randv2 checker.
+
+package randv2fix
+
+import (
+	"math/rand"
+	"time"
+)
+
+// This file only uses functions that exist in math/rand/v2,
+// so the suggested fix migrates it.
+func Migrate(s []int) time.Duration {
+	rand.Shuffle(len(s), func(i, j int) { s[i], s[j] = s[j], s[i] })
+	_ = rand.Float64()
+	_ = rand.Int31n(10)
+	f := rand.Intn
+	_ = f(3)
+	return time.Duration(rand.Int63n(int64(time.Second)))
+}
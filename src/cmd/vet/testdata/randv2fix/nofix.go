@@ -0,0 +1,14 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package randv2fix
+
+import mrand "math/rand"
+
+// This file uses a Source, which differs in math/rand/v2,
+// so no fix is suggested.
+func Keep() int {
+	r := mrand.New(mrand.NewSource(1))
+	return r.Intn(10) + mrand.Intn(10)
+}
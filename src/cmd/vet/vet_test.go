@@ -6,6 +6,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"internal/testenv"
@@ -77,6 +78,7 @@ func TestVet(t *testing.T) {
 		"method",
 		"nilfunc",
 		"print",
+		// "randv2" has its own test
 		"rangeloop",
 		"shift",
 		"slog",
@@ -148,6 +150,113 @@ func errchk(c *exec.Cmd, files []string, t *testing.T) {
 	}
 }
 
+// TestRandv2 verifies the diagnostics of the randv2 check.
+func TestRandv2(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join("testdata", "randv2")
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errchk(vetCmd(t, "-randv2", "randv2"), files, t)
+}
+
+// TestRandv2Fix verifies that the fixes suggested by the randv2 check
+// produce the expected code, which compiles.
+func TestRandv2Fix(t *testing.T) {
+	t.Parallel()
+
+	cmd := testenv.Command(t, testenv.GoToolPath(t), "vet", "-vettool="+vetPath(t), "-json", "-randv2", path.Join("cmd/vet/testdata", "randv2fix"))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("vet failed: %v\n%s", err, out)
+	}
+	// Skip the "# package" line before the JSON output.
+	if i := bytes.IndexByte(out, '{'); i >= 0 {
+		out = out[i:]
+	}
+	type edit struct {
+		Filename   string
+		Start, End int
+		New        string
+	}
+	var tree map[string]map[string][]struct {
+		Posn           string
+		Message        string
+		SuggestedFixes []struct{ Edits []edit } `json:"suggested_fixes"`
+	}
+	if err := json.Unmarshal(out, &tree); err != nil {
+		t.Fatalf("parsing vet output: %v\n%s", err, out)
+	}
+
+	// Every diagnostic in fix.go has the same fix, and none in nofix.go
+	// has one.
+	var edits []edit
+	n := 0
+	for _, pkg := range tree {
+		for _, d := range pkg["randv2"] {
+			n++
+			file := filepath.Base(strings.Split(d.Posn, ":")[0])
+			switch {
+			case file == "nofix.go" && len(d.SuggestedFixes) != 0:
+				t.Errorf("%s: %s: unexpected suggested fix", d.Posn, d.Message)
+			case file == "fix.go" && len(d.SuggestedFixes) != 1:
+				t.Errorf("%s: %s: got %d suggested fixes, want 1", d.Posn, d.Message, len(d.SuggestedFixes))
+			case file == "fix.go" && edits == nil:
+				edits = d.SuggestedFixes[0].Edits
+			case file == "fix.go" && fmt.Sprint(edits) != fmt.Sprint(d.SuggestedFixes[0].Edits):
+				t.Errorf("%s: %s: suggested fix differs from the others in the file", d.Posn, d.Message)
+			}
+		}
+	}
+	if n == 0 || edits == nil {
+		t.Fatalf("no suggested fix in vet output:\n%s", out)
+	}
+
+	// Apply the fix from the end of the file.
+	dir := filepath.Join("testdata", "randv2fix")
+	src, err := os.ReadFile(filepath.Join(dir, "fix.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		if filepath.Base(e.Filename) != "fix.go" || i > 0 && edits[i-1].End > e.Start {
+			t.Fatalf("unexpected edit %+v", e)
+		}
+		src = append(src[:e.Start:e.Start], append([]byte(e.New), src[e.End:]...)...)
+	}
+	want, err := os.ReadFile(filepath.Join(dir, "fix.go.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("fixed fix.go:\n%s\nwant:\n%s", src, want)
+	}
+
+	// The fixed package compiles.
+	tmp := t.TempDir()
+	nofix, err := os.ReadFile(filepath.Join(dir, "nofix.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"go.mod":   []byte("module randv2fix\n\ngo 1.22\n"),
+		"fix.go":   src,
+		"nofix.go": nofix,
+	} {
+		if err := os.WriteFile(filepath.Join(tmp, name), data, 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd = testenv.Command(t, testenv.GoToolPath(t), "build", ".")
+	cmd.Dir = tmp
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("building the fixed package failed: %v\n%s", err, out)
+	}
+}
+
 // TestTags verifies that the -tags argument controls which files to check.
 func TestTags(t *testing.T) {
 	t.Parallel()
@@ -128,3 +128,48 @@ func (rbr *reverseBitReader) fetch(b uint8) bool {
 func (rbr *reverseBitReader) makeError(msg string) error {
 	return rbr.r.makeError(int(rbr.off), msg)
 }
+
+// bitWriter writes a bit stream going forward.
+// Bits are written starting with the low bit of each byte.
+// A bit stream written this way may be read by a bitReader,
+// or, if it is closed by calling close, by a reverseBitReader.
+type bitWriter struct {
+	out  []byte // bytes written so far
+	bits uint64 // bits not yet written to out
+	cnt  uint32 // number of valid bits in the bits field
+}
+
+// addBits adds the low b bits of v to the stream.
+// b must be at most 32.
+func (bw *bitWriter) addBits(v uint32, b uint8) {
+	bw.bits |= (uint64(v) & (1<<b - 1)) << bw.cnt
+	bw.cnt += uint32(b)
+	if bw.cnt >= 32 {
+		bw.out = append(bw.out, byte(bw.bits), byte(bw.bits>>8), byte(bw.bits>>16), byte(bw.bits>>24))
+		bw.bits >>= 32
+		bw.cnt -= 32
+	}
+}
+
+// flush writes any remaining bits, padding the last byte with zeroes,
+// and returns the written data.
+func (bw *bitWriter) flush() []byte {
+	for bw.cnt > 0 {
+		bw.out = append(bw.out, byte(bw.bits))
+		bw.bits >>= 8
+		if bw.cnt < 8 {
+			bw.cnt = 0
+		} else {
+			bw.cnt -= 8
+		}
+	}
+	return bw.out
+}
+
+// close finishes a stream that will be read by a reverseBitReader.
+// It adds the single 1 bit that marks the start of the stream,
+// and returns the written data.
+func (bw *bitWriter) close() []byte {
+	bw.addBits(1, 1)
+	return bw.flush()
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// dictionaryMagic is the magic number at the start of
+// a dictionary in the zstd dictionary format. RFC 5.
+const dictionaryMagic = 0xec30a437
+
+// dictionary is a parsed zstd dictionary.
+type dictionary struct {
+	// The Dictionary_ID. This is 0 for a raw content dictionary.
+	id uint32
+
+	// The repeated offsets at the start of a frame.
+	repeatedOffsets [3]uint32
+
+	// The Huffman table for literals.
+	// huffmanTableBits is 0 for a raw content dictionary.
+	huffmanTable     []uint16
+	huffmanTableBits int
+
+	// The FSE tables for sequences.
+	// These are nil for a raw content dictionary.
+	seqTables    [3][]fseBaselineEntry
+	seqTableBits [3]uint8
+
+	// The dictionary content, which is treated as though
+	// it precedes the data of each frame.
+	content []byte
+}
+
+// parseDictionary parses a dictionary. If data does not start
+// with the dictionary magic number, it is treated as raw content.
+// RFC 5.
+func parseDictionary(data []byte) (*dictionary, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint32(data) != dictionaryMagic {
+		d := &dictionary{
+			repeatedOffsets: [3]uint32{1, 4, 8},
+			content:         data,
+		}
+		return d, nil
+	}
+
+	d := &dictionary{
+		id: binary.LittleEndian.Uint32(data[4:]),
+	}
+
+	// We use a Reader to parse the entropy tables,
+	// converting any errors it reports afterward.
+	var r Reader
+	off, err := d.readEntropyTables(&r, data, 8)
+	if err != nil {
+		var ze *zstdError
+		if errors.As(err, &ze) {
+			err = fmt.Errorf("zstd: invalid dictionary at offset %d: %v", ze.offset, ze.err)
+		}
+		return nil, err
+	}
+
+	if off+12 > len(data) {
+		return nil, errors.New("zstd: invalid dictionary: missing repeated offsets")
+	}
+	content := data[off+12:]
+	for i := range d.repeatedOffsets {
+		ro := binary.LittleEndian.Uint32(data[off+4*i:])
+		if ro == 0 || uint64(ro) > uint64(len(content)) {
+			return nil, fmt.Errorf("zstd: invalid dictionary: bad repeated offset %d", ro)
+		}
+		d.repeatedOffsets[i] = ro
+	}
+	d.content = content
+
+	return d, nil
+}
+
+// readEntropyTables reads the Huffman and FSE tables of a dictionary,
+// starting at off. It returns the offset of the repeated offsets.
+// RFC 5, Entropy_Tables.
+func (d *dictionary) readEntropyTables(r *Reader, data []byte, off int) (int, error) {
+	d.huffmanTable = make([]uint16, 1<<maxHuffmanBits)
+	huffmanTableBits, off, err := r.readHuff(data, off, d.huffmanTable)
+	if err != nil {
+		return 0, err
+	}
+	d.huffmanTableBits = huffmanTableBits
+
+	// The FSE tables are stored in the order offset,
+	// match length, literal length.
+	for _, kind := range [...]seqCode{seqOffset, seqMatch, seqLiteral} {
+		info := &seqCodeInfo[kind]
+		if len(r.fseScratch) < 1<<info.maxBits {
+			r.fseScratch = make([]fseEntry, 1<<info.maxBits)
+		}
+		tableBits, roff, err := r.readFSE(data, off, info.maxSym, info.maxBits, r.fseScratch)
+		if err != nil {
+			return 0, err
+		}
+		table := make([]fseBaselineEntry, 1<<tableBits)
+		if err := info.toBaseline(r, off, r.fseScratch[:1<<tableBits], table); err != nil {
+			return 0, err
+		}
+		d.seqTables[kind] = table
+		d.seqTableBits[kind] = uint8(tableBits)
+		off = roff
+	}
+
+	return off, nil
+}
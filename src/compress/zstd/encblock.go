@@ -0,0 +1,368 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"math"
+	"math/bits"
+)
+
+// encoder compresses blocks.
+type encoder struct {
+	matcher
+
+	// The Huffman code for literals, and scratch space
+	// to write the literals.
+	huff    huffmanEncoder
+	huffBuf []byte
+
+	// An FSE encoder used for Huffman weights,
+	// and a Reader and table used to check them.
+	weightEnc   fseEncoder
+	checkReader Reader
+	checkTable  []uint16
+
+	// The FSE encoders for the predefined tables,
+	// built when first used.
+	predef      [3]fseEncoder
+	predefBuilt bool
+
+	// The FSE encoders for the current block.
+	seqEnc [3]fseEncoder
+
+	// The codes for each sequence, indexed by seqCode.
+	codes [3][]uint8
+
+	// Scratch space for FSE table descriptions.
+	tableBuf [3][]byte
+}
+
+// seqCodeMaxSym is the maximum symbol for each kind of sequence code
+// that can be encoded with the predefined distributions.
+var seqCodeMaxSym = [3]int{
+	seqLiteral: len(literalPredefinedDistribution) - 1,
+	seqOffset:  len(offsetPredefinedDistribution) - 1,
+	seqMatch:   len(matchPredefinedDistribution) - 1,
+}
+
+// compressBlock compresses the block src[start:], where src[:start]
+// is history that the block may refer to, and appends the compressed
+// data to out. RFC 3.1.1.3.
+func (e *encoder) compressBlock(out, src []byte, start int) []byte {
+	e.findMatches(src, start)
+	out = e.appendLiterals(out, e.lits)
+	return e.appendSequences(out)
+}
+
+// appendLiterals appends a literals section for lits to out.
+// RFC 3.1.1.3.1.
+func (e *encoder) appendLiterals(out, lits []byte) []byte {
+	// Small sets of literals aren't worth compressing.
+	if len(lits) < 32 {
+		return appendRawLiterals(out, lits)
+	}
+
+	var counts [256]uint32
+	for _, c := range lits {
+		counts[c]++
+	}
+	if int(counts[lits[0]]) == len(lits) {
+		// RLE_Literals_Block.
+		out = appendLiteralsHeader(out, 1, len(lits))
+		return append(out, lits[0])
+	}
+
+	e.huff.build(&counts)
+
+	// Give up early if the literals are nearly random.
+	if e.huff.encodedBits(&counts)/8 >= len(lits)-len(lits)/32 {
+		return appendRawLiterals(out, lits)
+	}
+
+	buf, ok := e.appendHuffmanWeights(e.huffBuf[:0])
+	if !ok {
+		return appendRawLiterals(out, lits)
+	}
+
+	var sizeFormat int
+	if len(lits) < 256 {
+		// A single stream. RFC 3.1.1.3.1.6.
+		sizeFormat = 0
+		buf = e.huff.appendStream(buf, lits)
+	} else {
+		// Four streams, preceded by a jump table
+		// holding the sizes of the first three.
+		sizeFormat = 1
+		jump := len(buf)
+		buf = append(buf, 0, 0, 0, 0, 0, 0)
+		segment := (len(lits) + 3) / 4
+		for i := 0; i < 4; i++ {
+			streamStart := len(buf)
+			lo := i * segment
+			hi := lo + segment
+			if i == 3 {
+				hi = len(lits)
+			}
+			buf = e.huff.appendStream(buf, lits[lo:hi])
+			if i < 3 {
+				size := len(buf) - streamStart
+				if size > math.MaxUint16 {
+					e.huffBuf = buf
+					return appendRawLiterals(out, lits)
+				}
+				buf[jump+2*i] = byte(size)
+				buf[jump+2*i+1] = byte(size >> 8)
+			}
+		}
+	}
+	e.huffBuf = buf
+
+	if len(buf) >= len(lits)-len(lits)/64 {
+		return appendRawLiterals(out, lits)
+	}
+
+	// Literals section header for Compressed_Literals_Block.
+	// RFC 3.1.1.3.1.1.
+	regen, comp := uint64(len(lits)), uint64(len(buf))
+	switch {
+	case regen < 1<<10 && comp < 1<<10:
+		hdr := 2 | uint64(sizeFormat)<<2 | regen<<4 | comp<<14
+		out = append(out, byte(hdr), byte(hdr>>8), byte(hdr>>16))
+	case regen < 1<<14 && comp < 1<<14:
+		hdr := 2 | 2<<2 | regen<<4 | comp<<18
+		out = append(out, byte(hdr), byte(hdr>>8), byte(hdr>>16), byte(hdr>>24))
+	default:
+		hdr := 2 | 3<<2 | regen<<4 | comp<<22
+		out = append(out, byte(hdr), byte(hdr>>8), byte(hdr>>16), byte(hdr>>24), byte(hdr>>32))
+	}
+	return append(out, buf...)
+}
+
+// appendRawLiterals appends a Raw_Literals_Block to out.
+func appendRawLiterals(out, lits []byte) []byte {
+	out = appendLiteralsHeader(out, 0, len(lits))
+	return append(out, lits...)
+}
+
+// appendLiteralsHeader appends the literals section header for
+// a Raw_Literals_Block (if typ is 0) or an RLE_Literals_Block
+// (if typ is 1) with n literals. RFC 3.1.1.3.1.1.
+func appendLiteralsHeader(out []byte, typ byte, n int) []byte {
+	switch {
+	case n < 1<<5:
+		return append(out, typ|byte(n)<<3)
+	case n < 1<<12:
+		return append(out, typ|1<<2|byte(n)<<4, byte(n>>4))
+	default:
+		return append(out, typ|3<<2|byte(n)<<4, byte(n>>4), byte(n>>12))
+	}
+}
+
+// appendSequences appends a sequences section for e.seqs to out.
+// RFC 3.1.1.3.2.
+func (e *encoder) appendSequences(out []byte) []byte {
+	n := len(e.seqs)
+	switch {
+	case n < 128:
+		out = append(out, byte(n))
+	case n < 0x7f00:
+		out = append(out, byte(n>>8+128), byte(n))
+	default:
+		out = append(out, 255, byte(n-0x7f00), byte((n-0x7f00)>>8))
+	}
+	if n == 0 {
+		return out
+	}
+
+	// Work out the code for each value, and count them.
+	var counts [3][maxSeqSym + 1]uint32
+	for kind := range e.codes {
+		if cap(e.codes[kind]) < n {
+			e.codes[kind] = make([]uint8, n)
+		}
+		e.codes[kind] = e.codes[kind][:n]
+	}
+	llCodes, ofCodes, mlCodes := e.codes[seqLiteral], e.codes[seqOffset], e.codes[seqMatch]
+	for i, s := range e.seqs {
+		llCodes[i] = literalLengthCode(s.litLen)
+		ofCodes[i] = uint8(bits.Len32(s.offset) - 1)
+		mlCodes[i] = matchLengthCode(s.matchLen)
+		counts[seqLiteral][llCodes[i]]++
+		counts[seqOffset][ofCodes[i]]++
+		counts[seqMatch][mlCodes[i]]++
+	}
+
+	// Symbol_Compression_Modes, followed by the table descriptions
+	// in the order literal lengths, offsets, match lengths.
+	var modes [3]byte
+	for kind := range modes {
+		modes[kind] = e.chooseTable(seqCode(kind), counts[kind][:seqCodeInfo[kind].maxSym+1], n)
+	}
+	out = append(out, modes[seqLiteral]<<6|modes[seqOffset]<<4|modes[seqMatch]<<2)
+	out = append(out, e.tableBuf[seqLiteral]...)
+	out = append(out, e.tableBuf[seqOffset]...)
+	out = append(out, e.tableBuf[seqMatch]...)
+
+	// The bitstream is read backward, so we write the
+	// sequences in reverse order. RFC 3.1.1.3.2.2.
+	var llState, ofState, mlState fseState
+	bw := bitWriter{out: out}
+	last := n - 1
+	mlState.init(e.seqEncoder(seqMatch, modes[seqMatch]), mlCodes[last])
+	ofState.init(e.seqEncoder(seqOffset, modes[seqOffset]), ofCodes[last])
+	llState.init(e.seqEncoder(seqLiteral, modes[seqLiteral]), llCodes[last])
+	e.addExtraBits(&bw, last)
+	for i := last - 1; i >= 0; i-- {
+		ofState.encode(&bw, ofCodes[i])
+		mlState.encode(&bw, mlCodes[i])
+		llState.encode(&bw, llCodes[i])
+		e.addExtraBits(&bw, i)
+	}
+	mlState.flush(&bw)
+	ofState.flush(&bw)
+	llState.flush(&bw)
+	return bw.close()
+}
+
+// addExtraBits writes the bits that are added to the baselines
+// for sequence i.
+func (e *encoder) addExtraBits(bw *bitWriter, i int) {
+	s := &e.seqs[i]
+	if llc := e.codes[seqLiteral][i]; llc >= literalLengthOffset {
+		lb := literalLengthBase[llc-literalLengthOffset]
+		bw.addBits(s.litLen-(lb&0xffffff), uint8(lb>>24))
+	}
+	if mlc := e.codes[seqMatch][i]; mlc >= matchLengthOffset {
+		mb := matchLengthBase[mlc-matchLengthOffset]
+		bw.addBits(s.matchLen-(mb&0xffffff), uint8(mb>>24))
+	}
+	ofc := e.codes[seqOffset][i]
+	bw.addBits(s.offset, ofc)
+}
+
+// maxSeqSym is the largest symbol for any kind of sequence code.
+const maxSeqSym = 52
+
+// literalLengthCode returns the code for a literal length.
+// RFC 3.1.1.3.2.1.1.
+func literalLengthCode(ll uint32) uint8 {
+	if ll < literalLengthOffset {
+		return uint8(ll)
+	}
+	if ll >= 64 {
+		return uint8(bits.Len32(ll)) + 18
+	}
+	code := len(literalLengthBase) - 1
+	for literalLengthBase[code]&0xffffff > ll {
+		code--
+	}
+	return uint8(code + literalLengthOffset)
+}
+
+// matchLengthCode returns the code for a match length.
+// RFC 3.1.1.3.2.1.1.
+func matchLengthCode(ml uint32) uint8 {
+	if ml < matchLengthOffset+3 {
+		return uint8(ml - 3)
+	}
+	if ml >= 131 {
+		return uint8(bits.Len32(ml-3)) + 35
+	}
+	code := len(matchLengthBase) - 1
+	for matchLengthBase[code]&0xffffff > ml {
+		code--
+	}
+	return uint8(code + matchLengthOffset)
+}
+
+// chooseTable picks the compression mode for one kind of sequence code,
+// given the counts of each symbol. It sets e.tableBuf[kind] to the
+// table description, and builds e.seqEnc[kind] unless the predefined
+// table is used. It returns the mode. RFC 3.1.1.3.2.2.
+func (e *encoder) chooseTable(kind seqCode, counts []uint32, total int) byte {
+	e.tableBuf[kind] = e.tableBuf[kind][:0]
+
+	maxSym := len(counts) - 1
+	for counts[maxSym] == 0 {
+		maxSym--
+	}
+	counts = counts[:maxSym+1]
+
+	// RLE_Mode if there is only one symbol.
+	if int(counts[maxSym]) == total {
+		var norm [maxSeqSym + 1]int16
+		norm[maxSym] = 1
+		e.seqEnc[kind].build(norm[:maxSym+1], 0)
+		e.tableBuf[kind] = append(e.tableBuf[kind], byte(maxSym))
+		return 1
+	}
+
+	var predef []int16
+	switch kind {
+	case seqLiteral:
+		predef = literalPredefinedDistribution
+	case seqOffset:
+		predef = offsetPredefinedDistribution
+	case seqMatch:
+		predef = matchPredefinedDistribution
+	}
+	predefCost := math.Inf(1)
+	if maxSym <= seqCodeMaxSym[kind] {
+		predefCost = fseCost(counts, predef, seqCodeInfo[kind].predefTableBits)
+	}
+
+	// Don't bother building a table for a few sequences.
+	if total < 16 && !math.IsInf(predefCost, 1) {
+		return 0
+	}
+
+	var norm [maxSeqSym + 1]int16
+	tableBits := fseTableBits(counts, uint32(total), seqCodeInfo[kind].maxBits)
+	normalizeCounts(norm[:maxSym+1], counts, uint32(total), tableBits)
+	desc := appendNCount(e.tableBuf[kind], norm[:maxSym+1], tableBits)
+	cost := fseCost(counts, norm[:maxSym+1], tableBits) + float64(8*len(desc))
+	if cost >= predefCost {
+		return 0
+	}
+	e.tableBuf[kind] = desc
+	e.seqEnc[kind].build(norm[:maxSym+1], tableBits)
+	return 2
+}
+
+// seqEncoder returns the FSE encoder to use for kind
+// with the given compression mode.
+func (e *encoder) seqEncoder(kind seqCode, mode byte) *fseEncoder {
+	if mode != 0 {
+		return &e.seqEnc[kind]
+	}
+	if !e.predefBuilt {
+		e.predef[seqLiteral].build(literalPredefinedDistribution, seqCodeInfo[seqLiteral].predefTableBits)
+		e.predef[seqOffset].build(offsetPredefinedDistribution, seqCodeInfo[seqOffset].predefTableBits)
+		e.predef[seqMatch].build(matchPredefinedDistribution, seqCodeInfo[seqMatch].predefTableBits)
+		e.predefBuilt = true
+	}
+	return &e.predef[kind]
+}
+
+// fseCost returns the approximate number of bits needed to encode
+// symbols with the given counts using a table with the distribution
+// norm and tableBits bits.
+func fseCost(counts []uint32, norm []int16, tableBits int) float64 {
+	cost := 0.0
+	for i, c := range counts {
+		if c == 0 {
+			continue
+		}
+		if i >= len(norm) || norm[i] == 0 {
+			return math.Inf(1)
+		}
+		p := float64(norm[i])
+		if p < 0 {
+			p = 1
+		}
+		cost += float64(c) * (float64(tableBits) - math.Log2(p))
+	}
+	return cost
+}
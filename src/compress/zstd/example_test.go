@@ -0,0 +1,68 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd_test
+
+import (
+	"bytes"
+	"compress/zstd"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+func Example_writerReader() {
+	var buf bytes.Buffer
+	zw := zstd.NewWriter(&buf)
+
+	_, err := zw.Write([]byte("A long time ago in a galaxy far, far away..."))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	zr := zstd.NewReader(&buf)
+	if _, err := io.Copy(os.Stdout, zr); err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// A long time ago in a galaxy far, far away...
+}
+
+func ExampleNewWriterLevelDict() {
+	// A dictionary helps when compressing small messages that
+	// have a lot in common with each other.
+	dict := []byte(`{"name": "", "email": "@example.com", "admin": false}`)
+
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriterLevelDict(&buf, zstd.DefaultCompression, dict)
+	if err != nil {
+		log.Fatal(err)
+	}
+	msg := `{"name": "gopher", "email": "gopher@example.com", "admin": false}`
+	if _, err := io.WriteString(zw, msg); err != nil {
+		log.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	zr, err := zstd.NewReaderDict(&buf, dict)
+	if err != nil {
+		log.Fatal(err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(out))
+
+	// Output:
+	// {"name": "gopher", "email": "gopher@example.com", "admin": false}
+}
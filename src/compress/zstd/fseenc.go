@@ -0,0 +1,303 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"math/bits"
+)
+
+// maxFSEBits is the largest FSE table bits used for encoding.
+const maxFSEBits = 9
+
+// literalPredefinedDistribution is the predefined distribution table
+// for literal lengths. RFC 3.1.1.3.2.2.1.
+var literalPredefinedDistribution = []int16{
+	4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+	2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1,
+	-1, -1, -1, -1,
+}
+
+// offsetPredefinedDistribution is the predefined distribution table
+// for offsets. RFC 3.1.1.3.2.2.3.
+var offsetPredefinedDistribution = []int16{
+	1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1,
+}
+
+// matchPredefinedDistribution is the predefined distribution table
+// for match lengths. RFC 3.1.1.3.2.2.2.
+var matchPredefinedDistribution = []int16{
+	1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, -1, -1,
+	-1, -1, -1, -1, -1,
+}
+
+// fseEncoder is an FSE table used for encoding.
+// This uses the same approach as the reference implementation:
+// for each symbol we record how to compute the number of bits
+// to write from the current state, and where to find the next state.
+type fseEncoder struct {
+	tableBits  uint8
+	stateTable []uint16
+	symbols    []fseSymbolTransform
+}
+
+// fseSymbolTransform describes how to encode a single symbol.
+type fseSymbolTransform struct {
+	deltaNbBits    uint32
+	deltaFindState int32
+}
+
+// build sets up e to encode symbols with the normalized probabilities
+// in norm, using a table with tableBits bits. The symbols are spread
+// across the table exactly as buildFSE does when decoding.
+// A table with 0 bits encodes a single symbol using no bits;
+// this is how we implement RLE_Mode.
+func (e *fseEncoder) build(norm []int16, tableBits int) {
+	tableSize := 1 << tableBits
+	e.tableBits = uint8(tableBits)
+	if cap(e.stateTable) < tableSize {
+		e.stateTable = make([]uint16, tableSize)
+	}
+	e.stateTable = e.stateTable[:tableSize]
+	if cap(e.symbols) < len(norm) {
+		e.symbols = make([]fseSymbolTransform, len(norm))
+	}
+	e.symbols = e.symbols[:len(norm)]
+
+	var tableSymbol [1 << maxFSEBits]uint8
+	var cumul [257]uint16
+
+	highThreshold := tableSize - 1
+	for i, n := range norm {
+		if n == -1 {
+			cumul[i+1] = cumul[i] + 1
+			tableSymbol[highThreshold] = uint8(i)
+			highThreshold--
+		} else {
+			cumul[i+1] = cumul[i] + uint16(n)
+		}
+	}
+
+	pos := 0
+	step := (tableSize >> 1) + (tableSize >> 3) + 3
+	mask := tableSize - 1
+	for i, n := range norm {
+		for j := 0; j < int(n); j++ {
+			tableSymbol[pos] = uint8(i)
+			pos = (pos + step) & mask
+			for pos > highThreshold {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+
+	for i := 0; i < tableSize; i++ {
+		sym := tableSymbol[i]
+		e.stateTable[cumul[sym]] = uint16(tableSize + i)
+		cumul[sym]++
+	}
+
+	total := int32(0)
+	for i, n := range norm {
+		switch n {
+		case 0:
+			// Not used, but set for consistency.
+			e.symbols[i] = fseSymbolTransform{
+				deltaNbBits: uint32(tableBits+1)<<16 - uint32(tableSize),
+			}
+		case -1, 1:
+			e.symbols[i] = fseSymbolTransform{
+				deltaNbBits:    uint32(tableBits)<<16 - uint32(tableSize),
+				deltaFindState: total - 1,
+			}
+			total++
+		default:
+			maxBitsOut := uint32(tableBits - (bits.Len16(uint16(n-1)) - 1))
+			minStatePlus := uint32(n) << maxBitsOut
+			e.symbols[i] = fseSymbolTransform{
+				deltaNbBits:    maxBitsOut<<16 - minStatePlus,
+				deltaFindState: total - int32(n),
+			}
+			total += int32(n)
+		}
+	}
+}
+
+// fseState is the state of an FSE encoder.
+type fseState struct {
+	e     *fseEncoder
+	state uint32
+}
+
+// init sets the initial state to encode sym.
+// Nothing is written for the first symbol;
+// it is implied by the final state.
+func (s *fseState) init(e *fseEncoder, sym uint8) {
+	s.e = e
+	tt := e.symbols[sym]
+	nbBitsOut := (tt.deltaNbBits + 1<<15) >> 16
+	v := nbBitsOut<<16 - tt.deltaNbBits
+	s.state = uint32(e.stateTable[int32(v>>nbBitsOut)+tt.deltaFindState])
+}
+
+// encode writes the bits needed to move to a state for sym.
+func (s *fseState) encode(bw *bitWriter, sym uint8) {
+	tt := s.e.symbols[sym]
+	nbBitsOut := (s.state + tt.deltaNbBits) >> 16
+	bw.addBits(s.state, uint8(nbBitsOut))
+	s.state = uint32(s.e.stateTable[int32(s.state>>nbBitsOut)+tt.deltaFindState])
+}
+
+// flush writes the final state, which the decoder reads first.
+func (s *fseState) flush(bw *bitWriter) {
+	bw.addBits(s.state, s.e.tableBits)
+}
+
+// fseTableBits returns the number of table bits to use when
+// encoding total symbols with the given counts, where no table
+// may have more than maxBits bits.
+// This follows FSE_optimalTableLog in the reference implementation.
+func fseTableBits(counts []uint32, total uint32, maxBits int) int {
+	maxSym := 0
+	nsyms := 0
+	for i, c := range counts {
+		if c > 0 {
+			maxSym = i
+			nsyms++
+		}
+	}
+
+	tableBits := maxBits
+	if b := bits.Len32(total-1) - 3; b < tableBits {
+		tableBits = b
+	}
+	minBits := bits.Len32(total)
+	if b := bits.Len32(uint32(maxSym)) + 1; b < minBits {
+		minBits = b
+	}
+	if minBits > tableBits {
+		tableBits = minBits
+	}
+	if tableBits < 5 {
+		tableBits = 5
+	}
+	for 1<<tableBits < nsyms {
+		tableBits++
+	}
+	if tableBits > maxBits {
+		tableBits = maxBits
+	}
+	return tableBits
+}
+
+// normalizeCounts sets norm to a distribution of probabilities
+// for counts that sums to 1<<tableBits. Every symbol with a
+// non-zero count gets a probability of at least 1.
+// The number of symbols with non-zero counts must be
+// at most 1<<tableBits.
+func normalizeCounts(norm []int16, counts []uint32, total uint32, tableBits int) {
+	tableSize := int32(1) << tableBits
+	sum := int32(0)
+	largest := -1
+	for i, c := range counts {
+		if c == 0 {
+			norm[i] = 0
+			continue
+		}
+		n := int32((uint64(c)<<tableBits + uint64(total)/2) / uint64(total))
+		if n == 0 {
+			n = 1
+		}
+		norm[i] = int16(n)
+		sum += n
+		if largest < 0 || c > counts[largest] {
+			largest = i
+		}
+	}
+
+	// Rounding, and forcing small values to 1, can leave us with
+	// the wrong total. Adjust the largest probabilities to fix it.
+	for sum < tableSize {
+		norm[largest]++
+		sum++
+	}
+	for sum > tableSize {
+		big := largest
+		for i, n := range norm {
+			if n > norm[big] {
+				big = i
+			}
+		}
+		norm[big]--
+		sum--
+	}
+}
+
+// appendNCount appends the FSE table description for norm,
+// which uses tableBits bits, to out. This is the inverse of readFSE.
+// This follows FSE_writeNCount in the reference implementation.
+// RFC 4.1.1.
+func appendNCount(out []byte, norm []int16, tableBits int) []byte {
+	maxSym := len(norm) - 1
+	for maxSym > 0 && norm[maxSym] == 0 {
+		maxSym--
+	}
+
+	bw := bitWriter{out: out}
+	bw.addBits(uint32(tableBits-5), 4)
+
+	tableSize := 1 << tableBits
+	remaining := tableSize + 1
+	threshold := tableSize
+	bitsNeeded := tableBits + 1
+
+	prev0 := false
+	sym := 0
+	for sym <= maxSym && remaining > 1 {
+		if prev0 {
+			start := sym
+			for norm[sym] == 0 {
+				sym++
+			}
+			for sym >= start+24 {
+				start += 24
+				bw.addBits(0xffff, 16)
+			}
+			for sym >= start+3 {
+				start += 3
+				bw.addBits(3, 2)
+			}
+			bw.addBits(uint32(sym-start), 2)
+		}
+
+		count := int(norm[sym])
+		sym++
+		max := (2*threshold - 1) - remaining
+		if count < 0 {
+			remaining += count
+		} else {
+			remaining -= count
+		}
+		count++
+		if count >= threshold {
+			count += max
+		}
+		if count < max {
+			bw.addBits(uint32(count), uint8(bitsNeeded-1))
+		} else {
+			bw.addBits(uint32(count), uint8(bitsNeeded))
+		}
+		prev0 = count == 1
+
+		for remaining < threshold {
+			bitsNeeded--
+			threshold >>= 1
+		}
+	}
+
+	return bw.flush()
+}
@@ -138,3 +138,35 @@ func FuzzReverse(f *testing.F) {
 		}
 	})
 }
+
+// Fuzz test to check that we can decompress what we compress.
+func FuzzWriter(f *testing.F) {
+	for _, test := range tests {
+		f.Add([]byte(test.uncompressed), uint8(DefaultCompression))
+	}
+	f.Add(bytes.Repeat([]byte("abcdefghijklmnop"), 256), uint8(BestSpeed))
+	f.Add(bigData(f), uint8(DefaultCompression))
+
+	f.Fuzz(func(t *testing.T, b []byte, level uint8) {
+		level = level%DefaultCompression + 1
+		var buf bytes.Buffer
+		w, err := NewWriterLevel(&buf, int(level))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := io.ReadAll(NewReader(&buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, b) {
+			showDiffs(t, got, b)
+		}
+	})
+}
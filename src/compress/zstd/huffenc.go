@@ -0,0 +1,309 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+// huffmanEncoder is a Huffman code used to compress literals.
+// RFC 4.2.
+type huffmanEncoder struct {
+	codes     [256]uint16 // code for each symbol
+	lengths   [256]uint8  // code length for each symbol, 0 if not used
+	tableBits int         // maximum code length
+	maxSym    int         // largest symbol with a code
+
+	// Scratch space used to build the code.
+	leaves [256]huffmanLeaf
+	nodes  [511]huffmanNode
+}
+
+// huffmanLeaf is a symbol and its count.
+type huffmanLeaf struct {
+	count uint32
+	sym   uint8
+}
+
+// huffmanNode is a node in a Huffman tree while building it.
+type huffmanNode struct {
+	count  uint32
+	parent uint16
+	depth  uint8
+}
+
+// build builds a Huffman code for symbols with the given counts.
+// At least two symbols must have non-zero counts.
+// Code lengths are limited to maxHuffmanBits.
+func (h *huffmanEncoder) build(counts *[256]uint32) {
+	var scaled [256]uint32
+	scaled = *counts
+	for {
+		if h.buildLengths(&scaled) <= maxHuffmanBits {
+			break
+		}
+		// The code is too long. Flatten the distribution
+		// and try again. This always terminates, because
+		// eventually all counts are 1.
+		for i, c := range scaled {
+			if c > 0 {
+				scaled[i] = (c + 1) / 2
+			}
+		}
+	}
+	h.assignCodes()
+}
+
+// buildLengths sets h.lengths to the Huffman code lengths for counts,
+// and returns the maximum length.
+func (h *huffmanEncoder) buildLengths(counts *[256]uint32) int {
+	leaves := h.leaves[:0]
+	h.maxSym = 0
+	for i, c := range counts {
+		h.lengths[i] = 0
+		if c > 0 {
+			leaves = append(leaves, huffmanLeaf{count: c, sym: uint8(i)})
+			h.maxSym = i
+		}
+	}
+	// Sort the leaves by count. We use an insertion sort
+	// as there are at most 256 leaves, and they are often
+	// mostly sorted.
+	for i := 1; i < len(leaves); i++ {
+		l := leaves[i]
+		j := i
+		for ; j > 0 && leaves[j-1].count > l.count; j-- {
+			leaves[j] = leaves[j-1]
+		}
+		leaves[j] = l
+	}
+
+	// Build the tree using two queues: the sorted leaves,
+	// and the internal nodes, which are created in order
+	// of increasing count.
+	n := len(leaves)
+	nodes := h.nodes[:2*n-1]
+	for i, l := range leaves {
+		nodes[i].count = l.count
+	}
+	leaf, internal := 0, n
+	pick := func(next int) int {
+		if leaf < n && (internal >= next || nodes[leaf].count <= nodes[internal].count) {
+			leaf++
+			return leaf - 1
+		}
+		internal++
+		return internal - 1
+	}
+	for next := n; next < len(nodes); next++ {
+		a := pick(next)
+		b := pick(next)
+		nodes[next].count = nodes[a].count + nodes[b].count
+		nodes[a].parent = uint16(next)
+		nodes[b].parent = uint16(next)
+	}
+
+	maxLen := 0
+	nodes[len(nodes)-1].depth = 0
+	for i := len(nodes) - 2; i >= 0; i-- {
+		d := nodes[nodes[i].parent].depth + 1
+		nodes[i].depth = d
+		if i < n {
+			h.lengths[leaves[i].sym] = d
+			if int(d) > maxLen {
+				maxLen = int(d)
+			}
+		}
+	}
+	return maxLen
+}
+
+// assignCodes assigns codes based on h.lengths, in the order
+// that readHuff expects: symbols with longer codes come first,
+// and symbols with the same code length are in increasing order.
+func (h *huffmanEncoder) assignCodes() {
+	tableBits := 0
+	for _, l := range h.lengths {
+		if int(l) > tableBits {
+			tableBits = int(l)
+		}
+	}
+	h.tableBits = tableBits
+
+	// The weight of a symbol is tableBits+1-length.
+	// A symbol with weight w covers 1<<(w-1) entries
+	// in the decoding table.
+	var weightCount [maxHuffmanBits + 2]uint32
+	for _, l := range h.lengths[:h.maxSym+1] {
+		if l > 0 {
+			weightCount[tableBits+1-int(l)]++
+		}
+	}
+	var start [maxHuffmanBits + 2]uint32
+	next := uint32(0)
+	for w := 1; w <= tableBits; w++ {
+		start[w] = next
+		next += weightCount[w] << (w - 1)
+	}
+	for i, l := range h.lengths[:h.maxSym+1] {
+		if l == 0 {
+			continue
+		}
+		w := tableBits + 1 - int(l)
+		h.codes[i] = uint16(start[w] >> (w - 1))
+		start[w] += 1 << (w - 1)
+	}
+}
+
+// weights stores the weights of all symbols but the last in w,
+// and returns the number of weights. RFC 4.2.1.
+func (h *huffmanEncoder) weights(w *[256]uint8) int {
+	for i, l := range h.lengths[:h.maxSym] {
+		if l == 0 {
+			w[i] = 0
+		} else {
+			w[i] = uint8(h.tableBits + 1 - int(l))
+		}
+	}
+	return h.maxSym
+}
+
+// encodedBits returns the number of bits required to
+// encode symbols with the given counts.
+func (h *huffmanEncoder) encodedBits(counts *[256]uint32) int {
+	total := 0
+	for i, c := range counts[:h.maxSym+1] {
+		total += int(c) * int(h.lengths[i])
+	}
+	return total
+}
+
+// appendStream appends a single Huffman compressed stream of lits
+// to out. The literals are written in reverse order,
+// because the stream is read backward.
+func (h *huffmanEncoder) appendStream(out, lits []byte) []byte {
+	bw := bitWriter{out: out}
+	for i := len(lits) - 1; i >= 0; i-- {
+		c := lits[i]
+		bw.addBits(uint32(h.codes[c]), h.lengths[c])
+	}
+	return bw.close()
+}
+
+// appendHuffmanWeights appends the Huffman tree description
+// for e.huff to out. This reports false if the description
+// can't be written. RFC 4.2.1.
+func (e *encoder) appendHuffmanWeights(out []byte) ([]byte, bool) {
+	var weights [256]uint8
+	count := e.huff.weights(&weights)
+
+	// Try compressing the weights with FSE. RFC 4.2.1.2.
+	if count >= 2 {
+		if res, ok := e.appendFSEWeights(out, weights[:count]); ok {
+			if count > 128 || len(res)-len(out) < 1+(count+1)/2 {
+				return res, true
+			}
+			out = res[:len(out)]
+		}
+	}
+
+	// Write the weights directly, 4 bits each. RFC 4.2.1.1.
+	if count > 128 {
+		return out, false
+	}
+	out = append(out, byte(127+count))
+	for i := 0; i < count; i += 2 {
+		out = append(out, weights[i]<<4|weights[i+1])
+	}
+	return out, true
+}
+
+// appendFSEWeights appends the FSE compressed form of weights to out,
+// preceded by the header byte. This reports false if the compressed
+// form is too large or cannot be decoded unambiguously.
+func (e *encoder) appendFSEWeights(out []byte, weights []uint8) ([]byte, bool) {
+	var counts [maxHuffmanBits + 1]uint32
+	for _, w := range weights {
+		counts[w]++
+	}
+	maxW := len(counts) - 1
+	for counts[maxW] == 0 {
+		maxW--
+	}
+	var norm [maxHuffmanBits + 1]int16
+	tableBits := fseTableBits(counts[:maxW+1], uint32(len(weights)), 6)
+	normalizeCounts(norm[:maxW+1], counts[:maxW+1], uint32(len(weights)), tableBits)
+	fe := &e.weightEnc
+	fe.build(norm[:maxW+1], tableBits)
+
+	hdrOff := len(out)
+	out = append(out, 0)
+	start := len(out)
+	out = appendNCount(out, norm[:maxW+1], tableBits)
+
+	// There are two interleaved streams. The decoder reads
+	// alternately from state1 and state2, starting with state1,
+	// so we encode in reverse, ending with state1.
+	var state1, state2 fseState
+	bw := bitWriter{out: out}
+	i := len(weights)
+	if i&1 != 0 {
+		state1.init(fe, weights[i-1])
+		state2.init(fe, weights[i-2])
+		state1.encode(&bw, weights[i-3])
+		i -= 3
+	} else {
+		state2.init(fe, weights[i-1])
+		state1.init(fe, weights[i-2])
+		i -= 2
+	}
+	for i > 0 {
+		state2.encode(&bw, weights[i-1])
+		state1.encode(&bw, weights[i-2])
+		i -= 2
+	}
+	state2.flush(&bw)
+	state1.flush(&bw)
+	out = bw.close()
+
+	size := len(out) - start
+	if size >= 128 {
+		return out[:hdrOff], false
+	}
+	out[hdrOff] = byte(size)
+
+	// The decoder stops when it runs out of bits,
+	// which is ambiguous for some distributions.
+	// Make sure that we get back what we wrote.
+	if !e.checkWeights(out[hdrOff:]) {
+		return out[:hdrOff], false
+	}
+
+	return out, true
+}
+
+// checkWeights reports whether the Huffman tree description in data
+// decodes to the code in e.huff.
+func (e *encoder) checkWeights(data []byte) bool {
+	if len(e.checkTable) == 0 {
+		e.checkTable = make([]uint16, 1<<maxHuffmanBits)
+	}
+	table := e.checkTable
+	h := &e.huff
+	tableBits, _, err := e.checkReader.readHuff(data, 0, table)
+	if err != nil || tableBits != h.tableBits {
+		return false
+	}
+	for i, l := range h.lengths[:h.maxSym+1] {
+		if l == 0 {
+			continue
+		}
+		shift := tableBits - int(l)
+		start := int(h.codes[i]) << shift
+		want := uint16(i)<<8 | uint16(l)
+		for _, t := range table[start : start+1<<shift] {
+			if t != want {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,281 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"encoding/binary"
+)
+
+// minMatch is the shortest match that the encoder looks for.
+// The format permits matches of 3 bytes, but those are rarely
+// worth encoding.
+const minMatch = 4
+
+// seq is a single sequence: a run of literals followed by a match.
+// RFC 3.1.1.3.2.
+type seq struct {
+	litLen   uint32 // number of literals
+	matchLen uint32 // length of match, at least 3
+	offset   uint32 // Offset_Value: offset+3, or 1 for repeated offset 1
+}
+
+// levelParams are the parameters used for a compression level.
+type levelParams struct {
+	windowLog uint8 // log of the window size
+	hashBits  uint8 // number of bits in hash table index
+	chainBits uint8 // number of bits in hash chain index, 0 for no chain
+	depth     int   // maximum number of hash chain entries to check
+	lazy      bool  // whether to check for a better match at the next byte
+}
+
+// levels maps compression levels to parameters.
+var levels = [...]levelParams{
+	1: {windowLog: 19, hashBits: 15},
+	2: {windowLog: 20, hashBits: 16, chainBits: 16, depth: 4},
+	3: {windowLog: 20, hashBits: 17, chainBits: 17, depth: 16, lazy: true},
+}
+
+// hash4 hashes the 4 bytes in u into b bits.
+func hash4(u uint32, b uint8) uint32 {
+	return (u * 2654435761) >> (32 - b)
+}
+
+// load32 loads 4 bytes from b at i.
+func load32(b []byte, i int) uint32 {
+	return binary.LittleEndian.Uint32(b[i:])
+}
+
+// matchLen returns the length of the common prefix of a and b.
+// a must be no longer than b.
+func matchLen(a, b []byte) int {
+	for i := range a {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return len(a)
+}
+
+// matcher finds matches in a history buffer.
+// All positions are indexes into that buffer;
+// a negative position means no entry.
+type matcher struct {
+	params     levelParams
+	windowSize int
+
+	table []int32 // most recent position for each hash
+	chain []int32 // previous position with the same hash
+	next  int     // next position to add to the chain
+
+	// The current repeated offsets. RFC 3.1.1.5.
+	rep [3]uint32
+
+	seqs []seq
+	lits []byte
+}
+
+// init prepares m to find matches for a new frame.
+func (m *matcher) init(params levelParams, windowSize int, rep [3]uint32) {
+	m.params = params
+	m.windowSize = windowSize
+	m.table = resetPositions(m.table, 1<<params.hashBits)
+	if params.chainBits > 0 {
+		m.chain = resetPositions(m.chain, 1<<params.chainBits)
+	}
+	m.next = 0
+	m.rep = rep
+}
+
+// resetPositions returns a slice of n positions, all set to -1,
+// reusing s if possible.
+func resetPositions(s []int32, n int) []int32 {
+	if cap(s) < n {
+		s = make([]int32, n)
+	}
+	s = s[:n]
+	for i := range s {
+		s[i] = -1
+	}
+	return s
+}
+
+// shift adjusts all positions after the first n bytes
+// of the history buffer have been discarded.
+// n must be a multiple of the hash chain size.
+func (m *matcher) shift(n int) {
+	shiftPositions(m.table, n)
+	shiftPositions(m.chain, n)
+	m.next -= n
+	if m.next < 0 {
+		m.next = 0
+	}
+}
+
+// shiftPositions subtracts n from each position in s.
+func shiftPositions(s []int32, n int) {
+	for i, p := range s {
+		if p >= int32(n) {
+			s[i] = p - int32(n)
+		} else {
+			s[i] = -1
+		}
+	}
+}
+
+// prime adds the positions in hist, which is the dictionary content,
+// to the tables, so that matches can refer to it.
+func (m *matcher) prime(hist []byte) {
+	if m.params.chainBits > 0 {
+		m.insert(hist, len(hist))
+		return
+	}
+	for i := 0; i+minMatch <= len(hist); i++ {
+		m.table[hash4(load32(hist, i), m.params.hashBits)] = int32(i)
+	}
+}
+
+// insert adds the positions before end in src to the hash chains.
+func (m *matcher) insert(src []byte, end int) {
+	if end > len(src)-minMatch+1 {
+		end = len(src) - minMatch + 1
+	}
+	mask := len(m.chain) - 1
+	for ; m.next < end; m.next++ {
+		h := hash4(load32(src, m.next), m.params.hashBits)
+		m.chain[m.next&mask] = m.table[h]
+		m.table[h] = int32(m.next)
+	}
+}
+
+// findMatches finds the sequences for the block src[start:].
+// src[:start] is the history available for matches.
+// This sets m.seqs and m.lits.
+func (m *matcher) findMatches(src []byte, start int) {
+	m.seqs = m.seqs[:0]
+	m.lits = m.lits[:0]
+
+	// Don't look for matches that can't be verified by load32.
+	sLimit := len(src) - 8
+	litStart := start
+	s := start
+	for s < sLimit {
+		// Look for a match at the first repeated offset.
+		// We only do this after at least one literal,
+		// as otherwise the repeat code means something else.
+		if s > litStart {
+			if r := int(m.rep[0]); s-r >= 0 && load32(src, s-r) == load32(src, s) {
+				length := minMatch + matchLen(src[s+minMatch:], src[s-r+minMatch:])
+				m.addSeq(src, litStart, s, length, 1)
+				s += length
+				litStart = s
+				m.afterMatch(src, s)
+				continue
+			}
+		}
+
+		var cand, length int
+		if m.params.chainBits == 0 {
+			cand, length = m.fastMatch(src, s)
+		} else {
+			cand, length = m.chainMatch(src, s)
+			if length >= minMatch && m.params.lazy && s+1 < sLimit {
+				if cand1, length1 := m.chainMatch(src, s+1); length1 > length {
+					s++
+					cand, length = cand1, length1
+				}
+			}
+		}
+
+		if length < minMatch {
+			// Skip ahead faster when we aren't finding matches.
+			if m.params.chainBits == 0 {
+				s += 1 + (s-litStart)>>6
+			} else {
+				s++
+			}
+			continue
+		}
+
+		// Extend the match backward.
+		for s > litStart && cand > 0 && src[s-1] == src[cand-1] {
+			s--
+			cand--
+			length++
+		}
+
+		offset := s - cand
+		m.addSeq(src, litStart, s, length, uint32(offset)+3)
+		m.rep = [3]uint32{uint32(offset), m.rep[0], m.rep[1]}
+		s += length
+		litStart = s
+		m.afterMatch(src, s)
+	}
+
+	m.lits = append(m.lits, src[litStart:]...)
+}
+
+// addSeq adds a sequence with the literals src[litStart:s]
+// and a match of length at s.
+func (m *matcher) addSeq(src []byte, litStart, s, length int, offset uint32) {
+	m.lits = append(m.lits, src[litStart:s]...)
+	m.seqs = append(m.seqs, seq{
+		litLen:   uint32(s - litStart),
+		matchLen: uint32(length),
+		offset:   offset,
+	})
+}
+
+// afterMatch updates the hash table after a match that ends at s.
+func (m *matcher) afterMatch(src []byte, s int) {
+	if m.params.chainBits == 0 && s-2 >= 0 && s+2 <= len(src) {
+		m.table[hash4(load32(src, s-2), m.params.hashBits)] = int32(s - 2)
+	}
+}
+
+// fastMatch looks up the position s in the hash table,
+// replacing the entry with s. It returns the position and length
+// of a match, if any.
+func (m *matcher) fastMatch(src []byte, s int) (int, int) {
+	cur := load32(src, s)
+	h := hash4(cur, m.params.hashBits)
+	cand := int(m.table[h])
+	m.table[h] = int32(s)
+	if cand < 0 || s-cand > m.windowSize || load32(src, cand) != cur {
+		return 0, 0
+	}
+	return cand, minMatch + matchLen(src[s+minMatch:], src[cand+minMatch:])
+}
+
+// chainMatch searches the hash chain for the longest match at s.
+// It returns the position and length of the match, if any.
+func (m *matcher) chainMatch(src []byte, s int) (int, int) {
+	m.insert(src, s)
+
+	mask := len(m.chain) - 1
+	cur := load32(src, s)
+	bestCand, bestLen := 0, 0
+	cand := int(m.table[hash4(cur, m.params.hashBits)])
+	for depth := m.params.depth; depth > 0 && cand >= 0; depth-- {
+		if s-cand > m.windowSize || s-cand > mask {
+			break
+		}
+		if load32(src, cand) == cur && (bestLen == 0 || src[cand+bestLen] == src[s+bestLen]) {
+			length := minMatch + matchLen(src[s+minMatch:], src[cand+minMatch:])
+			if length > bestLen {
+				bestCand, bestLen = cand, length
+				if s+bestLen >= len(src) {
+					// Can't do any better.
+					break
+				}
+			}
+		}
+		next := int(m.chain[cand&mask])
+		if next >= cand {
+			break
+		}
+		cand = next
+	}
+	return bestCand, bestLen
+}
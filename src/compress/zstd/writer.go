@@ -0,0 +1,352 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// These constants are the compression levels accepted by
+// [NewWriterLevel] and [NewWriterLevelDict].
+const (
+	BestSpeed          = 1
+	DefaultCompression = 3
+)
+
+// maxBlockSize is the largest block size. RFC 3.1.1.2.4.
+const maxBlockSize = 128 << 10
+
+var errWriterClosed = errors.New("zstd: write to closed Writer")
+
+// A Writer is an io.WriteCloser.
+// Writes to a Writer are compressed and written to w.
+//
+// The output is a single zstd frame, which includes
+// a checksum of the uncompressed data.
+type Writer struct {
+	w      io.Writer
+	level  int
+	params levelParams
+	dict   *dictionary
+	err    error
+
+	wroteHeader bool
+	closed      bool
+
+	// hist holds the data that may be used for back references,
+	// followed by data that has not yet been compressed,
+	// which starts at hist[start:].
+	hist  []byte
+	start int
+
+	checksum xxhash64
+	enc      encoder
+
+	// Buffer for the current output block.
+	out []byte
+}
+
+// NewWriter returns a new Writer.
+// Writes to the returned writer are compressed and written to w.
+//
+// It is the caller's responsibility to call Close on the Writer when done.
+// Writes may be buffered and not flushed until Close.
+func NewWriter(w io.Writer) *Writer {
+	z, _ := NewWriterLevel(w, DefaultCompression)
+	return z
+}
+
+// NewWriterLevel is like [NewWriter] but specifies the compression level
+// instead of assuming [DefaultCompression].
+//
+// The compression level can be [BestSpeed], [DefaultCompression],
+// or any integer value between them.
+// The error returned will be nil if the level is valid.
+func NewWriterLevel(w io.Writer, level int) (*Writer, error) {
+	return NewWriterLevelDict(w, level, nil)
+}
+
+// NewWriterLevelDict is like [NewWriterLevel] but compresses using
+// the dictionary dict. The dictionary may use the zstd dictionary
+// format, or it may be raw content. The compressed data can only be
+// decompressed by a [Reader] created by [NewReaderDict] with the
+// same dictionary.
+//
+// The dictionary must not be modified while the Writer is in use.
+func NewWriterLevelDict(w io.Writer, level int, dict []byte) (*Writer, error) {
+	if level < BestSpeed || level > DefaultCompression {
+		return nil, fmt.Errorf("zstd: invalid compression level: %d", level)
+	}
+	z := &Writer{
+		level:  level,
+		params: levels[level],
+	}
+	if len(dict) > 0 {
+		d, err := parseDictionary(dict)
+		if err != nil {
+			return nil, err
+		}
+		z.dict = d
+	}
+	z.Reset(w)
+	return z, nil
+}
+
+// Reset discards the Writer z's state and makes it equivalent to the
+// result of its original state from [NewWriter] or [NewWriterLevel],
+// but writing to w instead. This permits reusing a Writer rather than
+// allocating a new one. The Writer continues to use the same compression
+// level and dictionary.
+func (z *Writer) Reset(w io.Writer) {
+	z.w = w
+	z.err = nil
+	z.wroteHeader = false
+	z.closed = false
+	z.checksum.reset()
+
+	windowSize := 1 << z.params.windowLog
+	rep := [3]uint32{1, 4, 8}
+	z.hist = z.hist[:0]
+	if z.dict != nil {
+		rep = z.dict.repeatedOffsets
+		content := z.dict.content
+		if len(content) > windowSize {
+			content = content[len(content)-windowSize:]
+		}
+		z.hist = append(z.hist, content...)
+	}
+	z.start = len(z.hist)
+
+	z.enc.init(z.params, windowSize, rep)
+	z.enc.prime(z.hist)
+}
+
+// Write writes a compressed form of p to the underlying io.Writer.
+// The compressed bytes are not necessarily flushed until
+// the Writer is closed.
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	if z.closed {
+		return 0, errWriterClosed
+	}
+	n := 0
+	for len(p) > 0 {
+		space := maxBlockSize - (len(z.hist) - z.start)
+		if space == 0 {
+			// Only write a full block when there is more data,
+			// so that Close can mark the final block.
+			if err := z.writeBlock(false); err != nil {
+				return n, err
+			}
+			continue
+		}
+		if space > len(p) {
+			space = len(p)
+		}
+		z.hist = append(z.hist, p[:space]...)
+		z.checksum.update(p[:space])
+		p = p[space:]
+		n += space
+	}
+	return n, nil
+}
+
+// Flush writes any pending data to the underlying writer.
+// It is useful mainly in compressed network protocols, to ensure that
+// a remote reader has enough data to reconstruct a packet.
+// Flush does not return until the data has been written.
+// If the underlying writer returns an error, Flush returns that error.
+//
+// Flushing may reduce the compression ratio.
+func (z *Writer) Flush() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.closed {
+		return nil
+	}
+	if len(z.hist) > z.start {
+		return z.writeBlock(false)
+	}
+	if !z.wroteHeader {
+		z.out = z.appendFrameHeader(z.out[:0], false)
+		return z.write(z.out)
+	}
+	return nil
+}
+
+// Close closes the Writer by flushing any unwritten data to the
+// underlying io.Writer and writing the end of the frame,
+// including the checksum. It does not close the underlying io.Writer.
+func (z *Writer) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.closed {
+		return nil
+	}
+	z.closed = true
+	if err := z.writeBlock(true); err != nil {
+		return err
+	}
+	var cs [4]byte
+	binary.LittleEndian.PutUint32(cs[:], uint32(z.checksum.digest()))
+	return z.write(cs[:])
+}
+
+// write writes b to the underlying writer,
+// recording any error.
+func (z *Writer) write(b []byte) error {
+	if _, err := z.w.Write(b); err != nil {
+		z.err = err
+		return err
+	}
+	return nil
+}
+
+// appendFrameHeader appends a frame header to out.
+// If known is true, the frame consists of just the data in z.hist
+// following z.start, so we can record the size. RFC 3.1.1.1.
+func (z *Writer) appendFrameHeader(out []byte, known bool) []byte {
+	z.wroteHeader = true
+
+	out = binary.LittleEndian.AppendUint32(out, 0xfd2fb528)
+
+	// Frame_Header_Descriptor.
+	// Always set the Content_Checksum_flag.
+	descriptor := byte(1 << 2)
+
+	size := len(z.hist) - z.start
+	switch {
+	case !known:
+	case size < 256:
+		descriptor |= 1 << 5
+	case size < 65536+256:
+		descriptor |= 1<<6 | 1<<5
+	default:
+		descriptor |= 2<<6 | 1<<5
+	}
+
+	var dictID uint32
+	if z.dict != nil {
+		dictID = z.dict.id
+	}
+	if dictID != 0 {
+		descriptor |= 3
+	}
+
+	out = append(out, descriptor)
+
+	if !known {
+		// Window_Descriptor, with a mantissa of 0.
+		out = append(out, (z.params.windowLog-10)<<3)
+	}
+
+	if dictID != 0 {
+		out = binary.LittleEndian.AppendUint32(out, dictID)
+	}
+
+	if known {
+		switch {
+		case size < 256:
+			out = append(out, byte(size))
+		case size < 65536+256:
+			out = binary.LittleEndian.AppendUint16(out, uint16(size-256))
+		default:
+			out = binary.LittleEndian.AppendUint32(out, uint32(size))
+		}
+	}
+
+	return out
+}
+
+// writeBlock compresses and writes the pending data as a block.
+// If last is true, this is the last block of the frame.
+func (z *Writer) writeBlock(last bool) error {
+	out := z.out[:0]
+	if !z.wroteHeader {
+		// If this is the only block in the frame,
+		// record the size in the frame header.
+		out = z.appendFrameHeader(out, last)
+	}
+
+	block := z.hist[z.start:]
+	hdr := len(out)
+	out = append(out, 0, 0, 0)
+
+	var typ uint32
+	switch {
+	case len(block) == 0:
+		// An empty Raw_Block.
+		typ = 0
+	case isRLE(block):
+		typ = 1
+		out = append(out, block[0])
+	default:
+		rep := z.enc.rep
+		out = z.enc.compressBlock(out, z.hist, z.start)
+		typ = 2
+		if len(out)-hdr-3 >= len(block) {
+			// Compression didn't help. The decoder won't
+			// see any sequences, so discard the repeated
+			// offsets we just found.
+			z.enc.rep = rep
+			out = append(out[:hdr+3], block...)
+			typ = 0
+		}
+	}
+
+	// Block_Header. RFC 3.1.1.2.
+	size := len(out) - hdr - 3
+	if typ == 1 {
+		size = len(block)
+	}
+	bh := typ << 1
+	if last {
+		bh |= 1
+	}
+	bh |= uint32(size) << 3
+	out[hdr] = byte(bh)
+	out[hdr+1] = byte(bh >> 8)
+	out[hdr+2] = byte(bh >> 16)
+
+	z.out = out
+	if err := z.write(out); err != nil {
+		return err
+	}
+
+	z.start = len(z.hist)
+	z.slide()
+	return nil
+}
+
+// isRLE reports whether all the bytes in b are the same.
+func isRLE(b []byte) bool {
+	for _, c := range b[1:] {
+		if c != b[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// slide discards history that is no longer needed.
+// It only discards a multiple of maxBlockSize bytes,
+// which keeps the hash chains consistent.
+func (z *Writer) slide() {
+	windowSize := 1 << z.params.windowLog
+	if len(z.hist) < 2*windowSize {
+		return
+	}
+	n := (len(z.hist) - windowSize) &^ (maxBlockSize - 1)
+	z.hist = z.hist[:copy(z.hist, z.hist[n:])]
+	z.start -= n
+	z.enc.shift(n)
+}
@@ -0,0 +1,368 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package zstd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writerTest is an input for the Writer tests.
+type writerTest struct {
+	name string
+	data []byte
+}
+
+// writerTests returns inputs for the Writer tests.
+func writerTests(t testing.TB) []writerTest {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 200<<10)
+	rnd.Read(random)
+
+	// Text with some random changes, so that there are
+	// matches at many different offsets.
+	big := bigData(t)
+	var mutated []byte
+	for i := 0; i < 5; i++ {
+		start := len(mutated)
+		mutated = append(mutated, big...)
+		for j := 0; j < 1000; j++ {
+			mutated[start+rnd.Intn(len(big))] = byte(rnd.Intn(256))
+		}
+	}
+
+	var counting bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&counting, "%d,", i)
+	}
+
+	ret := []writerTest{
+		{"empty", nil},
+		{"byte", []byte{'a'}},
+		{"short", []byte("hello, world\n")},
+		{"zeroes", make([]byte, 300<<10)},
+		{"repeat", bytes.Repeat([]byte("abcdefghijklmnop"), 10000)},
+		{"random", random},
+		{"counting", counting.Bytes()},
+		{"big", big},
+	}
+	if !testing.Short() {
+		ret = append(ret, writerTest{"mutated", mutated})
+	}
+	for _, test := range tests {
+		ret = append(ret, writerTest{test.name, []byte(test.uncompressed)})
+	}
+	return ret
+}
+
+// compress compresses data at the given level.
+func compress(t testing.TB, data []byte, level int, dict []byte) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriterLevelDict(&buf, level, dict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Write in uneven pieces to exercise buffering.
+	for len(data) > 0 {
+		n := 100<<10 + 1
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// decompress decompresses data, using dict if not nil.
+func decompress(t testing.TB, data, dict []byte) []byte {
+	var r *Reader
+	if dict == nil {
+		r = NewReader(bytes.NewReader(data))
+	} else {
+		var err error
+		r, err = NewReaderDict(bytes.NewReader(data), dict)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	for _, test := range writerTests(t) {
+		for level := BestSpeed; level <= DefaultCompression; level++ {
+			t.Run(fmt.Sprintf("%s/%d", test.name, level), func(t *testing.T) {
+				compressed := compress(t, test.data, level, nil)
+				t.Logf("compressed %d bytes to %d", len(test.data), len(compressed))
+				got := decompress(t, compressed, nil)
+				if !bytes.Equal(got, test.data) {
+					showDiffs(t, got, test.data)
+				}
+			})
+		}
+	}
+}
+
+func TestWriterCompresses(t *testing.T) {
+	data := bigData(t)
+	prev := len(data)
+	for level := BestSpeed; level <= DefaultCompression; level++ {
+		compressed := compress(t, data, level, nil)
+		t.Logf("level %d: compressed %d bytes to %d", level, len(data), len(compressed))
+		// Check for a reasonable compression ratio,
+		// which should improve with each level.
+		if len(compressed) > len(data)/2 || len(compressed) > prev {
+			t.Errorf("level %d: compressed %d bytes to %d", level, len(data), len(compressed))
+		}
+		prev = len(compressed)
+	}
+}
+
+func TestWriterLevel(t *testing.T) {
+	for _, level := range []int{-1, 0, DefaultCompression + 1} {
+		if _, err := NewWriterLevel(io.Discard, level); err == nil {
+			t.Errorf("NewWriterLevel(%d) succeeded unexpectedly", level)
+		}
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	r := NewReader(&buf)
+	for i := 0; i < 10; i++ {
+		msg := strings.Repeat(fmt.Sprintf("message %d\n", i), i+1)
+		if _, err := io.WriteString(w, msg); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, len(msg))
+		if _, err := io.ReadFull(r, got); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if string(got) != msg {
+			t.Fatalf("message %d: got %q, want %q", i, got, msg)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if rest, err := io.ReadAll(r); err != nil || len(rest) > 0 {
+		t.Errorf("at end got %q, %v; want no data, nil", rest, err)
+	}
+}
+
+func TestWriterReset(t *testing.T) {
+	data := bigData(t)
+	var buf1, buf2 bytes.Buffer
+	w := NewWriter(&buf1)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err == nil {
+		t.Error("Write after Close succeeded unexpectedly")
+	}
+
+	w.Reset(&buf2)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf1.Bytes(), buf2.Bytes()) {
+		t.Error("output after Reset differs")
+	}
+}
+
+func TestWriterDict(t *testing.T) {
+	data := bigData(t)
+	dict := data[:64<<10]
+	input := data[len(dict) : len(dict)+4<<10]
+
+	plain := compress(t, input, DefaultCompression, nil)
+	for level := BestSpeed; level <= DefaultCompression; level++ {
+		compressed := compress(t, input, level, dict)
+		t.Logf("level %d: compressed %d bytes to %d with dictionary, %d without", level, len(input), len(compressed), len(plain))
+		got := decompress(t, compressed, dict)
+		if !bytes.Equal(got, input) {
+			showDiffs(t, got, input)
+		}
+	}
+}
+
+func TestReaderChecksum(t *testing.T) {
+	compressed := compress(t, bigData(t), DefaultCompression, nil)
+	compressed[len(compressed)-1] ^= 0xff
+	_, err := io.ReadAll(NewReader(bytes.NewReader(compressed)))
+	if !errors.Is(err, ErrChecksum) {
+		t.Errorf("got error %v, want %v", err, ErrChecksum)
+	}
+}
+
+func TestReaderDictMismatch(t *testing.T) {
+	dict := trainedDict(t)
+	compressed := compress(t, []byte("hello, world\n"), DefaultCompression, dict)
+
+	if _, err := io.ReadAll(NewReader(bytes.NewReader(compressed))); err == nil {
+		t.Error("reading without dictionary succeeded unexpectedly")
+	}
+
+	r, err := NewReaderDict(bytes.NewReader(compressed), []byte("raw dictionary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); err == nil {
+		t.Error("reading with wrong dictionary succeeded unexpectedly")
+	}
+}
+
+func TestBadDict(t *testing.T) {
+	dict := append([]byte(nil), trainedDict(t)...)
+	for _, n := range []int{8, 9, 20, 100} {
+		if _, err := NewReaderDict(bytes.NewReader(nil), dict[:n]); err == nil {
+			t.Errorf("NewReaderDict with truncated dictionary %d succeeded unexpectedly", n)
+		}
+	}
+}
+
+// zstdCommand returns a command to run /usr/bin/zstd,
+// skipping the test if it does not exist.
+func zstdCommand(t testing.TB, args ...string) *exec.Cmd {
+	if _, err := os.Stat("/usr/bin/zstd"); err != nil {
+		t.Skip("skipping because /usr/bin/zstd does not exist")
+	}
+	cmd := exec.Command("/usr/bin/zstd", args...)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// runZstd runs /usr/bin/zstd with the given input and arguments.
+func runZstd(t testing.TB, input []byte, args ...string) []byte {
+	cmd := zstdCommand(t, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%v failed: %v", cmd, err)
+	}
+	return out
+}
+
+// trainedDict returns a dictionary in the zstd dictionary format,
+// trained by /usr/bin/zstd on lines of our large test file.
+func trainedDict(t testing.TB) []byte {
+	dir := t.TempDir()
+	lines := bytes.Split(bigData(t), []byte("\n"))
+	var files []string
+	for i := 0; i+8 <= len(lines) && len(files) < 1000; i += 8 {
+		name := filepath.Join(dir, fmt.Sprintf("sample%d", len(files)))
+		if err := os.WriteFile(name, bytes.Join(lines[i:i+8], []byte("\n")), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, name)
+	}
+	dictFile := filepath.Join(dir, "dict")
+	args := append([]string{"-q", "--train", "--maxdict=16384", "-o", dictFile}, files...)
+	cmd := zstdCommand(t, args...)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("%v failed: %v", cmd, err)
+	}
+	dict, err := os.ReadFile(dictFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dict
+}
+
+// Test that zstd can decompress what we compress.
+func TestWriterZstdDecompress(t *testing.T) {
+	for _, test := range writerTests(t) {
+		for level := BestSpeed; level <= DefaultCompression; level++ {
+			t.Run(fmt.Sprintf("%s/%d", test.name, level), func(t *testing.T) {
+				compressed := compress(t, test.data, level, nil)
+				got := runZstd(t, compressed, "-d")
+				if !bytes.Equal(got, test.data) {
+					showDiffs(t, got, test.data)
+				}
+			})
+		}
+	}
+}
+
+// Test dictionaries with zstd, in both directions.
+func TestZstdDict(t *testing.T) {
+	dict := trainedDict(t)
+	dictFile := filepath.Join(t.TempDir(), "dict")
+	if err := os.WriteFile(dictFile, dict, 0o666); err != nil {
+		t.Fatal(err)
+	}
+
+	data := bigData(t)
+	var inputs [][]byte
+	for _, n := range []int{100, 1000, 10000, 200000} {
+		inputs = append(inputs, data[len(data)-n:])
+	}
+
+	for _, input := range inputs {
+		t.Run(fmt.Sprint(len(input)), func(t *testing.T) {
+			// zstd uses the entropy tables from the dictionary.
+			compressed := runZstd(t, input, "-z", "-D", dictFile)
+			got := decompress(t, compressed, dict)
+			if !bytes.Equal(got, input) {
+				showDiffs(t, got, input)
+			}
+
+			for level := BestSpeed; level <= DefaultCompression; level++ {
+				compressed := compress(t, input, level, dict)
+				got := runZstd(t, compressed, "-d", "-D", dictFile)
+				if !bytes.Equal(got, input) {
+					showDiffs(t, got, input)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	data := bigData(b)
+	for level := BestSpeed; level <= DefaultCompression; level++ {
+		b.Run(fmt.Sprint(level), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			w, err := NewWriterLevel(io.Discard, level)
+			if err != nil {
+				b.Fatal(err)
+			}
+			for i := 0; i < b.N; i++ {
+				w.Reset(io.Discard)
+				w.Write(data)
+				w.Close()
+			}
+		})
+	}
+}
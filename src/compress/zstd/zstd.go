@@ -2,8 +2,13 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package zstd provides a decompressor for zstd streams,
-// described in RFC 8878. It does not support dictionaries.
+// Package zstd implements reading and writing of zstd compressed streams,
+// as described in RFC 8878.
+//
+// Both the [Reader] and the [Writer] support dictionaries,
+// as described in RFC 8878 section 5.
+// A dictionary may either use the zstd dictionary format,
+// as produced by "zstd --train", or be raw content.
 package zstd
 
 import (
@@ -13,6 +18,9 @@ import (
 	"io"
 )
 
+// ErrChecksum is returned when reading zstd data that has an invalid checksum.
+var ErrChecksum = errors.New("zstd: invalid checksum")
+
 // fuzzing is a fuzzer hook set to true when fuzzing.
 // This is used to reject cases where we don't match zstd.
 var fuzzing = false
@@ -83,6 +91,9 @@ type Reader struct {
 
 	// For checksum computation.
 	checksum xxhash64
+
+	// The dictionary to use, if any.
+	dict *dictionary
 }
 
 // NewReader creates a new Reader that decompresses data from the given reader.
@@ -92,8 +103,25 @@ func NewReader(input io.Reader) *Reader {
 	return r
 }
 
+// NewReaderDict is like [NewReader] but decompresses using
+// the dictionary dict. The dictionary may use the zstd dictionary
+// format, or it may be raw content. It returns an error if dict
+// starts with the dictionary magic number but cannot be parsed.
+func NewReaderDict(input io.Reader, dict []byte) (*Reader, error) {
+	d, err := parseDictionary(dict)
+	if err != nil {
+		return nil, err
+	}
+	r := new(Reader)
+	r.dict = d
+	r.Reset(input)
+	return r, nil
+}
+
 // Reset discards the current state and starts reading a new stream from r.
 // This permits reusing a Reader rather than allocating a new one.
+// The Reader continues to use the dictionary, if any,
+// that was passed to [NewReaderDict].
 func (r *Reader) Reset(input io.Reader) {
 	r.r = input
 
@@ -121,6 +149,8 @@ func (r *Reader) Reset(input io.Reader) {
 	// seqTableBuffers
 	// scratch
 	// fseScratch
+	// checksum
+	// dict
 }
 
 // Read implements [io.Reader].
@@ -222,13 +252,11 @@ retry:
 		r.checksum.reset()
 	}
 
-	if descriptor&3 != 0 {
-		return r.makeError(relativeOffset, "dictionaries are not supported")
-	}
+	dictIDFieldSize := [4]int{0, 1, 2, 4}[descriptor&3]
 
 	relativeOffset++
 
-	headerSize := windowDescriptorSize + fcsFieldSize
+	headerSize := windowDescriptorSize + dictIDFieldSize + fcsFieldSize
 
 	if _, err := io.ReadFull(r.r, r.scratch[:headerSize]); err != nil {
 		return r.wrapNonEOFError(relativeOffset, err)
@@ -237,10 +265,7 @@ retry:
 	// Figure out the maximum amount of data we need to retain
 	// for backreferences.
 
-	if singleSegment {
-		// No window required, as all the data is in a single buffer.
-		r.windowSize = 0
-	} else {
+	if !singleSegment {
 		// Window descriptor. RFC 3.1.1.1.2.
 		windowDescriptor := r.scratch[0]
 		exponent := uint64(windowDescriptor >> 3)
@@ -263,10 +288,30 @@ retry:
 		r.windowSize = int(windowSize)
 	}
 
+	// Dictionary_ID. RFC 3.1.1.1.3.
+	var dictID uint32
+	db := r.scratch[windowDescriptorSize:]
+	switch dictIDFieldSize {
+	case 1:
+		dictID = uint32(db[0])
+	case 2:
+		dictID = uint32(binary.LittleEndian.Uint16(db))
+	case 4:
+		dictID = binary.LittleEndian.Uint32(db)
+	}
+	if dictID != 0 {
+		if r.dict == nil {
+			return r.makeError(relativeOffset, "frame requires a dictionary")
+		}
+		if r.dict.id != dictID {
+			return r.makeError(relativeOffset, fmt.Sprintf("frame requires dictionary %d, have %d", dictID, r.dict.id))
+		}
+	}
+
 	// Frame_Content_Size. RFC 3.1.1.4.
 	r.frameSizeUnknown = false
 	r.remainingFrameSize = 0
-	fb := r.scratch[windowDescriptorSize:]
+	fb := r.scratch[windowDescriptorSize+dictIDFieldSize:]
 	switch fcsFieldSize {
 	case 0:
 		r.frameSizeUnknown = true
@@ -282,6 +327,16 @@ retry:
 		panic("unreachable")
 	}
 
+	if singleSegment {
+		// The window is the whole frame, RFC 3.1.1.1.2,
+		// which we limit to the same 8M as the window descriptor.
+		if r.remainingFrameSize > 8<<20 {
+			r.windowSize = 8 << 20
+		} else {
+			r.windowSize = int(r.remainingFrameSize)
+		}
+	}
+
 	relativeOffset += headerSize
 
 	r.sawFrameHeader = true
@@ -298,9 +353,41 @@ retry:
 	r.seqTables[1] = nil
 	r.seqTables[2] = nil
 
+	if r.dict != nil {
+		r.useDictionary()
+	}
+
 	return nil
 }
 
+// useDictionary sets up the state at the start of a frame
+// to use the entropy tables, repeated offsets, and content
+// of a dictionary. RFC 5.
+func (r *Reader) useDictionary() {
+	d := r.dict
+	r.repeatedOffset1 = d.repeatedOffsets[0]
+	r.repeatedOffset2 = d.repeatedOffsets[1]
+	r.repeatedOffset3 = d.repeatedOffsets[2]
+
+	if d.huffmanTableBits > 0 {
+		if len(r.huffmanTable) < 1<<maxHuffmanBits {
+			r.huffmanTable = make([]uint16, 1<<maxHuffmanBits)
+		}
+		copy(r.huffmanTable, d.huffmanTable)
+		r.huffmanTableBits = d.huffmanTableBits
+	}
+
+	// The dictionary tables are only read, never written,
+	// so we can use them directly.
+	r.seqTables = d.seqTables
+	r.seqTableBits = d.seqTableBits
+
+	// The dictionary content precedes the frame data
+	// for back references.
+	r.windowSize += len(d.content)
+	r.window = append(r.window, d.content...)
+}
+
 // skipFrame skips a skippable frame. RFC 3.1.2.
 func (r *Reader) skipFrame() error {
 	relativeOffset := 0
@@ -428,7 +515,7 @@ func (r *Reader) readBlock() error {
 			inputChecksum := binary.LittleEndian.Uint32(r.scratch[:4])
 			dataChecksum := uint32(r.checksum.digest())
 			if inputChecksum != dataChecksum {
-				return r.wrapError(0, fmt.Errorf("invalid checksum: got %#x want %#x", dataChecksum, inputChecksum))
+				return r.wrapError(0, fmt.Errorf("%w: got %#x want %#x", ErrChecksum, dataChecksum, inputChecksum))
 			}
 
 			r.blockOffset += 4
@@ -450,7 +537,8 @@ func (r *Reader) setBufferSize(size int) {
 }
 
 // saveWindow saves bytes in the backreference window.
-// TODO: use a circular buffer for less data movement.
+// The window may hold up to twice windowSize bytes,
+// so that we only move data once per windowSize bytes.
 func (r *Reader) saveWindow(buf []byte) {
 	if r.windowSize == 0 {
 		return
@@ -462,10 +550,10 @@ func (r *Reader) saveWindow(buf []byte) {
 		return
 	}
 
-	keep := r.windowSize - len(buf) // must be positive
-	if keep < len(r.window) {
+	if len(r.window)+len(buf) > 2*r.windowSize {
+		keep := r.windowSize - len(buf) // must be positive
 		remove := len(r.window) - keep
-		copy(r.window[:], r.window[remove:])
+		r.window = r.window[:copy(r.window, r.window[remove:])]
 	}
 
 	r.window = append(r.window, buf...)
@@ -166,8 +166,8 @@ func zstdBigData(t testing.TB) []byte {
 	return zstdBigBytes
 }
 
-// Test decompressing a large file. We don't have a compressor,
-// so this test only runs on systems with zstd installed.
+// Test decompressing a large file compressed by zstd.
+// This test only runs on systems with zstd installed.
 func TestLarge(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping expensive test in short mode")
@@ -0,0 +1,95 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package chacha20poly1305 implements the ChaCha20-Poly1305 AEAD and its
+// extended nonce variant XChaCha20-Poly1305, as specified in RFC 8439 and
+// draft-irtf-cfrg-xchacha-01.
+//
+// The returned AEADs implement [cipher.AEAD], and use the same optimized
+// implementation as crypto/tls.
+package chacha20poly1305
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/internal/cast"
+	"errors"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// KeySize is the size of the key used by this AEAD, in bytes.
+	KeySize = chacha20poly1305.KeySize
+
+	// NonceSize is the size of the nonce used with the standard variant of this
+	// AEAD, in bytes.
+	//
+	// Note that this is too short to be safely generated at random if the same
+	// key is reused more than 2³² times.
+	NonceSize = chacha20poly1305.NonceSize
+
+	// NonceSizeX is the size of the nonce used with the XChaCha20-Poly1305
+	// variant of this AEAD, in bytes.
+	NonceSizeX = chacha20poly1305.NonceSizeX
+
+	// Overhead is the size of the Poly1305 authentication tag, and the
+	// difference between a ciphertext length and its plaintext.
+	Overhead = chacha20poly1305.Overhead
+)
+
+var selfTest = cast.New("ChaCha20-Poly1305", func() error {
+	key := []byte{
+		0xa5, 0x11, 0x7e, 0x70, 0x95, 0x35, 0x68, 0xbf,
+		0x75, 0x08, 0x62, 0xdf, 0x9e, 0x6f, 0x92, 0xaf,
+		0x81, 0x67, 0x7c, 0x3a, 0x18, 0x8e, 0x84, 0x79,
+		0x17, 0xa4, 0xa9, 0x15, 0xbd, 0xa7, 0x79, 0x2e,
+	}
+	nonce := []byte{
+		0x12, 0x90, 0x39, 0xb5, 0x57, 0x2e, 0x8a, 0x7a,
+		0x81, 0x31, 0xf7, 0x6a,
+	}
+	aad := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x16, 0x03, 0x03, 0x00, 0x10,
+	}
+	plaintext := []byte{
+		0x14, 0x00, 0x00, 0x0c, 0xeb, 0xcc, 0xee, 0x3b,
+		0xf5, 0x61, 0xb2, 0x92, 0x34, 0x0f, 0xec, 0x60,
+	}
+	want := []byte{
+		0x2b, 0x48, 0x7a, 0x29, 0x41, 0xbc, 0x07, 0xf3,
+		0xcc, 0x76, 0xd1, 0xa5, 0x31, 0x66, 0x25, 0x88,
+		0xee, 0x7c, 0x25, 0x98, 0xe5, 0x97, 0x78, 0xc2,
+		0x4d, 0x5b, 0x27, 0x55, 0x9a, 0x80, 0xd1, 0x63,
+	}
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return err
+	}
+	if got := aead.Seal(nil, nonce, plaintext, aad); !bytes.Equal(got, want) {
+		return errors.New("unexpected result")
+	}
+	if got, err := aead.Open(nil, nonce, want, aad); err != nil || !bytes.Equal(got, plaintext) {
+		return errors.New("unexpected result")
+	}
+	return nil
+})
+
+// New returns a ChaCha20-Poly1305 AEAD that uses the given 256-bit key.
+func New(key []byte) (cipher.AEAD, error) {
+	selfTest()
+	return chacha20poly1305.New(key)
+}
+
+// NewX returns a XChaCha20-Poly1305 AEAD that uses the given 256-bit key.
+//
+// XChaCha20-Poly1305 is a ChaCha20-Poly1305 variant that takes a longer nonce,
+// suitable to be generated randomly without risk of collisions. It should be
+// preferred when nonce uniqueness cannot be trivially ensured, or whenever
+// nonces are randomly generated.
+func NewX(key []byte) (cipher.AEAD, error) {
+	selfTest()
+	return chacha20poly1305.NewX(key)
+}
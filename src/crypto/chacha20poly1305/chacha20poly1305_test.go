@@ -0,0 +1,138 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package chacha20poly1305_test
+
+import (
+	"bytes"
+	. "crypto/chacha20poly1305"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+var chacha20Poly1305Tests = []struct {
+	key, nonce, aad, plaintext, out string
+}{
+	// RFC 8439, Section 2.8.2.
+	{
+		"808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+
+		"070000004041424344454647",
+
+		"50515253c0c1c2c3c4c5c6c7",
+
+		"4c616469657320616e642047656e746c656d656e206f662074686520636c617373206f66202739393a204966204920636f756c64206f6666657220796f75206f6e6c79206f6e652074697020666f7220746865206675747572652c2073756e73637265656e20776f756c642062652069742e",
+
+		"d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b61161ae10b594f09e26a7e902ecbd0600691",
+	},
+	{
+		"a5117e70953568bf750862df9e6f92af81677c3a188e847917a4a915bda7792e",
+
+		"129039b5572e8a7a8131f76a",
+
+		"00000000000000001603030010",
+
+		"1400000cebccee3bf561b292340fec60",
+
+		"2b487a2941bc07f3cc76d1a531662588ee7c2598e59778c24d5b27559a80d163",
+	},
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVectors(t *testing.T) {
+	for i, tt := range chacha20Poly1305Tests {
+		key := mustDecodeHex(t, tt.key)
+		nonce := mustDecodeHex(t, tt.nonce)
+		aad := mustDecodeHex(t, tt.aad)
+		plaintext := mustDecodeHex(t, tt.plaintext)
+		want := mustDecodeHex(t, tt.out)
+
+		aead, err := New(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct := aead.Seal(nil, nonce, plaintext, aad)
+		if !bytes.Equal(ct, want) {
+			t.Errorf("#%d: got %x, want %x", i, ct, want)
+			continue
+		}
+		pt, err := aead.Open(nil, nonce, ct, aad)
+		if err != nil {
+			t.Errorf("#%d: Open failed: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(pt, plaintext) {
+			t.Errorf("#%d: plaintext mismatch: got %x, want %x", i, pt, plaintext)
+		}
+
+		if len(aad) > 0 {
+			aad[0] ^= 0x80
+			if _, err := aead.Open(nil, nonce, ct, aad); err == nil {
+				t.Errorf("#%d: Open succeeded with altered additional data", i)
+			}
+			aad[0] ^= 0x80
+		}
+		ct[0] ^= 0x80
+		if _, err := aead.Open(nil, nonce, ct, aad); err == nil {
+			t.Errorf("#%d: Open succeeded with altered ciphertext", i)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		new       func([]byte) (cipher.AEAD, error)
+		nonceSize int
+	}{
+		{"ChaCha20-Poly1305", New, NonceSize},
+		{"XChaCha20-Poly1305", NewX, NonceSizeX},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			key := make([]byte, KeySize)
+			rand.Read(key)
+			aead, err := tt.new(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if aead.NonceSize() != tt.nonceSize {
+				t.Errorf("NonceSize: got %d, want %d", aead.NonceSize(), tt.nonceSize)
+			}
+			if aead.Overhead() != Overhead {
+				t.Errorf("Overhead: got %d, want %d", aead.Overhead(), Overhead)
+			}
+			nonce := make([]byte, tt.nonceSize)
+			rand.Read(nonce)
+			for _, n := range []int{0, 1, 63, 64, 65, 1000} {
+				plaintext := make([]byte, n)
+				rand.Read(plaintext)
+				ct := aead.Seal(nil, nonce, plaintext, []byte("aad"))
+				if len(ct) != n+Overhead {
+					t.Errorf("%d bytes: unexpected ciphertext length %d", n, len(ct))
+				}
+				pt, err := aead.Open(nil, nonce, ct, []byte("aad"))
+				if err != nil {
+					t.Fatalf("%d bytes: Open failed: %v", n, err)
+				}
+				if !bytes.Equal(pt, plaintext) {
+					t.Errorf("%d bytes: plaintext mismatch", n)
+				}
+			}
+
+			if _, err := tt.new(key[:KeySize-1]); err == nil {
+				t.Error("expected error for short key")
+			}
+		})
+	}
+}
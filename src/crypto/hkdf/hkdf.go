@@ -0,0 +1,114 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hkdf implements the HMAC-based Extract-and-Expand Key Derivation
+// Function (HKDF) as defined in RFC 5869.
+//
+// HKDF is a cryptographic key derivation function (KDF) with the goal of
+// expanding limited input keying material into one or more cryptographically
+// strong secret keys.
+package hkdf
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/internal/cast"
+	"crypto/sha256"
+	"errors"
+	"hash"
+)
+
+var selfTest = cast.New("HKDF-SHA2-256", func() error {
+	input := []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+	}
+	want := []byte{
+		0xb6, 0x53, 0x00, 0x5b, 0x51, 0x6d, 0x2b, 0xc9,
+		0x4a, 0xe4, 0xf9, 0x51, 0x73, 0x1f, 0x71, 0x21,
+		0xa6, 0xc1, 0xde, 0x42, 0x4f, 0x2c, 0x99, 0x60,
+		0x64, 0xdb, 0x66, 0x3e, 0xec, 0xa6, 0x37, 0xff,
+	}
+	prk := extract(sha256.New, input, input)
+	got := expand(sha256.New, prk, string(input), len(want))
+	if !bytes.Equal(got, want) {
+		return errors.New("unexpected result")
+	}
+	return nil
+})
+
+// Extract generates a pseudorandom key for use with [Expand] from an input
+// secret and an optional independent salt.
+//
+// Only use this function if you need to reuse the extracted key with multiple
+// Expand invocations and different context values. Most common scenarios,
+// including the generation of multiple keys, should use [Key] instead.
+func Extract[H hash.Hash](h func() H, secret, salt []byte) ([]byte, error) {
+	selfTest()
+	return extract(h, secret, salt), nil
+}
+
+// Expand derives a key from the given hash, key, and optional context info,
+// returning a []byte of length keyLength that can be used as cryptographic key.
+// The extraction step is skipped.
+//
+// The key should have been generated by [Extract], or be a uniformly
+// random or pseudorandom cryptographically strong key. See RFC 5869, Section
+// 3.3. Most common scenarios will want to use [Key] instead.
+func Expand[H hash.Hash](h func() H, pseudorandomKey []byte, info string, keyLength int) ([]byte, error) {
+	selfTest()
+	if err := checkLength(h, keyLength); err != nil {
+		return nil, err
+	}
+	return expand(h, pseudorandomKey, info, keyLength), nil
+}
+
+// Key derives a key from the given hash, secret, salt and context info,
+// returning a []byte of length keyLength that can be used as cryptographic key.
+// Salt and info can be nil.
+func Key[H hash.Hash](h func() H, secret, salt []byte, info string, keyLength int) ([]byte, error) {
+	selfTest()
+	if err := checkLength(h, keyLength); err != nil {
+		return nil, err
+	}
+	return expand(h, extract(h, secret, salt), info, keyLength), nil
+}
+
+func checkLength[H hash.Hash](h func() H, keyLength int) error {
+	if keyLength < 0 {
+		return errors.New("hkdf: negative key length")
+	}
+	if keyLength > h().Size()*255 {
+		return errors.New("hkdf: requested key length too large")
+	}
+	return nil
+}
+
+func extract[H hash.Hash](h func() H, secret, salt []byte) []byte {
+	if salt == nil {
+		salt = make([]byte, h().Size())
+	}
+	extractor := hmac.New(func() hash.Hash { return h() }, salt)
+	extractor.Write(secret)
+	return extractor.Sum(nil)
+}
+
+func expand[H hash.Hash](h func() H, pseudorandomKey []byte, info string, keyLength int) []byte {
+	out := make([]byte, 0, keyLength)
+	expander := hmac.New(func() hash.Hash { return h() }, pseudorandomKey)
+	var counter uint8
+	var buf []byte
+	for len(out) < keyLength {
+		counter++
+		if counter > 1 {
+			expander.Reset()
+		}
+		expander.Write(buf)
+		expander.Write([]byte(info))
+		expander.Write([]byte{counter})
+		buf = expander.Sum(buf[:0])
+		out = append(out, buf[:min(keyLength-len(out), len(buf))]...)
+	}
+	return out
+}
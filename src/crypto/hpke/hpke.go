@@ -0,0 +1,264 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hpke implements Hybrid Public Key Encryption (HPKE) as defined in
+// [RFC 9180].
+//
+// The base and PSK modes are supported, with the DHKEM(P-256, HKDF-SHA256) and
+// DHKEM(X25519, HKDF-SHA256) KEMs, the HKDF-SHA256, HKDF-SHA384 and
+// HKDF-SHA512 KDFs, and the AES-128-GCM, AES-256-GCM and ChaCha20Poly1305
+// AEADs.
+//
+// [RFC 9180]: https://www.rfc-editor.org/rfc/rfc9180.html
+package hpke
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	modeBase uint8 = 0x00
+	modePSK  uint8 = 0x01
+)
+
+type context struct {
+	suiteID []byte
+	kdf     KDF
+
+	aead           cipher.AEAD
+	baseNonce      []byte
+	exporterSecret []byte
+	// seqNum starts at zero and is incremented for each Seal/Open call.
+	seqNum uint64
+}
+
+// Sender is a sending HPKE context. It is instantiated with a specific KEM
+// encapsulation key (i.e. the public key), and it is stateful, incrementing the
+// nonce counter for each [Sender.Seal] call.
+type Sender struct {
+	*context
+}
+
+// Recipient is a receiving HPKE context. It is instantiated with a specific KEM
+// decapsulation key (i.e. the secret key), and it is stateful, incrementing the
+// nonce counter for each successful [Recipient.Open] call.
+type Recipient struct {
+	*context
+}
+
+// newContext implements KeySchedule from RFC 9180, Section 5.1.
+func newContext(mode uint8, sharedSecret []byte, kemID uint16, kdf KDF, aead AEAD, info, psk, pskID []byte) (*context, error) {
+	// VerifyPSKInputs, RFC 9180, Section 5.1.
+	if (len(psk) == 0) != (len(pskID) == 0) {
+		return nil, errors.New("hpke: inconsistent PSK inputs")
+	}
+	if mode == modePSK && len(psk) == 0 {
+		return nil, errors.New("hpke: missing PSK")
+	}
+	if mode == modePSK && len(psk) < 32 {
+		return nil, errors.New("hpke: PSK must be at least 32 bytes")
+	}
+	if mode == modeBase && len(psk) != 0 {
+		return nil, errors.New("hpke: PSK provided in base mode")
+	}
+
+	sid := suiteID(kemID, kdf.ID(), aead.ID())
+
+	pskIDHash := kdf.labeledExtract(sid, nil, "psk_id_hash", pskID)
+	infoHash := kdf.labeledExtract(sid, nil, "info_hash", info)
+	ksContext := append([]byte{mode}, pskIDHash...)
+	ksContext = append(ksContext, infoHash...)
+
+	secret := kdf.labeledExtract(sid, sharedSecret, "secret", psk)
+
+	key := kdf.labeledExpand(sid, secret, "key", ksContext, uint16(aead.keySize()))
+	baseNonce := kdf.labeledExpand(sid, secret, "base_nonce", ksContext, uint16(aead.nonceSize()))
+	exporterSecret := kdf.labeledExpand(sid, secret, "exp", ksContext, uint16(kdf.size()))
+
+	a, err := aead.aead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &context{
+		suiteID:        sid,
+		kdf:            kdf,
+		aead:           a,
+		baseNonce:      baseNonce,
+		exporterSecret: exporterSecret,
+	}, nil
+}
+
+func newSender(mode uint8, pk PublicKey, kdf KDF, aead AEAD, info, psk, pskID []byte) ([]byte, *Sender, error) {
+	sharedSecret, encapsulatedKey, err := pk.encap()
+	if err != nil {
+		return nil, nil, err
+	}
+	context, err := newContext(mode, sharedSecret, pk.KEM().ID(), kdf, aead, info, psk, pskID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encapsulatedKey, &Sender{context}, nil
+}
+
+func newRecipient(mode uint8, enc []byte, k PrivateKey, kdf KDF, aead AEAD, info, psk, pskID []byte) (*Recipient, error) {
+	sharedSecret, err := k.decap(enc)
+	if err != nil {
+		return nil, err
+	}
+	context, err := newContext(mode, sharedSecret, k.KEM().ID(), kdf, aead, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	return &Recipient{context}, nil
+}
+
+// NewSender returns a sending HPKE context for the provided KEM encapsulation
+// key (i.e. the public key), and using the ciphersuite defined by the
+// combination of KEM, KDF, and AEAD. It implements SetupBaseS from RFC 9180.
+//
+// The info parameter is additional public information that must match between
+// sender and recipient.
+//
+// The returned enc ciphertext can be used to instantiate a matching receiving
+// HPKE context with the corresponding KEM decapsulation key.
+func NewSender(pk PublicKey, kdf KDF, aead AEAD, info []byte) (enc []byte, s *Sender, err error) {
+	return newSender(modeBase, pk, kdf, aead, info, nil, nil)
+}
+
+// NewSenderWithPSK is like [NewSender], but additionally authenticates the
+// sender with a pre-shared key psk, identified by pskID. It implements
+// SetupPSKS from RFC 9180.
+//
+// psk must be at least 32 bytes long, and both psk and pskID must be
+// non-empty.
+func NewSenderWithPSK(pk PublicKey, kdf KDF, aead AEAD, info, psk, pskID []byte) (enc []byte, s *Sender, err error) {
+	return newSender(modePSK, pk, kdf, aead, info, psk, pskID)
+}
+
+// NewRecipient returns a receiving HPKE context for the provided KEM
+// decapsulation key (i.e. the secret key), and using the ciphersuite defined by
+// the combination of KEM, KDF, and AEAD. It implements SetupBaseR from RFC 9180.
+//
+// The enc parameter must have been produced by a matching sending HPKE context
+// with the corresponding KEM encapsulation key. The info parameter is
+// additional public information that must match between sender and recipient.
+func NewRecipient(enc []byte, k PrivateKey, kdf KDF, aead AEAD, info []byte) (*Recipient, error) {
+	return newRecipient(modeBase, enc, k, kdf, aead, info, nil, nil)
+}
+
+// NewRecipientWithPSK is like [NewRecipient], but for a sending context
+// created with [NewSenderWithPSK] and the same psk and pskID. It implements
+// SetupPSKR from RFC 9180.
+func NewRecipientWithPSK(enc []byte, k PrivateKey, kdf KDF, aead AEAD, info, psk, pskID []byte) (*Recipient, error) {
+	return newRecipient(modePSK, enc, k, kdf, aead, info, psk, pskID)
+}
+
+// Seal encrypts the provided plaintext, optionally binding to the additional
+// public data aad.
+//
+// Seal uses incrementing counters for each call, and Open on the receiving side
+// must be called in the same order as Seal.
+func (s *Sender) Seal(aad, plaintext []byte) ([]byte, error) {
+	if s.aead == nil {
+		return nil, errors.New("hpke: export-only instantiation")
+	}
+	nonce, err := s.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := s.aead.Seal(nil, nonce, plaintext, aad)
+	s.seqNum++
+	return ciphertext, nil
+}
+
+// Seal instantiates a single-use HPKE sending HPKE context like [NewSender],
+// and then encrypts the provided plaintext like [Sender.Seal] (with no aad).
+// Seal returns the concatenation of the encapsulated key and the ciphertext.
+func Seal(pk PublicKey, kdf KDF, aead AEAD, info, plaintext []byte) ([]byte, error) {
+	enc, s, err := NewSender(pk, kdf, aead, info)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := s.Seal(nil, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return append(enc, ct...), nil
+}
+
+// Open decrypts the provided ciphertext, optionally binding to the additional
+// public data aad, or returns an error if decryption fails.
+//
+// Open uses incrementing counters for each successful call, and must be called
+// in the same order as Seal on the sending side.
+func (r *Recipient) Open(aad, ciphertext []byte) ([]byte, error) {
+	if r.aead == nil {
+		return nil, errors.New("hpke: export-only instantiation")
+	}
+	nonce, err := r.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := r.aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, err
+	}
+	r.seqNum++
+	return plaintext, nil
+}
+
+// Open instantiates a single-use HPKE receiving HPKE context like
+// [NewRecipient], and then decrypts the provided ciphertext like
+// [Recipient.Open] (with no aad). ciphertext must be the concatenation of the
+// encapsulated key and the actual ciphertext.
+func Open(k PrivateKey, kdf KDF, aead AEAD, info, ciphertext []byte) ([]byte, error) {
+	encSize := k.KEM().encSize()
+	if len(ciphertext) < encSize {
+		return nil, errors.New("hpke: ciphertext too short")
+	}
+	enc, ciphertext := ciphertext[:encSize], ciphertext[encSize:]
+	r, err := NewRecipient(enc, k, kdf, aead, info)
+	if err != nil {
+		return nil, err
+	}
+	return r.Open(nil, ciphertext)
+}
+
+// Export produces a secret value derived from the shared key between sender
+// and recipient, bound to exporterContext. length must be at most 255 times
+// the output size of the KDF hash.
+func (ctx *context) Export(exporterContext []byte, length int) ([]byte, error) {
+	if length < 0 || length > 255*ctx.kdf.size() {
+		return nil, errors.New("hpke: invalid exporter length")
+	}
+	return ctx.kdf.labeledExpand(ctx.suiteID, ctx.exporterSecret, "sec", exporterContext, uint16(length)), nil
+}
+
+func (ctx *context) nextNonce() ([]byte, error) {
+	// The sequence number must not wrap around, see RFC 9180, Section 5.2.
+	// All supported AEADs have 96-bit nonces, so it can't exceed the nonce
+	// space before exceeding 64 bits.
+	if ctx.seqNum == math.MaxUint64 {
+		return nil, errors.New("hpke: message limit reached")
+	}
+	nonce := make([]byte, ctx.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], ctx.seqNum)
+	for i := range ctx.baseNonce {
+		nonce[i] ^= ctx.baseNonce[i]
+	}
+	return nonce, nil
+}
+
+func suiteID(kemID, kdfID, aeadID uint16) []byte {
+	suiteID := make([]byte, 0, 4+2+2+2)
+	suiteID = append(suiteID, "HPKE"...)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, kemID)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, kdfID)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, aeadID)
+	return suiteID
+}
@@ -0,0 +1,446 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hpke
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/internal/sha3"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"testing"
+)
+
+func mustDecodeHex(t *testing.T, in string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRFC9180Vectors(t *testing.T) {
+	vectorsJSON, err := os.ReadFile("testdata/rfc9180.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []struct {
+		Mode  uint16 `json:"mode"`
+		KEM   uint16 `json:"kem_id"`
+		KDF   uint16 `json:"kdf_id"`
+		AEAD  uint16 `json:"aead_id"`
+		Info  string `json:"info"`
+		PSK   string `json:"psk"`
+		PSKID string `json:"psk_id"`
+		IkmE  string `json:"ikmE"`
+		IkmR  string `json:"ikmR"`
+		SkRm  string `json:"skRm"`
+		PkRm  string `json:"pkRm"`
+		Enc   string `json:"enc"`
+
+		// Instead of checking in the full set of encryptions and exports,
+		// these are accumulated SHAKE128 hashes of 1000 random operations.
+		AccEncryptions string `json:"encryptions_accumulated"`
+		AccExports     string `json:"exports_accumulated"`
+	}
+	if err := json.Unmarshal(vectorsJSON, &vectors); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, vector := range vectors {
+		name := fmt.Sprintf("mode %04x kem %04x kdf %04x aead %04x",
+			vector.Mode, vector.KEM, vector.KDF, vector.AEAD)
+		t.Run(name, func(t *testing.T) {
+			if vector.Mode != uint16(modeBase) && vector.Mode != uint16(modePSK) {
+				t.Skip("unsupported mode")
+			}
+			kem, err := NewKEM(vector.KEM)
+			if err != nil {
+				t.Skip("unsupported KEM")
+			}
+			kdf, err := NewKDF(vector.KDF)
+			if err != nil {
+				t.Skip("unsupported KDF")
+			}
+			aead, err := NewAEAD(vector.AEAD)
+			if err != nil {
+				t.Skip("unsupported AEAD")
+			}
+
+			info := mustDecodeHex(t, vector.Info)
+			psk := mustDecodeHex(t, vector.PSK)
+			pskID := mustDecodeHex(t, vector.PSKID)
+
+			pub, err := kem.NewPublicKey(mustDecodeHex(t, vector.PkRm))
+			if err != nil {
+				t.Fatal(err)
+			}
+			priv, err := kem.NewPrivateKey(mustDecodeHex(t, vector.SkRm))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(priv.PublicKey().Bytes(), pub.Bytes()) {
+				t.Errorf("unexpected public key, got %x, want %x", priv.PublicKey().Bytes(), pub.Bytes())
+			}
+			// Bytes doesn't necessarily match skRm, because SerializePrivateKey
+			// clamps X25519 keys, but it must round-trip to the same key.
+			reparsed, err := kem.NewPrivateKey(priv.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(reparsed.PublicKey().Bytes(), pub.Bytes()) {
+				t.Errorf("re-serialized private key mismatch, got %x, want %x", reparsed.PublicKey().Bytes(), pub.Bytes())
+			}
+
+			derived, err := kem.DeriveKeyPair(mustDecodeHex(t, vector.IkmR))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(derived.Bytes(), priv.Bytes()) {
+				t.Errorf("unexpected derived private key, got %x, want %x", derived.Bytes(), priv.Bytes())
+			}
+
+			ephemeral, err := kem.DeriveKeyPair(mustDecodeHex(t, vector.IkmE))
+			if err != nil {
+				t.Fatal(err)
+			}
+			testingOnlyGenerateKey = func() *ecdh.PrivateKey {
+				return ephemeral.(*dhKEMPrivateKey).priv
+			}
+			t.Cleanup(func() { testingOnlyGenerateKey = nil })
+
+			var enc []byte
+			var sender *Sender
+			var recipient *Recipient
+			if vector.Mode == uint16(modePSK) {
+				enc, sender, err = NewSenderWithPSK(pub, kdf, aead, info, psk, pskID)
+			} else {
+				enc, sender, err = NewSender(pub, kdf, aead, info)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected := mustDecodeHex(t, vector.Enc); !bytes.Equal(enc, expected) {
+				t.Errorf("unexpected encapsulated key, got %x, want %x", enc, expected)
+			}
+			if vector.Mode == uint16(modePSK) {
+				recipient, err = NewRecipientWithPSK(enc, priv, kdf, aead, info, psk, pskID)
+			} else {
+				recipient, err = NewRecipient(enc, priv, kdf, aead, info)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if aead != ExportOnly() {
+				source, sink := sha3.NewShake128(), sha3.NewShake128()
+				for i := 0; i < 1000; i++ {
+					aad, plaintext := drawRandomInput(t, source), drawRandomInput(t, source)
+					ciphertext, err := sender.Seal(aad, plaintext)
+					if err != nil {
+						t.Fatal(err)
+					}
+					sink.Write(ciphertext)
+					got, err := recipient.Open(aad, ciphertext)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(got, plaintext) {
+						t.Errorf("unexpected plaintext, got %x, want %x", got, plaintext)
+					}
+				}
+				encryptions := make([]byte, 16)
+				sink.Read(encryptions)
+				if expected := mustDecodeHex(t, vector.AccEncryptions); !bytes.Equal(encryptions, expected) {
+					t.Errorf("unexpected accumulated encryptions, got %x, want %x", encryptions, expected)
+				}
+			} else {
+				if _, err := sender.Seal(nil, nil); err == nil {
+					t.Error("expected error from Seal with export-only AEAD")
+				}
+				if _, err := recipient.Open(nil, nil); err == nil {
+					t.Error("expected error from Open with export-only AEAD")
+				}
+			}
+
+			source, sink := sha3.NewShake128(), sha3.NewShake128()
+			for l := 0; l < 1000; l++ {
+				context := drawRandomInput(t, source)
+				value, err := sender.Export(context, l)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sink.Write(value)
+				got, err := recipient.Export(context, l)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, value) {
+					t.Errorf("recipient: unexpected exported secret, got %x, want %x", got, value)
+				}
+			}
+			exports := make([]byte, 16)
+			sink.Read(exports)
+			if expected := mustDecodeHex(t, vector.AccExports); !bytes.Equal(exports, expected) {
+				t.Errorf("unexpected accumulated exports, got %x, want %x", exports, expected)
+			}
+		})
+	}
+}
+
+func drawRandomInput(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+	l := make([]byte, 1)
+	if _, err := r.Read(l); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, int(l[0]))
+	if _, err := r.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+var testPSK = bytes.Repeat([]byte{0x42}, 32)
+var testPSKID = []byte("psk id")
+
+func TestRoundTrip(t *testing.T) {
+	for _, kem := range []KEM{DHKEM(ecdh.P256()), DHKEM(ecdh.X25519())} {
+		for _, kdf := range []KDF{HKDFSHA256(), HKDFSHA384(), HKDFSHA512()} {
+			for _, aead := range []AEAD{AES128GCM(), AES256GCM(), ChaCha20Poly1305()} {
+				for _, psk := range []bool{false, true} {
+					name := fmt.Sprintf("kem %04x kdf %04x aead %04x psk %v", kem.ID(), kdf.ID(), aead.ID(), psk)
+					t.Run(name, func(t *testing.T) {
+						testRoundTrip(t, kem, kdf, aead, psk)
+					})
+				}
+			}
+		}
+	}
+}
+
+func testRoundTrip(t *testing.T, kem KEM, kdf KDF, aead AEAD, withPSK bool) {
+	priv, err := kem.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := []byte("info")
+	newRecipient := func(enc, info []byte) (*Recipient, error) {
+		if withPSK {
+			return NewRecipientWithPSK(enc, priv, kdf, aead, info, testPSK, testPSKID)
+		}
+		return NewRecipient(enc, priv, kdf, aead, info)
+	}
+
+	var enc []byte
+	var sender *Sender
+	if withPSK {
+		enc, sender, err = NewSenderWithPSK(priv.PublicKey(), kdf, aead, info, testPSK, testPSKID)
+	} else {
+		enc, sender, err = NewSender(priv.PublicKey(), kdf, aead, info)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(enc) != kem.encSize() {
+		t.Errorf("unexpected encapsulated key length %d, want %d", len(enc), kem.encSize())
+	}
+	recipient, err := newRecipient(enc, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		msg := []byte(fmt.Sprintf("message %d", i))
+		ct, err := sender.Seal([]byte("aad"), msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := recipient.Open([]byte("wrong aad"), ct); err == nil {
+			t.Error("expected error opening with the wrong aad")
+		}
+		pt, err := recipient.Open([]byte("aad"), ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pt, msg) {
+			t.Errorf("got %q, want %q", pt, msg)
+		}
+	}
+
+	exp1, err := sender.Export([]byte("context"), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp2, err := recipient.Export([]byte("context"), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(exp1, exp2) {
+		t.Errorf("exported secrets don't match: %x != %x", exp1, exp2)
+	}
+
+	// A recipient with a different info must fail to decrypt.
+	other, err := newRecipient(enc, []byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := sender.Seal(nil, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Open(nil, ct); err == nil {
+		t.Error("expected error opening with the wrong info")
+	}
+}
+
+func TestPSKMismatch(t *testing.T) {
+	kem, kdf, aead := DHKEM(ecdh.X25519()), HKDFSHA256(), AES128GCM()
+	priv, err := kem.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, sender, err := NewSenderWithPSK(priv.PublicKey(), kdf, aead, nil, testPSK, testPSKID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := sender.Seal(nil, []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherPSK := bytes.Repeat([]byte{0x43}, 32)
+	for name, newRecipient := range map[string]func() (*Recipient, error){
+		"base": func() (*Recipient, error) {
+			return NewRecipient(enc, priv, kdf, aead, nil)
+		},
+		"psk": func() (*Recipient, error) {
+			return NewRecipientWithPSK(enc, priv, kdf, aead, nil, otherPSK, testPSKID)
+		},
+		"psk id": func() (*Recipient, error) {
+			return NewRecipientWithPSK(enc, priv, kdf, aead, nil, testPSK, []byte("other id"))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := newRecipient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Open(nil, ct); err == nil {
+				t.Error("expected error opening with mismatched PSK")
+			}
+		})
+	}
+}
+
+func TestPSKInputs(t *testing.T) {
+	kem, kdf, aead := DHKEM(ecdh.X25519()), HKDFSHA256(), AES128GCM()
+	priv, err := kem.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name       string
+		psk, pskID []byte
+	}{
+		{"missing psk", nil, testPSKID},
+		{"missing psk id", testPSK, nil},
+		{"missing both", nil, nil},
+		{"short psk", testPSK[:31], testPSKID},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := NewSenderWithPSK(priv.PublicKey(), kdf, aead, nil, tt.psk, tt.pskID); err == nil {
+				t.Error("expected error from NewSenderWithPSK")
+			}
+			enc, _, err := NewSender(priv.PublicKey(), kdf, aead, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := NewRecipientWithPSK(enc, priv, kdf, aead, nil, tt.psk, tt.pskID); err == nil {
+				t.Error("expected error from NewRecipientWithPSK")
+			}
+		})
+	}
+}
+
+func TestSingleShot(t *testing.T) {
+	for _, kem := range []KEM{DHKEM(ecdh.P256()), DHKEM(ecdh.X25519())} {
+		priv, err := kem.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, err := Seal(priv.PublicKey(), HKDFSHA256(), ChaCha20Poly1305(), []byte("info"), []byte("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pt, err := Open(priv, HKDFSHA256(), ChaCha20Poly1305(), []byte("info"), ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(pt) != "hello" {
+			t.Errorf("got %q, want %q", pt, "hello")
+		}
+		if _, err := Open(priv, HKDFSHA256(), ChaCha20Poly1305(), []byte("info"), ct[:kem.encSize()-1]); err == nil {
+			t.Error("expected error for truncated ciphertext")
+		}
+	}
+}
+
+func TestUnsupportedAlgorithms(t *testing.T) {
+	if _, err := NewKEM(0x0012); err == nil {
+		t.Error("expected error for unsupported KEM")
+	}
+	if _, err := NewKDF(0x0004); err == nil {
+		t.Error("expected error for unsupported KDF")
+	}
+	if _, err := NewAEAD(0x0004); err == nil {
+		t.Error("expected error for unsupported AEAD")
+	}
+	if _, err := DHKEM(ecdh.P384()).GenerateKey(); err == nil {
+		t.Error("expected error for unsupported curve")
+	}
+	if _, err := DHKEM(ecdh.X25519()).NewPublicKey(make([]byte, 31)); err == nil {
+		t.Error("expected error for short public key")
+	}
+}
+
+func TestExportLength(t *testing.T) {
+	priv, err := DHKEM(ecdh.X25519()).GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sender, err := NewSender(priv.PublicKey(), HKDFSHA256(), ExportOnly(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sender.Export(nil, 255*32); err != nil {
+		t.Errorf("unexpected error at the maximum length: %v", err)
+	}
+	if _, err := sender.Export(nil, 255*32+1); err == nil {
+		t.Error("expected error above the maximum length")
+	}
+}
+
+func TestMessageLimit(t *testing.T) {
+	priv, err := DHKEM(ecdh.X25519()).GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, sender, err := NewSender(priv.PublicKey(), HKDFSHA256(), AES128GCM(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.seqNum = math.MaxUint64 - 1
+	if _, err := sender.Seal(nil, nil); err != nil {
+		t.Fatalf("unexpected error below the limit: %v", err)
+	}
+	if _, err := sender.Seal(nil, nil); err == nil {
+		t.Fatal("expected error at the limit")
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hpke
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// A KEM is a Key Encapsulation Mechanism, one of the three components of an
+// HPKE ciphersuite.
+type KEM interface {
+	// ID returns the HPKE KEM identifier.
+	ID() uint16
+
+	// GenerateKey generates a new key pair.
+	GenerateKey() (PrivateKey, error)
+
+	// NewPublicKey deserializes a public key from bytes.
+	//
+	// It implements DeserializePublicKey, as defined in RFC 9180.
+	NewPublicKey([]byte) (PublicKey, error)
+
+	// NewPrivateKey deserializes a private key from bytes.
+	//
+	// It implements DeserializePrivateKey, as defined in RFC 9180.
+	NewPrivateKey([]byte) (PrivateKey, error)
+
+	// DeriveKeyPair derives a key pair from the given input keying material.
+	//
+	// It implements DeriveKeyPair, as defined in RFC 9180.
+	DeriveKeyPair(ikm []byte) (PrivateKey, error)
+
+	encSize() int
+}
+
+// NewKEM returns the KEM implementation for the given KEM ID.
+//
+// Applications are encouraged to use [DHKEM] instead, unless runtime agility
+// is required.
+func NewKEM(id uint16) (KEM, error) {
+	switch id {
+	case 0x0010: // DHKEM(P-256, HKDF-SHA256)
+		return dhKEMP256, nil
+	case 0x0020: // DHKEM(X25519, HKDF-SHA256)
+		return dhKEMX25519, nil
+	default:
+		return nil, fmt.Errorf("hpke: unsupported KEM %04x", id)
+	}
+}
+
+// A PublicKey is an instantiation of a KEM (one of the three components of an
+// HPKE ciphersuite) with an encapsulation key (i.e. the public key).
+//
+// A PublicKey is usually obtained from a method of the corresponding [KEM] or
+// [PrivateKey], such as [KEM.NewPublicKey] or [PrivateKey.PublicKey].
+type PublicKey interface {
+	// KEM returns the instantiated KEM.
+	KEM() KEM
+
+	// Bytes returns the public key as the output of SerializePublicKey.
+	Bytes() []byte
+
+	encap() (sharedSecret, enc []byte, err error)
+}
+
+// A PrivateKey is an instantiation of a KEM (one of the three components of
+// an HPKE ciphersuite) with a decapsulation key (i.e. the secret key).
+//
+// A PrivateKey is usually obtained from a method of the corresponding [KEM],
+// such as [KEM.GenerateKey] or [KEM.NewPrivateKey].
+type PrivateKey interface {
+	// KEM returns the instantiated KEM.
+	KEM() KEM
+
+	// Bytes returns the private key as the output of SerializePrivateKey, as
+	// defined in RFC 9180.
+	//
+	// Note that for X25519 this might not match the input to NewPrivateKey.
+	// This is a requirement of RFC 9180, Section 7.1.2.
+	Bytes() []byte
+
+	// PublicKey returns the corresponding PublicKey.
+	PublicKey() PublicKey
+
+	decap(enc []byte) (sharedSecret []byte, err error)
+}
+
+// dhKEM implements DHKEM, as specified in RFC 9180, Section 4.1.
+type dhKEM struct {
+	kdf     *hkdfKDF
+	id      uint16
+	curve   ecdh.Curve
+	nSecret uint16
+	nSk     uint16
+	nEnc    int
+}
+
+var dhKEMP256 = &dhKEM{hkdfSHA256, 0x0010, ecdh.P256(), 32, 32, 65}
+var dhKEMX25519 = &dhKEM{hkdfSHA256, 0x0020, ecdh.X25519(), 32, 32, 32}
+
+// DHKEM returns a KEM implementing one of
+//
+//   - DHKEM(P-256, HKDF-SHA256)
+//   - DHKEM(X25519, HKDF-SHA256)
+//
+// depending on curve. Other curves are not supported, and the methods of the
+// returned KEM return errors.
+func DHKEM(curve ecdh.Curve) KEM {
+	switch curve {
+	case ecdh.P256():
+		return dhKEMP256
+	case ecdh.X25519():
+		return dhKEMX25519
+	default:
+		return unsupportedCurveKEM{}
+	}
+}
+
+type unsupportedCurveKEM struct{}
+
+var errUnsupportedCurve = errors.New("hpke: unsupported curve")
+
+func (unsupportedCurveKEM) ID() uint16                               { return 0 }
+func (unsupportedCurveKEM) GenerateKey() (PrivateKey, error)         { return nil, errUnsupportedCurve }
+func (unsupportedCurveKEM) NewPublicKey([]byte) (PublicKey, error)   { return nil, errUnsupportedCurve }
+func (unsupportedCurveKEM) NewPrivateKey([]byte) (PrivateKey, error) { return nil, errUnsupportedCurve }
+func (unsupportedCurveKEM) DeriveKeyPair([]byte) (PrivateKey, error) { return nil, errUnsupportedCurve }
+func (unsupportedCurveKEM) encSize() int                             { return 0 }
+
+func (kem *dhKEM) ID() uint16 {
+	return kem.id
+}
+
+func (kem *dhKEM) encSize() int {
+	return kem.nEnc
+}
+
+func (kem *dhKEM) suiteID() []byte {
+	return binary.BigEndian.AppendUint16([]byte("KEM"), kem.id)
+}
+
+func (kem *dhKEM) extractAndExpand(dhKey, kemContext []byte) []byte {
+	suiteID := kem.suiteID()
+	eaePRK := kem.kdf.labeledExtract(suiteID, nil, "eae_prk", dhKey)
+	return kem.kdf.labeledExpand(suiteID, eaePRK, "shared_secret", kemContext, kem.nSecret)
+}
+
+func (kem *dhKEM) GenerateKey() (PrivateKey, error) {
+	priv, err := kem.curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &dhKEMPrivateKey{kem, priv}, nil
+}
+
+func (kem *dhKEM) NewPublicKey(data []byte) (PublicKey, error) {
+	pub, err := kem.curve.NewPublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return &dhKEMPublicKey{kem, pub}, nil
+}
+
+func (kem *dhKEM) NewPrivateKey(data []byte) (PrivateKey, error) {
+	priv, err := kem.curve.NewPrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return &dhKEMPrivateKey{kem, priv}, nil
+}
+
+func (kem *dhKEM) DeriveKeyPair(ikm []byte) (PrivateKey, error) {
+	// DeriveKeyPair from RFC 9180, Section 7.1.3.
+	suiteID := kem.suiteID()
+	prk := kem.kdf.labeledExtract(suiteID, nil, "dkp_prk", ikm)
+	if kem == dhKEMX25519 {
+		return kem.NewPrivateKey(kem.kdf.labeledExpand(suiteID, prk, "sk", nil, kem.nSk))
+	}
+	for counter := 0; counter < 256; counter++ {
+		s := kem.kdf.labeledExpand(suiteID, prk, "candidate", []byte{uint8(counter)}, kem.nSk)
+		if k, err := kem.NewPrivateKey(s); err == nil {
+			return k, nil
+		}
+	}
+	return nil, errors.New("hpke: DeriveKeyPair failed")
+}
+
+type dhKEMPublicKey struct {
+	kem *dhKEM
+	pub *ecdh.PublicKey
+}
+
+// NewDHKEMPublicKey returns a PublicKey implementing DHKEM(P-256, HKDF-SHA256)
+// or DHKEM(X25519, HKDF-SHA256), depending on the curve of pub.
+func NewDHKEMPublicKey(pub *ecdh.PublicKey) (PublicKey, error) {
+	kem, ok := DHKEM(pub.Curve()).(*dhKEM)
+	if !ok {
+		return nil, errUnsupportedCurve
+	}
+	return &dhKEMPublicKey{kem, pub}, nil
+}
+
+func (pk *dhKEMPublicKey) KEM() KEM {
+	return pk.kem
+}
+
+func (pk *dhKEMPublicKey) Bytes() []byte {
+	return pk.pub.Bytes()
+}
+
+// testingOnlyGenerateKey is only used during testing, to provide
+// a fixed ephemeral key to use when checking the RFC 9180 vectors.
+var testingOnlyGenerateKey func() *ecdh.PrivateKey
+
+func (pk *dhKEMPublicKey) encap() (sharedSecret, enc []byte, err error) {
+	privEph, err := pk.pub.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if testingOnlyGenerateKey != nil {
+		privEph = testingOnlyGenerateKey()
+	}
+	dhVal, err := privEph.ECDH(pk.pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	encPubEph := privEph.PublicKey().Bytes()
+
+	kemContext := append(encPubEph[:len(encPubEph):len(encPubEph)], pk.pub.Bytes()...)
+	return pk.kem.extractAndExpand(dhVal, kemContext), encPubEph, nil
+}
+
+type dhKEMPrivateKey struct {
+	kem  *dhKEM
+	priv *ecdh.PrivateKey
+}
+
+// NewDHKEMPrivateKey returns a PrivateKey implementing DHKEM(P-256,
+// HKDF-SHA256) or DHKEM(X25519, HKDF-SHA256), depending on the curve of priv.
+func NewDHKEMPrivateKey(priv *ecdh.PrivateKey) (PrivateKey, error) {
+	kem, ok := DHKEM(priv.Curve()).(*dhKEM)
+	if !ok {
+		return nil, errUnsupportedCurve
+	}
+	return &dhKEMPrivateKey{kem, priv}, nil
+}
+
+func (k *dhKEMPrivateKey) KEM() KEM {
+	return k.kem
+}
+
+func (k *dhKEMPrivateKey) Bytes() []byte {
+	b := k.priv.Bytes()
+	if k.kem == dhKEMX25519 {
+		// SerializePrivateKey must clamp X25519 keys, see RFC 9180,
+		// Section 7.1.2.
+		b[0] &= 248
+		b[31] &= 127
+		b[31] |= 64
+	}
+	return b
+}
+
+func (k *dhKEMPrivateKey) PublicKey() PublicKey {
+	return &dhKEMPublicKey{k.kem, k.priv.PublicKey()}
+}
+
+func (k *dhKEMPrivateKey) decap(encPubEph []byte) ([]byte, error) {
+	pubEph, err := k.priv.Curve().NewPublicKey(encPubEph)
+	if err != nil {
+		return nil, err
+	}
+	dhVal, err := k.priv.ECDH(pubEph)
+	if err != nil {
+		return nil, err
+	}
+	kemContext := append(encPubEph[:len(encPubEph):len(encPubEph)], k.priv.PublicKey().Bytes()...)
+	return k.kem.extractAndExpand(dhVal, kemContext), nil
+}
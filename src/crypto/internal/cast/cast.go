@@ -0,0 +1,26 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cast implements Cryptographic Algorithm Self-Tests (CASTs).
+//
+// In the style of FIPS 140-3 modules, each algorithm is checked against a
+// known answer before it is first used, and the program aborts if the result
+// is wrong, rather than silently producing incorrect output.
+package cast
+
+import "sync"
+
+// New returns a function that runs the named self-test f the first time it is
+// called, and panics if f returns an error. Subsequent calls do nothing, or
+// panic again if the self-test failed.
+//
+// Packages should call the returned function before the first use of the
+// algorithm, such as at the top of their constructors.
+func New(name string, f func() error) func() {
+	return sync.OnceFunc(func() {
+		if err := f(); err != nil {
+			panic("crypto: " + name + " self-test failed: " + err.Error())
+		}
+	})
+}
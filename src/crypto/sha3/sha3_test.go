@@ -0,0 +1,175 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sha3_test
+
+import (
+	"bytes"
+	"crypto"
+	. "crypto/sha3"
+	"encoding"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+// Generated with Python's hashlib.
+var abcDigests = []struct {
+	name string
+	new  func() *SHA3
+	sum  func([]byte) []byte
+	hash crypto.Hash
+	want string
+}{
+	{"SHA3-224", New224, func(b []byte) []byte { s := Sum224(b); return s[:] }, crypto.SHA3_224,
+		"e642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf"},
+	{"SHA3-256", New256, func(b []byte) []byte { s := Sum256(b); return s[:] }, crypto.SHA3_256,
+		"3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+	{"SHA3-384", New384, func(b []byte) []byte { s := Sum384(b); return s[:] }, crypto.SHA3_384,
+		"ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25"},
+	{"SHA3-512", New512, func(b []byte) []byte { s := Sum512(b); return s[:] }, crypto.SHA3_512,
+		"b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0"},
+}
+
+var abcShakes = []struct {
+	name string
+	new  func() *SHAKE
+	sum  func([]byte, int) []byte
+	want string
+}{
+	{"SHAKE128", NewSHAKE128, SumSHAKE128,
+		"5881092dd818bf5cf8a3ddb793fbcba74097d5c526a6d35f97b83351940f2cc8"},
+	{"SHAKE256", NewSHAKE256, SumSHAKE256,
+		"483366601360a8771c6863080cc4114d8db44530f8f1e1ee4f94ea37e78b5739d5a15bef186a5386c75744c0527e1faa9f8726e462a12a4feb06bd8801e751e4"},
+}
+
+func TestVectors(t *testing.T) {
+	for _, tt := range abcDigests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.new()
+			h.Write([]byte("abc"))
+			if got := hex.EncodeToString(h.Sum(nil)); got != tt.want {
+				t.Errorf("New: got %s, want %s", got, tt.want)
+			}
+			if got := hex.EncodeToString(tt.sum([]byte("abc"))); got != tt.want {
+				t.Errorf("Sum: got %s, want %s", got, tt.want)
+			}
+			if !tt.hash.Available() {
+				t.Fatalf("%v is not available", tt.hash)
+			}
+			hh := tt.hash.New()
+			hh.Write([]byte("abc"))
+			if got := hex.EncodeToString(hh.Sum(nil)); got != tt.want {
+				t.Errorf("crypto.Hash: got %s, want %s", got, tt.want)
+			}
+			if hh.Size() != tt.hash.Size() {
+				t.Errorf("Size: got %d, want %d", hh.Size(), tt.hash.Size())
+			}
+		})
+	}
+	for _, tt := range abcShakes {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.new()
+			h.Write([]byte("abc"))
+			out := make([]byte, len(tt.want)/2)
+			h.Read(out)
+			if got := hex.EncodeToString(out); got != tt.want {
+				t.Errorf("New: got %s, want %s", got, tt.want)
+			}
+			if got := hex.EncodeToString(tt.sum([]byte("abc"), len(tt.want)/2)); got != tt.want {
+				t.Errorf("Sum: got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	var h SHA3
+	h.Write([]byte("abc"))
+	if got, want := h.Sum(nil), Sum256([]byte("abc")); !bytes.Equal(got, want[:]) {
+		t.Errorf("SHA3 zero value: got %x, want %x", got, want)
+	}
+	var _ hash.Hash = &h
+
+	var s SHAKE
+	s.Write([]byte("abc"))
+	out := make([]byte, 64)
+	s.Read(out)
+	if want := SumSHAKE256([]byte("abc"), 64); !bytes.Equal(out, want) {
+		t.Errorf("SHAKE zero value: got %x, want %x", out, want)
+	}
+}
+
+func TestCSHAKE(t *testing.T) {
+	// cSHAKE with empty N and S is equivalent to SHAKE.
+	for _, tt := range []struct {
+		name   string
+		cshake func(N, S []byte) *SHAKE
+		shake  func([]byte, int) []byte
+	}{
+		{"cSHAKE128", NewCSHAKE128, SumSHAKE128},
+		{"cSHAKE256", NewCSHAKE256, SumSHAKE256},
+	} {
+		h := tt.cshake(nil, nil)
+		h.Write([]byte("abc"))
+		got := make([]byte, 32)
+		h.Read(got)
+		if want := tt.shake([]byte("abc"), 32); !bytes.Equal(got, want) {
+			t.Errorf("%s: got %x, want %x", tt.name, got, want)
+		}
+
+		h = tt.cshake([]byte("N"), []byte("S"))
+		h.Write([]byte("abc"))
+		h.Read(got)
+		if want := tt.shake([]byte("abc"), 32); bytes.Equal(got, want) {
+			t.Errorf("%s: customization has no effect", tt.name)
+		}
+	}
+}
+
+type binaryState interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+	Write([]byte) (int, error)
+	Reset()
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	for _, tt := range abcDigests {
+		t.Run(tt.name, func(t *testing.T) {
+			testMarshalUnmarshal(t, tt.new(), func(h binaryState) []byte {
+				return h.(*SHA3).Sum(nil)
+			})
+		})
+	}
+	for _, tt := range abcShakes {
+		t.Run(tt.name, func(t *testing.T) {
+			testMarshalUnmarshal(t, tt.new(), func(h binaryState) []byte {
+				out := make([]byte, 32)
+				h.(*SHAKE).Read(out)
+				return out
+			})
+		})
+	}
+}
+
+func testMarshalUnmarshal(t *testing.T, h binaryState, sum func(binaryState) []byte) {
+	msg := []byte("The quick brown fox jumps over the lazy dog")
+	h.Write(msg)
+	want := sum(h)
+	h.Reset()
+	h.Write(msg[:10])
+	b, err := h.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+	h.Write([]byte("garbage"))
+	if err := h.UnmarshalBinary(b); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	h.Write(msg[10:])
+	if got := sum(h); !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,300 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestDecodeECHConfigLists(t *testing.T) {
+	for _, tc := range []struct {
+		list       string
+		numConfigs int
+	}{
+		{"0045fe0d0041590020002092a01233db2218518ccbbbbc24df20686af417b37388de6460e94011974777090004000100010012636c6f7564666c6172652d6563682e636f6d0000", 1},
+		{"0105badd00050504030201fe0d0066000010004104e62b69e2bf659f97be2f1e0d948a4cd5976bb7a91e0d46fbdda9a91e9ddcba5a01e7d697a80a18f9c3c4a31e56e27c8348db161a1cf51d7ef1942d4bcf7222c1000c000100010001000200010003400e7075626c69632e6578616d706c650000fe0d003d00002000207d661615730214aeee70533366f36a609ead65c0c208e62322346ab5bcd8de1c000411112222400e7075626c69632e6578616d706c650000fe0d004d000020002085bd6a03277c25427b52e269e0c77a8eb524ba1eb3d2f132662d4b0ac6cb7357000c000100010001000200010003400e7075626c69632e6578616d706c650008aaaa000474657374", 3},
+	} {
+		b, err := hex.DecodeString(tc.list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		configs, err := parseECHConfigList(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(configs) != tc.numConfigs {
+			t.Fatalf("unexpected number of configs parsed: got %d want %d", len(configs), tc.numConfigs)
+		}
+	}
+}
+
+func TestSkipBadConfigs(t *testing.T) {
+	b, err := hex.DecodeString("00c8badd00050504030201fe0d0029006666000401020304000c000100010001000200010003400e7075626c69632e6578616d706c650000fe0d003d000020002072e8a23b7aef67832bcc89d652e3870a60f88ca684ec65d6eace6b61f136064c000411112222400e7075626c69632e6578616d706c650000fe0d004d00002000200ce95810a81d8023f41e83679bc92701b2acd46c75869f95c72bc61c6b12297c000c000100010001000200010003400e7075626c69632e6578616d706c650008aaaa000474657374")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := parseECHConfigList(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, _, _ := pickECHConfig(configs)
+	if config != nil {
+		t.Fatal("pickECHConfig picked an invalid config")
+	}
+}
+
+func TestECHPadding(t *testing.T) {
+	const maxNameLength = 64
+	for _, serverName := range []string{"a.test", strings.Repeat("a", 30) + ".test", strings.Repeat("a", maxNameLength) + ".test", ""} {
+		inner := &clientHelloMsg{
+			vers:               VersionTLS12,
+			random:             make([]byte, 32),
+			serverName:         serverName,
+			cipherSuites:       []uint16{TLS_AES_128_GCM_SHA256},
+			compressionMethods: []uint8{compressionNone},
+			supportedVersions:  []uint16{VersionTLS13},
+		}
+		encoded, err := encodeInnerClientHello(inner, maxNameLength)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(encoded)%32 != 0 {
+			t.Errorf("server name %q: got encoded length %d, want multiple of 32", serverName, len(encoded))
+		}
+	}
+}
+
+func marshalECHConfig(id uint8, pubKey []byte, publicName string, maxNameLen uint8) []byte {
+	builder := cryptobyte.NewBuilder(nil)
+	builder.AddUint16(extensionEncryptedClientHello)
+	builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		builder.AddUint8(id)
+		builder.AddUint16(0x0020) // DHKEM(X25519, HKDF-SHA256)
+		builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+			builder.AddBytes(pubKey)
+		})
+		builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+			builder.AddUint16(0x0001) // HKDF-SHA256
+			builder.AddUint16(0x0001) // AES-128-GCM
+		})
+		builder.AddUint8(maxNameLen)
+		builder.AddUint8LengthPrefixed(func(builder *cryptobyte.Builder) {
+			builder.AddBytes([]byte(publicName))
+		})
+		builder.AddUint16(0) // extensions
+	})
+	return builder.BytesOrPanic()
+}
+
+func marshalECHConfigList(configs ...[]byte) []byte {
+	builder := cryptobyte.NewBuilder(nil)
+	builder.AddUint16LengthPrefixed(func(builder *cryptobyte.Builder) {
+		for _, c := range configs {
+			builder.AddBytes(c)
+		}
+	})
+	return builder.BytesOrPanic()
+}
+
+// testECHConfigs returns a client and a server Config which negotiate ECH
+// for the inner name "secret.example" behind the public name "public.example".
+func testECHConfigs(t *testing.T) (clientConfig, serverConfig *Config, publicCert, secretCert *x509.Certificate) {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"public.example"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	publicCertDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, k.Public(), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicCert, err = x509.ParseCertificate(publicCertDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.DNSNames[0] = "secret.example"
+	secretCertDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, k.Public(), k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretCert, err = x509.ParseCertificate(secretCertDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	echKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	echConfig := marshalECHConfig(123, echKey.PublicKey().Bytes(), "public.example", 32)
+
+	clientConfig, serverConfig = testConfig.Clone(), testConfig.Clone()
+	clientConfig.Time = nil
+	clientConfig.Rand = nil
+	clientConfig.MinVersion = VersionTLS13
+	clientConfig.ServerName = "secret.example"
+	clientConfig.InsecureSkipVerify = false
+	clientConfig.RootCAs = x509.NewCertPool()
+	clientConfig.RootCAs.AddCert(secretCert)
+	clientConfig.RootCAs.AddCert(publicCert)
+	clientConfig.EncryptedClientHelloConfigList = marshalECHConfigList(echConfig)
+	serverConfig.Time = nil
+	serverConfig.Rand = nil
+	serverConfig.MinVersion = VersionTLS13
+	serverConfig.Certificates = []Certificate{
+		{Certificate: [][]byte{publicCertDER}, PrivateKey: k},
+		{Certificate: [][]byte{secretCertDER}, PrivateKey: k},
+	}
+	serverConfig.NameToCertificate = nil
+	serverConfig.EncryptedClientHelloKeys = []EncryptedClientHelloKey{
+		{Config: echConfig, PrivateKey: echKey.Bytes(), SendAsRetry: true},
+	}
+	return clientConfig, serverConfig, publicCert, secretCert
+}
+
+func TestECH(t *testing.T) {
+	clientConfig, serverConfig, _, secretCert := testECHConfigs(t)
+	clientConfig.ClientSessionCache = NewLRUClientSessionCache(2)
+
+	check := func(t *testing.T, wantResume bool) {
+		ss, cs, err := testHandshake(t, clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("unexpected failure: %s", err)
+		}
+		if !ss.ECHAccepted {
+			t.Error("server ConnectionState shows ECH not accepted")
+		}
+		if !cs.ECHAccepted {
+			t.Error("client ConnectionState shows ECH not accepted")
+		}
+		if cs.ServerName != "secret.example" || ss.ServerName != "secret.example" {
+			t.Errorf("unexpected ConnectionState.ServerName, want %q, got server: %q, client: %q", "secret.example", ss.ServerName, cs.ServerName)
+		}
+		if cs.DidResume != wantResume {
+			t.Errorf("got DidResume %v, want %v", cs.DidResume, wantResume)
+		}
+		if !wantResume && (len(cs.VerifiedChains) != 1 || !cs.VerifiedChains[0][0].Equal(secretCert)) {
+			t.Error("unexpected verified chains")
+		}
+	}
+
+	t.Run("Accepted", func(t *testing.T) { check(t, false) })
+	t.Run("Resumed", func(t *testing.T) { check(t, true) })
+
+	clientConfig.ClientSessionCache = nil
+	serverConfig.CurvePreferences = []CurveID{CurveP384}
+	t.Run("HelloRetryRequest", func(t *testing.T) { check(t, false) })
+}
+
+func TestECHRejected(t *testing.T) {
+	clientConfig, serverConfig, publicCert, _ := testECHConfigs(t)
+
+	// Replace the server key, so the client's config is stale.
+	randKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retryConfig := marshalECHConfig(32, randKey.PublicKey().Bytes(), "public.example", 32)
+	serverConfig.EncryptedClientHelloKeys = []EncryptedClientHelloKey{
+		{Config: retryConfig, PrivateKey: randKey.Bytes(), SendAsRetry: true},
+	}
+
+	_, _, err = testHandshake(t, clientConfig, serverConfig)
+	var echErr *ECHRejectionError
+	if !errors.As(err, &echErr) {
+		t.Fatalf("got error %v, want ECHRejectionError", err)
+	}
+	if !bytes.Equal(echErr.RetryConfigList, marshalECHConfigList(retryConfig)) {
+		t.Errorf("unexpected retry configs %x", echErr.RetryConfigList)
+	}
+
+	// Retrying with the configs sent by the server must succeed.
+	clientConfig.EncryptedClientHelloConfigList = echErr.RetryConfigList
+	_, cs, err := testHandshake(t, clientConfig, serverConfig)
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if !cs.ECHAccepted {
+		t.Error("ECH not accepted with the retry configs")
+	}
+
+	// A server without ECH keys sends no retry configs, and the client still
+	// authenticates it as the public name, and calls the rejection callback.
+	serverConfig.EncryptedClientHelloKeys = nil
+	var called bool
+	clientConfig.EncryptedClientHelloRejectionVerify = func(cs ConnectionState) error {
+		called = true
+		if len(cs.PeerCertificates) != 1 || !cs.PeerCertificates[0].Equal(publicCert) {
+			t.Error("unexpected peer certificates in EncryptedClientHelloRejectionVerify")
+		}
+		if cs.ECHAccepted {
+			t.Error("ECHAccepted set in EncryptedClientHelloRejectionVerify")
+		}
+		return nil
+	}
+	_, _, err = testHandshake(t, clientConfig, serverConfig)
+	if !errors.As(err, &echErr) {
+		t.Fatalf("got error %v, want ECHRejectionError", err)
+	}
+	if echErr.RetryConfigList != nil {
+		t.Errorf("unexpected retry configs %x", echErr.RetryConfigList)
+	}
+	if !called {
+		t.Error("EncryptedClientHelloRejectionVerify not called")
+	}
+
+	clientConfig.EncryptedClientHelloRejectionVerify = func(ConnectionState) error {
+		return errors.New("rejected")
+	}
+	_, _, err = testHandshake(t, clientConfig, serverConfig)
+	if err == nil || !strings.Contains(err.Error(), "bad certificate") {
+		t.Errorf("got error %v, want bad certificate alert", err)
+	}
+}
+
+func TestECHConfigVersions(t *testing.T) {
+	clientConfig, _, _, _ := testECHConfigs(t)
+	handshake := func() error {
+		c, s := localPipe(t)
+		defer c.Close()
+		defer s.Close()
+		return Client(c, clientConfig).Handshake()
+	}
+
+	clientConfig.MinVersion = VersionTLS12
+	if err := handshake(); err == nil || !strings.Contains(err.Error(), "MinVersion") {
+		t.Errorf("got error %v, want MinVersion error", err)
+	}
+
+	clientConfig.MinVersion = 0
+	clientConfig.MaxVersion = VersionTLS12
+	if err := handshake(); err == nil || !strings.Contains(err.Error(), "MaxVersion") {
+		t.Errorf("got error %v, want MaxVersion error", err)
+	}
+
+	clientConfig.MaxVersion = 0
+	clientConfig.EncryptedClientHelloConfigList = []byte{0, 0}
+	if err := handshake(); err == nil || !strings.Contains(err.Error(), "no valid configs") {
+		t.Errorf("got error %v, want no valid configs error", err)
+	}
+}
@@ -312,6 +312,11 @@ func (test *clientTest) run(t *testing.T, write bool) {
 		if config == nil {
 			config = testConfig
 		}
+		if config.CurvePreferences == nil {
+			// The recorded ClientHellos predate the X25519MLKEM768 default.
+			config = config.Clone()
+			config.CurvePreferences = []CurveID{X25519, CurveP256, CurveP384, CurveP521}
+		}
 		client := Client(clientConn, config)
 		defer client.Close()
 
@@ -391,7 +391,7 @@ func testHandshake(t *testing.T, clientConfig, serverConfig *Config) (serverStat
 		cli := Client(c, clientConfig)
 		err := cli.Handshake()
 		if err != nil {
-			errChan <- fmt.Errorf("client: %v", err)
+			errChan <- fmt.Errorf("client: %w", err)
 			c.Close()
 			return
 		}
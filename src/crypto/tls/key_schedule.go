@@ -7,6 +7,7 @@ package tls
 import (
 	"crypto/ecdh"
 	"crypto/hmac"
+	"crypto/internal/mlkem768"
 	"errors"
 	"fmt"
 	"hash"
@@ -117,6 +118,52 @@ func (c *cipherSuiteTLS13) exportKeyingMaterial(masterSecret []byte, transcript
 	}
 }
 
+// keySharePrivateKeys holds the private keys of the key share sent by the
+// client for curveID. For X25519MLKEM768, ecdhe is the X25519 key, which may
+// also be used for a separate X25519 key share.
+type keySharePrivateKeys struct {
+	curveID CurveID
+	ecdhe   *ecdh.PrivateKey
+	mlkem   *mlkem768.DecapsulationKey
+}
+
+const x25519PublicKeySize = 32
+
+// generateKeyShare returns a key share for curveID, along with the private
+// keys necessary to complete the key exchange.
+func generateKeyShare(rand io.Reader, curveID CurveID) (*keySharePrivateKeys, keyShare, error) {
+	if curveID != X25519MLKEM768 {
+		if _, ok := curveForCurveID(curveID); !ok {
+			return nil, keyShare{}, errors.New("tls: CurvePreferences includes unsupported curve")
+		}
+		key, err := generateECDHEKey(rand, curveID)
+		if err != nil {
+			return nil, keyShare{}, err
+		}
+		return &keySharePrivateKeys{curveID: curveID, ecdhe: key},
+			keyShare{group: curveID, data: key.PublicKey().Bytes()}, nil
+	}
+
+	key, err := generateECDHEKey(rand, X25519)
+	if err != nil {
+		return nil, keyShare{}, err
+	}
+	seed := make([]byte, mlkem768.SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, keyShare{}, err
+	}
+	dk, err := mlkem768.NewDecapsulationKey(seed)
+	if err != nil {
+		return nil, keyShare{}, err
+	}
+	// draft-kwiatkowski-tls-ecdhe-mlkem-02, Section 3.1.1: the client's key
+	// share is the concatenation of the ML-KEM encapsulation key and the
+	// X25519 ephemeral share.
+	data := append(dk.EncapsulationKey().Bytes(), key.PublicKey().Bytes()...)
+	return &keySharePrivateKeys{curveID: curveID, ecdhe: key, mlkem: dk},
+		keyShare{group: curveID, data: data}, nil
+}
+
 // generateECDHEKey returns a PrivateKey that implements Diffie-Hellman
 // according to RFC 8446, Section 4.2.8.2.
 func generateECDHEKey(rand io.Reader, curveID CurveID) (*ecdh.PrivateKey, error) {
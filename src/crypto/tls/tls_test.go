@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"crypto"
+	"crypto/internal/mlkem768"
 	"crypto/x509"
 	"encoding/json"
 	"errors"
@@ -764,7 +765,7 @@ func TestWarningAlertFlood(t *testing.T) {
 }
 
 func TestCloneFuncFields(t *testing.T) {
-	const expectedCount = 8
+	const expectedCount = 9
 	called := 0
 
 	c1 := Config{
@@ -800,6 +801,10 @@ func TestCloneFuncFields(t *testing.T) {
 			called |= 1 << 7
 			return nil, nil
 		},
+		EncryptedClientHelloRejectionVerify: func(ConnectionState) error {
+			called |= 1 << 8
+			return nil
+		},
 	}
 
 	c2 := c1.Clone()
@@ -812,6 +817,7 @@ func TestCloneFuncFields(t *testing.T) {
 	c2.VerifyConnection(ConnectionState{})
 	c2.UnwrapSession(nil, ConnectionState{})
 	c2.WrapSession(ConnectionState{}, nil)
+	c2.EncryptedClientHelloRejectionVerify(ConnectionState{})
 
 	if called != (1<<expectedCount)-1 {
 		t.Fatalf("expected %d calls but saw calls %b", expectedCount, called)
@@ -830,7 +836,7 @@ func TestCloneNonFuncFields(t *testing.T) {
 		switch fn := typ.Field(i).Name; fn {
 		case "Rand":
 			f.Set(reflect.ValueOf(io.Reader(os.Stdin)))
-		case "Time", "GetCertificate", "GetConfigForClient", "VerifyPeerCertificate", "VerifyConnection", "GetClientCertificate", "WrapSession", "UnwrapSession":
+		case "Time", "GetCertificate", "GetConfigForClient", "VerifyPeerCertificate", "VerifyConnection", "GetClientCertificate", "WrapSession", "UnwrapSession", "EncryptedClientHelloRejectionVerify":
 			// DeepEqual can't compare functions. If you add a
 			// function field to this list, you must also change
 			// TestCloneFuncFields to ensure that the func field is
@@ -865,6 +871,12 @@ func TestCloneNonFuncFields(t *testing.T) {
 			f.Set(reflect.ValueOf([]CurveID{CurveP256}))
 		case "Renegotiation":
 			f.Set(reflect.ValueOf(RenegotiateOnceAsClient))
+		case "EncryptedClientHelloConfigList":
+			f.Set(reflect.ValueOf([]byte{'x'}))
+		case "EncryptedClientHelloKeys":
+			f.Set(reflect.ValueOf([]EncryptedClientHelloKey{
+				{Config: []byte{1}, PrivateKey: []byte{1}},
+			}))
 		case "mutex", "autoSessionTicketKeys", "sessionTicketKeys":
 			continue // these are unexported fields that are handled separately
 		default:
@@ -1802,3 +1814,204 @@ func testVerifyCertificates(t *testing.T, version uint16) {
 		})
 	}
 }
+
+func TestHandshakeMLKEM(t *testing.T) {
+	if needFIPS() {
+		t.Skip("ML-KEM not supported in FIPS mode")
+	}
+	defaultWithPQ := []CurveID{X25519MLKEM768, X25519, CurveP256, CurveP384, CurveP521}
+	defaultWithoutPQ := []CurveID{X25519, CurveP256, CurveP384, CurveP521}
+	var tests = []struct {
+		name           string
+		clientConfig   func(*Config)
+		serverConfig   func(*Config)
+		preparation    func(*testing.T)
+		expectClient   []CurveID
+		expectSelected CurveID
+		expectHRR      bool
+		expectVersion  uint16
+		expectFail     bool
+	}{
+		{
+			name:           "Default",
+			expectClient:   defaultWithPQ,
+			expectSelected: X25519MLKEM768,
+		},
+		{
+			name: "ClientCurvePreferences",
+			clientConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{X25519}
+			},
+			expectClient:   []CurveID{X25519},
+			expectSelected: X25519,
+		},
+		{
+			name: "ServerCurvePreferencesX25519",
+			serverConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{X25519}
+			},
+			expectClient:   defaultWithPQ,
+			expectSelected: X25519,
+		},
+		{
+			name: "ServerCurvePreferencesHRR",
+			serverConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{CurveP256}
+			},
+			expectClient:   defaultWithPQ,
+			expectSelected: CurveP256,
+			expectHRR:      true,
+		},
+		{
+			name: "ClientMLKEMOnly",
+			clientConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{X25519MLKEM768}
+			},
+			expectClient:   []CurveID{X25519MLKEM768},
+			expectSelected: X25519MLKEM768,
+		},
+		{
+			name: "MLKEMHRR",
+			clientConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{CurveP256, X25519MLKEM768}
+			},
+			serverConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{X25519MLKEM768}
+			},
+			expectClient:   []CurveID{CurveP256, X25519MLKEM768},
+			expectSelected: X25519MLKEM768,
+			expectHRR:      true,
+		},
+		{
+			name: "ClientTLSv12",
+			clientConfig: func(config *Config) {
+				config.MaxVersion = VersionTLS12
+			},
+			expectClient:  defaultWithoutPQ,
+			expectVersion: VersionTLS12,
+		},
+		{
+			name: "ServerTLSv12",
+			serverConfig: func(config *Config) {
+				config.MaxVersion = VersionTLS12
+			},
+			expectClient:  defaultWithPQ,
+			expectVersion: VersionTLS12,
+		},
+		{
+			name: "ServerMLKEMOnlyTLSv12",
+			clientConfig: func(config *Config) {
+				config.MaxVersion = VersionTLS12
+				config.CipherSuites = []uint16{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+			},
+			serverConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{X25519MLKEM768}
+			},
+			expectClient: defaultWithoutPQ,
+			expectFail:   true,
+		},
+		{
+			name: "GODEBUG tlsmlkem=0",
+			preparation: func(t *testing.T) {
+				t.Setenv("GODEBUG", "tlsmlkem=0")
+			},
+			expectClient:   defaultWithoutPQ,
+			expectSelected: X25519,
+		},
+		{
+			name: "CurvePreferences override GODEBUG",
+			preparation: func(t *testing.T) {
+				t.Setenv("GODEBUG", "tlsmlkem=0")
+			},
+			clientConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{X25519MLKEM768, CurveP256}
+			},
+			serverConfig: func(config *Config) {
+				config.CurvePreferences = []CurveID{X25519MLKEM768, CurveP256}
+			},
+			expectClient:   []CurveID{X25519MLKEM768, CurveP256},
+			expectSelected: X25519MLKEM768,
+		},
+	}
+
+	baseConfig := testConfig.Clone()
+	baseConfig.CurvePreferences = nil
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.preparation != nil {
+				test.preparation(t)
+			}
+			serverConfig := baseConfig.Clone()
+			if test.serverConfig != nil {
+				test.serverConfig(serverConfig)
+			}
+			serverConfig.GetConfigForClient = func(hello *ClientHelloInfo) (*Config, error) {
+				if !reflect.DeepEqual(hello.SupportedCurves, test.expectClient) {
+					t.Errorf("got client curves %v, expected %v", hello.SupportedCurves, test.expectClient)
+				}
+				return nil, nil
+			}
+			clientConfig := baseConfig.Clone()
+			if test.clientConfig != nil {
+				test.clientConfig(clientConfig)
+			}
+			ss, cs, err := testHandshake(t, clientConfig, serverConfig)
+			if test.expectFail {
+				if err == nil {
+					t.Fatal("expected handshake to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectVersion := test.expectVersion
+			if expectVersion == 0 {
+				expectVersion = VersionTLS13
+			}
+			if ss.Version != expectVersion || cs.Version != expectVersion {
+				t.Errorf("got versions %x and %x, expected %x", ss.Version, cs.Version, expectVersion)
+			}
+			if ss.testingOnlyCurveID != test.expectSelected {
+				t.Errorf("server selected curve %v, expected %v", ss.testingOnlyCurveID, test.expectSelected)
+			}
+			if cs.testingOnlyCurveID != test.expectSelected {
+				t.Errorf("client selected curve %v, expected %v", cs.testingOnlyCurveID, test.expectSelected)
+			}
+			if test.expectHRR {
+				if !ss.testingOnlyDidHRR {
+					t.Error("server did not use HRR")
+				}
+				if !cs.testingOnlyDidHRR {
+					t.Error("client did not use HRR")
+				}
+			} else {
+				if ss.testingOnlyDidHRR {
+					t.Error("server used HRR")
+				}
+				if cs.testingOnlyDidHRR {
+					t.Error("client used HRR")
+				}
+			}
+		})
+	}
+}
+
+func TestX25519MLKEM768KeyShareSizes(t *testing.T) {
+	keys, ks, err := generateKeyShare(zeroSource{}, X25519MLKEM768)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ks.data) != mlkem768.EncapsulationKeySize+x25519PublicKeySize {
+		t.Errorf("got client key share of %d bytes, expected %d", len(ks.data), mlkem768.EncapsulationKeySize+x25519PublicKeySize)
+	}
+	if keys.ecdhe == nil || keys.mlkem == nil {
+		t.Fatal("missing private keys")
+	}
+	if !bytes.Equal(ks.data[mlkem768.EncapsulationKeySize:], keys.ecdhe.PublicKey().Bytes()) {
+		t.Error("X25519 share is not at the end of the key share")
+	}
+	if !bytes.Equal(ks.data[:mlkem768.EncapsulationKeySize], keys.mlkem.EncapsulationKey().Bytes()) {
+		t.Error("ML-KEM encapsulation key is not at the start of the key share")
+	}
+}
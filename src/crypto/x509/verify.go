@@ -126,6 +126,28 @@ func (h HostnameError) Error() string {
 	return "x509: certificate is valid for " + valid + ", not " + h.Host
 }
 
+// RevokedError results when a certificate is listed as revoked in one of the
+// certificate revocation lists in VerifyOptions.CRLs.
+type RevokedError struct {
+	// Cert is the revoked certificate.
+	Cert *Certificate
+	// CRL is the revocation list that lists Cert as revoked.
+	CRL *RevocationList
+	// Entry is the entry of CRL for Cert.
+	Entry RevocationListEntry
+}
+
+func (e RevokedError) Error() string {
+	s := "x509: certificate with serial " + e.Cert.SerialNumber.String() + " has been revoked"
+	if !e.Entry.RevocationTime.IsZero() {
+		s += " at " + e.Entry.RevocationTime.UTC().Format(time.RFC3339)
+	}
+	if e.Entry.ReasonCode != 0 {
+		s += fmt.Sprintf(" (reason code %d)", e.Entry.ReasonCode)
+	}
+	return s
+}
+
 // UnknownAuthorityError results when the certificate issuer is unknown
 type UnknownAuthorityError struct {
 	Cert *Certificate
@@ -201,6 +223,24 @@ type VerifyOptions struct {
 	// certificates from consuming excessive amounts of CPU time when
 	// validating. It does not apply to the platform verifier.
 	MaxConstraintComparisions int
+
+	// CRLs is an optional set of certificate revocation lists, as returned by
+	// ParseRevocationList, used to check the revocation status of the
+	// certificates in the chain.
+	//
+	// A CRL applies to a certificate if its issuer matches the subject of the
+	// certificate's parent in the chain, its signature is valid for the
+	// parent's public key, and it is current at CurrentTime, that is,
+	// CurrentTime is not before ThisUpdate nor after NextUpdate (if set).
+	// Other CRLs are ignored. If an applicable CRL lists the serial number of a
+	// certificate, chains through that certificate and parent are rejected, and
+	// if no other chains can be built Verify returns a RevokedError.
+	//
+	// CRLs are not fetched from the certificates' CRLDistributionPoints, and
+	// certificates for which no applicable CRL is provided are not considered
+	// revoked. Indirect CRLs, where the CRL issuer is not the certificate
+	// issuer, are not supported.
+	CRLs []*RevocationList
 }
 
 const (
@@ -744,7 +784,8 @@ func (c *Certificate) isValid(certType int, currentChain []*Certificate, opts *V
 //
 // Certificates other than c in the returned chains should not be modified.
 //
-// WARNING: this function doesn't do any revocation checking.
+// WARNING: this function doesn't do any revocation checking beyond what is
+// provided in VerifyOptions.CRLs.
 func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err error) {
 	// Platform-specific verification needs the ASN.1 contents so
 	// this makes the behavior consistent across platforms.
@@ -767,14 +808,21 @@ func (c *Certificate) Verify(opts VerifyOptions) (chains [][]*Certificate, err e
 		// i.e. if SetFallbackRoots was called with x509usefallbackroots=1.
 		systemPool := systemRootsPool()
 		if opts.Roots == nil && (systemPool == nil || systemPool.systemPool) {
-			return c.systemVerify(&opts)
+			platformChains, err := c.systemVerify(&opts)
+			if err != nil {
+				return nil, err
+			}
+			return filterRevokedChains(platformChains, &opts)
 		}
 		if opts.Roots != nil && opts.Roots.systemPool {
 			platformChains, err := c.systemVerify(&opts)
 			// If the platform verifier succeeded, or there are no additional
 			// roots, return the platform verifier result. Otherwise, continue
 			// with the Go verifier.
-			if err == nil || opts.Roots.len() == 0 {
+			if err == nil {
+				return filterRevokedChains(platformChains, &opts)
+			}
+			if opts.Roots.len() == 0 {
 				return platformChains, err
 			}
 		}
@@ -887,15 +935,19 @@ func alreadyInChain(candidate *Certificate, chain []*Certificate) bool {
 }
 
 // maxChainSignatureChecks is the maximum number of CheckSignatureFrom calls
-// that an invocation of buildChains will (transitively) make. Most chains are
-// less than 15 certificates long, so this leaves space for multiple chains and
-// for failed checks due to different intermediates having the same Subject.
+// that an invocation of buildChains will (transitively) make, including the
+// checks of the signatures of CRLs. Most chains are less than 15 certificates
+// long, so this leaves space for multiple chains and for failed checks due to
+// different intermediates having the same Subject.
 const maxChainSignatureChecks = 100
 
+var errSignatureChecksLimit = errors.New("x509: signature check attempts limit reached while verifying certificate chain")
+
 func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, opts *VerifyOptions) (chains [][]*Certificate, err error) {
 	var (
-		hintErr  error
-		hintCert *Certificate
+		hintErr    error
+		hintCert   *Certificate
+		revokedErr error
 	)
 
 	considerCandidate := func(certType int, candidate *Certificate) {
@@ -908,7 +960,7 @@ func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, o
 		}
 		*sigChecks++
 		if *sigChecks > maxChainSignatureChecks {
-			err = errors.New("x509: signature check attempts limit reached while verifying certificate chain")
+			err = errSignatureChecksLimit
 			return
 		}
 
@@ -929,6 +981,15 @@ func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, o
 			return
 		}
 
+		if rerr := c.checkRevocation(candidate, sigChecks, opts); rerr != nil {
+			if rerr == errSignatureChecksLimit {
+				err = rerr
+			} else {
+				revokedErr = rerr
+			}
+			return
+		}
+
 		switch certType {
 		case rootCertificate:
 			chains = append(chains, appendToFreshChain(currentChain, candidate))
@@ -950,12 +1011,79 @@ func (c *Certificate) buildChains(currentChain []*Certificate, sigChecks *int, o
 		err = nil
 	}
 	if len(chains) == 0 && err == nil {
-		err = UnknownAuthorityError{c, hintErr, hintCert}
+		if revokedErr != nil {
+			err = revokedErr
+		} else {
+			err = UnknownAuthorityError{c, hintErr, hintCert}
+		}
 	}
 
 	return
 }
 
+// checkRevocation returns a RevokedError if c, issued by parent, is listed as
+// revoked in any of the applicable CRLs in opts.CRLs. Each check of the
+// signature of a CRL counts against the same maxChainSignatureChecks budget
+// as the checks of certificate signatures, tracked by sigChecks.
+func (c *Certificate) checkRevocation(parent *Certificate, sigChecks *int, opts *VerifyOptions) error {
+	if len(opts.CRLs) == 0 {
+		return nil
+	}
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	for _, crl := range opts.CRLs {
+		if crl == nil || !bytes.Equal(crl.RawIssuer, parent.RawSubject) {
+			continue
+		}
+		if now.Before(crl.ThisUpdate) || !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+			continue
+		}
+		*sigChecks++
+		if *sigChecks > maxChainSignatureChecks {
+			return errSignatureChecksLimit
+		}
+		if crl.CheckSignatureFrom(parent) != nil {
+			continue
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber != nil && c.SerialNumber != nil && entry.SerialNumber.Cmp(c.SerialNumber) == 0 {
+				return RevokedError{Cert: c, CRL: crl, Entry: entry}
+			}
+		}
+	}
+	return nil
+}
+
+// filterRevokedChains removes the chains with revoked certificates from the
+// result of a platform verifier, which doesn't take opts.CRLs into account.
+func filterRevokedChains(chains [][]*Certificate, opts *VerifyOptions) ([][]*Certificate, error) {
+	if len(opts.CRLs) == 0 {
+		return chains, nil
+	}
+	var revokedErr error
+	var sigChecks int
+	valid := make([][]*Certificate, 0, len(chains))
+NextChain:
+	for _, chain := range chains {
+		for i := 0; i+1 < len(chain); i++ {
+			if err := chain[i].checkRevocation(chain[i+1], &sigChecks, opts); err != nil {
+				if err == errSignatureChecksLimit {
+					return nil, err
+				}
+				revokedErr = err
+				continue NextChain
+			}
+		}
+		valid = append(valid, chain)
+	}
+	if len(valid) == 0 && revokedErr != nil {
+		return nil, revokedErr
+	}
+	return valid, nil
+}
+
 func validHostnamePattern(host string) bool { return validHostname(host, true) }
 func validHostnameInput(host string) bool   { return validHostname(host, false) }
 
@@ -2693,3 +2693,269 @@ func TestVerifyEKURootAsLeaf(t *testing.T) {
 	}
 
 }
+
+// generateCRLIssuer returns a CA certificate that is allowed to sign CRLs. If
+// issuer is nil, the certificate is self-signed.
+func generateCRLIssuer(t *testing.T, cn string, serial int64, key *ecdsa.PrivateKey, issuer *Certificate, issuerKey crypto.Signer) *Certificate {
+	t.Helper()
+	tmpl := &Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              KeyUsageCertSign | KeyUsageCRLSign | KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if issuer == nil {
+		issuer, issuerKey = tmpl, key
+	}
+	der, err := CreateCertificate(rand.Reader, tmpl, issuer, key.Public(), issuerKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	cert, err := ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %s", err)
+	}
+	return cert
+}
+
+func TestVerifyCRLs(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := generateCRLIssuer(t, "CRL Root", 1, rootKey, nil, nil)
+	inter := generateCRLIssuer(t, "CRL Intermediate", 2, interKey, root, rootKey)
+	other := generateCRLIssuer(t, "CRL Intermediate", 3, otherKey, nil, nil)
+	leaf, _, err := generateCert("CRL Leaf", false, inter, interKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	makeCRL := func(issuer *Certificate, key crypto.Signer, thisUpdate, nextUpdate time.Time, serials ...*big.Int) *RevocationList {
+		t.Helper()
+		tmpl := &RevocationList{
+			Number:     big.NewInt(1),
+			ThisUpdate: thisUpdate,
+			NextUpdate: nextUpdate,
+		}
+		for _, s := range serials {
+			tmpl.RevokedCertificateEntries = append(tmpl.RevokedCertificateEntries, RevocationListEntry{
+				SerialNumber:   s,
+				RevocationTime: now.Add(-time.Minute),
+				ReasonCode:     1,
+			})
+		}
+		der, err := CreateRevocationList(rand.Reader, tmpl, issuer, key)
+		if err != nil {
+			t.Fatalf("failed to create CRL: %s", err)
+		}
+		crl, err := ParseRevocationList(der)
+		if err != nil {
+			t.Fatalf("failed to parse CRL: %s", err)
+		}
+		return crl
+	}
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	for _, tc := range []struct {
+		name    string
+		crls    []*RevocationList
+		revoked *Certificate
+	}{
+		{
+			name: "no CRLs",
+		},
+		{
+			name: "leaf not revoked",
+			crls: []*RevocationList{makeCRL(inter, interKey, past, future, big.NewInt(42))},
+		},
+		{
+			name:    "leaf revoked",
+			crls:    []*RevocationList{makeCRL(inter, interKey, past, future, big.NewInt(42), leaf.SerialNumber)},
+			revoked: leaf,
+		},
+		{
+			name:    "intermediate revoked",
+			crls:    []*RevocationList{makeCRL(root, rootKey, past, future, inter.SerialNumber)},
+			revoked: inter,
+		},
+		{
+			name: "wrong issuer",
+			crls: []*RevocationList{makeCRL(root, rootKey, past, future, leaf.SerialNumber)},
+		},
+		{
+			// other has the same subject as inter, but a different key.
+			name: "bad signature",
+			crls: []*RevocationList{makeCRL(other, otherKey, past, future, leaf.SerialNumber)},
+		},
+		{
+			name: "expired CRL",
+			crls: []*RevocationList{makeCRL(inter, interKey, now.Add(-2*time.Hour), past, leaf.SerialNumber)},
+		},
+		{
+			name: "future CRL",
+			crls: []*RevocationList{makeCRL(inter, interKey, future, now.Add(2*time.Hour), leaf.SerialNumber)},
+		},
+		{
+			name: "nil CRL",
+			crls: []*RevocationList{nil},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			roots := NewCertPool()
+			roots.AddCert(root)
+			intermediates := NewCertPool()
+			intermediates.AddCert(inter)
+
+			chains, err := leaf.Verify(VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				CRLs:          tc.crls,
+			})
+			if tc.revoked == nil {
+				if err != nil {
+					t.Fatalf("Verify failed: %s", err)
+				}
+				if len(chains) != 1 {
+					t.Fatalf("unexpected number of chains: got %d, want 1", len(chains))
+				}
+				return
+			}
+			var revokedErr RevokedError
+			if !errors.As(err, &revokedErr) {
+				t.Fatalf("Verify returned %v, want RevokedError", err)
+			}
+			if !revokedErr.Cert.Equal(tc.revoked) {
+				t.Errorf("RevokedError.Cert has subject %q, want %q", revokedErr.Cert.Subject, tc.revoked.Subject)
+			}
+			if revokedErr.Entry.SerialNumber.Cmp(tc.revoked.SerialNumber) != 0 {
+				t.Errorf("RevokedError.Entry has serial %v, want %v", revokedErr.Entry.SerialNumber, tc.revoked.SerialNumber)
+			}
+			if revokedErr.Entry.ReasonCode != 1 {
+				t.Errorf("RevokedError.Entry has reason code %d, want 1", revokedErr.Entry.ReasonCode)
+			}
+		})
+	}
+}
+
+func TestVerifyCRLsAlternatePath(t *testing.T) {
+	// Two intermediates share a subject and key, but only one of them is
+	// revoked by the root, so path building must pick the other.
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := generateCRLIssuer(t, "CRL Root", 1, rootKey, nil, nil)
+	inters := []*Certificate{
+		generateCRLIssuer(t, "CRL Intermediate", 100, interKey, root, rootKey),
+		generateCRLIssuer(t, "CRL Intermediate", 101, interKey, root, rootKey),
+	}
+	leaf, _, err := generateCert("CRL Leaf", false, inters[0], interKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := CreateRevocationList(rand.Reader, &RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: time.Now().Add(time.Hour),
+		RevokedCertificateEntries: []RevocationListEntry{
+			{SerialNumber: inters[0].SerialNumber, RevocationTime: time.Now().Add(-time.Minute)},
+		},
+	}, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	intermediates := NewCertPool()
+	for _, inter := range inters {
+		intermediates.AddCert(inter)
+	}
+	chains, err := leaf.Verify(VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CRLs:          []*RevocationList{crl},
+	})
+	if err != nil {
+		t.Fatalf("Verify failed: %s", err)
+	}
+	if len(chains) != 1 {
+		t.Fatalf("unexpected number of chains: got %d, want 1", len(chains))
+	}
+	if !chains[0][1].Equal(inters[1]) {
+		t.Errorf("chain uses revoked intermediate with serial %v", chains[0][1].SerialNumber)
+	}
+}
+
+func TestVerifyCRLsSignatureChecksLimit(t *testing.T) {
+	// The signatures of CRLs count against the same limit as the
+	// signatures of certificates.
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := generateCRLIssuer(t, "CRL Root", 1, rootKey, nil, nil)
+	leaf, _, err := generateCert("CRL Leaf", false, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := CreateRevocationList(rand.Reader, &RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, root, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crls := make([]*RevocationList, maxChainSignatureChecks)
+	for i := range crls {
+		crls[i] = crl
+	}
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	_, err = leaf.Verify(VerifyOptions{
+		Roots: roots,
+		CRLs:  crls,
+	})
+	if err == nil || !strings.Contains(err.Error(), "signature check attempts limit") {
+		t.Errorf("expected verification to fail with a signature checks limit error; got %v", err)
+	}
+
+	// One fewer CRL fits in the limit, along with the check of the
+	// signature of the leaf.
+	_, err = leaf.Verify(VerifyOptions{
+		Roots: roots,
+		CRLs:  crls[1:],
+	})
+	if err != nil {
+		t.Errorf("Verify failed: %s", err)
+	}
+}
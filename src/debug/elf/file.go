@@ -23,7 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"internal/saferio"
-	"internal/zstd"
+	"compress/zstd"
 	"io"
 	"os"
 	"strings"
@@ -51,9 +51,7 @@ var depsRules = `
 
 	# These packages depend only on internal/goarch and unsafe.
 	internal/goarch, unsafe
-	< internal/abi;
-
-	unsafe < maps;
+	< internal/abi, internal/chacha8rand;
 
 	# RUNTIME is the core runtime group of packages, all of them very light-weight.
 	internal/abi, internal/cpu, internal/goarch,
@@ -66,6 +64,7 @@ var depsRules = `
 	< runtime/internal/syscall
 	< runtime/internal/atomic
 	< runtime/internal/math
+	< runtime/internal/cgroup
 	< runtime
 	< sync/atomic
 	< internal/race
@@ -77,11 +76,16 @@ var depsRules = `
 	< internal/oserror, math/bits
 	< RUNTIME;
 
+	RUNTIME
+	< iter;
+
 	# slices depends on unsafe for overlapping check, cmp for comparison
 	# semantics, and math/bits for # calculating bitlength of numbers.
-	unsafe, cmp, math/bits
+	iter, unsafe, cmp, math/bits
 	< slices;
 
+	iter, unsafe < maps;
+
 	RUNTIME, slices
 	< sort;
 
@@ -133,6 +137,18 @@ var depsRules = `
 	MATH
 	< math/rand;
 
+	MATH, internal/chacha8rand
+	< math/rand/v2;
+
+	RUNTIME
+	< weak;
+
+	math/rand/v2
+	< internal/concurrent;
+
+	internal/concurrent, weak
+	< unique;
+
 	MATH
 	< runtime/metrics;
 
@@ -142,7 +158,7 @@ var depsRules = `
 	unicode !< strconv;
 
 	# STR is basic string and buffer manipulation.
-	RUNTIME, io, unicode/utf8, unicode/utf16, unicode
+	RUNTIME, iter, io, unicode/utf8, unicode/utf16, unicode
 	< bytes, strings
 	< bufio;
 
@@ -236,7 +252,7 @@ var depsRules = `
 
 	# compression
 	FMT, encoding/binary, hash/adler32, hash/crc32
-	< compress/bzip2, compress/flate, compress/lzw, internal/zstd
+	< compress/bzip2, compress/flate, compress/lzw, compress/zstd
 	< archive/zip, compress/gzip, compress/zlib;
 
 	# templates
@@ -261,7 +277,7 @@ var depsRules = `
 	< index/suffixarray;
 
 	# executable parsing
-	FMT, encoding/binary, compress/zlib, internal/saferio, internal/zstd
+	FMT, encoding/binary, compress/zlib, internal/saferio, compress/zstd
 	< runtime/debug
 	< debug/dwarf
 	< debug/elf, debug/gosym, debug/macho, debug/pe, debug/plan9obj, internal/xcoff
@@ -352,11 +368,7 @@ var depsRules = `
 	  golang.org/x/net/lif,
 	  golang.org/x/net/route;
 
-	os, runtime, strconv, sync, unsafe,
-	internal/godebug
-	< internal/intern;
-
-	internal/bytealg, internal/intern, internal/itoa, math/bits, sort, strconv
+	internal/bytealg, internal/itoa, math/bits, sort, strconv, unique
 	< net/netip;
 
 	# net is unavoidable when doing any networking,
@@ -437,6 +449,11 @@ var depsRules = `
 	< crypto/internal/edwards25519/field
 	< crypto/internal/edwards25519;
 
+	crypto/subtle, internal/goarch
+	< crypto/internal/sha3;
+
+	sync < crypto/internal/cast;
+
 	crypto/boring
 	< crypto/aes, crypto/des, crypto/hmac, crypto/md5, crypto/rc4,
 	  crypto/sha1, crypto/sha256, crypto/sha512;
@@ -448,12 +465,15 @@ var depsRules = `
 	crypto/des,
 	crypto/ecdh,
 	crypto/hmac,
+	crypto/internal/cast,
 	crypto/internal/edwards25519,
+	crypto/internal/sha3,
 	crypto/md5,
 	crypto/rc4,
 	crypto/sha1,
 	crypto/sha256,
 	crypto/sha512
+	< crypto/hkdf, crypto/pbkdf2, crypto/sha3
 	< CRYPTO;
 
 	CGO, fmt, net !< CRYPTO;
@@ -480,7 +500,10 @@ var depsRules = `
 	< golang.org/x/crypto/chacha20
 	< golang.org/x/crypto/internal/poly1305
 	< golang.org/x/crypto/chacha20poly1305
+	< crypto/chacha20poly1305
 	< golang.org/x/crypto/hkdf
+	< crypto/hpke
+	< crypto/internal/mlkem768
 	< crypto/x509/internal/macos
 	< crypto/x509/pkix;
 
@@ -522,6 +545,8 @@ var depsRules = `
 	< net/http/httptrace;
 
 	compress/gzip,
+	compress/zlib,
+	compress/zstd,
 	golang.org/x/net/http/httpguts,
 	golang.org/x/net/http/httpproxy,
 	golang.org/x/net/http2/hpack,
@@ -581,6 +606,10 @@ var depsRules = `
 	FMT, DEBUG, flag, runtime/trace, internal/sysinfo, math/rand
 	< testing;
 
+	RUNTIME
+	< internal/synctest
+	< testing/synctest;
+
 	FMT, crypto/sha256, encoding/json, go/ast, go/parser, go/token,
 	internal/godebug, math/rand, encoding/hex, crypto/sha256
 	< internal/fuzz;
@@ -606,6 +635,9 @@ var depsRules = `
 	FMT, container/heap, math/rand
 	< internal/trace;
 
+	internal/trace, iter
+	< runtime/trace/parse;
+
 	FMT
 	< internal/diff, internal/txtar;
 
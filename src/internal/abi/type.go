@@ -162,6 +162,26 @@ var kindNames = []string{
 
 func (t *Type) Kind() Kind { return Kind(t.Kind_ & KindMask) }
 
+// EmptyInterface describes the layout of an "interface{}" or an "any".
+// Its first word always points to an abi.Type.
+type EmptyInterface struct {
+	Type *Type
+	Data unsafe.Pointer
+}
+
+// TypeOf returns the abi.Type of some value.
+func TypeOf(a any) *Type {
+	return (*EmptyInterface)(unsafe.Pointer(&a)).Type
+}
+
+// TypeFor returns the abi.Type for a type parameter.
+//
+// It goes through *T so that it works for interface kinds and
+// never needs to convert a (possibly large) T value to an interface.
+func TypeFor[T any]() *Type {
+	return TypeOf((*T)(nil)).Elem()
+}
+
 func (t *Type) HasName() bool {
 	return t.TFlag&TFlagNamed != 0
 }
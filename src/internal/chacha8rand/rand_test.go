@@ -0,0 +1,181 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package chacha8rand
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+	"testing"
+)
+
+var seed = [32]byte([]byte("chacha8rand test seed 0123456789"))
+
+// refBlock computes a single ChaCha8 block in the straightforward
+// way, as in RFC 8439 but with 8 rounds and with the feed-forward
+// addition applied only to the key words.
+func refBlock(key [8]uint32, counter uint32) [16]uint32 {
+	in := [16]uint32{
+		0x61707865, 0x3320646e, 0x79622d32, 0x6b206574,
+		key[0], key[1], key[2], key[3], key[4], key[5], key[6], key[7],
+		counter, 0, 0, 0,
+	}
+	x := in
+	qr := func(a, b, c, d int) {
+		x[a] += x[b]
+		x[d] = bits.RotateLeft32(x[d]^x[a], 16)
+		x[c] += x[d]
+		x[b] = bits.RotateLeft32(x[b]^x[c], 12)
+		x[a] += x[b]
+		x[d] = bits.RotateLeft32(x[d]^x[a], 8)
+		x[c] += x[d]
+		x[b] = bits.RotateLeft32(x[b]^x[c], 7)
+	}
+	for i := 0; i < 4; i++ {
+		qr(0, 4, 8, 12)
+		qr(1, 5, 9, 13)
+		qr(2, 6, 10, 14)
+		qr(3, 7, 11, 15)
+		qr(0, 5, 10, 15)
+		qr(1, 6, 11, 12)
+		qr(2, 7, 8, 13)
+		qr(3, 4, 9, 14)
+	}
+	for i := 4; i < 12; i++ {
+		x[i] += in[i]
+	}
+	return x
+}
+
+// refOutput returns the first n values produced by the generator
+// seeded with seed, computed from refBlock as described in
+// https://c2sp.org/chacha8rand.
+func refOutput(seed [32]byte, n int) []uint64 {
+	var out []uint64
+	for {
+		var key [8]uint32
+		for i := range key {
+			key[i] = binary.LittleEndian.Uint32(seed[4*i:])
+		}
+		var buf []byte
+		for c := uint32(0); c < 16; c += 4 {
+			var blocks [4][16]uint32
+			for j := range blocks {
+				blocks[j] = refBlock(key, c+uint32(j))
+			}
+			// Interleave the four blocks.
+			for i := 0; i < 16; i++ {
+				for j := 0; j < 4; j++ {
+					buf = binary.LittleEndian.AppendUint32(buf, blocks[j][i])
+				}
+			}
+		}
+		for i := 0; i < len(buf)-32; i += 8 {
+			out = append(out, binary.LittleEndian.Uint64(buf[i:]))
+			if len(out) == n {
+				return out
+			}
+		}
+		copy(seed[:], buf[len(buf)-32:])
+	}
+}
+
+func TestOutput(t *testing.T) {
+	want := refOutput(seed, 1000)
+	var s State
+	s.Init(seed)
+	for i := range want {
+		x, ok := s.Next()
+		if !ok {
+			s.Refill()
+			x, ok = s.Next()
+		}
+		if !ok || x != want[i] {
+			t.Fatalf("#%d: Next() = %#x, %v, want %#x, true", i, x, ok, want[i])
+		}
+	}
+}
+
+// TestSpecVector checks the start of the sample output
+// given in the specification at https://c2sp.org/chacha8rand.
+func TestSpecVector(t *testing.T) {
+	want := []uint64{
+		0xb773b6063d4616a5, 0x1160af22a66abc3c, 0x8c2599d9418d287c,
+		0x7ee07e037edc5cd6, 0xcfaa9ee02d1c16ad,
+	}
+	var s State
+	s.Init([32]byte([]byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ123456")))
+	for i, w := range want {
+		x, ok := s.Next()
+		if !ok || x != w {
+			t.Fatalf("#%d: Next() = %#x, %v, want %#x, true", i, x, ok, w)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	want := refOutput(seed, 1000)
+	var s State
+	s.Init(seed)
+	for i := range want {
+		data := Marshal(&s)
+		var s2 State
+		if err := Unmarshal(&s2, data); err != nil {
+			t.Fatalf("#%d: Unmarshal(%x): %v", i, data, err)
+		}
+		if data2 := Marshal(&s2); !bytes.Equal(data, data2) {
+			t.Fatalf("#%d: Marshal(Unmarshal(%x)) = %x", i, data, data2)
+		}
+		x, ok := s2.Next()
+		if !ok {
+			s2.Refill()
+			x, ok = s2.Next()
+		}
+		if !ok || x != want[i] {
+			t.Fatalf("#%d: after Unmarshal, Next() = %#x, %v, want %#x, true", i, x, ok, want[i])
+		}
+		s = s2
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	var s State
+	s.Init(seed)
+	data := Marshal(&s)
+	for _, bad := range [][]byte{
+		nil,
+		data[:len(data)-1],
+		append([]byte("chacha9:"), data[8:]...),
+		append(append([]byte("chacha8:"), 0, 0, 0, 0, 0, 0, 0, 125), data[16:]...),
+	} {
+		if err := Unmarshal(&s, bad); err == nil {
+			t.Errorf("Unmarshal(%x) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestReseed(t *testing.T) {
+	var s State
+	s.Init(seed)
+	old := Marshal(&s)
+	s.Reseed()
+	if bytes.Equal(Marshal(&s), old) {
+		t.Errorf("Reseed did not change state")
+	}
+	for i := 0; i < len(s.buf); i++ {
+		if _, ok := s.Next(); !ok {
+			break
+		}
+	}
+}
+
+func BenchmarkBlock(b *testing.B) {
+	var seed [4]uint64
+	var blocks [32]uint64
+	for i := 0; i < b.N; i++ {
+		block(&seed, &blocks, 0)
+	}
+	b.SetBytes(32 * 8)
+}
@@ -113,4 +113,7 @@ type Flags struct {
 	// NewInliner enables a new+improved version of the function
 	// inlining phase within the Go compiler.
 	NewInliner bool
+
+	// SwissMap enables the Swiss table Go map implementation.
+	SwissMap bool
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -230,3 +231,13 @@ func (fd *FD) RawRead(f func(uintptr) bool) error {
 func (fd *FD) RawWrite(f func(uintptr) bool) error {
 	return errors.New("not implemented")
 }
+
+// DupCloseOnExec dups fd. Plan 9 has no close-on-exec flag for dup,
+// so the new descriptor is inherited by child processes.
+func DupCloseOnExec(fd int) (int, string, error) {
+	nfd, err := syscall.Dup(fd, -1)
+	if err != nil {
+		return -1, "dup", err
+	}
+	return nfd, "", nil
+}
@@ -53,6 +53,22 @@ func (pd *pollDesc) close() {
 	pd.runtimeCtx = 0
 }
 
+// SocketCloser returns a function that unregisters the network socket fd
+// from the runtime poller and closes it, without referring to fd itself.
+// It is meant to be the argument of a cleanup, set up once fd has been
+// initialized, for an object containing fd, as such an object must not be
+// reachable from its cleanup (see runtime.AddCleanup). The object must stop
+// the cleanup before closing fd through the Close method.
+func (fd *FD) SocketCloser() func() error {
+	sysfd, pd := fd.Sysfd, fd.pd
+	return func() error {
+		// As in Close and destroy, unregister fd before closing it.
+		pd.evict()
+		pd.close()
+		return CloseFunc(sysfd)
+	}
+}
+
 // Evict evicts fd from the pending list, unblocking any I/O running on fd.
 func (pd *pollDesc) evict() {
 	if pd.runtimeCtx == 0 {
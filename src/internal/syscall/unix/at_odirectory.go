@@ -0,0 +1,12 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix && !solaris
+
+package unix
+
+import "syscall"
+
+// O_DIRECTORY is not defined by package syscall on Solaris.
+const O_DIRECTORY = syscall.O_DIRECTORY
@@ -14,5 +14,7 @@ const (
 
 	unlinkatTrap       uintptr = syscall.SYS_UNLINKAT
 	openatTrap         uintptr = syscall.SYS_OPENAT
+	mkdiratTrap        uintptr = syscall.SYS_MKDIRAT
+	readlinkatTrap     uintptr = syscall.SYS_READLINKAT
 	posixFallocateTrap uintptr = syscall.SYS_POSIX_FALLOCATE
 )
@@ -7,4 +7,5 @@ package unix
 const (
 	getrandomTrap     uintptr = 355
 	copyFileRangeTrap uintptr = 377
+	openat2Trap       uintptr = 437
 )
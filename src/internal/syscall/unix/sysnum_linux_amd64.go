@@ -7,4 +7,5 @@ package unix
 const (
 	getrandomTrap     uintptr = 318
 	copyFileRangeTrap uintptr = 326
+	openat2Trap       uintptr = 437
 )
@@ -7,4 +7,5 @@ package unix
 const (
 	getrandomTrap     uintptr = 384
 	copyFileRangeTrap uintptr = 391
+	openat2Trap       uintptr = 437
 )
@@ -13,4 +13,5 @@ package unix
 const (
 	getrandomTrap     uintptr = 278
 	copyFileRangeTrap uintptr = 285
+	openat2Trap       uintptr = 437
 )
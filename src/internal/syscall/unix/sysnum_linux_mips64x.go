@@ -9,4 +9,5 @@ package unix
 const (
 	getrandomTrap     uintptr = 5313
 	copyFileRangeTrap uintptr = 5320
+	openat2Trap       uintptr = 5437
 )
@@ -9,4 +9,5 @@ package unix
 const (
 	getrandomTrap     uintptr = 4353
 	copyFileRangeTrap uintptr = 4360
+	openat2Trap       uintptr = 4437
 )
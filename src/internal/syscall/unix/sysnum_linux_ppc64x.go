@@ -9,4 +9,5 @@ package unix
 const (
 	getrandomTrap     uintptr = 359
 	copyFileRangeTrap uintptr = 379
+	openat2Trap       uintptr = 437
 )
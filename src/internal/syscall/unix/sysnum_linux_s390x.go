@@ -7,4 +7,5 @@ package unix
 const (
 	getrandomTrap     uintptr = 349
 	copyFileRangeTrap uintptr = 375
+	openat2Trap       uintptr = 437
 )
@@ -14,6 +14,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	_ "unsafe"
@@ -101,18 +102,106 @@ func Parse(r io.Reader, bin string) (ParseResult, error) {
 // parse parses, post-processes and verifies the trace. It returns the
 // trace version and the list of events.
 func parse(r io.Reader, bin string) (int, ParseResult, error) {
-	ver, rawEvents, strings, err := readTrace(r)
+	gr := NewGenerationReader(r)
+	var res ParseResult
+	for {
+		gen, err := gr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, ParseResult{}, err
+		}
+		if res.Stacks == nil {
+			res = gen
+			continue
+		}
+		n := len(res.Events)
+		res.Events = append(res.Events, gen.Events...)
+		for id, stk := range gen.Stacks {
+			res.Stacks[id] = stk
+		}
+		// CPU samples are written some time after they are taken, so the
+		// first events of a generation may precede the last ones of the
+		// previous generation.
+		if merged := res.Events; len(merged) > n && merged[n].Ts < merged[n-1].Ts {
+			i := sort.Search(n, func(i int) bool { return merged[i].Ts > merged[n].Ts })
+			tail := merged[i:]
+			sort.SliceStable(tail, func(i, j int) bool {
+				return tail[i].Ts < tail[j].Ts
+			})
+		}
+	}
+	ver := gr.Version()
+	if ver < 1007 && bin != "" {
+		if err := symbolize(res.Events, bin); err != nil {
+			return 0, ParseResult{}, err
+		}
+	}
+	return ver, res, nil
+}
+
+// A GenerationReader parses a trace one generation at a time.
+//
+// A trace may be made of several generations, each of which is a complete
+// trace starting with its own header. The runtime starts a new generation
+// periodically while tracing, so that readers can process a long trace
+// without holding it all in memory, and so that a flight recorder (see
+// runtime/trace.FlightRecorder) can keep only the most recent ones.
+//
+// The events of successive generations form a single consistent trace:
+// timestamps share the same origin, stack IDs are unique across the trace,
+// and the events that describe the state of goroutines already seen at the
+// start of a generation are dropped.
+type GenerationReader struct {
+	r      *bufio.Reader
+	off    int    // offset of the next generation
+	done   bool   // no generation left
+	origin int64  // raw timestamp of the first event
+	stkMax uint64 // largest stack ID so far
+	pp     *postProcessor
+}
+
+// NewGenerationReader returns a GenerationReader reading the trace from r.
+func NewGenerationReader(r io.Reader) *GenerationReader {
+	return &GenerationReader{r: bufio.NewReader(r)}
+}
+
+// Version returns the version of the trace, such as 1021 for Go 1.21, once
+// the first generation has been read.
+func (r *GenerationReader) Version() int {
+	if r.pp == nil {
+		return 0
+	}
+	return r.pp.ver
+}
+
+// Next parses, post-processes and verifies the next generation of the
+// trace, and returns its events, sorted by time, and stacks.
+// Next returns io.EOF after the last generation.
+func (r *GenerationReader) Next() (ParseResult, error) {
+	if r.done {
+		return ParseResult{}, io.EOF
+	}
+	ver, rawEvents, strings, off, more, err := readTrace(r.r, r.off)
 	if err != nil {
-		return 0, ParseResult{}, err
+		return ParseResult{}, err
 	}
-	events, stacks, err := parseEvents(ver, rawEvents, strings)
+	if r.pp == nil {
+		r.pp = newPostProcessor(ver)
+	} else if ver != r.pp.ver {
+		return ParseResult{}, fmt.Errorf("trace generation at offset 0x%x has version %v, want %v", r.off, ver, r.pp.ver)
+	}
+	r.off, r.done = off, !more
+	events, stacks, err := parseEvents(ver, rawEvents, strings, &r.origin)
 	if err != nil {
-		return 0, ParseResult{}, err
+		return ParseResult{}, err
 	}
+	stacks = r.shiftStacks(events, stacks)
+	events = r.dropKnown(events)
 	events = removeFutile(events)
-	err = postProcessTrace(ver, events)
-	if err != nil {
-		return 0, ParseResult{}, err
+	if err := r.pp.process(events); err != nil {
+		return ParseResult{}, err
 	}
 	// Attach stack traces.
 	for _, ev := range events {
@@ -120,12 +209,57 @@ func parse(r io.Reader, bin string) (int, ParseResult, error) {
 			ev.Stk = stacks[ev.StkID]
 		}
 	}
-	if ver < 1007 && bin != "" {
-		if err := symbolize(events, bin); err != nil {
-			return 0, ParseResult{}, err
+	return ParseResult{Events: events, Stacks: stacks}, nil
+}
+
+// shiftStacks shifts the stack IDs of a generation past those of the
+// previous ones, and returns its stacks keyed by the new IDs.
+func (r *GenerationReader) shiftStacks(events []*Event, stacks map[uint64][]*Frame) map[uint64][]*Frame {
+	base := r.stkMax
+	for id := range stacks {
+		r.stkMax = max(r.stkMax, base+id)
+	}
+	if base == 0 {
+		return stacks
+	}
+	shifted := make(map[uint64][]*Frame, len(stacks))
+	for id, stk := range stacks {
+		shifted[base+id] = stk
+	}
+	for _, ev := range events {
+		if ev.StkID != 0 {
+			ev.StkID += base
+		}
+		if ev.Type == EvGoCreate && ev.Args[1] != 0 {
+			ev.Args[1] += base
+		}
+	}
+	return shifted
+}
+
+// dropKnown drops the events describing the initial state of goroutines
+// that are already known from previous generations.
+func (r *GenerationReader) dropKnown(events []*Event) []*Event {
+	var known map[uint64]bool
+	kept := events[:0]
+	for _, ev := range events {
+		switch ev.Type {
+		case EvGoCreate:
+			if _, ok := r.pp.gs[ev.Args[0]]; ok {
+				if known == nil {
+					known = make(map[uint64]bool)
+				}
+				known[ev.Args[0]] = true
+				continue
+			}
+		case EvGoWaiting, EvGoInSyscall:
+			if known[ev.G] {
+				continue
+			}
 		}
+		kept = append(kept, ev)
 	}
-	return ver, ParseResult{Events: events, Stacks: stacks}, nil
+	return kept
 }
 
 // rawEvent is a helper type used during parsing.
@@ -136,16 +270,20 @@ type rawEvent struct {
 	sargs []string
 }
 
-// readTrace does wire-format parsing and verification.
+// readTrace does wire-format parsing and verification of the trace
+// generation starting at offset off0 of the trace.
 // It does not care about specific event types and argument meaning.
-func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]string, err error) {
+// It returns the offset at which the generation ends, and whether it is
+// followed by another generation.
+func readTrace(r *bufio.Reader, off0 int) (ver int, events []rawEvent, strings map[uint64]string, off int, more bool, err error) {
 	// Read and validate trace header.
 	var buf [16]byte
-	off, err := io.ReadFull(r, buf[:])
+	off, err = io.ReadFull(r, buf[:])
 	if err != nil {
 		err = fmt.Errorf("failed to read header: read %v, err %v", off, err)
 		return
 	}
+	off += off0
 	ver, err = parseHeader(buf[:])
 	if err != nil {
 		return
@@ -163,6 +301,14 @@ func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]stri
 	// Read events.
 	strings = make(map[uint64]string)
 	for {
+		// Stop at the header of the next generation, if any.
+		if hdr, _ := r.Peek(len(buf)); len(hdr) == len(buf) && hdr[0] == 'g' {
+			if _, err := parseHeader(hdr); err == nil {
+				more = true
+				break
+			}
+		}
+
 		// Read event type and number of arguments (1 byte).
 		off0 := off
 		var n int
@@ -314,7 +460,9 @@ func parseHeader(buf []byte) (int, error) {
 
 // Parse events transforms raw events into events.
 // It does analyze and verify per-event-type arguments.
-func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (events []*Event, stacks map[uint64][]*Frame, err error) {
+// Event timestamps are made relative to the raw timestamp *origin, which
+// is set to that of the first event if zero.
+func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string, origin *int64) (events []*Event, stacks map[uint64][]*Frame, err error) {
 	var ticksPerSec, lastSeq, lastTs int64
 	var lastG uint64
 	var lastP int
@@ -511,7 +659,10 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 	}
 
 	// Translate cpu ticks to real time.
-	minTs := events[0].Ts
+	if *origin == 0 {
+		*origin = events[0].Ts
+	}
+	minTs := *origin
 	// Use floating point to avoid integer overflows.
 	freq := 1e9 / float64(ticksPerSec)
 	for _, ev := range events {
@@ -592,34 +743,54 @@ var ErrTimeOrder = fmt.Errorf("time stamps out of order")
 // (for example, a P does not run two Gs at the same time, or a G is indeed
 // blocked before an unblock event).
 func postProcessTrace(ver int, events []*Event) error {
-	const (
-		gDead = iota
-		gRunnable
-		gRunning
-		gWaiting
-	)
-	type gdesc struct {
-		state        int
-		ev           *Event
-		evStart      *Event
-		evCreate     *Event
-		evMarkAssist *Event
-	}
-	type pdesc struct {
-		running bool
-		g       uint64
-		evSTW   *Event
-		evSweep *Event
+	return newPostProcessor(ver).process(events)
+}
+
+type ppGDesc struct {
+	state        gStatus
+	ev           *Event
+	evStart      *Event
+	evCreate     *Event
+	evMarkAssist *Event
+}
+
+type ppPDesc struct {
+	running bool
+	g       uint64
+	evSTW   *Event
+	evSweep *Event
+}
+
+// A postProcessor does the work of postProcessTrace, keeping its state
+// across successive generations of a trace.
+type postProcessor struct {
+	ver           int
+	gs            map[uint64]ppGDesc
+	ps            map[int]ppPDesc
+	tasks         map[uint64]*Event   // task id to task creation events
+	activeRegions map[uint64][]*Event // goroutine id to stack of regions
+	evGC, evSTW   *Event
+}
+
+func newPostProcessor(ver int) *postProcessor {
+	pp := &postProcessor{
+		ver:           ver,
+		gs:            make(map[uint64]ppGDesc),
+		ps:            make(map[int]ppPDesc),
+		tasks:         make(map[uint64]*Event),
+		activeRegions: make(map[uint64][]*Event),
 	}
+	pp.gs[0] = ppGDesc{state: gRunning}
+	return pp
+}
 
-	gs := make(map[uint64]gdesc)
-	ps := make(map[int]pdesc)
-	tasks := make(map[uint64]*Event)           // task id to task creation events
-	activeRegions := make(map[uint64][]*Event) // goroutine id to stack of regions
-	gs[0] = gdesc{state: gRunning}
-	var evGC, evSTW *Event
+// process post-processes events, which follow those of any previous call.
+func (pp *postProcessor) process(events []*Event) error {
+	ver, gs, ps, tasks, activeRegions := pp.ver, pp.gs, pp.ps, pp.tasks, pp.activeRegions
+	evGC, evSTW := pp.evGC, pp.evSTW
+	defer func() { pp.evGC, pp.evSTW = evGC, evSTW }()
 
-	checkRunning := func(p pdesc, g gdesc, ev *Event, allowG0 bool) error {
+	checkRunning := func(p ppPDesc, g ppGDesc, ev *Event, allowG0 bool) error {
 		name := EventDescriptions[ev.Type].Name
 		if g.state != gRunning {
 			return fmt.Errorf("g %v is not running while %v (offset %v, time %v)", ev.G, name, ev.Off, ev.Ts)
@@ -727,7 +898,7 @@ func postProcessTrace(ver int, events []*Event) error {
 			if _, ok := gs[ev.Args[0]]; ok {
 				return fmt.Errorf("g %v already exists (offset %v, time %v)", ev.Args[0], ev.Off, ev.Ts)
 			}
-			gs[ev.Args[0]] = gdesc{state: gRunnable, ev: ev, evCreate: ev}
+			gs[ev.Args[0]] = ppGDesc{state: gRunnable, ev: ev, evCreate: ev}
 		case EvGoStart, EvGoStartLabel:
 			if g.state != gRunnable {
 				return fmt.Errorf("g %v is not runnable before start (offset %v, time %v)", ev.G, ev.Off, ev.Ts)
@@ -1171,4 +1342,5 @@ var stwReasonStringsGo121 = [...]string{
 	"ReadMemStatsSlow (test)",
 	"PageCachePagesLeaked (test)",
 	"ResetDebugLog (test)",
+	"trace advance",
 }
@@ -0,0 +1,88 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// range over functions
+
+package p
+
+type Seq[V any] func(yield func(V) bool)
+type Seq2[K, V any] func(yield func(K, V) bool)
+
+func f0(func() bool)
+func f1(func(int) bool)
+func f2(func(int, string) bool)
+func f3(func(int, string, error) bool)
+func f4(func(int) int)
+func f5(func(int))
+func f6(int)
+func f7(func(int) bool) bool
+func f8(func(int) bool, int)
+
+func _() {
+	for range f0 {
+	}
+	for x /* ERROR "range over f0 (value of type func(func() bool)) permits no iteration variables" */ := range f0 {
+		_ = x
+	}
+
+	for range f1 {
+	}
+	for x := range f1 {
+		var _ int = x
+	}
+	for x, y /* ERROR "range over f1 (value of type func(func(int) bool)) permits only one iteration variable" */ := range f1 {
+		_, _ = x, y
+	}
+
+	for x, y := range f2 {
+		var _ int = x
+		var _ string = y
+	}
+	var (
+		i int
+		s string
+	)
+	for i, s = range f2 {
+	}
+	for s /* ERROR "cannot use s (value of type int) as string value in assignment" */, i /* ERROR "cannot use i (value of type string) as int value in assignment" */ = range f2 {
+	}
+	_, _ = i, s
+
+	for range f3 /* ERROR "yield func has too many parameters" */ {
+	}
+	for range f4 /* ERROR "yield func does not return bool" */ {
+	}
+	for range f5 /* ERROR "yield func does not return bool" */ {
+	}
+	for range f6 /* ERROR "argument is not func" */ {
+	}
+	for range f7 /* ERROR "unexpected results" */ {
+	}
+	for range f8 /* ERROR "wrong argument count" */ {
+	}
+}
+
+func _[T any](seq Seq[T], seq2 Seq2[string, T]) {
+	for x := range seq {
+		var _ T = x
+	}
+	for k, v := range seq2 {
+		var _ string = k
+		var _ T = v
+	}
+	for x := range func(yield func(*T) bool) {} {
+		var _ *T = x
+	}
+}
+
+func _[F Seq[int] | func(func(int) bool)](f F) {
+	for x := range f {
+		var _ int = x
+	}
+}
+
+func _[F Seq[int] | Seq[string]](f F) {
+	for range f /* ERRORx `cannot range over f.*no core type` */ {
+	}
+}
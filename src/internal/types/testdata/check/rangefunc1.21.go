@@ -0,0 +1,16 @@
+// -lang=go1.21
+
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Check that range over functions requires go1.22.
+
+package p
+
+func f(func(int) bool) {}
+
+func _() {
+	for range f /* ERROR "requires go1.22 or later" */ {
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rand_test
+
+import (
+	. "math/rand/v2"
+	"testing"
+)
+
+// chacha8seed and chacha8output are the sample seed and
+// the start of the corresponding output given in
+// the specification at https://c2sp.org/chacha8rand.
+var chacha8seed = [32]byte([]byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ123456"))
+
+var chacha8output = []uint64{
+	0xb773b6063d4616a5, 0x1160af22a66abc3c, 0x8c2599d9418d287c,
+	0x7ee07e037edc5cd6, 0xcfaa9ee02d1c16ad,
+}
+
+func TestChaCha8(t *testing.T) {
+	p := NewChaCha8(chacha8seed)
+	for i, x := range chacha8output {
+		if u := p.Uint64(); u != x {
+			t.Errorf("ChaCha8 #%d = %#x, want %#x", i, u, x)
+		}
+	}
+
+	p.Seed(chacha8seed)
+	for i, x := range chacha8output {
+		if u := p.Uint64(); u != x {
+			t.Errorf("ChaCha8 #%d after Seed = %#x, want %#x", i, u, x)
+		}
+	}
+}
+
+func TestChaCha8Marshal(t *testing.T) {
+	p := NewChaCha8(chacha8seed)
+	var want []uint64
+	for i := 0; i < 1000; i++ {
+		want = append(want, p.Uint64())
+	}
+
+	p.Seed(chacha8seed)
+	for i := range want {
+		data, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("#%d: MarshalBinary: %v", i, err)
+		}
+		q := new(ChaCha8)
+		if err := q.UnmarshalBinary(data); err != nil {
+			t.Fatalf("#%d: UnmarshalBinary: %v", i, err)
+		}
+		if x := q.Uint64(); x != want[i] {
+			t.Fatalf("#%d: after round trip, Uint64() = %#x, want %#x", i, x, want[i])
+		}
+		if x := p.Uint64(); x != want[i] {
+			t.Fatalf("#%d: Uint64() = %#x, want %#x", i, x, want[i])
+		}
+	}
+}
+
+func BenchmarkChaCha8(b *testing.B) {
+	p := NewChaCha8([32]byte{1, 2, 3, 4, 5})
+	var t uint64
+	for n := b.N; n > 0; n-- {
+		t += p.Uint64()
+	}
+	Sink = t
+}
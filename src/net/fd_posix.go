@@ -24,16 +24,19 @@ type netFD struct {
 	net         string
 	laddr       Addr
 	raddr       Addr
+
+	cleanup runtime.Cleanup // closes the socket when fd is no longer referenced
 }
 
 func (fd *netFD) setAddr(laddr, raddr Addr) {
 	fd.laddr = laddr
 	fd.raddr = raddr
-	runtime.SetFinalizer(fd, (*netFD).Close)
+	// Close the socket when the netFD is not live.
+	fd.cleanup = runtime.AddCleanup(fd, func(close func() error) { close() }, fd.pfd.SocketCloser())
 }
 
 func (fd *netFD) Close() error {
-	runtime.SetFinalizer(fd, nil)
+	fd.cleanup.Stop()
 	return fd.pfd.Close()
 }
 
@@ -30,6 +30,8 @@ type netFD struct {
 	laddr       Addr
 	raddr       Addr
 
+	cleanup runtime.Cleanup // closes the socket when fd is no longer referenced
+
 	// The only networking available in WASI preview 1 is the ability to
 	// sock_accept on an pre-opened socket, and then fd_read, fd_write,
 	// fd_close, and sock_shutdown on the resulting connection. We
@@ -105,14 +107,15 @@ func (fd *netFD) accept() (netfd *netFD, err error) {
 func (fd *netFD) setAddr(laddr, raddr Addr) {
 	fd.laddr = laddr
 	fd.raddr = raddr
-	runtime.SetFinalizer(fd, (*netFD).Close)
+	// Close the socket when the netFD is not live.
+	fd.cleanup = runtime.AddCleanup(fd, func(close func() error) { close() }, fd.pfd.SocketCloser())
 }
 
 func (fd *netFD) Close() error {
 	if fd.fakeNetFD != nil {
 		return fd.fakeNetFD.Close()
 	}
-	runtime.SetFinalizer(fd, nil)
+	fd.cleanup.Stop()
 	return fd.pfd.Close()
 }
 
@@ -530,8 +530,12 @@ func TestH12_HandlerWritesTooMuch(t *testing.T) {
 func TestH12_AutoGzip(t *testing.T) {
 	h12Compare{
 		Handler: func(w ResponseWriter, r *Request) {
-			if ae := r.Header.Get("Accept-Encoding"); ae != "gzip" {
-				t.Errorf("%s Accept-Encoding = %q; want gzip", r.Proto, ae)
+			want := "gzip, zstd"
+			if r.ProtoMajor == 2 {
+				want = "gzip"
+			}
+			if ae := r.Header.Get("Accept-Encoding"); ae != want {
+				t.Errorf("%s Accept-Encoding = %q; want %q", r.Proto, ae, want)
 			}
 			w.Header().Set("Content-Encoding", "gzip")
 			gz := gzip.NewWriter(w)
@@ -541,6 +545,16 @@ func TestH12_AutoGzip(t *testing.T) {
 	}.run(t)
 }
 
+// defaultAcceptEncoding returns the Accept-Encoding header that the
+// Transport adds to requests in the given mode. The bundled HTTP/2
+// transport requests gzip only.
+func defaultAcceptEncoding(mode testMode) string {
+	if mode == http2Mode {
+		return "gzip"
+	}
+	return "gzip, zstd"
+}
+
 func TestH12_AutoGzip_Disabled(t *testing.T) {
 	h12Compare{
 		Opts: []any{
@@ -0,0 +1,377 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// HTTP response compression.
+
+package http
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"compress/zstd"
+	"io"
+	"net/http/internal/ascii"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// CompressHandler returns a Handler that compresses the responses of h
+// with the zstd, gzip or deflate content coding, as negotiated with the
+// request's Accept-Encoding header. If the client accepts none of those
+// codings, responses are sent uncompressed.
+//
+// A response is compressed only if h doesn't set a Content-Encoding
+// itself, the response isn't very small, and its Content-Type is not a
+// format that is normally already compressed, such as most image,
+// audio and video formats. If h doesn't set a Content-Type,
+// CompressHandler sets one using DetectContentType on the uncompressed
+// data, as the server would otherwise sniff the compressed data.
+//
+// Responses to HEAD requests and requests with a Range header, and
+// responses with a status of 206 Partial Content, are not compressed,
+// since the ranges refer to the uncompressed content. When it compresses
+// a response, CompressHandler removes any Content-Length and
+// Accept-Ranges headers, and turns a strong ETag into a weak one.
+// It adds Accept-Encoding to the Vary header of every response.
+//
+// To make these decisions, CompressHandler buffers the first few
+// hundred bytes of the response body before writing the header.
+// Calling Flush on the ResponseWriter, directly or using a
+// ResponseController, writes the header and any buffered data,
+// flushing the compressor and then the underlying ResponseWriter.
+func CompressHandler(h Handler) Handler {
+	return &compressHandler{h}
+}
+
+type compressHandler struct {
+	handler Handler
+}
+
+func (h *compressHandler) ServeHTTP(w ResponseWriter, r *Request) {
+	cw := &compressWriter{rw: w, req: r}
+	if r.Method != "HEAD" && len(r.Header["Range"]) == 0 {
+		cw.coding = negotiateCoding(r.Header["Accept-Encoding"])
+	}
+	h.handler.ServeHTTP(cw, r)
+	cw.close()
+}
+
+// minCompressSize is the size of the smallest response body
+// that CompressHandler compresses.
+const minCompressSize = 256
+
+// compressor is implemented by the Writers of the
+// compress/gzip, compress/zlib and compress/zstd packages.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// A contentCoding is a content coding supported by CompressHandler.
+type contentCoding struct {
+	name      string
+	newWriter func(io.Writer) compressor
+	pool      sync.Pool // of compressor
+}
+
+// contentCodings are the content codings supported by CompressHandler,
+// in order of preference.
+//
+// The deflate content coding is the zlib format. RFC 9110 Section 8.4.1.2.
+var contentCodings = [...]*contentCoding{
+	{
+		name:      "zstd",
+		newWriter: func(w io.Writer) compressor { return zstd.NewWriter(w) },
+	},
+	{
+		name:      "gzip",
+		newWriter: func(w io.Writer) compressor { return gzip.NewWriter(w) },
+	},
+	{
+		name:      "deflate",
+		newWriter: func(w io.Writer) compressor { return zlib.NewWriter(w) },
+	},
+}
+
+// getWriter returns a compressor for c that writes to w.
+func (c *contentCoding) getWriter(w io.Writer) compressor {
+	if zw, ok := c.pool.Get().(compressor); ok {
+		zw.Reset(w)
+		return zw
+	}
+	return c.newWriter(w)
+}
+
+// negotiateCoding returns the most preferred content coding that is
+// acceptable according to the Accept-Encoding header values accept,
+// or nil if there is none. RFC 9110 Section 12.5.3.
+func negotiateCoding(accept []string) *contentCoding {
+	var best *contentCoding
+	bestQ := 0.0
+	for _, c := range contentCodings {
+		if q := codingQuality(accept, c.name); q > bestQ {
+			best, bestQ = c, q
+		}
+	}
+	return best
+}
+
+// codingQuality returns the quality value of the content coding name
+// in the Accept-Encoding header values accept.
+// A quality of 0 means that the coding is not acceptable.
+func codingQuality(accept []string, name string) float64 {
+	q, wildcard := -1.0, -1.0
+	for _, v := range accept {
+		for v != "" {
+			var elem string
+			elem, v, _ = strings.Cut(v, ",")
+			coding, params, _ := strings.Cut(elem, ";")
+			coding = textproto.TrimString(coding)
+			eq := 1.0
+			for params != "" {
+				var param string
+				param, params, _ = strings.Cut(params, ";")
+				key, val, _ := strings.Cut(param, "=")
+				if !ascii.EqualFold(textproto.TrimString(key), "q") {
+					continue
+				}
+				f, err := strconv.ParseFloat(textproto.TrimString(val), 64)
+				if err != nil || f < 0 || f > 1 {
+					f = 0
+				}
+				eq = f
+			}
+			switch {
+			case ascii.EqualFold(coding, name),
+				name == "gzip" && ascii.EqualFold(coding, "x-gzip"):
+				if eq > q {
+					q = eq
+				}
+			case coding == "*":
+				wildcard = eq
+			}
+		}
+	}
+	switch {
+	case q >= 0:
+		return q
+	case wildcard >= 0:
+		return wildcard
+	}
+	return 0
+}
+
+// compressibleType reports whether it is worth compressing
+// content with the media type of the Content-Type ct.
+func compressibleType(ct string) bool {
+	mt, _, _ := strings.Cut(ct, ";")
+	mt, _ = ascii.ToLower(textproto.TrimString(mt))
+	if mt == "image/svg+xml" || mt == "image/bmp" || mt == "image/x-icon" {
+		return true
+	}
+	if typ, _, _ := strings.Cut(mt, "/"); typ == "image" || typ == "audio" || typ == "video" {
+		return false
+	}
+	switch mt {
+	case "application/gzip",
+		"application/x-gzip",
+		"application/zip",
+		"application/zstd",
+		"application/x-bzip2",
+		"application/x-xz",
+		"application/x-7z-compressed",
+		"application/x-rar-compressed",
+		"font/woff",
+		"font/woff2":
+		return false
+	}
+	return true
+}
+
+// compressWriter is the ResponseWriter used by CompressHandler.
+// Until it decides whether to compress, it buffers the
+// status code and the start of the body.
+type compressWriter struct {
+	rw     ResponseWriter
+	req    *Request
+	coding *contentCoding // negotiated content coding, or nil
+
+	decided bool       // whether the header has been written
+	status  int        // status code passed to WriteHeader, or 0
+	buf     []byte     // body written before deciding
+	zw      compressor // if compressing the body
+}
+
+var _ rwUnwrapper = (*compressWriter)(nil)
+
+func (cw *compressWriter) Header() Header { return cw.rw.Header() }
+
+func (cw *compressWriter) Unwrap() ResponseWriter { return cw.rw }
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.decided {
+		// Let the underlying ResponseWriter report the superfluous call.
+		cw.rw.WriteHeader(code)
+		return
+	}
+	checkWriteHeaderCode(code)
+	if code >= 100 && code <= 199 && code != StatusSwitchingProtocols {
+		// Informational headers don't commit the response.
+		cw.rw.WriteHeader(code)
+		return
+	}
+	if cw.status != 0 {
+		caller := relevantCaller()
+		logf(cw.req, "http: superfluous response.WriteHeader call from %s (%s:%d)", caller.Function, path.Base(caller.File), caller.Line)
+		return
+	}
+	cw.status = code
+	if cw.coding == nil || !bodyAllowedForStatus(code) {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	n := 0
+	if !cw.decided {
+		if cw.coding != nil {
+			if len(cw.buf)+len(p) < sniffLen {
+				cw.buf = append(cw.buf, p...)
+				return len(p), nil
+			}
+			n = sniffLen - len(cw.buf)
+			cw.buf = append(cw.buf, p[:n]...)
+			p = p[n:]
+		}
+		if err := cw.decide(false); err != nil {
+			return 0, err
+		}
+		if len(p) == 0 {
+			return n, nil
+		}
+	}
+	var m int
+	var err error
+	if cw.zw != nil {
+		m, err = cw.zw.Write(p)
+	} else {
+		m, err = cw.rw.Write(p)
+	}
+	return n + m, err
+}
+
+// FlushError writes any buffered data and flushes the compressor
+// and the underlying ResponseWriter.
+func (cw *compressWriter) FlushError() error {
+	if !cw.decided {
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.zw != nil {
+		if err := cw.zw.Flush(); err != nil {
+			return err
+		}
+	}
+	return NewResponseController(cw.rw).Flush()
+}
+
+func (cw *compressWriter) Flush() {
+	cw.FlushError()
+}
+
+// shouldCompress reports whether to compress the response.
+// If final is true, the handler has returned, so cw.buf holds
+// the whole body.
+func (cw *compressWriter) shouldCompress(final bool) bool {
+	if cw.coding == nil || !bodyAllowedForStatus(cw.status) || cw.status == StatusPartialContent {
+		return false
+	}
+	h := cw.rw.Header()
+	if _, ok := h["Content-Encoding"]; ok {
+		return false
+	}
+	if final && len(cw.buf) < minCompressSize {
+		return false
+	}
+	if cl := h.get("Content-Length"); cl != "" {
+		if n, err := strconv.ParseInt(cl, 10, 64); err == nil && n < minCompressSize {
+			return false
+		}
+	}
+	return compressibleType(h.get("Content-Type"))
+}
+
+// decide decides whether to compress the response and writes the
+// header, followed by any buffered data. If final is true,
+// the handler has returned.
+func (cw *compressWriter) decide(final bool) error {
+	cw.decided = true
+	if cw.status == 0 {
+		cw.status = StatusOK
+	}
+	h := cw.rw.Header()
+	addVaryAcceptEncoding(h)
+	if _, haveType := h["Content-Type"]; !haveType && len(cw.buf) > 0 && cw.coding != nil {
+		// Sniff the uncompressed data. If we don't compress,
+		// this gives the same result as the server would.
+		h.Set("Content-Type", DetectContentType(cw.buf))
+	}
+	if cw.shouldCompress(final) {
+		h.Set("Content-Encoding", cw.coding.name)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.get("Etag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("Etag", "W/"+etag)
+		}
+		cw.zw = cw.coding.getWriter(cw.rw)
+	}
+	cw.rw.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.zw != nil {
+		_, err = cw.zw.Write(buf)
+	} else {
+		_, err = cw.rw.Write(buf)
+	}
+	return err
+}
+
+// close finishes the response after the handler returns.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// Nothing was written. Leave the response to the server,
+			// which may also mean that the handler hijacked it.
+			addVaryAcceptEncoding(cw.rw.Header())
+			return
+		}
+		cw.decide(true)
+	}
+	if cw.zw != nil {
+		if err := cw.zw.Close(); err == nil {
+			cw.coding.pool.Put(cw.zw)
+		}
+		cw.zw = nil
+	}
+}
+
+// addVaryAcceptEncoding adds Accept-Encoding to the Vary header in h,
+// unless it is already present.
+func addVaryAcceptEncoding(h Header) {
+	if !httpguts.HeaderValuesContainsToken(h["Vary"], "Accept-Encoding") {
+		h.Add("Vary", "Accept-Encoding")
+	}
+}
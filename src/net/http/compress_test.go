@@ -0,0 +1,330 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package http_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"compress/zstd"
+	"fmt"
+	"io"
+	. "net/http"
+	"strings"
+	"testing"
+)
+
+// compressTestBody is a response body that is worth compressing.
+var compressTestBody = strings.Repeat("<p>The quick brown fox jumps over the lazy dog.</p>\n", 40)
+
+// decodeBody decodes body, which has the given Content-Encoding.
+func decodeBody(t *testing.T, body io.Reader, encoding string) string {
+	t.Helper()
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "":
+		r = body
+	case "zstd":
+		r = zstd.NewReader(body)
+	case "gzip":
+		r, err = gzip.NewReader(body)
+	case "deflate":
+		r, err = zlib.NewReader(body)
+	default:
+		t.Fatalf("unexpected Content-Encoding %q", encoding)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCompressHandler(t *testing.T) { run(t, testCompressHandler) }
+func testCompressHandler(t *testing.T, mode testMode) {
+	tests := []struct {
+		name    string
+		reqHdr  Header
+		method  string
+		handler func(w ResponseWriter)
+		body    string // default compressTestBody
+		noBody  bool
+
+		wantEncoding string
+		wantType     string
+		wantHdr      Header // additional headers to check
+	}{
+		{
+			name:         "zstd",
+			reqHdr:       Header{"Accept-Encoding": {"gzip, deflate, zstd"}},
+			wantEncoding: "zstd",
+			wantType:     "text/html; charset=utf-8",
+		},
+		{
+			name:         "gzip",
+			reqHdr:       Header{"Accept-Encoding": {"gzip"}},
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "x-gzip",
+			reqHdr:       Header{"Accept-Encoding": {"x-gzip"}},
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "deflate",
+			reqHdr:       Header{"Accept-Encoding": {"Deflate"}},
+			wantEncoding: "deflate",
+		},
+		{
+			name:         "quality",
+			reqHdr:       Header{"Accept-Encoding": {"zstd;q=0.5, deflate", "gzip ; q=0.8"}},
+			wantEncoding: "deflate",
+		},
+		{
+			name:         "wildcard",
+			reqHdr:       Header{"Accept-Encoding": {"zstd;q=0, *;q=0.1"}},
+			wantEncoding: "gzip",
+		},
+		{
+			name:   "not acceptable",
+			reqHdr: Header{"Accept-Encoding": {"zstd;q=0, gzip;q=0, br"}},
+		},
+		{
+			name:   "identity",
+			reqHdr: Header{"Accept-Encoding": {"identity"}},
+		},
+		{
+			name: "no accept-encoding",
+		},
+		{
+			name:   "small",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}},
+			body:   "hello, world\n",
+		},
+		{
+			name:   "range",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}, "Range": {"bytes=0-10"}},
+		},
+		{
+			name:   "head",
+			method: "HEAD",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}},
+		},
+		{
+			name:   "image",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}},
+			handler: func(w ResponseWriter) {
+				w.Header().Set("Content-Type", "image/png")
+			},
+			wantType: "image/png",
+		},
+		{
+			name:   "svg",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}},
+			handler: func(w ResponseWriter) {
+				w.Header().Set("Content-Type", "image/svg+xml")
+			},
+			wantEncoding: "gzip",
+			wantType:     "image/svg+xml",
+		},
+		{
+			name:   "already encoded",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}},
+			handler: func(w ResponseWriter) {
+				w.Header().Set("Content-Encoding", "identity")
+			},
+			wantHdr: Header{"Content-Encoding": {"identity"}},
+		},
+		{
+			name:   "headers",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}},
+			handler: func(w ResponseWriter) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Header().Set("Content-Length", fmt.Sprint(len(compressTestBody)))
+				w.Header().Set("Accept-Ranges", "bytes")
+				w.Header().Set("Etag", `"abc"`)
+				w.Header().Set("Vary", "Origin")
+				w.WriteHeader(StatusCreated)
+			},
+			wantEncoding: "gzip",
+			wantType:     "text/plain",
+			wantHdr: Header{
+				"Accept-Ranges": nil,
+				"Etag":          {`W/"abc"`},
+				"Vary":          {"Origin", "Accept-Encoding"},
+			},
+		},
+		{
+			name:   "short content-length",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}},
+			handler: func(w ResponseWriter) {
+				w.Header().Set("Content-Length", "100")
+				w.WriteHeader(StatusOK)
+			},
+			body: strings.Repeat("a", 100),
+		},
+		{
+			name:   "not modified",
+			reqHdr: Header{"Accept-Encoding": {"gzip"}},
+			handler: func(w ResponseWriter) {
+				w.WriteHeader(StatusNotModified)
+			},
+			noBody: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := compressTestBody
+			switch {
+			case tt.noBody:
+				body = ""
+			case tt.body != "":
+				body = tt.body
+			}
+			cst := newClientServerTest(t, mode, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+				if tt.handler != nil {
+					tt.handler(w)
+				}
+				io.WriteString(w, body)
+			})), func(tr *Transport) {
+				tr.DisableCompression = true
+			})
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req, _ := NewRequest(method, cst.ts.URL, nil)
+			for k, v := range tt.reqHdr {
+				req.Header[k] = v
+			}
+			res, err := cst.c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			encoding := res.Header.Get("Content-Encoding")
+			if encoding == "identity" {
+				encoding = ""
+			}
+			if encoding != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+			if tt.wantType != "" {
+				if got := res.Header.Get("Content-Type"); got != tt.wantType {
+					t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+				}
+			}
+			if tt.wantEncoding != "" && res.ContentLength == int64(len(body)) {
+				t.Errorf("ContentLength = %d, the uncompressed length", res.ContentLength)
+			}
+			wantHdr := Header{"Vary": {"Accept-Encoding"}}
+			for k, v := range tt.wantHdr {
+				wantHdr[k] = v
+			}
+			for k, v := range wantHdr {
+				if got := res.Header.Values(k); strings.Join(got, ",") != strings.Join(v, ",") {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+			if method == "HEAD" {
+				return
+			}
+			if got := decodeBody(t, res.Body, encoding); got != body {
+				t.Errorf("body = %q, want %q", got, body)
+			}
+		})
+	}
+}
+
+// Test that the Transport transparently decodes
+// the responses of CompressHandler.
+func TestCompressHandlerTransport(t *testing.T) { run(t, testCompressHandlerTransport) }
+func testCompressHandlerTransport(t *testing.T, mode testMode) {
+	cst := newClientServerTest(t, mode, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+		io.WriteString(w, compressTestBody)
+	})))
+	res, err := cst.c.Get(cst.ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if !res.Uncompressed {
+		t.Errorf("Uncompressed = false, want true")
+	}
+	if got := res.Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding = %q, want none", got)
+	}
+	if got := decodeBody(t, res.Body, ""); got != compressTestBody {
+		t.Errorf("body = %q, want %q", got, compressTestBody)
+	}
+}
+
+func TestCompressHandlerFlush(t *testing.T) { run(t, testCompressHandlerFlush) }
+func testCompressHandlerFlush(t *testing.T, mode testMode) {
+	for _, encoding := range []string{"zstd", "gzip", "deflate"} {
+		t.Run(encoding, func(t *testing.T) {
+			next := make(chan bool)
+			cst := newClientServerTest(t, mode, CompressHandler(HandlerFunc(func(w ResponseWriter, r *Request) {
+				rc := NewResponseController(w)
+				for i := 0; i < 3; i++ {
+					fmt.Fprintf(w, "line %d\n", i)
+					if err := rc.Flush(); err != nil {
+						t.Errorf("Flush: %v", err)
+						return
+					}
+					if !<-next {
+						return
+					}
+				}
+			})), func(tr *Transport) {
+				tr.DisableCompression = true
+			})
+			defer close(next)
+
+			req, _ := NewRequest("GET", cst.ts.URL, nil)
+			req.Header.Set("Accept-Encoding", encoding)
+			res, err := cst.c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if got := res.Header.Get("Content-Encoding"); got != encoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+			}
+
+			var zr io.Reader
+			switch encoding {
+			case "zstd":
+				zr = zstd.NewReader(res.Body)
+			case "gzip":
+				zr, err = gzip.NewReader(res.Body)
+			case "deflate":
+				zr, err = zlib.NewReader(res.Body)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			br := bufio.NewReader(zr)
+			for i := 0; i < 3; i++ {
+				line, err := br.ReadString('\n')
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := fmt.Sprintf("line %d\n", i); line != want {
+					t.Fatalf("got %q, want %q", line, want)
+				}
+				next <- true
+			}
+			if rest, err := io.ReadAll(br); err != nil || len(rest) > 0 {
+				t.Errorf("at end got %q, %v; want no data, nil", rest, err)
+			}
+		})
+	}
+}
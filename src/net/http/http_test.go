@@ -100,7 +100,9 @@ func TestOmitHTTP2(t *testing.T) {
 func TestOmitHTTP2Vet(t *testing.T) {
 	t.Parallel()
 	goTool := testenv.GoToolPath(t)
-	out, err := testenv.Command(t, goTool, "vet", "-tags=nethttpomithttp2", "net/http").CombinedOutput()
+	// Passing -tags makes the vet flags explicit, so turn off
+	// -randv2 as cmd/go does for GOROOT packages by default.
+	out, err := testenv.Command(t, goTool, "vet", "-tags=nethttpomithttp2", "-randv2=false", "net/http").CombinedOutput()
 	if err != nil {
 		t.Fatalf("go vet failed: %v, %s", err, out)
 	}
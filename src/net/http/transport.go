@@ -12,6 +12,7 @@ package http
 import (
 	"bufio"
 	"compress/gzip"
+	"compress/zstd"
 	"container/list"
 	"context"
 	"crypto/tls"
@@ -77,6 +78,10 @@ const DefaultMaxIdleConnsPerHost = 2
 // To explicitly enable HTTP/2 on a transport, use golang.org/x/net/http2
 // and call ConfigureTransport. See the package docs for more about HTTP/2.
 //
+// Unless DisableCompression is set, Transport asks for compressed
+// responses and decodes them. Over HTTP/1.1, it accepts gzip and zstd.
+// Over HTTP/2, it accepts gzip only.
+//
 // Responses with status codes in the 1xx range are either handled
 // automatically (100 expect-continue) or ignored. The one
 // exception is HTTP status code 101 (Switching Protocols), which is
@@ -185,13 +190,16 @@ type Transport struct {
 	DisableKeepAlives bool
 
 	// DisableCompression, if true, prevents the Transport from
-	// requesting compression with an "Accept-Encoding: gzip"
+	// requesting compression with an "Accept-Encoding: gzip, zstd"
 	// request header when the Request contains no existing
-	// Accept-Encoding value. If the Transport requests gzip on
-	// its own and gets a gzipped response, it's transparently
-	// decoded in the Response.Body. However, if the user
-	// explicitly requested gzip it is not automatically
+	// Accept-Encoding value. If the Transport requests compression
+	// on its own and gets a gzip or zstd compressed response, it's
+	// transparently decoded in the Response.Body. However, if the
+	// user explicitly requested compression it is not automatically
 	// uncompressed.
+	//
+	// zstd is only requested and decoded over HTTP/1.1. HTTP/2
+	// requests send "Accept-Encoding: gzip" and decode gzip only.
 	DisableCompression bool
 
 	// MaxIdleConns controls the maximum number of idle (keep-alive)
@@ -2231,8 +2239,8 @@ func (pc *persistConn) readLoop() {
 		}
 
 		resp.Body = body
-		if rc.addedGzip && ascii.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
-			resp.Body = &gzipReader{body: body}
+		if newReader := rc.decompressor(resp.Header.Get("Content-Encoding")); newReader != nil {
+			resp.Body = &decompressReader{body: body, newReader: newReader}
 			resp.Header.Del("Content-Encoding")
 			resp.Header.Del("Content-Length")
 			resp.ContentLength = -1
@@ -2519,9 +2527,9 @@ type requestAndChan struct {
 	ch        chan responseAndError // unbuffered; always send in select on callerGone
 
 	// whether the Transport (as opposed to the user client code)
-	// added the Accept-Encoding gzip header. If the Transport
-	// set it, only then do we transparently decode the gzip.
-	addedGzip bool
+	// added the Accept-Encoding header. If the Transport
+	// set it, only then do we transparently decode the response.
+	addedAcceptEncoding bool
 
 	// Optional blocking chan for Expect: 100-continue (for send).
 	// If the request has an "Expect: 100-continue" header and
@@ -2593,15 +2601,15 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 
 	// Ask for a compressed version if the caller didn't set their
 	// own value for Accept-Encoding. We only attempt to
-	// uncompress the gzip stream if we were the layer that
+	// uncompress the stream if we were the layer that
 	// requested it.
-	requestedGzip := false
+	requestedCompression := false
 	if !pc.t.DisableCompression &&
 		req.Header.Get("Accept-Encoding") == "" &&
 		req.Header.Get("Range") == "" &&
 		req.Method != "HEAD" {
-		// Request gzip only, not deflate. Deflate is ambiguous and
-		// not as universally supported anyway.
+		// Request gzip and zstd only, not deflate. Deflate is
+		// ambiguous and not as universally supported anyway.
 		// See: https://zlib.net/zlib_faq.html#faq39
 		//
 		// Note that we don't request this for HEAD requests,
@@ -2609,11 +2617,11 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 		//   https://trac.nginx.org/nginx/ticket/358
 		//   https://golang.org/issue/5522
 		//
-		// We don't request gzip if the request is for a range, since
-		// auto-decoding a portion of a gzipped document will just fail
-		// anyway. See https://golang.org/issue/8923
-		requestedGzip = true
-		req.extraHeaders().Set("Accept-Encoding", "gzip")
+		// We don't request compression if the request is for a range,
+		// since auto-decoding a portion of a compressed document will
+		// just fail anyway. See https://golang.org/issue/8923
+		requestedCompression = true
+		req.extraHeaders().Set("Accept-Encoding", "gzip, zstd")
 	}
 
 	var continueCh chan struct{}
@@ -2650,9 +2658,10 @@ func (pc *persistConn) roundTrip(req *transportRequest) (resp *Response, err err
 		req:        req.Request,
 		cancelKey:  req.cancelKey,
 		ch:         resc,
-		addedGzip:  requestedGzip,
 		continueCh: continueCh,
 		callerGone: gone,
+
+		addedAcceptEncoding: requestedCompression,
 	}
 
 	var respHeaderTimer <-chan time.Time
@@ -2856,39 +2865,68 @@ func (es *bodyEOFSignal) condfn(err error) error {
 	return err
 }
 
-// gzipReader wraps a response body so it can lazily
-// call gzip.NewReader on the first call to Read
-type gzipReader struct {
-	_    incomparable
-	body *bodyEOFSignal // underlying HTTP/1 response body framing
-	zr   *gzip.Reader   // lazily-initialized gzip reader
-	zerr error          // any error from gzip.NewReader; sticky
+// decompressor returns a function to create a reader that decodes
+// a response body with the given Content-Encoding, or nil if the
+// body should be returned as is.
+func (rc *requestAndChan) decompressor(contentEncoding string) func(io.Reader) (io.Reader, error) {
+	if !rc.addedAcceptEncoding {
+		return nil
+	}
+	switch {
+	case ascii.EqualFold(contentEncoding, "gzip"):
+		return newGzipReader
+	case ascii.EqualFold(contentEncoding, "zstd"):
+		return newZstdReader
+	}
+	return nil
+}
+
+func newGzipReader(r io.Reader) (io.Reader, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return zr, nil
+}
+
+func newZstdReader(r io.Reader) (io.Reader, error) {
+	return zstd.NewReader(r), nil
+}
+
+// decompressReader wraps a response body so it can lazily
+// create a decompressing reader on the first call to Read
+type decompressReader struct {
+	_         incomparable
+	body      *bodyEOFSignal // underlying HTTP/1 response body framing
+	newReader func(io.Reader) (io.Reader, error)
+	zr        io.Reader // lazily-initialized decompressing reader
+	zerr      error     // any error from newReader; sticky
 }
 
-func (gz *gzipReader) Read(p []byte) (n int, err error) {
-	if gz.zr == nil {
-		if gz.zerr == nil {
-			gz.zr, gz.zerr = gzip.NewReader(gz.body)
+func (dr *decompressReader) Read(p []byte) (n int, err error) {
+	if dr.zr == nil {
+		if dr.zerr == nil {
+			dr.zr, dr.zerr = dr.newReader(dr.body)
 		}
-		if gz.zerr != nil {
-			return 0, gz.zerr
+		if dr.zerr != nil {
+			return 0, dr.zerr
 		}
 	}
 
-	gz.body.mu.Lock()
-	if gz.body.closed {
+	dr.body.mu.Lock()
+	if dr.body.closed {
 		err = errReadOnClosedResBody
 	}
-	gz.body.mu.Unlock()
+	dr.body.mu.Unlock()
 
 	if err != nil {
 		return 0, err
 	}
-	return gz.zr.Read(p)
+	return dr.zr.Read(p)
 }
 
-func (gz *gzipReader) Close() error {
-	return gz.body.Close()
+func (dr *decompressReader) Close() error {
+	return dr.body.Close()
 }
 
 type tlsHandshakeTimeoutError struct{}
@@ -524,6 +524,46 @@ func TestCloseUnblocksRead(t *testing.T) {
 	withTCPConnPair(t, client, server)
 }
 
+// Verify that the socket of a connection that is no longer referenced is
+// closed by its cleanup.
+func TestConnCleanup(t *testing.T) {
+	switch runtime.GOOS {
+	case "plan9":
+		t.Skipf("not supported on %s", runtime.GOOS)
+	}
+
+	ln := newLocalListener(t, "tcp")
+	defer ln.Close()
+
+	// Drop the client end of the connection right away.
+	if _, err := Dial("tcp", ln.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	ss, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ss.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ss.Read([]byte{0})
+		done <- err
+	}()
+	for i := 0; i < 1000; i++ {
+		runtime.GC()
+		select {
+		case err := <-done:
+			if err != io.EOF {
+				t.Errorf("Read = %v, want EOF", err)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("socket of an unreferenced connection was not closed")
+}
+
 // Issue 24808: verify that ECONNRESET is not temporary for read.
 func TestNotTemporaryRead(t *testing.T) {
 	t.Parallel()
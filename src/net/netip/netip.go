@@ -17,8 +17,8 @@ import (
 	"strconv"
 
 	"internal/bytealg"
-	"internal/intern"
 	"internal/itoa"
+	"unique"
 )
 
 // Sizes: (64-bit)
@@ -54,20 +54,26 @@ type Addr struct {
 
 	// z is a combination of the address family and the IPv6 zone.
 	//
-	// nil means invalid IP address (for a zero Addr).
+	// The zero value means invalid IP address (for a zero Addr).
 	// z4 means an IPv4 address.
 	// z6noz means an IPv6 address without a zone.
 	//
-	// Otherwise it's the interned zone name string.
-	z *intern.Value
+	// Otherwise it's the canonicalized zone name string.
+	z unique.Handle[addrDetail]
+}
+
+// addrDetail represents the details of an Addr, like address family and IPv6 zone.
+type addrDetail struct {
+	isV6   bool   // IPv4 is false, IPv6 is true.
+	zoneV6 string // != "" only if IsV6 is true.
 }
 
 // z0, z4, and z6noz are sentinel Addr.z values.
 // See the Addr type's field docs.
 var (
-	z0    = (*intern.Value)(nil)
-	z4    = new(intern.Value)
-	z6noz = new(intern.Value)
+	z0    unique.Handle[addrDetail]
+	z4    = unique.Make(addrDetail{})
+	z6noz = unique.Make(addrDetail{isV6: true})
 )
 
 // IPv6LinkLocalAllNodes returns the IPv6 link-local all nodes multicast
@@ -397,11 +403,10 @@ func (ip Addr) BitLen() int {
 
 // Zone returns ip's IPv6 scoped addressing zone, if any.
 func (ip Addr) Zone() string {
-	if ip.z == nil {
+	if ip.z == z0 {
 		return ""
 	}
-	zone, _ := ip.z.Get().(string)
-	return zone
+	return ip.z.Value().zoneV6
 }
 
 // Compare returns an integer comparing two IPs.
@@ -486,7 +491,7 @@ func (ip Addr) WithZone(zone string) Addr {
 		ip.z = z6noz
 		return ip
 	}
-	ip.z = intern.GetByString(zone)
+	ip.z = unique.Make(addrDetail{isV6: true, zoneV6: zone})
 	return ip
 }
 
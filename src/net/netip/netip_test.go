@@ -9,7 +9,6 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"internal/intern"
 	"internal/testenv"
 	"net"
 	. "net/netip"
@@ -17,6 +16,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"unique"
 )
 
 var long = flag.Bool("long", false, "run long tests")
@@ -107,18 +107,18 @@ func TestParseAddr(t *testing.T) {
 		// IPv6 with a zone specifier.
 		{
 			in: "fd7a:115c:a1e0:ab12:4843:cd96:626b:430b%eth0",
-			ip: MkAddr(Mk128(0xfd7a115ca1e0ab12, 0x4843cd96626b430b), intern.Get("eth0")),
+			ip: MkAddr(Mk128(0xfd7a115ca1e0ab12, 0x4843cd96626b430b), unique.Make(MakeAddrDetail(true, "eth0"))),
 		},
 		// IPv6 with dotted decimal and zone specifier.
 		{
 			in:  "1:2::ffff:192.168.140.255%eth1",
-			ip:  MkAddr(Mk128(0x0001000200000000, 0x0000ffffc0a88cff), intern.Get("eth1")),
+			ip:  MkAddr(Mk128(0x0001000200000000, 0x0000ffffc0a88cff), unique.Make(MakeAddrDetail(true, "eth1"))),
 			str: "1:2::ffff:c0a8:8cff%eth1",
 		},
 		// 4-in-6 with zone
 		{
 			in:  "::ffff:192.168.140.255%eth1",
-			ip:  MkAddr(Mk128(0, 0x0000ffffc0a88cff), intern.Get("eth1")),
+			ip:  MkAddr(Mk128(0, 0x0000ffffc0a88cff), unique.Make(MakeAddrDetail(true, "eth1"))),
 			str: "::ffff:192.168.140.255%eth1",
 		},
 		// IPv6 with capital letters.
@@ -1548,7 +1548,7 @@ var parseBenchInputs = []struct {
 }
 
 func BenchmarkParseAddr(b *testing.B) {
-	sinkInternValue = intern.Get("eth1") // Pin to not benchmark the intern package
+	sinkInternValue = unique.Make(MakeAddrDetail(true, "eth1")) // Pin to not benchmark the unique package
 	for _, test := range parseBenchInputs {
 		b.Run(test.name, func(b *testing.B) {
 			b.ReportAllocs()
@@ -1829,7 +1829,7 @@ var (
 	sinkAddrPort    AddrPort
 	sinkPrefix      Prefix
 	sinkPrefixSlice []Prefix
-	sinkInternValue *intern.Value
+	sinkInternValue unique.Handle[AddrDetail]
 	sinkIP16        [16]byte
 	sinkIP4         [4]byte
 	sinkBool        bool
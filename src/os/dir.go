@@ -5,6 +5,8 @@
 package os
 
 import (
+	"internal/safefilepath"
+	"io"
 	"io/fs"
 	"sort"
 )
@@ -123,3 +125,77 @@ func ReadDir(name string) ([]DirEntry, error) {
 	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() < dirs[j].Name() })
 	return dirs, err
 }
+
+// CopyFS copies the file system fsys into the directory dir,
+// creating dir if necessary.
+//
+// Files are created with mode 0o666 plus any execute permissions
+// from the source, and directories are created with mode 0o777
+// (before umask).
+//
+// CopyFS will not overwrite existing files. If a file name in fsys
+// already exists in the destination, CopyFS will return an error
+// such that errors.Is(err, fs.ErrExist) will be true.
+// Existing directories are reused.
+//
+// Files are written through a [Root] for dir, so CopyFS never
+// writes outside of dir, even if dir contains symbolic links
+// that refer to locations outside of it.
+//
+// Symbolic links in fsys are not supported. A *PathError with Err set
+// to ErrInvalid is returned when copying from a symbolic link.
+//
+// Copying stops at and returns the first error encountered.
+func CopyFS(dir string, fsys fs.FS) error {
+	if err := MkdirAll(dir, 0o777); err != nil {
+		return err
+	}
+	root, err := OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "." {
+			return nil
+		}
+		name, err := safefilepath.FromFS(path)
+		if err != nil {
+			return &PathError{Op: "CopyFS", Path: path, Err: ErrInvalid}
+		}
+		if d.IsDir() {
+			err := root.Mkdir(name, 0o777)
+			if err != nil && IsExist(err) {
+				if fi, statErr := root.Stat(name); statErr == nil && fi.IsDir() {
+					return nil
+				}
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return &PathError{Op: "CopyFS", Path: path, Err: ErrInvalid}
+		}
+
+		r, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		info, err := r.Stat()
+		if err != nil {
+			return err
+		}
+		w, err := root.OpenFile(name, O_CREATE|O_EXCL|O_WRONLY, 0o666|info.Mode()&0o111)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, r); err != nil {
+			w.Close()
+			return &PathError{Op: "Copy", Path: w.Name(), Err: err}
+		}
+		return w.Close()
+	})
+}
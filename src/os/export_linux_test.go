@@ -8,4 +8,5 @@ var (
 	PollCopyFileRangeP = &pollCopyFileRange
 	PollSpliceFile     = &pollSplice
 	GetPollFDForTest   = getPollFD
+	Openat2Disabled    = &openat2Disabled
 )
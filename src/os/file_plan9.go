@@ -19,21 +19,22 @@ func fixLongPath(path string) string {
 
 // file is the real representation of *File.
 // The extra level of indirection ensures that no clients of os
-// can overwrite this data, which could cause the finalizer
+// can overwrite this data, which could cause the cleanup
 // to close the wrong file descriptor.
 type file struct {
 	fdmu       poll.FDMutex
 	fd         int
 	name       string
-	dirinfo    *dirInfo // nil unless directory being read
-	appendMode bool     // whether file is opened for appending
+	dirinfo    *dirInfo        // nil unless directory being read
+	appendMode bool            // whether file is opened for appending
+	cleanup    runtime.Cleanup // cleanup closes the file when no longer referenced
 }
 
 // Fd returns the integer Plan 9 file descriptor referencing the open file.
 // If f is closed, the file descriptor becomes invalid.
-// If f is garbage collected, a finalizer may close the file descriptor,
-// making it invalid; see runtime.SetFinalizer for more information on when
-// a finalizer might be run. On Unix systems this will cause the SetDeadline
+// If f is garbage collected, a cleanup may close the file descriptor,
+// making it invalid; see runtime.AddCleanup for more information on when
+// a cleanup might be run. On Unix systems this will cause the SetDeadline
 // methods to stop working.
 //
 // As an alternative, see the f.SyscallConn method.
@@ -53,7 +54,8 @@ func NewFile(fd uintptr, name string) *File {
 		return nil
 	}
 	f := &File{&file{fd: fdi, name: name}}
-	runtime.SetFinalizer(f.file, (*file).close)
+	// Close the file when the File is not live.
+	f.cleanup = runtime.AddCleanup(f, func(file *file) { file.close() }, f.file)
 	return f
 }
 
@@ -159,8 +161,8 @@ func (file *file) close() error {
 
 	err := file.decref()
 
-	// no need for a finalizer anymore
-	runtime.SetFinalizer(file, nil)
+	// There is no need for a cleanup anymore.
+	file.cleanup.Stop()
 	return err
 }
 
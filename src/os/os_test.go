@@ -3317,3 +3317,78 @@ func TestPipeCloseRace(t *testing.T) {
 		t.Errorf("got nils %d errs %d, want 2 2", nils, errs)
 	}
 }
+
+func TestCopyFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"a":       {Data: []byte("a"), Mode: 0o644},
+		"dir/b":   {Data: []byte("b"), Mode: 0o755},
+		"dir/c/d": {Data: []byte("d"), Mode: 0o600},
+		"empty":   {Mode: fs.ModeDir | 0o755},
+	}
+	dir := filepath.Join(t.TempDir(), "dst")
+	if err := CopyFS(dir, fsys); err != nil {
+		t.Fatal("CopyFS:", err)
+	}
+	if err := fstest.TestFS(DirFS(dir), "a", "dir/b", "dir/c/d", "empty"); err != nil {
+		t.Fatal(err)
+	}
+	for name, f := range fsys {
+		if f.Mode.IsDir() {
+			continue
+		}
+		data, err := ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(f.Data) {
+			t.Errorf("%s: got %q, want %q", name, data, f.Data)
+		}
+	}
+	if runtime.GOOS != "windows" && runtime.GOOS != "plan9" {
+		fi, err := Stat(filepath.Join(dir, "dir", "b"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode()&0o100 == 0 {
+			t.Errorf("dir/b: mode %v, want execute permission", fi.Mode())
+		}
+	}
+
+	// CopyFS must not overwrite existing files.
+	if err := CopyFS(dir, fsys); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("second CopyFS = %v, want an error wrapping fs.ErrExist", err)
+	}
+
+	// Symbolic links in fsys are not supported.
+	symlinkFS := fstest.MapFS{"link": {Data: []byte("a"), Mode: fs.ModeSymlink | 0o777}}
+	if err := CopyFS(t.TempDir(), symlinkFS); !errors.Is(err, ErrInvalid) {
+		t.Errorf("CopyFS with symbolic link = %v, want an error wrapping ErrInvalid", err)
+	}
+}
+
+func TestCopyFSEscape(t *testing.T) {
+	testenv.MustHaveSymlink(t)
+	t.Parallel()
+
+	tmp := t.TempDir()
+	outside := filepath.Join(tmp, "outside")
+	dir := filepath.Join(tmp, "dst")
+	if err := Mkdir(outside, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := Mkdir(dir, 0o777); err != nil {
+		t.Fatal(err)
+	}
+	if err := Symlink(outside, filepath.Join(dir, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"sub/x": {Data: []byte("x"), Mode: 0o644}}
+	if err := CopyFS(dir, fsys); err == nil {
+		t.Errorf("CopyFS through a symbolic link out of dir succeeded, want error")
+	}
+	if _, err := Stat(filepath.Join(outside, "x")); err == nil {
+		t.Errorf("CopyFS wrote a file outside of dir")
+	}
+}
@@ -404,6 +404,35 @@ func testPipeEOF(t *testing.T, r io.ReadCloser, w io.WriteCloser) {
 	}
 }
 
+// TestPipeCleanup tests that the cleanup of a File that is no longer
+// referenced closes its descriptor.
+func TestPipeCleanup(t *testing.T) {
+	// Drop the write end of the pipe right away.
+	r, _, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		done <- err
+	}()
+	for i := 0; i < 1000; i++ {
+		runtime.GC()
+		select {
+		case err := <-done:
+			if err != io.EOF {
+				t.Errorf("Read = %v, want io.EOF", err)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("descriptor of an unreferenced File was not closed")
+}
+
 // Issue 24481.
 func TestFdRace(t *testing.T) {
 	// This test starts 100 simultaneous goroutines, which could bury a more
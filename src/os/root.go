@@ -0,0 +1,285 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os
+
+import (
+	"errors"
+	"internal/safefilepath"
+	"internal/testlog"
+	"io/fs"
+	"sort"
+)
+
+// Root may be used to only access files within a single directory tree.
+//
+// Methods on Root can only access files and directories beneath a root directory.
+// If any component of a file name passed to a method of Root references a location
+// outside the root, the method returns an error.
+// File names may reference the directory itself (.).
+//
+// Methods on Root will follow symbolic links, but symbolic links may not
+// reference a location outside the root.
+// Symbolic links must not be absolute.
+//
+// Methods on Root do not prohibit traversal of filesystem boundaries,
+// Linux bind mounts, /proc special files, or access to Unix device files.
+//
+// Methods on Root are safe to be used from multiple goroutines simultaneously.
+//
+// On Unix systems, creating a Root opens a file descriptor referencing
+// the directory, and files are resolved relative to that descriptor
+// one path component at a time, without following symbolic links
+// that lead outside the root. If the directory is moved, methods on
+// Root reference the original directory in its new location.
+// On Linux, Root uses the openat2 system call with RESOLVE_BENEATH
+// when it is available.
+//
+// On other systems, Root records the name of the directory,
+// and methods check that a path stays within the root before using it.
+// On those systems, a concurrent rename of a directory within the root
+// may permit an operation to escape from the root.
+type Root struct {
+	root *root
+}
+
+// rootMaxSymlinks is the maximum number of symbolic links followed
+// when resolving a file name in a Root. It matches the Linux limit.
+const rootMaxSymlinks = 40
+
+// errPathEscapes is the error returned for a file name that
+// refers to a location outside of a Root.
+var errPathEscapes = errors.New("path escapes from parent")
+
+// OpenRoot opens the named directory for use as a Root.
+// If there is an error, it will be of type *PathError.
+func OpenRoot(name string) (*Root, error) {
+	testlog.Open(name)
+	return openRootNolog(name)
+}
+
+// Name returns the name of the directory presented to OpenRoot.
+//
+// It is safe to call Name after Close.
+func (r *Root) Name() string {
+	return r.root.name
+}
+
+// Close closes the Root.
+// After Close is called, methods on Root return errors.
+func (r *Root) Close() error {
+	return r.root.Close()
+}
+
+// Open opens the named file in the root for reading.
+// See [Open] for more details.
+func (r *Root) Open(name string) (*File, error) {
+	return r.OpenFile(name, O_RDONLY, 0)
+}
+
+// Create creates or truncates the named file in the root.
+// See [Create] for more details.
+func (r *Root) Create(name string) (*File, error) {
+	return r.OpenFile(name, O_RDWR|O_CREATE|O_TRUNC, 0666)
+}
+
+// OpenFile opens the named file in the root.
+// See [OpenFile] for more details.
+//
+// If perm contains bits other than the nine least-significant bits (0o777),
+// OpenFile returns an error.
+func (r *Root) OpenFile(name string, flag int, perm FileMode) (*File, error) {
+	if perm&0o777 != perm {
+		return nil, &PathError{Op: "openat", Path: name, Err: errors.New("unsupported file mode")}
+	}
+	r.logOpen(name)
+	f, err := rootOpenFileNolog(r, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	f.appendMode = flag&O_APPEND != 0
+	return f, nil
+}
+
+// Mkdir creates a new directory in the root
+// with the specified name and permission bits (before umask).
+// See [Mkdir] for more details.
+//
+// If perm contains bits other than the nine least-significant bits (0o777),
+// Mkdir returns an error.
+func (r *Root) Mkdir(name string, perm FileMode) error {
+	if perm&0o777 != perm {
+		return &PathError{Op: "mkdirat", Path: name, Err: errors.New("unsupported file mode")}
+	}
+	return rootMkdir(r, name, perm)
+}
+
+// Remove removes the named file or (empty) directory in the root.
+// See [Remove] for more details.
+func (r *Root) Remove(name string) error {
+	return rootRemove(r, name)
+}
+
+// Stat returns a FileInfo describing the named file in the root.
+// See [Stat] for more details.
+func (r *Root) Stat(name string) (FileInfo, error) {
+	r.logStat(name)
+	return rootStat(r, name, false)
+}
+
+// Lstat returns a FileInfo describing the named file in the root.
+// If the file is a symbolic link, the returned FileInfo
+// describes the symbolic link.
+// See [Lstat] for more details.
+func (r *Root) Lstat(name string) (FileInfo, error) {
+	r.logStat(name)
+	return rootStat(r, name, true)
+}
+
+func (r *Root) logOpen(name string) {
+	if log := testlog.Logger(); log != nil {
+		// This won't be right if r's name has changed since it was opened,
+		// but it's the best we can do.
+		log.Open(joinPath(r.Name(), name))
+	}
+}
+
+func (r *Root) logStat(name string) {
+	if log := testlog.Logger(); log != nil {
+		// This won't be right if r's name has changed since it was opened,
+		// but it's the best we can do.
+		log.Stat(joinPath(r.Name(), name))
+	}
+}
+
+// splitPathInRoot splits a file name relative to a Root into its
+// components, dropping empty and "." components.
+// It reports an error if the name is absolute or has a volume name.
+//
+// If the name ends in a path separator and follow is true,
+// the result ends with a "." component, so that the last named
+// file must be a directory. The result never ends with "..".
+func splitPathInRoot(name string, follow bool) ([]string, error) {
+	if name == "" {
+		return nil, ErrNotExist
+	}
+	if IsPathSeparator(name[0]) || volumeName(name) != "" {
+		return nil, errPathEscapes
+	}
+	var parts []string
+	for i := 0; i < len(name); {
+		j := i
+		for j < len(name) && !IsPathSeparator(name[j]) {
+			j++
+		}
+		if part := name[i:j]; part != "" && part != "." {
+			parts = append(parts, part)
+		}
+		i = j + 1
+	}
+	switch {
+	case len(parts) == 0,
+		parts[len(parts)-1] == "..",
+		follow && IsPathSeparator(name[len(name)-1]):
+		parts = append(parts, ".")
+	}
+	return parts, nil
+}
+
+// replaceSymlink returns parts with the symbolic link parts[i]
+// replaced by the components of its target, link.
+func replaceSymlink(parts []string, i int, link string) ([]string, error) {
+	linkParts, err := splitPathInRoot(link, true)
+	if err != nil {
+		return nil, err
+	}
+	if linkParts[len(linkParts)-1] == "." && i < len(parts)-1 {
+		linkParts = linkParts[:len(linkParts)-1]
+	}
+	newParts := make([]string, 0, len(parts)-1+len(linkParts))
+	newParts = append(newParts, parts[:i]...)
+	newParts = append(newParts, linkParts...)
+	newParts = append(newParts, parts[i+1:]...)
+	return newParts, nil
+}
+
+// FS returns a file system (an fs.FS) for the tree of files in the root.
+//
+// The result implements [io/fs.StatFS], [io/fs.ReadFileFS] and
+// [io/fs.ReadDirFS].
+func (r *Root) FS() fs.FS {
+	return (*rootFS)(r)
+}
+
+type rootFS Root
+
+func (rfs *rootFS) Open(name string) (fs.File, error) {
+	r := (*Root)(rfs)
+	localName, err := rootFSName(name)
+	if err != nil {
+		return nil, &PathError{Op: "open", Path: name, Err: err}
+	}
+	f, err := r.Open(localName)
+	if err != nil {
+		// The name passed to the fs.FS is slash separated;
+		// report that name rather than the local one.
+		err.(*PathError).Path = name
+		return nil, err
+	}
+	return f, nil
+}
+
+// The ReadFile method reads the named file in the root.
+// Through this method, the result of [Root.FS] implements
+// [io/fs.ReadFileFS].
+func (rfs *rootFS) ReadFile(name string) ([]byte, error) {
+	f, err := rfs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readFileContents(f.(*File))
+}
+
+// ReadDir reads the named directory in the root, returning all its
+// directory entries sorted by filename. Through this method, the
+// result of [Root.FS] implements [io/fs.ReadDirFS].
+func (rfs *rootFS) ReadDir(name string) ([]DirEntry, error) {
+	f, err := rfs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dirs, err := f.(*File).ReadDir(-1)
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].Name() < dirs[j].Name() })
+	return dirs, err
+}
+
+func (rfs *rootFS) Stat(name string) (FileInfo, error) {
+	r := (*Root)(rfs)
+	localName, err := rootFSName(name)
+	if err != nil {
+		return nil, &PathError{Op: "stat", Path: name, Err: err}
+	}
+	fi, err := r.Stat(localName)
+	if err != nil {
+		// See comment in rootFS.Open.
+		err.(*PathError).Path = name
+		return nil, err
+	}
+	return fi, nil
+}
+
+// rootFSName converts a name passed to the result of Root.FS
+// to a local name.
+func rootFSName(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", ErrInvalid
+	}
+	name, err := safefilepath.FromFS(name)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	return name, nil
+}
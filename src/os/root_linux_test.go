@@ -0,0 +1,23 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os_test
+
+import (
+	. "os"
+	"testing"
+)
+
+// TestRootNoOpenat2 runs the Root tests without openat2,
+// resolving names one component at a time.
+func TestRootNoOpenat2(t *testing.T) {
+	defer Openat2Disabled.Store(Openat2Disabled.Load())
+	Openat2Disabled.Store(true)
+	t.Run("Open", TestRootOpen)
+	t.Run("Create", TestRootCreate)
+	t.Run("Mkdir", TestRootMkdir)
+	t.Run("Remove", TestRootRemove)
+	t.Run("Stat", TestRootStat)
+	t.Run("TrailingSlash", TestRootTrailingSlash)
+}
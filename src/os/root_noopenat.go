@@ -0,0 +1,173 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package os
+
+import (
+	"errors"
+	"sync/atomic"
+	"syscall"
+)
+
+type root struct {
+	name   string
+	closed atomic.Bool
+}
+
+func (r *root) Close() error {
+	r.closed.Store(true)
+	return nil
+}
+
+// openRootNolog is OpenRoot without test logging.
+func openRootNolog(name string) (*Root, error) {
+	fi, err := Stat(name)
+	if err != nil {
+		return nil, &PathError{Op: "open", Path: name, Err: underlyingError(err)}
+	}
+	if !fi.IsDir() {
+		return nil, &PathError{Op: "open", Path: name, Err: syscall.ENOTDIR}
+	}
+	return &Root{&root{name: name}}, nil
+}
+
+// errTooManySymlinks is returned when resolving a name
+// follows more than rootMaxSymlinks symbolic links.
+var errTooManySymlinks = errors.New("too many levels of symbolic links")
+
+// rootResolve returns the name of the file name in the root,
+// with all symbolic links resolved except possibly the last,
+// which is resolved only if follow is true.
+//
+// Since the result is only checked at the time rootResolve is called,
+// a concurrent rename or symbolic link creation in the root
+// may cause an operation on the result to escape from the root.
+func rootResolve(r *Root, name string, follow bool) (string, error) {
+	if r.root.closed.Load() {
+		return "", ErrClosed
+	}
+	parts, err := splitPathInRoot(name, follow)
+	if err != nil {
+		return "", err
+	}
+	var resolved []string
+	symlinks := 0
+	for i := 0; i < len(parts); {
+		part := parts[i]
+		if part == ".." {
+			// Every component in resolved is a directory,
+			// so we may remove the last one.
+			if len(resolved) == 0 {
+				return "", errPathEscapes
+			}
+			resolved = resolved[:len(resolved)-1]
+			i++
+			continue
+		}
+		final := i == len(parts)-1
+		if part != "." && (!final || follow) {
+			path := rootJoin(r.root.name, append(resolved, part))
+			fi, err := Lstat(path)
+			switch {
+			case err == nil && fi.Mode()&ModeSymlink != 0:
+				link, err := Readlink(path)
+				if err != nil {
+					return "", underlyingError(err)
+				}
+				symlinks++
+				if symlinks > rootMaxSymlinks {
+					return "", errTooManySymlinks
+				}
+				parts, err = replaceSymlink(parts, i, link)
+				if err != nil {
+					return "", err
+				}
+				continue
+			case final:
+			case err != nil:
+				return "", underlyingError(err)
+			case !fi.IsDir():
+				return "", syscall.ENOTDIR
+			}
+		}
+		resolved = append(resolved, part)
+		i++
+	}
+	return rootJoin(r.root.name, resolved), nil
+}
+
+// rootJoin joins the name of a root directory with the components parts.
+func rootJoin(dir string, parts []string) string {
+	name := dir
+	for _, part := range parts {
+		if len(name) > 0 && !IsPathSeparator(name[len(name)-1]) {
+			name += string(PathSeparator)
+		}
+		name += part
+	}
+	return name
+}
+
+// rootOpenFileNolog is Root.OpenFile without test logging.
+func rootOpenFileNolog(r *Root, name string, flag int, perm FileMode) (*File, error) {
+	follow := flag&(O_CREATE|O_EXCL) != O_CREATE|O_EXCL
+	path, err := rootResolve(r, name, follow)
+	if err != nil {
+		return nil, &PathError{Op: "openat", Path: name, Err: err}
+	}
+	f, err := openFileNolog(path, flag, perm)
+	if err != nil {
+		return nil, &PathError{Op: "openat", Path: name, Err: underlyingError(err)}
+	}
+	f.name = joinPath(r.Name(), name)
+	return f, nil
+}
+
+// rootMkdir implements Root.Mkdir.
+func rootMkdir(r *Root, name string, perm FileMode) error {
+	path, err := rootResolve(r, name, false)
+	if err == nil {
+		err = Mkdir(path, perm)
+	}
+	if err != nil {
+		return &PathError{Op: "mkdirat", Path: name, Err: underlyingError(err)}
+	}
+	return nil
+}
+
+// rootRemove implements Root.Remove.
+func rootRemove(r *Root, name string) error {
+	path, err := rootResolve(r, name, false)
+	if err == nil {
+		err = Remove(path)
+	}
+	if err != nil {
+		return &PathError{Op: "removeat", Path: name, Err: underlyingError(err)}
+	}
+	return nil
+}
+
+// rootStat implements Root.Stat and Root.Lstat.
+func rootStat(r *Root, name string, lstat bool) (FileInfo, error) {
+	op := "statat"
+	if lstat {
+		op = "lstatat"
+	}
+	path, err := rootResolve(r, name, !lstat)
+	if err != nil {
+		return nil, &PathError{Op: op, Path: name, Err: err}
+	}
+	var fi FileInfo
+	if lstat {
+		fi, err = Lstat(path)
+	} else {
+		fi, err = Stat(path)
+	}
+	if err != nil {
+		return nil, &PathError{Op: op, Path: name, Err: underlyingError(err)}
+	}
+	return fi, nil
+}
@@ -0,0 +1,56 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os
+
+import (
+	"internal/syscall/unix"
+	"sync/atomic"
+	"syscall"
+)
+
+// openat2Disabled is set when the openat2 system call
+// is not available or not permitted.
+var openat2Disabled atomic.Bool
+
+// openDirBeneath opens the directory named by the components parts
+// beneath the directory rootfd, using openat2 with RESOLVE_BENEATH.
+// It returns errOpenat2Unavailable if the caller should resolve
+// the name one component at a time instead.
+func openDirBeneath(rootfd int, parts []string) (int, error) {
+	if openat2Disabled.Load() {
+		return -1, errOpenat2Unavailable
+	}
+	name := parts[0]
+	for _, part := range parts[1:] {
+		name += "/" + part
+	}
+	how := &unix.OpenHow{
+		Flags:   syscall.O_RDONLY | syscall.O_DIRECTORY | syscall.O_CLOEXEC,
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	}
+	var fd int
+	err := ignoringEINTR(func() error {
+		var err error
+		fd, err = unix.Openat2(rootfd, name, how)
+		return err
+	})
+	switch err {
+	case nil:
+		return fd, nil
+	case syscall.EXDEV:
+		// The name refers to a location outside of the root.
+		return -1, errPathEscapes
+	case syscall.ENOSYS, syscall.EPERM, syscall.EINVAL:
+		// The kernel is older than Linux 5.6, or a seccomp
+		// filter rejects the call.
+		openat2Disabled.Store(true)
+		return -1, errOpenat2Unavailable
+	case syscall.EAGAIN:
+		// A concurrent rename made the resolution unsafe.
+		// Fall back to resolving one component at a time.
+		return -1, errOpenat2Unavailable
+	}
+	return -1, err
+}
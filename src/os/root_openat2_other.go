@@ -0,0 +1,13 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix && !linux
+
+package os
+
+// openDirBeneath is only implemented on Linux.
+// Other systems resolve names one component at a time.
+func openDirBeneath(rootfd int, parts []string) (int, error) {
+	return -1, errOpenat2Unavailable
+}
@@ -0,0 +1,482 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package os_test
+
+import (
+	"errors"
+	"internal/testenv"
+	"io"
+	"io/fs"
+	. "os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// makeRootTestFS creates files in dir.
+// Each entry is a file name, a directory name ending in "/",
+// or a symbolic link "name => target".
+func makeRootTestFS(t *testing.T, dir string, entries []string) {
+	t.Helper()
+	for _, e := range entries {
+		e = filepath.FromSlash(e)
+		name, target, isLink := strings.Cut(e, " => ")
+		if err := MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case isLink:
+			if err := Symlink(target, filepath.Join(dir, name)); err != nil {
+				t.Fatal(err)
+			}
+		case strings.HasSuffix(e, string(PathSeparator)):
+			if err := MkdirAll(filepath.Join(dir, e), 0o777); err != nil {
+				t.Fatal(err)
+			}
+		default:
+			if err := WriteFile(filepath.Join(dir, e), []byte(e), 0o666); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// rootTest is a test case for a Root operation.
+// The test case creates a directory containing a subdirectory "ROOT"
+// and the files in fs, and performs the operation on "ROOT/"+open.
+type rootTest struct {
+	name string
+
+	// fs is the test filesystem layout. See makeRootTestFS.
+	// "$ABS" is replaced by the absolute path of the test directory.
+	fs []string
+
+	// open is the filename to access in the test.
+	open string
+
+	// target is the filename that we expect to be accessed, after resolving all symlinks.
+	// For test cases where the operation fails due to an escaping path such as ../ROOT/x,
+	// the target is the filename that should not have been opened.
+	target string
+
+	// ltarget is the filename that we expect to accessed, after resolving all symlinks
+	// except the last one. This is the file we expect to be removed by Remove or statted
+	// by Lstat.
+	//
+	// If the last path component in open is not a symlink, ltarget should be "".
+	ltarget string
+
+	// wantError is true if accessing the file should fail.
+	wantError bool
+}
+
+func (test rootTest) needsSymlinks() bool {
+	for _, e := range test.fs {
+		if strings.Contains(e, " => ") {
+			return true
+		}
+	}
+	return false
+}
+
+var rootTestCases = []rootTest{{
+	name:   "plain path",
+	fs:     []string{"ROOT/target"},
+	open:   "target",
+	target: "ROOT/target",
+}, {
+	name:   "path in directory",
+	fs:     []string{"ROOT/a/b/c/target"},
+	open:   "a/b/c/target",
+	target: "ROOT/a/b/c/target",
+}, {
+	name:   "dot in path",
+	fs:     []string{"ROOT/a/target"},
+	open:   "./a/./target",
+	target: "ROOT/a/target",
+}, {
+	name:   "dotdot in path",
+	fs:     []string{"ROOT/a/", "ROOT/target"},
+	open:   "a/../target",
+	target: "ROOT/target",
+}, {
+	name:      "dotdot in path after nonexistent directory",
+	fs:        []string{"ROOT/target"},
+	open:      "a/../target",
+	wantError: true,
+}, {
+	name:      "dotdot escapes root",
+	fs:        []string{"target", "ROOT/"},
+	open:      "../target",
+	target:    "target",
+	wantError: true,
+}, {
+	name:      "dotdot in path escapes root",
+	fs:        []string{"target", "ROOT/a/"},
+	open:      "a/../../target",
+	target:    "target",
+	wantError: true,
+}, {
+	name:      "absolute path",
+	fs:        []string{"target", "ROOT/"},
+	open:      "$ABS/target",
+	target:    "target",
+	wantError: true,
+}, {
+	name:    "symlink",
+	fs:      []string{"ROOT/link => target", "ROOT/target"},
+	open:    "link",
+	target:  "ROOT/target",
+	ltarget: "ROOT/link",
+}, {
+	name:   "symlink to directory",
+	fs:     []string{"ROOT/link => a/b", "ROOT/a/b/target"},
+	open:   "link/target",
+	target: "ROOT/a/b/target",
+}, {
+	name:   "symlink with dotdot",
+	fs:     []string{"ROOT/link => a/../b", "ROOT/a/", "ROOT/b/target"},
+	open:   "link/target",
+	target: "ROOT/b/target",
+}, {
+	name:   "dotdot after symlink",
+	fs:     []string{"ROOT/link => a/b", "ROOT/a/b/", "ROOT/a/target"},
+	open:   "link/../target",
+	target: "ROOT/a/target",
+}, {
+	name:    "symlink chain",
+	fs:      []string{"ROOT/link => a/link2", "ROOT/a/link2 => ../b/target", "ROOT/b/target"},
+	open:    "link",
+	target:  "ROOT/b/target",
+	ltarget: "ROOT/link",
+}, {
+	name:      "symlink escapes root",
+	fs:        []string{"target", "ROOT/link => ../target"},
+	open:      "link",
+	target:    "target",
+	ltarget:   "ROOT/link",
+	wantError: true,
+}, {
+	name:      "symlink to directory escapes root",
+	fs:        []string{"target", "ROOT/link => ..", "ROOT/"},
+	open:      "link/target",
+	target:    "target",
+	wantError: true,
+}, {
+	name:      "absolute symlink",
+	fs:        []string{"target", "ROOT/link => $ABS/target"},
+	open:      "link",
+	target:    "target",
+	ltarget:   "ROOT/link",
+	wantError: true,
+}, {
+	name:      "absolute symlink into root",
+	fs:        []string{"ROOT/link => $ABS/ROOT/target", "ROOT/target"},
+	open:      "link",
+	target:    "ROOT/target",
+	ltarget:   "ROOT/link",
+	wantError: true,
+}, {
+	name:      "symlink loop",
+	fs:        []string{"ROOT/link => link"},
+	open:      "link",
+	ltarget:   "ROOT/link",
+	wantError: true,
+}}
+
+// run sets up the test filesystem and returns the opened Root,
+// the name to access in it, and the names of the target and ltarget files.
+func (test rootTest) run(t *testing.T) (root *Root, open, target, ltarget string) {
+	t.Helper()
+	if test.needsSymlinks() {
+		testenv.MustHaveSymlink(t)
+	}
+	dir := t.TempDir()
+	fs := make([]string, len(test.fs))
+	for i, e := range test.fs {
+		fs[i] = strings.ReplaceAll(e, "$ABS", dir)
+	}
+	makeRootTestFS(t, dir, fs)
+	if test.target != "" {
+		target = filepath.Join(dir, filepath.FromSlash(test.target))
+	}
+	if test.ltarget != "" {
+		ltarget = filepath.Join(dir, filepath.FromSlash(test.ltarget))
+	} else {
+		ltarget = target
+	}
+	root, err := OpenRoot(filepath.Join(dir, "ROOT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { root.Close() })
+	open = filepath.FromSlash(strings.ReplaceAll(test.open, "$ABS", dir))
+	return root, open, target, ltarget
+}
+
+// checkRootError checks the error from a Root operation.
+func checkRootError(t *testing.T, op string, err error, wantError bool) {
+	t.Helper()
+	if wantError {
+		if err == nil {
+			t.Fatalf("%v: succeeded, want error", op)
+		}
+		var pe *PathError
+		if !errors.As(err, &pe) {
+			t.Errorf("%v: error %v is not a *PathError", op, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("%v: %v", op, err)
+	}
+}
+
+func TestRootOpen(t *testing.T) {
+	for _, test := range rootTestCases {
+		t.Run(test.name, func(t *testing.T) {
+			root, open, target, _ := test.run(t)
+			f, err := root.Open(open)
+			if test.wantError {
+				checkRootError(t, "Open", err, true)
+				return
+			}
+			checkRootError(t, "Open", err, false)
+			defer f.Close()
+			got, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ReadFile(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("read %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestRootCreate(t *testing.T) {
+	for _, test := range rootTestCases {
+		t.Run(test.name, func(t *testing.T) {
+			root, open, target, _ := test.run(t)
+			if target != "" {
+				Remove(target)
+			}
+			f, err := root.Create(open)
+			checkRootError(t, "Create", err, test.wantError)
+			if target != "" {
+				_, statErr := Lstat(target)
+				if exists := statErr == nil; exists == test.wantError {
+					t.Errorf("after Create: target exists = %v, want %v", exists, !test.wantError)
+				}
+			}
+			if err == nil {
+				f.Close()
+			}
+		})
+	}
+}
+
+func TestRootMkdir(t *testing.T) {
+	for _, test := range rootTestCases {
+		t.Run(test.name, func(t *testing.T) {
+			root, open, _, ltarget := test.run(t)
+			if ltarget != "" {
+				Remove(ltarget)
+			}
+			err := root.Mkdir(open, 0o777)
+			checkRootError(t, "Mkdir", err, test.wantError && test.ltarget == "")
+			if err != nil || ltarget == "" {
+				return
+			}
+			fi, err := Lstat(ltarget)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fi.IsDir() {
+				t.Errorf("after Mkdir: %v is not a directory", ltarget)
+			}
+		})
+	}
+}
+
+func TestRootRemove(t *testing.T) {
+	for _, test := range rootTestCases {
+		t.Run(test.name, func(t *testing.T) {
+			root, open, target, ltarget := test.run(t)
+			err := root.Remove(open)
+			// Remove does not follow a final symlink,
+			// so removing a symlink that escapes succeeds.
+			wantError := test.wantError && test.ltarget == ""
+			checkRootError(t, "Remove", err, wantError)
+			if ltarget != "" {
+				_, statErr := Lstat(ltarget)
+				if exists := statErr == nil; exists != wantError {
+					t.Errorf("after Remove: %v exists = %v, want %v", ltarget, exists, wantError)
+				}
+			}
+			if target != "" && target != ltarget {
+				if _, err := Lstat(target); err != nil {
+					t.Errorf("after Remove: symlink target %v was removed", target)
+				}
+			}
+		})
+	}
+}
+
+func TestRootStat(t *testing.T) {
+	for _, test := range rootTestCases {
+		t.Run(test.name, func(t *testing.T) {
+			root, open, target, ltarget := test.run(t)
+
+			fi, err := root.Stat(open)
+			checkRootError(t, "Stat", err, test.wantError)
+			if err == nil {
+				want, err := Stat(target)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !SameFile(fi, want) {
+					t.Errorf("Stat(%q) does not describe %v", open, target)
+				}
+			}
+
+			fi, err = root.Lstat(open)
+			checkRootError(t, "Lstat", err, test.wantError && test.ltarget == "")
+			if err == nil {
+				want, err := Lstat(ltarget)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !SameFile(fi, want) {
+					t.Errorf("Lstat(%q) does not describe %v", open, ltarget)
+				}
+			}
+		})
+	}
+}
+
+func TestRootEscapeError(t *testing.T) {
+	dir := t.TempDir()
+	root, err := OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	_, err = root.Open("../x")
+	if !errors.Is(err, ErrPathEscapes) {
+		t.Errorf(`Open("../x") = %v, want an error wrapping %v`, err, ErrPathEscapes)
+	}
+	_, err = root.Open("")
+	if !errors.Is(err, ErrNotExist) {
+		t.Errorf(`Open("") = %v, want ErrNotExist`, err)
+	}
+}
+
+func TestRootTrailingSlash(t *testing.T) {
+	dir := t.TempDir()
+	makeRootTestFS(t, dir, []string{"dir/", "file"})
+	root, err := OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	if fi, err := root.Stat("dir/"); err != nil || !fi.IsDir() {
+		t.Errorf(`Stat("dir/") = %v, %v; want directory`, fi, err)
+	}
+	if _, err := root.Open("file/"); err == nil {
+		t.Errorf(`Open("file/") succeeded, want error`)
+	}
+}
+
+func TestRootOpenFileMode(t *testing.T) {
+	dir := t.TempDir()
+	root, err := OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	if _, err := root.OpenFile("x", O_RDWR|O_CREATE, 0o777|ModeSetuid); err == nil {
+		t.Errorf("OpenFile with ModeSetuid succeeded, want error")
+	}
+	if err := root.Mkdir("d", 0o777|ModeSticky); err == nil {
+		t.Errorf("Mkdir with ModeSticky succeeded, want error")
+	}
+	f, err := root.OpenFile("x", O_WRONLY|O_CREATE|O_EXCL, 0o666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := root.OpenFile("x", O_WRONLY|O_CREATE|O_EXCL, 0o666); !errors.Is(err, ErrExist) {
+		t.Errorf("OpenFile with O_EXCL of existing file = %v, want ErrExist", err)
+	}
+	if want := filepath.Join(dir, "x"); f.Name() != want {
+		t.Errorf("f.Name() = %q, want %q", f.Name(), want)
+	}
+}
+
+func TestRootClose(t *testing.T) {
+	dir := t.TempDir()
+	makeRootTestFS(t, dir, []string{"file"})
+	root, err := OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := root.Name(); got != dir {
+		t.Errorf("root.Name() = %q, want %q", got, dir)
+	}
+	if err := root.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := root.Open("file"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Open after Close = %v, want ErrClosed", err)
+	}
+	if _, err := root.Stat("file"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Stat after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestOpenRootNotDir(t *testing.T) {
+	dir := t.TempDir()
+	makeRootTestFS(t, dir, []string{"file"})
+	_, err := OpenRoot(filepath.Join(dir, "file"))
+	if err == nil {
+		t.Fatalf("OpenRoot of a file succeeded, want error")
+	}
+	if _, err := OpenRoot(filepath.Join(dir, "missing")); !errors.Is(err, ErrNotExist) {
+		t.Errorf("OpenRoot of a missing directory = %v, want ErrNotExist", err)
+	}
+}
+
+func TestRootFS(t *testing.T) {
+	dir := t.TempDir()
+	makeRootTestFS(t, dir, []string{"a/b/c", "a/d", "e"})
+	root, err := OpenRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	fsys := root.FS()
+	if err := fstest.TestFS(fsys, "a/b/c", "a/d", "e"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"../e", "/e", "a/../e", "a\\d"} {
+		if runtime.GOOS != "windows" && name == "a\\d" {
+			continue
+		}
+		if _, err := fsys.Open(name); err == nil {
+			t.Errorf("FS().Open(%q) succeeded, want error", name)
+		}
+	}
+	b, err := fs.ReadFile(fsys, "a/d")
+	if err != nil || string(b) != filepath.FromSlash("a/d") {
+		t.Errorf("ReadFile = %q, %v; want %q", b, err, filepath.FromSlash("a/d"))
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package os
+
+import (
+	"errors"
+	"internal/syscall/unix"
+	"runtime"
+	"sync"
+	"syscall"
+)
+
+type root struct {
+	name string
+
+	mu     sync.Mutex
+	fd     int
+	refs   int  // number of active operations
+	closed bool // set when Close is called
+}
+
+func (r *root) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed && r.refs == 0 {
+		syscall.Close(r.fd)
+	}
+	r.closed = true
+	runtime.SetFinalizer(r, nil) // no need for a finalizer any more
+	return nil
+}
+
+// incref records the start of an operation using r.fd.
+func (r *root) incref() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	r.refs++
+	return nil
+}
+
+// decref records the end of an operation using r.fd.
+func (r *root) decref() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refs <= 0 {
+		panic("bad Root refcount")
+	}
+	r.refs--
+	if r.closed && r.refs == 0 {
+		syscall.Close(r.fd)
+	}
+}
+
+// openRootNolog is OpenRoot without test logging.
+func openRootNolog(name string) (*Root, error) {
+	var fd int
+	err := ignoringEINTR(func() error {
+		var err error
+		fd, _, err = open(name, syscall.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		return err
+	})
+	if err != nil {
+		return nil, &PathError{Op: "open", Path: name, Err: err}
+	}
+	// There's a race here with fork/exec, which we are
+	// content to live with. See ../syscall/exec_unix.go.
+	if !supportsCloseOnExec {
+		syscall.CloseOnExec(fd)
+	}
+	r := &Root{&root{
+		fd:   fd,
+		name: name,
+	}}
+	runtime.SetFinalizer(r.root, (*root).Close)
+	return r, nil
+}
+
+// rootOpenFileNolog is Root.OpenFile without test logging.
+func rootOpenFileNolog(root *Root, name string, flag int, perm FileMode) (*File, error) {
+	follow := flag&(O_CREATE|O_EXCL) != O_CREATE|O_EXCL
+	fd, err := doInRoot(root, name, follow, func(parent int, name string) (fd int, err error) {
+		err = ignoringEINTR(func() error {
+			fd, err = unix.Openat(parent, name, syscall.O_NOFOLLOW|syscall.O_CLOEXEC|flag, uint32(syscallMode(perm)))
+			if err != nil && follow {
+				// The file may be a symlink to follow.
+				err = checkSymlink(parent, name, err)
+			}
+			return err
+		})
+		return fd, err
+	})
+	if err != nil {
+		return nil, &PathError{Op: "openat", Path: name, Err: err}
+	}
+	if !supportsCloseOnExec {
+		syscall.CloseOnExec(fd)
+	}
+	kind := kindOpenFile
+	if unix.HasNonblockFlag(flag) {
+		kind = kindNonBlock
+	}
+	return newFile(fd, joinPath(root.Name(), name), kind), nil
+}
+
+// rootMkdir implements Root.Mkdir.
+func rootMkdir(r *Root, name string, perm FileMode) error {
+	_, err := doInRoot(r, name, false, func(parent int, name string) (struct{}, error) {
+		return struct{}{}, ignoringEINTR(func() error {
+			return unix.Mkdirat(parent, name, syscallMode(perm))
+		})
+	})
+	if err != nil {
+		return &PathError{Op: "mkdirat", Path: name, Err: err}
+	}
+	return nil
+}
+
+// rootRemove implements Root.Remove.
+func rootRemove(r *Root, name string) error {
+	_, err := doInRoot(r, name, false, func(parent int, name string) (struct{}, error) {
+		// As in Remove, try removing both a file and a directory.
+		e := ignoringEINTR(func() error {
+			return unix.Unlinkat(parent, name, 0)
+		})
+		if e == nil {
+			return struct{}{}, nil
+		}
+		e1 := ignoringEINTR(func() error {
+			return unix.Unlinkat(parent, name, unix.AT_REMOVEDIR)
+		})
+		if e1 == nil {
+			return struct{}{}, nil
+		}
+		// See the comment in Remove.
+		if e1 != syscall.ENOTDIR {
+			e = e1
+		}
+		return struct{}{}, e
+	})
+	if err != nil {
+		return &PathError{Op: "removeat", Path: name, Err: err}
+	}
+	return nil
+}
+
+// rootStat implements Root.Stat and Root.Lstat.
+func rootStat(r *Root, name string, lstat bool) (FileInfo, error) {
+	fi, err := doInRoot(r, name, !lstat, func(parent int, n string) (FileInfo, error) {
+		var fs fileStat
+		if err := ignoringEINTR(func() error {
+			return unix.Fstatat(parent, n, &fs.sys, unix.AT_SYMLINK_NOFOLLOW)
+		}); err != nil {
+			return nil, err
+		}
+		fillFileStatFromSys(&fs, name)
+		if !lstat && fs.Mode()&ModeSymlink != 0 {
+			return nil, checkSymlink(parent, n, syscall.ELOOP)
+		}
+		return &fs, nil
+	})
+	if err != nil {
+		op := "statat"
+		if lstat {
+			op = "lstatat"
+		}
+		return nil, &PathError{Op: op, Path: name, Err: err}
+	}
+	return fi, nil
+}
+
+// errSymlink reports that the file being operated on is a symbolic
+// link that should be followed, and holds the link's target.
+// It is never returned to the user.
+type errSymlink string
+
+func (errSymlink) Error() string { return "symbolic link" }
+
+// checkSymlink checks whether the file name in the directory parent
+// is a symbolic link. If it is, it returns an errSymlink holding the
+// link's target. Otherwise it returns origError.
+func checkSymlink(parent int, name string, origError error) error {
+	link, err := readlinkat(parent, name)
+	if err != nil {
+		return origError
+	}
+	return errSymlink(link)
+}
+
+// readlinkat reads the target of the symbolic link name
+// in the directory dirfd.
+func readlinkat(dirfd int, name string) (string, error) {
+	for len := 128; ; len *= 2 {
+		b := make([]byte, len)
+		var (
+			n int
+			e error
+		)
+		ignoringEINTR(func() error {
+			n, e = unix.Readlinkat(dirfd, name, b)
+			return e
+		})
+		if e != nil {
+			return "", e
+		}
+		if n < len {
+			return string(b[:n]), nil
+		}
+	}
+}
+
+// rootOpenDir opens the directory name in the directory parent,
+// without following a symbolic link. If name is a symbolic link,
+// it returns an errSymlink.
+func rootOpenDir(parent int, name string) (int, error) {
+	var fd int
+	err := ignoringEINTR(func() error {
+		var err error
+		fd, err = unix.Openat(parent, name, syscall.O_NOFOLLOW|syscall.O_CLOEXEC|unix.O_DIRECTORY, 0)
+		if err != nil {
+			err = checkSymlink(parent, name, err)
+		}
+		return err
+	})
+	return fd, err
+}
+
+// doInRoot performs an operation on a file name in a Root.
+//
+// It opens the directory containing the last component of the name,
+// and calls f with the directory's descriptor and that component.
+// If follow is true and the last component is a symbolic link,
+// f must return an errSymlink, and doInRoot calls f again
+// for the target of the link.
+//
+// Symbolic links in other components are always followed.
+// Any component that refers to a location outside the root,
+// including a symbolic link with an absolute target,
+// makes doInRoot return errPathEscapes.
+func doInRoot[T any](r *Root, name string, follow bool, f func(parent int, name string) (T, error)) (ret T, err error) {
+	if err := r.root.incref(); err != nil {
+		return ret, err
+	}
+	defer r.root.decref()
+
+	parts, err := splitPathInRoot(name, follow)
+	if err != nil {
+		return ret, err
+	}
+
+	rootfd := r.root.fd
+	dirfd := rootfd
+	defer func() {
+		if dirfd != rootfd {
+			syscall.Close(dirfd)
+		}
+	}()
+
+	// parts[:i] are the components that have been resolved to dirfd.
+	// Each time we step back out of a directory with "..",
+	// or replace a symbolic link, we start again from the root.
+	// This is simpler than keeping a stack of open directories,
+	// and these cases are uncommon.
+	symlinks := 0
+	i := 0
+	for {
+		if i == 0 && len(parts) > 1 {
+			// Try to resolve the whole directory in one step.
+			fd, err := openDirBeneath(rootfd, parts[:len(parts)-1])
+			if err == nil {
+				dirfd = fd
+				i = len(parts) - 1
+			} else if err != errOpenat2Unavailable {
+				return ret, err
+			}
+		}
+
+		if i == len(parts)-1 {
+			ret, err = f(dirfd, parts[i])
+			if _, ok := err.(errSymlink); !ok {
+				return ret, err
+			}
+		} else if parts[i] == ".." {
+			// Remove parts[i-1] and the "..", and start again.
+			if i == 0 {
+				return ret, errPathEscapes
+			}
+			parts = append(parts[:i-1], parts[i+1:]...)
+		} else {
+			var fd int
+			fd, err = rootOpenDir(dirfd, parts[i])
+			if err == nil {
+				if dirfd != rootfd {
+					syscall.Close(dirfd)
+				}
+				dirfd = fd
+				i++
+				continue
+			}
+			if _, ok := err.(errSymlink); !ok {
+				return ret, err
+			}
+		}
+
+		if e, ok := err.(errSymlink); ok {
+			symlinks++
+			if symlinks > rootMaxSymlinks {
+				return ret, syscall.ELOOP
+			}
+			parts, err = replaceSymlink(parts, i, string(e))
+			if err != nil {
+				return ret, err
+			}
+		}
+
+		if dirfd != rootfd {
+			syscall.Close(dirfd)
+			dirfd = rootfd
+		}
+		i = 0
+	}
+}
+
+// errOpenat2Unavailable is returned by openDirBeneath when
+// the directory must be opened one component at a time.
+var errOpenat2Unavailable = errors.New("openat2 unavailable")
@@ -0,0 +1,36 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.swissmap
+
+package reflect
+
+import "unsafe"
+
+// bucketOverflowSize is the size of the overflow pointer at the end
+// of a map bucket. Swiss table groups have none.
+const bucketOverflowSize = 0
+
+// hiter's structure matches runtime.hiter's structure.
+// Having a clone here allows us to embed a map iterator
+// inside type MapIter so that MapIters can be re-used
+// without doing any allocations.
+type hiter struct {
+	key         unsafe.Pointer
+	elem        unsafe.Pointer
+	t           unsafe.Pointer
+	h           unsafe.Pointer
+	group       unsafe.Pointer
+	tab         unsafe.Pointer
+	entryOffset uintptr
+	entryIdx    uintptr
+	dirOffset   uintptr
+	dirIdx      uintptr
+	clearSeq    uint64
+	globalDepth uint8
+}
+
+func (h *hiter) initialized() bool {
+	return h.t != nil
+}
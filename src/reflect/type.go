@@ -1980,13 +1980,13 @@ func bucketOf(ktyp, etyp *abi.Type) *abi.Type {
 	var gcdata *byte
 	var ptrdata uintptr
 
-	size := bucketSize*(1+ktyp.Size_+etyp.Size_) + goarch.PtrSize
+	size := bucketSize*(1+ktyp.Size_+etyp.Size_) + bucketOverflowSize
 	if size&uintptr(ktyp.Align_-1) != 0 || size&uintptr(etyp.Align_-1) != 0 {
 		panic("reflect: bad size computation in MapOf")
 	}
 
 	if ktyp.PtrBytes != 0 || etyp.PtrBytes != 0 {
-		nptr := (bucketSize*(1+ktyp.Size_+etyp.Size_) + goarch.PtrSize) / goarch.PtrSize
+		nptr := size / goarch.PtrSize
 		n := (nptr + 7) / 8
 
 		// Runtime needs pointer masks to be a multiple of uintptr in size.
@@ -2004,13 +2004,20 @@ func bucketOf(ktyp, etyp *abi.Type) *abi.Type {
 		}
 		base += bucketSize * etyp.Size_ / goarch.PtrSize
 
-		word := base
-		mask[word/8] |= 1 << (word % 8)
+		if bucketOverflowSize != 0 {
+			word := base
+			mask[word/8] |= 1 << (word % 8)
+			base++
+		}
+		// The pointer data ends with the last pointer word.
+		for mask[(base-1)/8]&(1<<((base-1)%8)) == 0 {
+			base--
+		}
 		gcdata = &mask[0]
-		ptrdata = (word + 1) * goarch.PtrSize
+		ptrdata = base * goarch.PtrSize
 
 		// overflow word must be last
-		if ptrdata != size {
+		if bucketOverflowSize != 0 && ptrdata != size {
 			panic("reflect: bad layout computation in MapOf")
 		}
 	}
@@ -0,0 +1,40 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "runtime/internal/cgroup"
+
+// cgroupCPU is the cgroup of the process that has the CPU controller.
+// It is set up by schedinit, and then only used by sysmon.
+var cgroupCPU struct {
+	ok      bool
+	cpu     cgroup.CPU
+	scratch [cgroup.ScratchSize]byte
+}
+
+// cgroupCPUInit finds the cgroup of the process that has the CPU
+// controller, unless disabled by GODEBUG=containermaxprocs=0, and
+// reports whether it did.
+func cgroupCPUInit() bool {
+	if debug.containermaxprocs == 0 {
+		return false
+	}
+	err := cgroup.FindCPU(&cgroupCPU.cpu, "/proc/self/cgroup", "/proc/self/mountinfo", &cgroupCPU.scratch)
+	cgroupCPU.ok = err == nil
+	return cgroupCPU.ok
+}
+
+// cgroupCPULimit returns the current CPU bandwidth limit of the cgroup
+// of the process, in CPUs, if any.
+func cgroupCPULimit() (float64, bool) {
+	if !cgroupCPU.ok {
+		return 0, false
+	}
+	limit, ok, err := cgroupCPU.cpu.Limit(&cgroupCPU.scratch)
+	if err != nil {
+		return 0, false
+	}
+	return limit, ok
+}
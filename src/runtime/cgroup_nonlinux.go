@@ -0,0 +1,15 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package runtime
+
+func cgroupCPUInit() bool {
+	return false
+}
+
+func cgroupCPULimit() (float64, bool) {
+	return 0, false
+}
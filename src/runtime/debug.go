@@ -10,9 +10,19 @@ import (
 )
 
 // GOMAXPROCS sets the maximum number of CPUs that can be executing
-// simultaneously and returns the previous setting. It defaults to
-// the value of runtime.NumCPU. If n < 1, it does not change the current setting.
-// This call will go away when the scheduler improves.
+// simultaneously and returns the previous setting. If n < 1, it does not change
+// the current setting.
+//
+// If the GOMAXPROCS environment variable is set to a positive whole number,
+// GOMAXPROCS defaults to that value. Otherwise, it defaults to the value of
+// runtime.NumCPU, lowered on Linux to the CPU bandwidth limit of the cgroup
+// (container) of the process, if any. Such a limit is rounded up to a whole
+// number of CPUs, with a minimum of 2. While GOMAXPROCS has its default value,
+// the runtime periodically checks the limit and updates GOMAXPROCS if it
+// changes. Calling GOMAXPROCS with n >= 1 disables these updates.
+//
+// The cgroup limit and the updates can be disabled with the GODEBUG settings
+// containermaxprocs=0 and updatemaxprocs=0, respectively.
 func GOMAXPROCS(n int) int {
 	if GOARCH == "wasm" && n > 1 {
 		n = 1 // WebAssembly has no threads yet, so only one CPU is possible.
@@ -21,12 +31,15 @@ func GOMAXPROCS(n int) int {
 	lock(&sched.lock)
 	ret := int(gomaxprocs)
 	unlock(&sched.lock)
-	if n <= 0 || n == ret {
+	if n <= 0 || n == ret && maxprocs.custom.Load() {
 		return ret
 	}
 
 	stopTheWorldGC(stwGOMAXPROCS)
 
+	// Stop updating the default GOMAXPROCS.
+	maxprocs.custom.Store(true)
+
 	// newprocs will be processed by startTheWorld
 	newprocs = int32(n)
 
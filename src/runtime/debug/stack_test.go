@@ -8,19 +8,41 @@ import (
 	"bytes"
 	"fmt"
 	"internal/testenv"
+	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	. "runtime/debug"
 	"strings"
+	"sync"
 	"testing"
 )
 
 func TestMain(m *testing.M) {
-	if os.Getenv("GO_RUNTIME_DEBUG_TEST_DUMP_GOROOT") != "" {
+	switch {
+	case os.Getenv("GO_RUNTIME_DEBUG_TEST_DUMP_GOROOT") != "":
 		fmt.Println(runtime.GOROOT())
 		os.Exit(0)
+
+	case os.Getenv("GO_RUNTIME_DEBUG_TEST_ENTRYPOINT") != "":
+		// Run a crashing program with crash output set to a file.
+		f, err := os.Create(os.Getenv("GO_RUNTIME_DEBUG_TEST_CRASH_OUTPUT"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := SetCrashOutput(f, CrashOptions{}); err != nil {
+			log.Fatal(err)
+		}
+		f.Close() // SetCrashOutput dups f
+		switch os.Getenv("GO_RUNTIME_DEBUG_TEST_ENTRYPOINT") {
+		case "panic":
+			panic("oops")
+		case "fatal":
+			var mu sync.Mutex
+			mu.Unlock()
+		}
+		log.Fatal("unknown entrypoint")
 	}
 	os.Exit(m.Run())
 }
@@ -119,3 +141,44 @@ func TestStack(t *testing.T) {
 	frame("runtime/debug/stack_test.go", "runtime/debug_test.TestStack")
 	frame("testing/testing.go", "")
 }
+
+func TestSetCrashOutput(t *testing.T) {
+	testenv.MustHaveExec(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		entrypoint string
+		want       []string
+	}{
+		{"panic", []string{"panic: oops", "goroutine 1 [running]:", "runtime/debug_test.TestMain("}},
+		{"fatal", []string{"fatal error: sync: unlock of unlocked mutex"}},
+	} {
+		t.Run(tt.entrypoint, func(t *testing.T) {
+			crashOutput := filepath.Join(t.TempDir(), "crash.out")
+			cmd := exec.Command(exe)
+			cmd.Env = append(os.Environ(),
+				"GO_RUNTIME_DEBUG_TEST_ENTRYPOINT="+tt.entrypoint,
+				"GO_RUNTIME_DEBUG_TEST_CRASH_OUTPUT="+crashOutput)
+			stderr, err := cmd.CombinedOutput()
+			if err == nil {
+				t.Fatalf("program did not crash:\n%s", stderr)
+			}
+			crash, err := os.ReadFile(crashOutput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !bytes.Contains(crash, []byte(want)) {
+					t.Errorf("crash output does not contain %q:\n%s", want, crash)
+				}
+			}
+			// The crash output is a copy of the report on stderr.
+			if !bytes.Contains(stderr, crash) {
+				t.Errorf("crash output is not a copy of stderr:\ncrash output:\n%s\nstderr:\n%s", crash, stderr)
+			}
+		})
+	}
+}
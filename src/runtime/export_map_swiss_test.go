@@ -0,0 +1,138 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.swissmap
+
+package runtime
+
+import "unsafe"
+
+// MapTableCount returns the number of tables of m, or 0 for a small map.
+func MapTableCount(m map[int]int) int {
+	h := *(**hmap)(unsafe.Pointer(&m))
+	n := 0
+	for i := 0; i < h.dirLen; i++ {
+		if h.directoryAt(uintptr(i)).index == i {
+			n++
+		}
+	}
+	return n
+}
+
+// MapCheckInvariants panics if the internal state of m is inconsistent.
+func MapCheckInvariants(m map[int]int) {
+	h := *(**hmap)(unsafe.Pointer(&m))
+	i := any(m)
+	t := *(**maptype)(unsafe.Pointer(&i))
+
+	if h.dirLen == 0 {
+		if h.dirPtr == nil {
+			if h.count != 0 {
+				panic("small map with entries and no group")
+			}
+			return
+		}
+		g := (*bmap)(h.dirPtr)
+		n := 0
+		for i := uintptr(0); i < bucketCnt; i++ {
+			switch c := g.ctrl[i]; {
+			case c&ctrlFull != 0:
+				n++
+			case c != ctrlEmpty:
+				panic("small map with deleted slot")
+			}
+		}
+		if n != h.count {
+			panic("small map count mismatch")
+		}
+		return
+	}
+
+	if h.dirLen != 1<<h.globalDepth {
+		panic("directory length mismatch")
+	}
+	count := 0
+	for i := 0; i < h.dirLen; i++ {
+		tab := h.directoryAt(uintptr(i))
+		if tab.localDepth > h.globalDepth {
+			panic("table deeper than directory")
+		}
+		n := 1 << (h.globalDepth - tab.localDepth)
+		if tab.index%n != 0 || i < tab.index || i >= tab.index+n {
+			panic("table index mismatch")
+		}
+		if tab.index != i {
+			continue
+		}
+		used, deleted, empty := 0, 0, 0
+		for gi := uintptr(0); gi <= tab.groupMask; gi++ {
+			g := tab.group(t, gi)
+			for j := uintptr(0); j < bucketCnt; j++ {
+				switch c := g.ctrl[j]; {
+				case c&ctrlFull != 0:
+					used++
+					k := g.key(t, j)
+					hash := t.Hasher(k, h.seed)
+					if uintptr(c) != ctrlFull|h2(hash) {
+						panic("control byte mismatch")
+					}
+					if h.tableFor(hash) != tab {
+						panic("key in wrong table")
+					}
+					if _, _, ok := tab.lookup(t, hash, k); !ok {
+						panic("key not found")
+					}
+				case c == ctrlDeleted:
+					deleted++
+				case c == ctrlEmpty:
+					empty++
+				default:
+					panic("bad control byte")
+				}
+			}
+		}
+		if used != tab.used {
+			panic("table used mismatch")
+		}
+		if empty == 0 {
+			panic("table without empty slots")
+		}
+		if tab.growthLeft != tab.capacity*maxAvgGroupLoad/bucketCnt-used-deleted {
+			panic("table growthLeft mismatch")
+		}
+		count += used
+	}
+	if count != h.count {
+		panic("map count mismatch")
+	}
+}
+
+// Calls through these variables are not replaced with intrinsics.
+var (
+	ctrlGroupMatchH2             = ctrlGroup.matchH2
+	ctrlGroupMatchEmpty          = ctrlGroup.matchEmpty
+	ctrlGroupMatchEmptyOrDeleted = ctrlGroup.matchEmptyOrDeleted
+)
+
+// MapCtrlMatch returns the slots of a group with control word ctrl
+// that are full with the given h2, that are empty, and that are empty
+// or deleted. If indirect is set, it calls the match methods through
+// function values, so that they are not replaced with intrinsics.
+func MapCtrlMatch(ctrl uint64, h2 uintptr, indirect bool) (full, empty, emptyOrDeleted []uintptr) {
+	g := ctrlGroup(ctrl)
+	var m [3]bitset
+	if indirect {
+		m = [3]bitset{ctrlGroupMatchH2(g, h2), ctrlGroupMatchEmpty(g), ctrlGroupMatchEmptyOrDeleted(g)}
+	} else {
+		m = [3]bitset{g.matchH2(h2), g.matchEmpty(), g.matchEmptyOrDeleted()}
+	}
+	var slots [3][]uintptr
+	for i, b := range m {
+		for b != 0 {
+			slots[i] = append(slots[i], b.first())
+			b = b.removeFirst()
+		}
+	}
+	return slots[0], slots[1], slots[2]
+}
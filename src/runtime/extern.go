@@ -42,6 +42,10 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	clobber the memory content of an object with bad content when it frees
 	the object.
 
+	containermaxprocs: setting containermaxprocs=0 makes the default GOMAXPROCS
+	ignore the CPU bandwidth limit of the Linux cgroup (container) of the process.
+	See the GOMAXPROCS function for details.
+
 	cpu.*: cpu.all=off disables the use of all optional instruction set extensions.
 	cpu.extension=off disables use of instructions from the specified instruction set extension.
 	extension is the lower case name for the instruction set extension such as sse41 or avx
@@ -188,6 +192,10 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	This increases tracer overhead, but could be helpful as a workaround or for
 	debugging unexpected regressions caused by frame pointer unwinding.
 
+	updatemaxprocs: setting updatemaxprocs=0 disables the periodic updates of
+	the default GOMAXPROCS to changes of the CPU bandwidth limit of the Linux
+	cgroup (container) of the process. See the GOMAXPROCS function for details.
+
 	asyncpreemptoff: asyncpreemptoff=1 disables signal-based
 	asynchronous goroutine preemption. This makes some loops
 	non-preemptible for long periods, which may delay GC and
@@ -202,7 +210,7 @@ The GOMAXPROCS variable limits the number of operating system threads that
 can execute user-level Go code simultaneously. There is no limit to the number of threads
 that can be blocked in system calls on behalf of Go code; those do not count against
 the GOMAXPROCS limit. This package's GOMAXPROCS function queries and changes
-the limit.
+the limit, and documents its default value when the variable is not set.
 
 The GORACE variable configures the race detector, for programs built using -race.
 See https://golang.org/doc/articles/race_detector.html for details.
@@ -0,0 +1,474 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cgroup finds the CPU bandwidth limit of the Linux cgroup of
+// the current process, which the runtime uses to pick the default
+// GOMAXPROCS.
+//
+// The runtime uses it before the heap is set up, and from sysmon, so
+// it does not allocate: the caller provides all buffers.
+package cgroup
+
+import (
+	"internal/bytealg"
+	"runtime/internal/syscall"
+)
+
+// Version is a cgroup version.
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+)
+
+const (
+	// PathSize is the maximum length of a path, including the NUL
+	// terminator.
+	PathSize = 4096
+
+	// readSize is the size of the buffer for reading files, which
+	// bounds the length of their lines.
+	readSize = 4096
+
+	// ScratchSize is the size of the scratch buffer used by FindCPU
+	// and CPU.Limit.
+	ScratchSize = PathSize + readSize
+)
+
+// Errors returned by FindCPU and CPU.Limit.
+var (
+	ErrNoCgroup = cgroupError("no cgroup with a CPU controller")
+
+	errMalformed   = cgroupError("malformed cgroup file")
+	errPathTooLong = cgroupError("cgroup path too long")
+	errLineTooLong = cgroupError("line too long in cgroup file")
+	errOpen        = cgroupError("cannot open cgroup file")
+	errRead        = cgroupError("cannot read cgroup file")
+)
+
+type cgroupError string
+
+func (e cgroupError) Error() string { return string(e) }
+
+// CPU is the cgroup of a process that has the CPU controller.
+type CPU struct {
+	version Version
+
+	// path[:n] is the path of the cgroup directory, NUL-terminated,
+	// and path[:mnt] is the mount point of its cgroup hierarchy.
+	path   [PathSize]byte
+	n, mnt int
+}
+
+// Version returns the version of the cgroup.
+func (c *CPU) Version() Version {
+	return c.version
+}
+
+// Path returns the path of the cgroup directory.
+func (c *CPU) Path() []byte {
+	return c.path[:c.n]
+}
+
+// FindCPU finds the cgroup that has the CPU controller of the current
+// process, using the cgroup and mount information in cgroupFile and
+// mountinfoFile, normally /proc/self/cgroup and /proc/self/mountinfo.
+// It returns ErrNoCgroup if there is none.
+func FindCPU(c *CPU, cgroupFile, mountinfoFile string, scratch *[ScratchSize]byte) error {
+	*c = CPU{}
+	if err := c.findPath(cgroupFile, scratch); err != nil {
+		return err
+	}
+	return c.findMount(mountinfoFile, scratch)
+}
+
+// findPath stores the path of the cgroup within its hierarchy, as
+// listed in cgroupFile, in c.path[:c.n].
+//
+// Each line of cgroupFile is hierarchy-ID:controller-list:cgroup-path.
+// The cgroup v2 hierarchy has ID 0 and no controllers. A cgroup v1
+// hierarchy with the CPU controller takes precedence, as the controller
+// is only available in one hierarchy.
+func (c *CPU) findPath(cgroupFile string, scratch *[ScratchSize]byte) error {
+	r, err := openLines(cgroupFile, scratch)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+	for {
+		line, err := r.next()
+		if err != nil {
+			return err
+		}
+		if line == nil {
+			break
+		}
+		id, rest, ok := cut(line, ':')
+		if !ok {
+			return errMalformed
+		}
+		controllers, path, ok := cut(rest, ':')
+		if !ok {
+			return errMalformed
+		}
+		var v Version
+		switch {
+		case string(id) == "0" && len(controllers) == 0:
+			if c.version != 0 {
+				continue
+			}
+			v = V2
+		case hasField(controllers, ',', "cpu"):
+			v = V1
+		default:
+			continue
+		}
+		if len(path) >= len(c.path) {
+			return errPathTooLong
+		}
+		c.version = v
+		c.n = copy(c.path[:], path)
+		if v == V1 {
+			break
+		}
+	}
+	if c.version == 0 {
+		return ErrNoCgroup
+	}
+	return nil
+}
+
+// findMount finds the mount point of the cgroup hierarchy of c in
+// mountinfoFile, and turns c.path into the path of the cgroup directory.
+//
+// Each line of mountinfoFile is
+//
+//	mount-ID parent-ID major:minor root mount-point options [optional-fields...] - fstype source super-options
+//
+// where root is the path of the mounted directory within the cgroup
+// hierarchy. Paths have spaces, tabs, newlines and backslashes escaped
+// in octal.
+func (c *CPU) findMount(mountinfoFile string, scratch *[ScratchSize]byte) error {
+	r, err := openLines(mountinfoFile, scratch)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+	for {
+		line, err := r.next()
+		if err != nil {
+			return err
+		}
+		if line == nil {
+			return ErrNoCgroup
+		}
+		var root, mnt []byte
+		for i := 0; i < 5; i++ {
+			var field []byte
+			field, line = cutField(line)
+			switch i {
+			case 3:
+				root = field
+			case 4:
+				mnt = field
+			}
+		}
+		if mnt == nil {
+			return errMalformed
+		}
+		// Skip the options and optional fields.
+		for {
+			var field []byte
+			field, line = cutField(line)
+			if field == nil {
+				return errMalformed
+			}
+			if string(field) == "-" {
+				break
+			}
+		}
+		fstype, line := cutField(line)
+		_, line = cutField(line)
+		superOptions, _ := cutField(line)
+		switch c.version {
+		case V1:
+			if string(fstype) != "cgroup" || !hasField(superOptions, ',', "cpu") {
+				continue
+			}
+		case V2:
+			if string(fstype) != "cgroup2" {
+				continue
+			}
+		}
+
+		// The cgroup must be within the mounted directory.
+		root = unescape(root)
+		path := c.path[:c.n]
+		rel := path
+		if string(root) != "/" {
+			if !hasPrefix(path, root) || len(path) > len(root) && path[len(root)] != '/' {
+				continue
+			}
+			rel = path[len(root):]
+		}
+		if string(rel) == "/" {
+			rel = nil
+		}
+
+		// Replace path with mnt+rel, NUL-terminated.
+		mnt = unescape(mnt)
+		n := len(mnt) + len(rel)
+		if n >= len(c.path) {
+			return errPathTooLong
+		}
+		copy(c.path[len(mnt):], rel)
+		copy(c.path[:], mnt)
+		c.path[n] = 0
+		c.n = n
+		c.mnt = len(mnt)
+		return nil
+	}
+}
+
+// Limit returns the CPU bandwidth limit of the cgroup, in CPUs: the
+// lowest limit of the cgroup and its ancestors within the mounted
+// hierarchy. ok is false if there is no limit.
+func (c *CPU) Limit(scratch *[ScratchSize]byte) (limit float64, ok bool, err error) {
+	n := c.n
+	for {
+		var l float64
+		var found bool
+		switch c.version {
+		case V1:
+			l, found, err = c.limitV1(n, scratch)
+		case V2:
+			l, found, err = c.limitV2(n, scratch)
+		default:
+			return 0, false, ErrNoCgroup
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		if found && (!ok || l < limit) {
+			limit, ok = l, true
+		}
+		if n <= c.mnt {
+			return limit, ok, nil
+		}
+		// Continue with the parent.
+		for n > c.mnt && c.path[n-1] != '/' {
+			n--
+		}
+		n = max(n-1, c.mnt)
+	}
+}
+
+// limitV2 returns the limit of the cgroup v2 directory c.path[:n], in
+// its cpu.max file, which contains "$MAX $PERIOD", with a $MAX of "max"
+// for no limit.
+func (c *CPU) limitV2(n int, scratch *[ScratchSize]byte) (limit float64, ok bool, err error) {
+	line, err := c.readFirstLine(n, "cpu.max", scratch)
+	if line == nil || err != nil {
+		return 0, false, err
+	}
+	quota, line := cutField(line)
+	period, _ := cutField(line)
+	if string(quota) == "max" {
+		return 0, false, nil
+	}
+	q, ok1 := parseUint(quota)
+	p, ok2 := parseUint(period)
+	if !ok1 || !ok2 || p == 0 {
+		return 0, false, errMalformed
+	}
+	return float64(q) / float64(p), true, nil
+}
+
+// limitV1 returns the limit of the cgroup v1 directory c.path[:n], in
+// its cpu.cfs_quota_us file, which is -1 for no limit, and its
+// cpu.cfs_period_us file.
+func (c *CPU) limitV1(n int, scratch *[ScratchSize]byte) (limit float64, ok bool, err error) {
+	line, err := c.readFirstLine(n, "cpu.cfs_quota_us", scratch)
+	if line == nil || err != nil {
+		return 0, false, err
+	}
+	if string(line) == "-1" {
+		return 0, false, nil
+	}
+	q, ok := parseUint(line)
+	if !ok {
+		return 0, false, errMalformed
+	}
+	line, err = c.readFirstLine(n, "cpu.cfs_period_us", scratch)
+	if line == nil || err != nil {
+		return 0, false, err
+	}
+	p, ok := parseUint(line)
+	if !ok || p == 0 {
+		return 0, false, errMalformed
+	}
+	return float64(q) / float64(p), true, nil
+}
+
+// readFirstLine returns the first line of the file name in the directory
+// c.path[:n], or nil if it does not exist. The line is valid until the
+// next use of scratch.
+func (c *CPU) readFirstLine(n int, name string, scratch *[ScratchSize]byte) ([]byte, error) {
+	path := scratch[:PathSize]
+	if n+1+len(name) >= len(path) {
+		return nil, errPathTooLong
+	}
+	copy(path, c.path[:n])
+	path[n] = '/'
+	copy(path[n+1:], name)
+	path[n+1+len(name)] = 0
+	fd, errno := syscall.Open(&path[0], syscall.O_RDONLY, 0)
+	if errno == syscall.ENOENT {
+		return nil, nil
+	}
+	if errno != 0 {
+		return nil, errOpen
+	}
+	r := lineReader{fd: fd, buf: scratch[PathSize:]}
+	defer r.close()
+	line, err := r.next()
+	if err == nil && line == nil {
+		err = errMalformed
+	}
+	return line, err
+}
+
+// lineReader reads the lines of a file into a fixed buffer.
+type lineReader struct {
+	fd   int
+	buf  []byte
+	r, w int // buf[r:w] has not been returned yet
+	eof  bool
+}
+
+// openLines opens the file name to read its lines, using scratch for
+// both the NUL-terminated name and the lines.
+func openLines(name string, scratch *[ScratchSize]byte) (lineReader, error) {
+	path := scratch[:PathSize]
+	if len(name) >= len(path) {
+		return lineReader{}, errPathTooLong
+	}
+	copy(path, name)
+	path[len(name)] = 0
+	fd, errno := syscall.Open(&path[0], syscall.O_RDONLY, 0)
+	if errno != 0 {
+		return lineReader{}, errOpen
+	}
+	return lineReader{fd: fd, buf: scratch[PathSize:]}, nil
+}
+
+// next returns the next line, without its newline, or nil at the end
+// of the file. The line is valid until the next call.
+func (r *lineReader) next() ([]byte, error) {
+	for {
+		if i := bytealg.IndexByte(r.buf[r.r:r.w], '\n'); i >= 0 {
+			line := r.buf[r.r : r.r+i]
+			r.r += i + 1
+			return line, nil
+		}
+		if r.eof {
+			if r.r == r.w {
+				return nil, nil
+			}
+			line := r.buf[r.r:r.w]
+			r.r = r.w
+			return line, nil
+		}
+		// Move the partial line to the front and read more.
+		r.w = copy(r.buf, r.buf[r.r:r.w])
+		r.r = 0
+		if r.w == len(r.buf) {
+			return nil, errLineTooLong
+		}
+		n, errno := syscall.Read(r.fd, r.buf[r.w:])
+		if errno != 0 {
+			return nil, errRead
+		}
+		if n == 0 {
+			r.eof = true
+		}
+		r.w += n
+	}
+}
+
+func (r *lineReader) close() {
+	syscall.Close(r.fd)
+}
+
+// cut slices s around the first instance of sep.
+func cut(s []byte, sep byte) (before, after []byte, found bool) {
+	if i := bytealg.IndexByte(s, sep); i >= 0 {
+		return s[:i], s[i+1:], true
+	}
+	return s, nil, false
+}
+
+// cutField returns the first space-separated field of s and the rest of
+// s, or a nil field if there is none.
+func cutField(s []byte) (field, rest []byte) {
+	for len(s) > 0 && s[0] == ' ' {
+		s = s[1:]
+	}
+	if len(s) == 0 {
+		return nil, nil
+	}
+	field, rest, _ = cut(s, ' ')
+	return field, rest
+}
+
+// hasField reports whether the sep-separated list s contains f.
+func hasField(s []byte, sep byte, f string) bool {
+	for len(s) > 0 {
+		var field []byte
+		field, s, _ = cut(s, sep)
+		if string(field) == f {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(s, prefix []byte) bool {
+	return len(s) >= len(prefix) && string(s[:len(prefix)]) == string(prefix)
+}
+
+// unescape decodes the octal escapes of a path in mountinfo, in place.
+func unescape(s []byte) []byte {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if b == '\\' && i+3 < len(s) && isOctal(s[i+1]) && isOctal(s[i+2]) && isOctal(s[i+3]) {
+			b = (s[i+1]-'0')<<6 | (s[i+2]-'0')<<3 | (s[i+3] - '0')
+			i += 3
+		}
+		s[n] = b
+		n++
+	}
+	return s[:n]
+}
+
+func isOctal(b byte) bool {
+	return '0' <= b && b <= '7'
+}
+
+// parseUint parses a decimal number.
+func parseUint(s []byte) (uint64, bool) {
+	if len(s) == 0 || len(s) > 19 {
+		return 0, false
+	}
+	var n uint64
+	for _, b := range s {
+		if b < '0' || b > '9' {
+			return 0, false
+		}
+		n = n*10 + uint64(b-'0')
+	}
+	return n, true
+}
@@ -0,0 +1,293 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cgroup_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/internal/cgroup"
+	"strings"
+	"testing"
+)
+
+type limitTest struct {
+	name string
+
+	// cgroup is the content of /proc/self/cgroup.
+	cgroup string
+	// mountinfo is the content of /proc/self/mountinfo, in which
+	// $ROOT is replaced with the root of the synthetic tree.
+	mountinfo string
+	// files maps paths relative to the root to their contents.
+	files map[string]string
+
+	wantErr     error
+	wantVersion cgroup.Version
+	wantPath    string // relative to the root
+	wantLimit   float64
+	wantOK      bool
+}
+
+const (
+	v2Mountinfo = `22 1 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+29 1 0:26 / $ROOT/sys/fs/cgroup rw,nosuid,nodev,noexec,relatime shared:4 - cgroup2 cgroup2 rw,nsdelegate
+`
+	v1Mountinfo = `22 1 0:21 / /proc rw,nosuid,nodev,noexec,relatime shared:12 - proc proc rw
+30 29 0:27 / $ROOT/sys/fs/cgroup/memory rw,nosuid,nodev,noexec,relatime shared:5 - cgroup cgroup rw,memory
+31 29 0:28 / $ROOT/sys/fs/cgroup/cpu,cpuacct rw,nosuid,nodev,noexec,relatime shared:6 - cgroup cgroup rw,cpu,cpuacct
+`
+	v1Cgroup = `12:memory:/app
+4:cpu,cpuacct:/app
+0::/app
+`
+)
+
+var limitTests = []limitTest{
+	{
+		name:      "v2",
+		cgroup:    "0::/app\n",
+		mountinfo: v2Mountinfo,
+		files: map[string]string{
+			"sys/fs/cgroup/cpu.max":     "max 100000\n",
+			"sys/fs/cgroup/app/cpu.max": "250000 100000\n",
+		},
+		wantVersion: cgroup.V2,
+		wantPath:    "sys/fs/cgroup/app",
+		wantLimit:   2.5,
+		wantOK:      true,
+	},
+	{
+		name:      "v2-no-limit",
+		cgroup:    "0::/app\n",
+		mountinfo: v2Mountinfo,
+		files: map[string]string{
+			"sys/fs/cgroup/app/cpu.max": "max 100000\n",
+		},
+		wantVersion: cgroup.V2,
+		wantPath:    "sys/fs/cgroup/app",
+	},
+	{
+		name:      "v2-root",
+		cgroup:    "0::/\n",
+		mountinfo: v2Mountinfo,
+		files: map[string]string{
+			"sys/fs/cgroup/cpu.max": "50000 100000\n",
+		},
+		wantVersion: cgroup.V2,
+		wantPath:    "sys/fs/cgroup",
+		wantLimit:   0.5,
+		wantOK:      true,
+	},
+	{
+		name:      "v2-nested",
+		cgroup:    "0::/a/b/c\n",
+		mountinfo: v2Mountinfo,
+		files: map[string]string{
+			"sys/fs/cgroup/a/cpu.max":     "300000 100000\n",
+			"sys/fs/cgroup/a/b/cpu.max":   "150000 100000\n",
+			"sys/fs/cgroup/a/b/c/cpu.max": "max 100000\n",
+		},
+		wantVersion: cgroup.V2,
+		wantPath:    "sys/fs/cgroup/a/b/c",
+		wantLimit:   1.5,
+		wantOK:      true,
+	},
+	{
+		name:      "v2-missing-files",
+		cgroup:    "0::/a/b\n",
+		mountinfo: v2Mountinfo,
+		files: map[string]string{
+			"sys/fs/cgroup/a/cpu.max": "400000 200000\n",
+			"sys/fs/cgroup/a/b/x":     "",
+		},
+		wantVersion: cgroup.V2,
+		wantPath:    "sys/fs/cgroup/a/b",
+		wantLimit:   2,
+		wantOK:      true,
+	},
+	{
+		name:   "v2-container",
+		cgroup: "0::/docker/abc\n",
+		// A container without a cgroup namespace, with its own
+		// cgroup mounted.
+		mountinfo: "29 1 0:26 /docker/abc $ROOT/sys/fs/cgroup rw - cgroup2 cgroup2 rw\n",
+		files: map[string]string{
+			"sys/fs/cgroup/cpu.max": "100000 100000\n",
+		},
+		wantVersion: cgroup.V2,
+		wantPath:    "sys/fs/cgroup",
+		wantLimit:   1,
+		wantOK:      true,
+	},
+	{
+		name:   "v2-bind-mount",
+		cgroup: "0::/app/worker\n",
+		// A container that has its cgroup bind-mounted.
+		mountinfo: "29 1 0:26 /app $ROOT/sys/fs/cgroup rw - cgroup2 cgroup2 rw\n",
+		files: map[string]string{
+			"sys/fs/cgroup/cpu.max":        "200000 100000\n",
+			"sys/fs/cgroup/worker/cpu.max": "max 100000\n",
+		},
+		wantVersion: cgroup.V2,
+		wantPath:    "sys/fs/cgroup/worker",
+		wantLimit:   2,
+		wantOK:      true,
+	},
+	{
+		name:   "v2-escaped",
+		cgroup: "0::/a b\n",
+		mountinfo: `29 1 0:26 / $ROOT/cgroup\040fs rw shared:4 - cgroup2 cgroup2 rw
+`,
+		files: map[string]string{
+			"cgroup fs/a b/cpu.max": "100000 50000\n",
+		},
+		wantVersion: cgroup.V2,
+		wantPath:    "cgroup fs/a b",
+		wantLimit:   2,
+		wantOK:      true,
+	},
+	{
+		name:      "v1",
+		cgroup:    v1Cgroup,
+		mountinfo: v1Mountinfo,
+		files: map[string]string{
+			"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_quota_us":      "-1\n",
+			"sys/fs/cgroup/cpu,cpuacct/cpu.cfs_period_us":     "100000\n",
+			"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_quota_us":  "350000\n",
+			"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_period_us": "100000\n",
+		},
+		wantVersion: cgroup.V1,
+		wantPath:    "sys/fs/cgroup/cpu,cpuacct/app",
+		wantLimit:   3.5,
+		wantOK:      true,
+	},
+	{
+		name:      "v1-no-limit",
+		cgroup:    v1Cgroup,
+		mountinfo: v1Mountinfo,
+		files: map[string]string{
+			"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_quota_us":  "-1\n",
+			"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_period_us": "100000\n",
+		},
+		wantVersion: cgroup.V1,
+		wantPath:    "sys/fs/cgroup/cpu,cpuacct/app",
+	},
+	{
+		// Hybrid hierarchy: the CPU controller is in v1, the
+		// unified hierarchy has no controllers.
+		name:   "hybrid",
+		cgroup: v1Cgroup,
+		mountinfo: v1Mountinfo + `32 29 0:29 / $ROOT/sys/fs/cgroup/unified rw shared:7 - cgroup2 cgroup2 rw
+`,
+		files: map[string]string{
+			"sys/fs/cgroup/unified/app/cpu.max":               "100000 100000\n",
+			"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_quota_us":  "400000\n",
+			"sys/fs/cgroup/cpu,cpuacct/app/cpu.cfs_period_us": "100000\n",
+		},
+		wantVersion: cgroup.V1,
+		wantPath:    "sys/fs/cgroup/cpu,cpuacct/app",
+		wantLimit:   4,
+		wantOK:      true,
+	},
+	{
+		name:      "no-cpu-controller",
+		cgroup:    "12:memory:/app\n",
+		mountinfo: v1Mountinfo,
+		wantErr:   cgroup.ErrNoCgroup,
+	},
+	{
+		name:      "no-mount",
+		cgroup:    "0::/app\n",
+		mountinfo: v1Mountinfo,
+		wantErr:   cgroup.ErrNoCgroup,
+	},
+	{
+		name:   "mount-outside-root",
+		cgroup: "0::/other\n",
+		mountinfo: `29 1 0:26 /app $ROOT/sys/fs/cgroup rw - cgroup2 cgroup2 rw
+`,
+		wantErr: cgroup.ErrNoCgroup,
+	},
+}
+
+func TestLimit(t *testing.T) {
+	for _, tt := range limitTests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			write := func(name, content string) {
+				path := filepath.Join(root, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			write("proc/self/cgroup", tt.cgroup)
+			mountinfo := strings.ReplaceAll(tt.mountinfo, "$ROOT", strings.ReplaceAll(root, " ", `\040`))
+			write("proc/self/mountinfo", mountinfo)
+			for name, content := range tt.files {
+				write(name, content)
+			}
+
+			var c cgroup.CPU
+			var scratch [cgroup.ScratchSize]byte
+			err := cgroup.FindCPU(&c, filepath.Join(root, "proc/self/cgroup"), filepath.Join(root, "proc/self/mountinfo"), &scratch)
+			if err != tt.wantErr {
+				t.Fatalf("FindCPU got err %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if c.Version() != tt.wantVersion {
+				t.Errorf("Version got %d, want %d", c.Version(), tt.wantVersion)
+			}
+			if got, want := string(c.Path()), filepath.Join(root, tt.wantPath); got != want {
+				t.Errorf("Path got %q, want %q", got, want)
+			}
+
+			limit, ok, err := c.Limit(&scratch)
+			if err != nil {
+				t.Fatalf("Limit got err %v, want nil", err)
+			}
+			if limit != tt.wantLimit || ok != tt.wantOK {
+				t.Errorf("Limit got %v, %v, want %v, %v", limit, ok, tt.wantLimit, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	root := t.TempDir()
+	cgroupFile := filepath.Join(root, "cgroup")
+	mountinfoFile := filepath.Join(root, "mountinfo")
+	os.WriteFile(cgroupFile, []byte("0::/\n"), 0o644)
+	os.WriteFile(mountinfoFile, []byte("29 1 0:26 / "+root+" rw - cgroup2 cgroup2 rw\n"), 0o644)
+
+	for _, content := range []string{"", "100000", "x 100000", "100000 0"} {
+		os.WriteFile(filepath.Join(root, "cpu.max"), []byte(content), 0o644)
+		var c cgroup.CPU
+		var scratch [cgroup.ScratchSize]byte
+		if err := cgroup.FindCPU(&c, cgroupFile, mountinfoFile, &scratch); err != nil {
+			t.Fatalf("FindCPU got err %v, want nil", err)
+		}
+		if _, _, err := c.Limit(&scratch); err == nil {
+			t.Errorf("Limit with cpu.max %q got err nil, want non-nil", content)
+		}
+	}
+}
+
+func TestNoAlloc(t *testing.T) {
+	var c cgroup.CPU
+	var scratch [cgroup.ScratchSize]byte
+	allocs := testing.AllocsPerRun(10, func() {
+		if cgroup.FindCPU(&c, "/proc/self/cgroup", "/proc/self/mountinfo", &scratch) == nil {
+			c.Limit(&scratch)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocs, want 0", allocs)
+	}
+}
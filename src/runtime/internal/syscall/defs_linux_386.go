@@ -10,6 +10,9 @@ const (
 	SYS_EPOLL_PWAIT   = 319
 	SYS_EPOLL_CREATE1 = 329
 	SYS_EPOLL_PWAIT2  = 441
+	SYS_OPENAT        = 295
+	SYS_READ          = 3
+	SYS_CLOSE         = 6
 
 	EPOLLIN       = 0x1
 	EPOLLOUT      = 0x4
@@ -10,6 +10,9 @@ const (
 	SYS_EPOLL_PWAIT   = 281
 	SYS_EPOLL_CREATE1 = 291
 	SYS_EPOLL_PWAIT2  = 441
+	SYS_OPENAT        = 257
+	SYS_READ          = 0
+	SYS_CLOSE         = 3
 
 	EPOLLIN       = 0x1
 	EPOLLOUT      = 0x4
@@ -10,6 +10,9 @@ const (
 	SYS_EPOLL_PWAIT   = 346
 	SYS_EPOLL_CREATE1 = 357
 	SYS_EPOLL_PWAIT2  = 441
+	SYS_OPENAT        = 322
+	SYS_READ          = 3
+	SYS_CLOSE         = 6
 
 	EPOLLIN       = 0x1
 	EPOLLOUT      = 0x4
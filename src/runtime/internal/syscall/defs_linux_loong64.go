@@ -10,6 +10,9 @@ const (
 	SYS_EPOLL_PWAIT   = 22
 	SYS_FCNTL         = 25
 	SYS_EPOLL_PWAIT2  = 441
+	SYS_OPENAT        = 56
+	SYS_READ          = 63
+	SYS_CLOSE         = 57
 
 	EPOLLIN       = 0x1
 	EPOLLOUT      = 0x4
@@ -12,6 +12,9 @@ const (
 	SYS_EPOLL_PWAIT   = 5272
 	SYS_EPOLL_CREATE1 = 5285
 	SYS_EPOLL_PWAIT2  = 5441
+	SYS_OPENAT        = 5247
+	SYS_READ          = 5000
+	SYS_CLOSE         = 5003
 
 	EPOLLIN       = 0x1
 	EPOLLOUT      = 0x4
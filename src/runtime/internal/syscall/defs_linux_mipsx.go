@@ -12,6 +12,9 @@ const (
 	SYS_EPOLL_PWAIT   = 4313
 	SYS_EPOLL_CREATE1 = 4326
 	SYS_EPOLL_PWAIT2  = 4441
+	SYS_OPENAT        = 4288
+	SYS_READ          = 4003
+	SYS_CLOSE         = 4006
 
 	EPOLLIN       = 0x1
 	EPOLLOUT      = 0x4
@@ -12,6 +12,9 @@ const (
 	SYS_EPOLL_PWAIT   = 303
 	SYS_EPOLL_CREATE1 = 315
 	SYS_EPOLL_PWAIT2  = 441
+	SYS_OPENAT        = 286
+	SYS_READ          = 3
+	SYS_CLOSE         = 6
 
 	EPOLLIN       = 0x1
 	EPOLLOUT      = 0x4
@@ -10,6 +10,9 @@ const (
 	SYS_EPOLL_PWAIT   = 312
 	SYS_EPOLL_CREATE1 = 327
 	SYS_EPOLL_PWAIT2  = 441
+	SYS_OPENAT        = 288
+	SYS_READ          = 3
+	SYS_CLOSE         = 6
 
 	EPOLLIN       = 0x1
 	EPOLLOUT      = 0x4
@@ -60,3 +60,35 @@ func EpollCtl(epfd, op, fd int32, event *EpollEvent) (errno uintptr) {
 	_, _, e := Syscall6(SYS_EPOLL_CTL, uintptr(epfd), uintptr(op), uintptr(fd), uintptr(unsafe.Pointer(event)), 0, 0)
 	return e
 }
+
+// Flags and errors for Open, the same on all architectures.
+const (
+	AT_FDCWD  = -0x64
+	O_RDONLY  = 0x0
+	O_CLOEXEC = 0x80000
+	ENOENT    = 0x2
+)
+
+// Open opens the file with the NUL-terminated name path, relative to
+// the current directory. The file is always opened with O_CLOEXEC.
+func Open(path *byte, mode int, perm uint32) (fd int, errno uintptr) {
+	dfd := AT_FDCWD
+	r1, _, e := Syscall6(SYS_OPENAT, uintptr(dfd), uintptr(unsafe.Pointer(path)), uintptr(mode|O_CLOEXEC), uintptr(perm), 0, 0)
+	return int(r1), e
+}
+
+func Read(fd int, p []byte) (n int, errno uintptr) {
+	var ptr unsafe.Pointer
+	if len(p) > 0 {
+		ptr = unsafe.Pointer(&p[0])
+	} else {
+		ptr = unsafe.Pointer(&_zero)
+	}
+	r1, _, e := Syscall6(SYS_READ, uintptr(fd), uintptr(ptr), uintptr(len(p)), 0, 0, 0)
+	return int(r1), e
+}
+
+func Close(fd int) (errno uintptr) {
+	_, _, e := Syscall6(SYS_CLOSE, uintptr(fd), 0, 0, 0, 0, 0)
+	return e
+}
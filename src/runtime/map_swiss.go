@@ -0,0 +1,1054 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.swissmap
+
+package runtime
+
+// This file contains an implementation of Go's map type based on
+// Swiss tables (see https://abseil.io/about/design/swisstables).
+//
+// Entries are stored in groups of bucketCnt slots. Each slot has a
+// control byte, which is either empty, deleted (a tombstone), or full.
+// A full control byte holds the low 7 bits of the hash of the slot's
+// key (h2). A lookup compares h2 against all the control bytes of a
+// group at once, and only compares the keys of the matching slots.
+//
+// Groups have bucketCnt (8) slots, so their control bytes fit in a
+// single 64-bit control word. On amd64, the compiler matches control
+// words with SSE2 instructions (see map_swiss_amd64.go); elsewhere,
+// matching uses portable word-wide bit manipulation (SWAR, SIMD within
+// a register).
+//
+// A group is laid out like a bucket of the implementation in
+// map_noswiss.go, without the final overflow pointer: the control word
+// takes the place of the tophash array, and is followed by bucketCnt
+// keys and bucketCnt elems. The compiler, the linker and reflect
+// generate group types with this layout. Zeroed memory is a group of
+// empty slots.
+//
+// A small map, with at most bucketCnt entries, is a single group.
+// A larger map is split into tables. Each table is an open-addressing
+// hash table of groups, probed with a quadratic sequence starting at
+// a group selected by the hash. Tables are found through a directory
+// indexed by the top bits of the hash (extendible hashing).
+//
+// A table holds at most maxTableCapacity slots. When a table runs out
+// of room, only that table is rehashed into a new table twice as big
+// or, at the maximum capacity, split into two tables, doubling the
+// directory if needed. The work done by a single insert is thus bounded
+// by the size of a table rather than the size of the map.
+//
+// A table or group replaced by growth is never modified again. An
+// iterator that is in the middle of one keeps going through its slots,
+// and looks up each key in the current map to observe updates and
+// deletions made since the replacement.
+
+import (
+	"internal/abi"
+	"internal/goarch"
+	"runtime/internal/math"
+	"runtime/internal/sys"
+	"unsafe"
+)
+
+const (
+	// Maximum average load of a table, in entries per group.
+	maxAvgGroupLoad = 7
+
+	// Maximum number of slots in a table. A full table of this
+	// size is split in two instead of growing.
+	maxTableCapacity = 1024
+
+	// Control byte values. A full slot has the ctrlFull bit set,
+	// and the h2 of its key in the low 7 bits.
+	ctrlEmpty   = 0b0000_0000
+	ctrlDeleted = 0b0000_0001
+	ctrlFull    = 0b1000_0000
+
+	h2Mask = 0x7f
+
+	// flags
+	hashWriting = 4 // a goroutine is writing to the map
+)
+
+// exported value for testing
+const hashLoad = float32(maxAvgGroupLoad) / float32(bucketCnt)
+
+// A header for a Go map.
+type hmap struct {
+	// Note: the format of the hmap is also encoded in cmd/compile/internal/reflectdata/map_swiss.go.
+	// Make sure this stays in sync with the compiler's definition.
+	count       int // # live cells == size of map.  Must be first (used by len() builtin)
+	flags       uint8
+	globalDepth uint8   // log_2 of # of directory entries
+	globalShift uint8   // shift of the hash selecting a directory entry
+	seed        uintptr // hash seed
+
+	// For a small map (dirLen == 0), dirPtr is its single group,
+	// or nil if it has not been allocated yet. Otherwise dirPtr
+	// points to the directory, an array of dirLen *table.
+	dirPtr unsafe.Pointer
+	dirLen int
+
+	// clearSeq counts calls to clear, so that iterators can tell
+	// that the entries they have yet to produce were cleared.
+	clearSeq uint64
+}
+
+// A group of slots of a Go map. Only the control bytes are described
+// here; see the comment at the top of the file for the full layout.
+type bmap struct {
+	ctrl [bucketCnt]uint8
+	// Followed by bucketCnt keys and then bucketCnt elems.
+}
+
+// A table is an open-addressing hash table holding the entries of a
+// large map whose hashes share the same top localDepth bits.
+type table struct {
+	used       int // # live entries
+	capacity   int // # slots; a power of two, at least 2*bucketCnt
+	growthLeft int // # empty slots that can be filled before the table must grow
+
+	localDepth uint8 // # of top hash bits shared by all keys in the table
+
+	// index is the first directory entry referring to the table,
+	// or -1 if the table has been replaced by growth.
+	index int
+
+	groups    unsafe.Pointer // array of capacity/bucketCnt groups
+	groupMask uintptr        // capacity/bucketCnt - 1
+}
+
+// A hash iteration structure.
+// If you modify hiter, also change cmd/compile/internal/reflectdata/map_swiss.go
+// and reflect/map_swiss.go to match the layout of this structure.
+type hiter struct {
+	key  unsafe.Pointer // Must be in first position.  Write nil to indicate iteration end (see cmd/compile/internal/walk/range.go).
+	elem unsafe.Pointer // Must be in second position (see cmd/compile/internal/walk/range.go).
+	t    *maptype
+	h    *hmap
+
+	group unsafe.Pointer // group of a small map at iteration start; nil for large maps
+	tab   *table         // table being iterated, for large maps
+
+	entryOffset uintptr // random offset of the first slot visited in a group or table
+	entryIdx    uintptr // # of slots of the current group or table visited
+	dirOffset   uintptr // random offset of the first directory entry visited
+	dirIdx      uintptr // # of directory entries visited
+	clearSeq    uint64  // h.clearSeq at iteration start
+	globalDepth uint8   // h.globalDepth at the last directory entry visited
+}
+
+// ctrlGroup is the control word of a group, with the control byte of
+// slot i in bits 8*i through 8*i+7.
+type ctrlGroup uint64
+
+// bitset is the set of slots of a group matched by a control word.
+// Its representation depends on the architecture; see first.
+type bitset uint64
+
+const (
+	bitsetLSB  = 0x0101010101010101
+	bitsetMSB  = 0x8080808080808080
+	bitsetLow7 = 0x7f7f7f7f7f7f7f7f
+)
+
+// ctrls returns the control word of b.
+func (b *bmap) ctrls() ctrlGroup {
+	v := *(*uint64)(unsafe.Pointer(&b.ctrl))
+	if goarch.BigEndian {
+		v = sys.Bswap64(v)
+	}
+	return ctrlGroup(v)
+}
+
+// matchZero returns the bytes of v that are zero, as the high bit of
+// each byte. Unlike the shorter (v - lsb) &^ v & msb, it never reports
+// false positives.
+func matchZero(v uint64) uint64 {
+	t := (v & bitsetLow7) + bitsetLow7
+	return ^(t | v | bitsetLow7)
+}
+
+// The match methods of ctrlGroup are replaced by intrinsics on amd64
+// (see cmd/compile/internal/ssagen). Keep them in sync.
+
+// matchH2 returns the full slots of g whose key hash has the given h2.
+func (g ctrlGroup) matchH2(h2 uintptr) bitset {
+	return bitsetOf(matchZero(uint64(g) ^ bitsetLSB*uint64(ctrlFull|h2)))
+}
+
+// matchEmpty returns the empty slots of g.
+func (g ctrlGroup) matchEmpty() bitset {
+	return bitsetOf(matchZero(uint64(g)))
+}
+
+// matchEmptyOrDeleted returns the empty and deleted slots of g.
+func (g ctrlGroup) matchEmptyOrDeleted() bitset {
+	return bitsetOf(^uint64(g) & bitsetMSB)
+}
+
+// removeFirst returns b without its first match.
+func (b bitset) removeFirst() bitset {
+	return b & (b - 1)
+}
+
+// h2 returns the part of hash stored in control bytes.
+func h2(hash uintptr) uintptr {
+	return hash & h2Mask
+}
+
+// keySlot returns a pointer to the key slot i of b. For indirect keys
+// the slot holds a pointer to the key.
+func (b *bmap) keySlot(t *maptype, i uintptr) unsafe.Pointer {
+	return add(unsafe.Pointer(b), dataOffset+i*uintptr(t.KeySize))
+}
+
+// elemSlot returns a pointer to the elem slot i of b. For indirect
+// elems the slot holds a pointer to the elem.
+func (b *bmap) elemSlot(t *maptype, i uintptr) unsafe.Pointer {
+	return add(unsafe.Pointer(b), dataOffset+bucketCnt*uintptr(t.KeySize)+i*uintptr(t.ValueSize))
+}
+
+// key returns a pointer to the key in slot i of b.
+func (b *bmap) key(t *maptype, i uintptr) unsafe.Pointer {
+	k := b.keySlot(t, i)
+	if t.IndirectKey() {
+		k = *((*unsafe.Pointer)(k))
+	}
+	return k
+}
+
+// elem returns a pointer to the elem in slot i of b.
+func (b *bmap) elem(t *maptype, i uintptr) unsafe.Pointer {
+	e := b.elemSlot(t, i)
+	if t.IndirectElem() {
+		e = *((*unsafe.Pointer)(e))
+	}
+	return e
+}
+
+// lookup returns the index of the slot of b holding key, which has the
+// given hash, or ok == false if no slot of b holds key.
+func (b *bmap) lookup(t *maptype, hash uintptr, key unsafe.Pointer) (i uintptr, ok bool) {
+	match := b.ctrls().matchH2(h2(hash))
+	for match != 0 {
+		i := match.first()
+		if t.Key.Equal(key, b.key(t, i)) {
+			return i, true
+		}
+		match = match.removeFirst()
+	}
+	return 0, false
+}
+
+// insert stores key, which has the given hash, in the empty or deleted
+// slot i of b. It returns a pointer to the slot's elem, which is zero.
+func (b *bmap) insert(t *maptype, i uintptr, hash uintptr, key unsafe.Pointer) unsafe.Pointer {
+	k := b.keySlot(t, i)
+	if t.IndirectKey() {
+		kmem := newobject(t.Key)
+		*(*unsafe.Pointer)(k) = kmem
+		k = kmem
+	}
+	typedmemmove(t.Key, k, key)
+	e := b.elemSlot(t, i)
+	if t.IndirectElem() {
+		emem := newobject(t.Elem)
+		*(*unsafe.Pointer)(e) = emem
+		e = emem
+	}
+	b.ctrl[i] = uint8(ctrlFull | h2(hash))
+	return e
+}
+
+// clearSlot clears the key and elem of slot i of b, without
+// changing its control byte.
+func (b *bmap) clearSlot(t *maptype, i uintptr) {
+	k := b.keySlot(t, i)
+	if t.IndirectKey() {
+		*(*unsafe.Pointer)(k) = nil
+	} else if t.Key.PtrBytes != 0 {
+		memclrHasPointers(k, t.Key.Size_)
+	}
+	e := b.elemSlot(t, i)
+	if t.IndirectElem() {
+		*(*unsafe.Pointer)(e) = nil
+	} else if t.Elem.PtrBytes != 0 {
+		memclrHasPointers(e, t.Elem.Size_)
+	} else {
+		memclrNoHeapPointers(e, t.Elem.Size_)
+	}
+}
+
+// A probeSeq is the sequence of groups visited looking for a hash in a
+// table: offset, offset+1, offset+3, offset+6, ... modulo the number
+// of groups. Since the number of groups is a power of two, this
+// triangular sequence visits every group.
+type probeSeq struct {
+	mask   uintptr
+	offset uintptr
+	index  uintptr
+}
+
+func makeProbeSeq(hash uintptr, mask uintptr) probeSeq {
+	return probeSeq{mask: mask, offset: (hash >> 7) & mask}
+}
+
+func (s probeSeq) next() probeSeq {
+	s.index++
+	s.offset = (s.offset + s.index) & s.mask
+	return s
+}
+
+func newTable(t *maptype, capacity int, localDepth uint8) *table {
+	ngroups := uintptr(capacity) / bucketCnt
+	return &table{
+		capacity:   capacity,
+		growthLeft: capacity * maxAvgGroupLoad / bucketCnt,
+		localDepth: localDepth,
+		groups:     newarray(t.Bucket, int(ngroups)),
+		groupMask:  ngroups - 1,
+	}
+}
+
+// group returns group i of tab.
+func (tab *table) group(t *maptype, i uintptr) *bmap {
+	return (*bmap)(add(tab.groups, i*uintptr(t.BucketSize)))
+}
+
+// lookup returns the group and slot index holding key, which has the
+// given hash, or ok == false if key is not in tab.
+func (tab *table) lookup(t *maptype, hash uintptr, key unsafe.Pointer) (g *bmap, i uintptr, ok bool) {
+	for seq := makeProbeSeq(hash, tab.groupMask); ; seq = seq.next() {
+		g := tab.group(t, seq.offset)
+		if i, ok := g.lookup(t, hash, key); ok {
+			return g, i, true
+		}
+		if g.ctrls().matchEmpty() != 0 {
+			// The probe sequence of key would have stopped here.
+			return nil, 0, false
+		}
+	}
+}
+
+// putSlot is like hmap.putSlot for a large map, except that it reports
+// ok == false instead if tab must grow to make room for key.
+func (tab *table) putSlot(t *maptype, h *hmap, hash uintptr, key unsafe.Pointer) (elem unsafe.Pointer, ok bool) {
+	var insertG *bmap
+	var insertI uintptr
+	for seq := makeProbeSeq(hash, tab.groupMask); ; seq = seq.next() {
+		g := tab.group(t, seq.offset)
+		if i, ok := g.lookup(t, hash, key); ok {
+			if t.NeedKeyUpdate() {
+				typedmemmove(t.Key, g.key(t, i), key)
+			}
+			return g.elem(t, i), true
+		}
+		ctrls := g.ctrls()
+		if insertG == nil {
+			// Remember the first free slot, reusing a tombstone
+			// if there is one along the probe sequence.
+			if match := ctrls.matchEmptyOrDeleted(); match != 0 {
+				insertG, insertI = g, match.first()
+			}
+		}
+		if ctrls.matchEmpty() != 0 {
+			break
+		}
+	}
+	if insertG.ctrl[insertI] == ctrlEmpty {
+		if tab.growthLeft == 0 {
+			return nil, false
+		}
+		tab.growthLeft--
+	}
+	elem = insertG.insert(t, insertI, hash, key)
+	tab.used++
+	h.count++
+	return elem, true
+}
+
+// putEntry moves the entry in slot i of src, whose key has the given
+// hash, into tab. tab must have room for it, not contain its key, and
+// have no deleted slots.
+func (tab *table) putEntry(t *maptype, hash uintptr, src *bmap, i uintptr) {
+	for seq := makeProbeSeq(hash, tab.groupMask); ; seq = seq.next() {
+		g := tab.group(t, seq.offset)
+		match := g.ctrls().matchEmpty()
+		if match == 0 {
+			continue
+		}
+		j := match.first()
+		if t.IndirectKey() {
+			*(*unsafe.Pointer)(g.keySlot(t, j)) = *(*unsafe.Pointer)(src.keySlot(t, i))
+		} else {
+			typedmemmove(t.Key, g.keySlot(t, j), src.keySlot(t, i))
+		}
+		if t.IndirectElem() {
+			*(*unsafe.Pointer)(g.elemSlot(t, j)) = *(*unsafe.Pointer)(src.elemSlot(t, i))
+		} else {
+			typedmemmove(t.Elem, g.elemSlot(t, j), src.elemSlot(t, i))
+		}
+		g.ctrl[j] = src.ctrl[i]
+		tab.used++
+		tab.growthLeft--
+		return
+	}
+}
+
+// deleteKey deletes key, which has the given hash, from tab.
+func (tab *table) deleteKey(t *maptype, h *hmap, hash uintptr, key unsafe.Pointer) {
+	g, i, ok := tab.lookup(t, hash, key)
+	if !ok {
+		return
+	}
+	g.clearSlot(t, i)
+	if g.ctrls().matchEmpty() != 0 {
+		// No probe sequence continues past g, so the slot can
+		// be reused without leaving a tombstone.
+		g.ctrl[i] = ctrlEmpty
+		tab.growthLeft++
+	} else {
+		g.ctrl[i] = ctrlDeleted
+	}
+	tab.used--
+	h.count--
+}
+
+// clear deletes all the entries of tab in place.
+func (tab *table) clear(t *maptype) {
+	size := (tab.groupMask + 1) * uintptr(t.BucketSize)
+	if t.Bucket.PtrBytes != 0 {
+		memclrHasPointers(tab.groups, size)
+	} else {
+		memclrNoHeapPointers(tab.groups, size)
+	}
+	tab.used = 0
+	tab.growthLeft = tab.capacity * maxAvgGroupLoad / bucketCnt
+}
+
+// directoryAt returns directory entry i of h, which must be a large map.
+func (h *hmap) directoryAt(i uintptr) *table {
+	return *(**table)(add(h.dirPtr, i*goarch.PtrSize))
+}
+
+// directorySet sets directory entry i of h to tab.
+func (h *hmap) directorySet(i uintptr, tab *table) {
+	*(**table)(add(h.dirPtr, i*goarch.PtrSize)) = tab
+}
+
+// tableFor returns the table of the large map h that holds hash.
+func (h *hmap) tableFor(hash uintptr) *table {
+	if h.dirLen == 1 {
+		return h.directoryAt(0)
+	}
+	return h.directoryAt(hash >> h.globalShift)
+}
+
+// setDirectory makes dir the directory of h and updates the index of its tables.
+func (h *hmap) setDirectory(dir []*table) {
+	for i, tab := range dir {
+		if i == 0 || dir[i-1] != tab {
+			tab.index = i
+		}
+	}
+	h.dirPtr = unsafe.Pointer(&dir[0])
+	h.dirLen = len(dir)
+	h.globalDepth = uint8(sys.TrailingZeros64(uint64(len(dir))))
+	h.globalShift = uint8(goarch.PtrSize*8) - h.globalDepth
+}
+
+// getWithKey returns pointers to key as stored in h and its elem,
+// or ok == false if key is not in h.
+func (h *hmap) getWithKey(t *maptype, hash uintptr, key unsafe.Pointer) (k, e unsafe.Pointer, ok bool) {
+	if h.dirLen == 0 {
+		g := (*bmap)(h.dirPtr)
+		if g == nil {
+			return nil, nil, false
+		}
+		i, ok := g.lookup(t, hash, key)
+		if !ok {
+			return nil, nil, false
+		}
+		return g.key(t, i), g.elem(t, i), true
+	}
+	g, i, ok := h.tableFor(hash).lookup(t, hash, key)
+	if !ok {
+		return nil, nil, false
+	}
+	return g.key(t, i), g.elem(t, i), true
+}
+
+// putSlot returns a pointer to the elem of key, which has the given
+// hash, adding key to h with a zero elem if it is not present.
+func (h *hmap) putSlot(t *maptype, hash uintptr, key unsafe.Pointer) unsafe.Pointer {
+	if h.dirLen == 0 {
+		if h.dirPtr == nil {
+			h.dirPtr = newobject(t.Bucket)
+		}
+		g := (*bmap)(h.dirPtr)
+		if i, ok := g.lookup(t, hash, key); ok {
+			if t.NeedKeyUpdate() {
+				typedmemmove(t.Key, g.key(t, i), key)
+			}
+			return g.elem(t, i)
+		}
+		if match := g.ctrls().matchEmpty(); match != 0 {
+			h.count++
+			return g.insert(t, match.first(), hash, key)
+		}
+		// The group is full.
+		h.growToTable(t)
+	}
+	for {
+		tab := h.tableFor(hash)
+		if elem, ok := tab.putSlot(t, h, hash, key); ok {
+			return elem
+		}
+		h.grow(t, tab)
+	}
+}
+
+// growToTable turns the small map h into a large map with a single table.
+func (h *hmap) growToTable(t *maptype) {
+	tab := newTable(t, 2*bucketCnt, 0)
+	g := (*bmap)(h.dirPtr)
+	for i := uintptr(0); i < bucketCnt; i++ {
+		if g.ctrl[i]&ctrlFull == 0 {
+			continue
+		}
+		tab.putEntry(t, t.Hasher(g.key(t, i), h.seed), g, i)
+	}
+	h.setDirectory([]*table{tab})
+}
+
+// grow replaces tab, which has no room left for new entries, with a
+// rehashed table with room for more entries, or with two tables that
+// each hold half of its entries.
+func (h *hmap) grow(t *maptype, tab *table) {
+	newCapacity := tab.capacity
+	if tab.used >= tab.capacity*maxAvgGroupLoad/bucketCnt/2 {
+		// Most of the slots in use hold live entries rather than
+		// tombstones, so rehashing in place would not free enough.
+		newCapacity *= 2
+	}
+	if newCapacity > maxTableCapacity {
+		h.split(t, tab)
+		return
+	}
+	newTab := newTable(t, newCapacity, tab.localDepth)
+	tab.forEach(t, h.seed, func(hash uintptr, g *bmap, i uintptr) {
+		newTab.putEntry(t, hash, g, i)
+	})
+	n := 1 << (h.globalDepth - tab.localDepth)
+	for j := tab.index; j < tab.index+n; j++ {
+		h.directorySet(uintptr(j), newTab)
+	}
+	newTab.index = tab.index
+	tab.index = -1
+}
+
+// split replaces tab with two tables, one for each value of the hash
+// bit following the localDepth bits shared by all keys of tab.
+func (h *hmap) split(t *maptype, tab *table) {
+	if tab.localDepth == h.globalDepth {
+		// Double the directory, so that there is an entry
+		// for each of the two new tables.
+		dir := make([]*table, 2*h.dirLen)
+		for i := range dir {
+			dir[i] = h.directoryAt(uintptr(i / 2))
+		}
+		h.setDirectory(dir)
+	}
+	localDepth := tab.localDepth + 1
+	left := newTable(t, tab.capacity, localDepth)
+	right := newTable(t, tab.capacity, localDepth)
+	bit := uintptr(1) << (goarch.PtrSize*8 - uintptr(localDepth))
+	tab.forEach(t, h.seed, func(hash uintptr, g *bmap, i uintptr) {
+		if hash&bit == 0 {
+			left.putEntry(t, hash, g, i)
+		} else {
+			right.putEntry(t, hash, g, i)
+		}
+	})
+	n := 1 << (h.globalDepth - tab.localDepth)
+	for j := tab.index; j < tab.index+n/2; j++ {
+		h.directorySet(uintptr(j), left)
+	}
+	for j := tab.index + n/2; j < tab.index+n; j++ {
+		h.directorySet(uintptr(j), right)
+	}
+	left.index = tab.index
+	right.index = tab.index + n/2
+	tab.index = -1
+}
+
+// forEach calls f for each full slot of tab, along with the hash of
+// its key for the given seed.
+func (tab *table) forEach(t *maptype, seed uintptr, f func(hash uintptr, g *bmap, i uintptr)) {
+	for gi := uintptr(0); gi <= tab.groupMask; gi++ {
+		g := tab.group(t, gi)
+		for i := uintptr(0); i < bucketCnt; i++ {
+			if g.ctrl[i]&ctrlFull == 0 {
+				continue
+			}
+			f(t.Hasher(g.key(t, i), seed), g, i)
+		}
+	}
+}
+
+// deleteKey deletes key, which has the given hash, from h.
+func (h *hmap) deleteKey(t *maptype, hash uintptr, key unsafe.Pointer) {
+	if h.dirLen == 0 {
+		g := (*bmap)(h.dirPtr)
+		if i, ok := g.lookup(t, hash, key); ok {
+			// A small map is never probed past its only group,
+			// so there is no need for a tombstone.
+			g.clearSlot(t, i)
+			g.ctrl[i] = ctrlEmpty
+			h.count--
+		}
+	} else {
+		h.tableFor(hash).deleteKey(t, h, hash, key)
+	}
+	if h.count == 0 {
+		// Reset the hash seed to make it more difficult for attackers to
+		// repeatedly trigger hash collisions. See issue 25237.
+		h.seed = uintptr(fastrand64())
+	}
+}
+
+// makemap_small implements Go map creation for make(map[k]v) and
+// make(map[k]v, hint) when hint is known to be at most bucketCnt
+// at compile time and the map needs to be allocated on the heap.
+func makemap_small() *hmap {
+	h := new(hmap)
+	h.seed = uintptr(fastrand64())
+	return h
+}
+
+// makemap implements Go map creation for make(map[k]v, hint).
+// If the compiler has determined that the map or the first group
+// can be created on the stack, h and/or h.dirPtr may be non-nil.
+// If h != nil, the map can be created directly in h.
+// If h.dirPtr != nil, the group pointed to can be used as the group
+// of a small map.
+func makemap(t *maptype, hint int, h *hmap) *hmap {
+	mem, overflow := math.MulUintptr(uintptr(hint), t.Bucket.Size_)
+	if overflow || mem > maxAlloc {
+		hint = 0
+	}
+
+	// initialize Hmap
+	if h == nil {
+		h = new(hmap)
+	}
+	h.seed = uintptr(fastrand64())
+
+	if hint <= bucketCnt {
+		// A small map. Its group is allocated lazily by mapassign,
+		// unless the compiler has already provided one.
+		return h
+	}
+
+	// Preallocate enough tables to hold hint entries at the
+	// maximum load without growing.
+	capacity := (uintptr(hint)*bucketCnt + maxAvgGroupLoad - 1) / maxAvgGroupLoad
+	dirLen := uintptr(1)
+	if capacity > maxTableCapacity {
+		dirLen = (capacity + maxTableCapacity - 1) / maxTableCapacity
+		dirLen = 1 << sys.Len64(uint64(dirLen-1))
+		capacity = maxTableCapacity
+	} else {
+		capacity = 1 << sys.Len64(uint64(capacity-1))
+	}
+	dir := make([]*table, dirLen)
+	localDepth := uint8(sys.TrailingZeros64(uint64(dirLen)))
+	for i := range dir {
+		dir[i] = newTable(t, int(capacity), localDepth)
+	}
+	h.setDirectory(dir)
+	return h
+}
+
+// mapaccess1 returns a pointer to h[key].  Never returns nil, instead
+// it will return a reference to the zero object for the elem type if
+// the key is not in the map.
+// NOTE: The returned pointer may keep the whole map live, so don't
+// hold onto it for very long.
+func mapaccess1(t *maptype, h *hmap, key unsafe.Pointer) unsafe.Pointer {
+	if raceenabled && h != nil {
+		callerpc := getcallerpc()
+		pc := abi.FuncPCABIInternal(mapaccess1)
+		racereadpc(unsafe.Pointer(h), callerpc, pc)
+		raceReadObjectPC(t.Key, key, callerpc, pc)
+	}
+	if msanenabled && h != nil {
+		msanread(key, t.Key.Size_)
+	}
+	if asanenabled && h != nil {
+		asanread(key, t.Key.Size_)
+	}
+	e, _ := mapaccessKey(t, h, key)
+	return e
+}
+
+func mapaccess2(t *maptype, h *hmap, key unsafe.Pointer) (unsafe.Pointer, bool) {
+	if raceenabled && h != nil {
+		callerpc := getcallerpc()
+		pc := abi.FuncPCABIInternal(mapaccess2)
+		racereadpc(unsafe.Pointer(h), callerpc, pc)
+		raceReadObjectPC(t.Key, key, callerpc, pc)
+	}
+	if msanenabled && h != nil {
+		msanread(key, t.Key.Size_)
+	}
+	if asanenabled && h != nil {
+		asanread(key, t.Key.Size_)
+	}
+	return mapaccessKey(t, h, key)
+}
+
+// mapaccessKey implements the mapaccess functions, without instrumentation.
+func mapaccessKey(t *maptype, h *hmap, key unsafe.Pointer) (unsafe.Pointer, bool) {
+	if h == nil || h.count == 0 {
+		if err := mapKeyError(t, key); err != nil {
+			panic(err) // see issue 23734
+		}
+		return unsafe.Pointer(&zeroVal[0]), false
+	}
+	if h.flags&hashWriting != 0 {
+		fatal("concurrent map read and map write")
+	}
+	hash := t.Hasher(key, h.seed)
+	if _, e, ok := h.getWithKey(t, hash, key); ok {
+		return e, true
+	}
+	return unsafe.Pointer(&zeroVal[0]), false
+}
+
+// Like mapaccess, but allocates a slot for the key if it is not present in the map.
+func mapassign(t *maptype, h *hmap, key unsafe.Pointer) unsafe.Pointer {
+	if h == nil {
+		panic(plainError("assignment to entry in nil map"))
+	}
+	if raceenabled {
+		callerpc := getcallerpc()
+		pc := abi.FuncPCABIInternal(mapassign)
+		racewritepc(unsafe.Pointer(h), callerpc, pc)
+		raceReadObjectPC(t.Key, key, callerpc, pc)
+	}
+	if msanenabled {
+		msanread(key, t.Key.Size_)
+	}
+	if asanenabled {
+		asanread(key, t.Key.Size_)
+	}
+	return mapassignKey(t, h, key)
+}
+
+// mapassignKey implements the mapassign functions, without instrumentation.
+func mapassignKey(t *maptype, h *hmap, key unsafe.Pointer) unsafe.Pointer {
+	if h == nil {
+		panic(plainError("assignment to entry in nil map"))
+	}
+	if h.flags&hashWriting != 0 {
+		fatal("concurrent map writes")
+	}
+	hash := t.Hasher(key, h.seed)
+
+	// Set hashWriting after calling t.hasher, since t.hasher may panic,
+	// in which case we have not actually done a write.
+	h.flags ^= hashWriting
+
+	elem := h.putSlot(t, hash, key)
+
+	if h.flags&hashWriting == 0 {
+		fatal("concurrent map writes")
+	}
+	h.flags &^= hashWriting
+	return elem
+}
+
+func mapdelete(t *maptype, h *hmap, key unsafe.Pointer) {
+	if raceenabled && h != nil {
+		callerpc := getcallerpc()
+		pc := abi.FuncPCABIInternal(mapdelete)
+		racewritepc(unsafe.Pointer(h), callerpc, pc)
+		raceReadObjectPC(t.Key, key, callerpc, pc)
+	}
+	if msanenabled && h != nil {
+		msanread(key, t.Key.Size_)
+	}
+	if asanenabled && h != nil {
+		asanread(key, t.Key.Size_)
+	}
+	mapdeleteKey(t, h, key)
+}
+
+// mapdeleteKey implements the mapdelete functions, without instrumentation.
+func mapdeleteKey(t *maptype, h *hmap, key unsafe.Pointer) {
+	if h == nil || h.count == 0 {
+		if err := mapKeyError(t, key); err != nil {
+			panic(err) // see issue 23734
+		}
+		return
+	}
+	if h.flags&hashWriting != 0 {
+		fatal("concurrent map writes")
+	}
+
+	hash := t.Hasher(key, h.seed)
+
+	// Set hashWriting after calling t.hasher, since t.hasher may panic,
+	// in which case we have not actually done a write (delete).
+	h.flags ^= hashWriting
+
+	h.deleteKey(t, hash, key)
+
+	if h.flags&hashWriting == 0 {
+		fatal("concurrent map writes")
+	}
+	h.flags &^= hashWriting
+}
+
+// mapiterinit initializes the hiter struct used for ranging over maps.
+// The hiter struct pointed to by 'it' is allocated on the stack
+// by the compilers order pass or on the heap by reflect_mapiterinit.
+// Both need to have zeroed hiter since the struct contains pointers.
+func mapiterinit(t *maptype, h *hmap, it *hiter) {
+	if raceenabled && h != nil {
+		callerpc := getcallerpc()
+		racereadpc(unsafe.Pointer(h), callerpc, abi.FuncPCABIInternal(mapiterinit))
+	}
+
+	it.t = t
+	if h == nil || h.count == 0 {
+		return
+	}
+
+	if unsafe.Sizeof(hiter{}) != 11*goarch.PtrSize+8 {
+		throw("hash_iter size incorrect") // see cmd/compile/internal/reflectdata/map_swiss.go
+	}
+	it.h = h
+	it.clearSeq = h.clearSeq
+
+	// decide where to start
+	it.entryOffset = uintptr(fastrand())
+	it.dirOffset = uintptr(fastrand())
+	if h.dirLen == 0 {
+		it.group = h.dirPtr
+	} else {
+		it.globalDepth = h.globalDepth
+	}
+
+	mapiternext(it)
+}
+
+func mapiternext(it *hiter) {
+	h := it.h
+	if raceenabled {
+		callerpc := getcallerpc()
+		racereadpc(unsafe.Pointer(h), callerpc, abi.FuncPCABIInternal(mapiternext))
+	}
+	if h.flags&hashWriting != 0 {
+		fatal("concurrent map iteration and map write")
+	}
+	t := it.t
+	if it.clearSeq != h.clearSeq {
+		// The map was cleared, taking all the entries
+		// not produced yet with it.
+		it.key = nil
+		it.elem = nil
+		return
+	}
+
+	if it.group != nil {
+		// The map was small when iteration started. If it has
+		// grown since, its group was not modified afterwards.
+		g := (*bmap)(it.group)
+		stale := h.dirLen != 0
+		for ; it.entryIdx < bucketCnt; it.entryIdx++ {
+			i := (it.entryIdx + it.entryOffset) & (bucketCnt - 1)
+			if g.ctrl[i]&ctrlFull == 0 {
+				continue
+			}
+			k, e := g.key(t, i), g.elem(t, i)
+			if stale {
+				var ok bool
+				if k, e, ok = h.currentEntry(t, k, e); !ok {
+					continue
+				}
+			}
+			it.key = k
+			it.elem = e
+			it.entryIdx++
+			return
+		}
+		it.key = nil
+		it.elem = nil
+		return
+	}
+
+	for {
+		if it.globalDepth != h.globalDepth {
+			// The directory grew since the last entry was visited.
+			// Each entry became 2^d consecutive entries referring
+			// to the same table.
+			d := h.globalDepth - it.globalDepth
+			it.dirIdx <<= d
+			it.dirOffset <<= d
+			it.globalDepth = h.globalDepth
+		}
+
+		if it.tab == nil {
+			if it.dirIdx >= uintptr(h.dirLen) {
+				it.key = nil
+				it.elem = nil
+				return
+			}
+			idx := (it.dirIdx + it.dirOffset) & uintptr(h.dirLen-1)
+			tab := h.directoryAt(idx)
+			if uintptr(tab.index) != idx {
+				// Only the randomly chosen first entry can be
+				// in the middle of the entries referring to
+				// a table. Start at its first entry instead,
+				// so that all the entries are visited once.
+				it.dirOffset -= idx - uintptr(tab.index)
+			}
+			it.tab = tab
+			it.entryIdx = 0
+		}
+
+		// If tab was replaced by growth, it is not modified anymore,
+		// and each of its keys must be looked up in the current map.
+		tab := it.tab
+		stale := tab.index == -1
+		mask := uintptr(tab.capacity - 1)
+		for ; it.entryIdx < uintptr(tab.capacity); it.entryIdx++ {
+			slot := (it.entryIdx + it.entryOffset) & mask
+			g := tab.group(t, slot/bucketCnt)
+			i := slot % bucketCnt
+			if g.ctrl[i]&ctrlFull == 0 {
+				continue
+			}
+			k, e := g.key(t, i), g.elem(t, i)
+			if stale {
+				var ok bool
+				if k, e, ok = h.currentEntry(t, k, e); !ok {
+					continue
+				}
+			}
+			it.key = k
+			it.elem = e
+			it.entryIdx++
+			return
+		}
+
+		// Skip the directory entries referring to tab, or to
+		// the tables that replaced it.
+		it.dirIdx += 1 << (h.globalDepth - tab.localDepth)
+		it.tab = nil
+	}
+}
+
+// currentEntry returns the current key and elem of the entry with key k
+// and elem e in a group or table replaced by growth, or ok == false if
+// the key has since been deleted.
+func (h *hmap) currentEntry(t *maptype, k, e unsafe.Pointer) (unsafe.Pointer, unsafe.Pointer, bool) {
+	if !t.ReflexiveKey() && !t.Key.Equal(k, k) {
+		// A key not equal to itself, such as NaN, cannot be updated
+		// or deleted (except by clear), so the old entry is current.
+		return k, e, true
+	}
+	return h.getWithKey(t, t.Hasher(k, h.seed), k)
+}
+
+// mapclear deletes all keys from a map.
+func mapclear(t *maptype, h *hmap) {
+	if raceenabled && h != nil {
+		callerpc := getcallerpc()
+		pc := abi.FuncPCABIInternal(mapclear)
+		racewritepc(unsafe.Pointer(h), callerpc, pc)
+	}
+
+	if h == nil || h.count == 0 {
+		return
+	}
+
+	if h.flags&hashWriting != 0 {
+		fatal("concurrent map writes")
+	}
+
+	h.flags ^= hashWriting
+
+	if h.dirLen == 0 {
+		if t.Bucket.PtrBytes != 0 {
+			memclrHasPointers(h.dirPtr, t.Bucket.Size_)
+		} else {
+			memclrNoHeapPointers(h.dirPtr, t.Bucket.Size_)
+		}
+	} else {
+		for i := 0; i < h.dirLen; i++ {
+			if tab := h.directoryAt(uintptr(i)); tab.index == i {
+				tab.clear(t)
+			}
+		}
+	}
+	h.count = 0
+	// Stop existing iterators, see issue #59411.
+	h.clearSeq++
+
+	// Reset the hash seed to make it more difficult for attackers to
+	// repeatedly trigger hash collisions. See issue 25237.
+	h.seed = uintptr(fastrand64())
+
+	if h.flags&hashWriting == 0 {
+		fatal("concurrent map writes")
+	}
+	h.flags &^= hashWriting
+}
+
+// mapclone2 returns a copy of the map src. Keys and elems are copied
+// rather than shared, even if they are stored indirectly.
+func mapclone2(t *maptype, src *hmap) *hmap {
+	dst := makemap(t, src.count, nil)
+	if src.count == 0 {
+		return dst
+	}
+	if src.flags&hashWriting != 0 {
+		fatal("concurrent map clone and map write")
+	}
+	put := func(g *bmap, i uintptr) {
+		k := g.key(t, i)
+		e := dst.putSlot(t, t.Hasher(k, dst.seed), k)
+		typedmemmove(t.Elem, e, g.elem(t, i))
+	}
+	if src.dirLen == 0 {
+		g := (*bmap)(src.dirPtr)
+		for i := uintptr(0); i < bucketCnt; i++ {
+			if g.ctrl[i]&ctrlFull != 0 {
+				put(g, i)
+			}
+		}
+		return dst
+	}
+	for i := 0; i < src.dirLen; i++ {
+		tab := src.directoryAt(uintptr(i))
+		if tab.index != i {
+			continue
+		}
+		for gi := uintptr(0); gi <= tab.groupMask; gi++ {
+			g := tab.group(t, gi)
+			for j := uintptr(0); j < bucketCnt; j++ {
+				if g.ctrl[j]&ctrlFull != 0 {
+					put(g, j)
+				}
+			}
+		}
+	}
+	return dst
+}
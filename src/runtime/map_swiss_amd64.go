@@ -0,0 +1,30 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.swissmap
+
+package runtime
+
+import "runtime/internal/sys"
+
+// On amd64, a bitset has bit i set if slot i matches. This is what the
+// SSE2 instruction PMOVMSKB produces from the result of PCMPEQB, which
+// compares all the control bytes of a group at once. The compiler
+// implements the ctrlGroup match methods with these instructions, so
+// the code here is only used when intrinsics are disabled.
+
+// bitsetGather moves bit 8*i of a word to bit 56+i when multiplied
+// with it.
+const bitsetGather = 0x0102040810204080
+
+// bitsetOf returns the bitset of the bytes of v whose high bit is set.
+// The other bits of v must be zero.
+func bitsetOf(v uint64) bitset {
+	return bitset((v >> 7) * bitsetGather >> 56)
+}
+
+// first returns the slot index of the first match in b, which must be non-zero.
+func (b bitset) first() uintptr {
+	return uintptr(sys.TrailingZeros64(uint64(b)))
+}
@@ -0,0 +1,23 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.swissmap && !amd64
+
+package runtime
+
+import "runtime/internal/sys"
+
+// A bitset has the high bit of byte i set if slot i matches, as
+// produced by the SWAR match methods of ctrlGroup.
+
+// bitsetOf returns the bitset of the bytes of v whose high bit is set.
+// The other bits of v must be zero.
+func bitsetOf(v uint64) bitset {
+	return bitset(v)
+}
+
+// first returns the slot index of the first match in b, which must be non-zero.
+func (b bitset) first() uintptr {
+	return uintptr(sys.TrailingZeros64(uint64(b))) >> 3
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build goexperiment.swissmap
+
+package runtime_test
+
+import (
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestMapInvariants(t *testing.T) {
+	m := map[int]int{}
+	runtime.MapCheckInvariants(m)
+	const N = 10000
+	// Fill a map, growing it through small, single table
+	// and split tables.
+	for i := 0; i < N; i++ {
+		m[i] = i
+		if i%97 == 0 {
+			runtime.MapCheckInvariants(m)
+		}
+	}
+	runtime.MapCheckInvariants(m)
+	if n := runtime.MapTableCount(m); n < 2 {
+		t.Errorf("map with %d entries has %d tables, want several", N, n)
+	}
+	// Delete half of the entries.
+	for i := 0; i < N; i += 2 {
+		delete(m, i)
+	}
+	runtime.MapCheckInvariants(m)
+	// Add new entries to fill in holes.
+	for i := N; i < 3*N/2; i++ {
+		m[i] = i
+	}
+	runtime.MapCheckInvariants(m)
+	for i := 1; i < 3*N/2; i++ {
+		want := i
+		if i < N && i%2 == 0 {
+			want = 0
+		}
+		if got := m[i]; got != want {
+			t.Fatalf("m[%d] = %d, want %d", i, got, want)
+		}
+	}
+	// Delete everything.
+	for i := 0; i < 3*N/2; i++ {
+		delete(m, i)
+	}
+	runtime.MapCheckInvariants(m)
+	if len(m) != 0 {
+		t.Fatalf("len(m) = %d after deleting everything", len(m))
+	}
+	// Refill the emptied tables.
+	for i := 0; i < N; i++ {
+		m[i] = i
+	}
+	runtime.MapCheckInvariants(m)
+	clear(m)
+	runtime.MapCheckInvariants(m)
+}
+
+func TestMapInvariantsHint(t *testing.T) {
+	for _, hint := range []int{0, 1, 8, 9, 100, 1000, 5000} {
+		m := make(map[int]int, hint)
+		for i := 0; i < hint; i++ {
+			m[i] = i
+		}
+		runtime.MapCheckInvariants(m)
+		if hint <= 8 {
+			if n := runtime.MapTableCount(m); n != 0 {
+				t.Errorf("make(map[int]int, %d) has %d tables, want 0", hint, n)
+			}
+		}
+	}
+}
+
+func TestMapIterGrowSplit(t *testing.T) {
+	// Iterate over a map while it grows and splits its tables. Every
+	// entry present at the start and never deleted must be produced
+	// exactly once, and deleted entries must not be produced.
+	const N = 2000
+	for seed := 0; seed < 10; seed++ {
+		m := map[int]int{}
+		for i := 0; i < N; i++ {
+			m[i] = i
+		}
+		seen := map[int]int{}
+		deleted := map[int]bool{}
+		n := 0
+		for k, v := range m {
+			if v != k && v != -k {
+				t.Fatalf("m[%d] = %d during iteration", k, v)
+			}
+			if deleted[k] {
+				t.Fatalf("key %d produced after being deleted", k)
+			}
+			seen[k]++
+			if n < 4*N {
+				// Insert new entries, forcing growth.
+				for j := 0; j < 4; j++ {
+					m[N+n] = N + n
+					n++
+				}
+			}
+			// Delete an entry that may not have been visited yet.
+			delete(m, (k+N/2)%N)
+			deleted[(k+N/2)%N] = true
+			if k < N {
+				// Updates must be visible.
+				if _, ok := m[(k+1)%N]; ok {
+					m[(k+1)%N] = -((k + 1) % N)
+				}
+			}
+		}
+		runtime.MapCheckInvariants(m)
+		for k, c := range seen {
+			if c != 1 {
+				t.Fatalf("key %d produced %d times", k, c)
+			}
+		}
+		for i := 0; i < N; i++ {
+			if _, ok := m[i]; ok && seen[i] == 0 {
+				t.Fatalf("key %d in the map at the start and end of iteration was not produced", i)
+			}
+		}
+	}
+}
+
+func TestMapCtrlMatch(t *testing.T) {
+	for i := 0; i < 10000; i++ {
+		// Build a control word from random empty, deleted and full
+		// control bytes, with h2s likely to collide.
+		var ctrl uint64
+		var full, empty, emptyOrDeleted []uintptr
+		h2 := uintptr(rand.Intn(4))
+		for j := uintptr(0); j < 8; j++ {
+			var c uint64
+			switch rand.Intn(3) {
+			case 0:
+				empty = append(empty, j)
+				emptyOrDeleted = append(emptyOrDeleted, j)
+			case 1:
+				c = 0b0000_0001 // deleted
+				emptyOrDeleted = append(emptyOrDeleted, j)
+			case 2:
+				c = 0x80 | uint64(rand.Intn(4))
+				if c&0x7f == uint64(h2) {
+					full = append(full, j)
+				}
+			}
+			ctrl |= c << (8 * j)
+		}
+		for _, indirect := range []bool{false, true} {
+			gotFull, gotEmpty, gotEmptyOrDeleted := runtime.MapCtrlMatch(ctrl, h2, indirect)
+			if !reflect.DeepEqual(gotFull, full) || !reflect.DeepEqual(gotEmpty, empty) || !reflect.DeepEqual(gotEmptyOrDeleted, emptyOrDeleted) {
+				t.Fatalf("MapCtrlMatch(%#016x, %d, %v) = %v, %v, %v; want %v, %v, %v",
+					ctrl, h2, indirect, gotFull, gotEmpty, gotEmptyOrDeleted, full, empty, emptyOrDeleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "runtime/internal/atomic"
+
+// Default GOMAXPROCS.
+//
+// Unless set by the GOMAXPROCS environment variable, GOMAXPROCS
+// defaults to the number of CPUs, lowered to the CPU bandwidth limit
+// of the cgroup of the process, if any, rounded up and with a minimum
+// of 2. Since the limit of a container may change, sysmon periodically
+// recomputes the default, and has updateMaxProcsG apply it, until
+// GOMAXPROCS is set by runtime.GOMAXPROCS.
+//
+// GODEBUG=containermaxprocs=0 ignores the cgroup limit, and
+// GODEBUG=updatemaxprocs=0 disables the periodic updates.
+
+// maxprocsUpdatePeriod is the period in nanoseconds between checks of
+// the default GOMAXPROCS.
+const maxprocsUpdatePeriod = 1e9
+
+var maxprocs struct {
+	// custom reports whether GOMAXPROCS was set by the GOMAXPROCS
+	// environment variable or runtime.GOMAXPROCS. It is only set
+	// with the world stopped.
+	custom atomic.Bool
+
+	// cgroup reports whether the process has a cgroup with the CPU
+	// controller, which may limit its CPUs.
+	cgroup bool
+
+	// defaultProcs is the last computed default GOMAXPROCS, and
+	// cpuLimit the float64 bits of the CPU limit it was computed
+	// from, or 0 if there is none.
+	defaultProcs atomic.Int32
+	cpuLimit     atomic.Uint64
+
+	// lastUpdate is the time of the last check, owned by sysmon.
+	lastUpdate int64
+}
+
+// defaultGOMAXPROCS returns the default GOMAXPROCS, and records it for
+// runtime/metrics. It must be called from schedinit or sysmon.
+func defaultGOMAXPROCS() int32 {
+	procs := ncpu
+	limit, ok := cgroupCPULimit()
+	if ok {
+		// Round up, since a fractional limit can still use that
+		// many CPUs at once, and keep a minimum of 2 so that
+		// one goroutine cannot block all others.
+		n := int32(2)
+		if limit < float64(procs) {
+			n = max(n, int32(limit))
+			if float64(n) < limit {
+				n++
+			}
+		} else {
+			n = max(n, procs)
+		}
+		procs = min(procs, n)
+	} else {
+		limit = 0
+	}
+	maxprocs.cpuLimit.Store(float64bits(limit))
+	maxprocs.defaultProcs.Store(procs)
+	return procs
+}
+
+// maxprocsInit computes the initial GOMAXPROCS. It is called by
+// schedinit.
+func maxprocsInit() int32 {
+	maxprocs.cgroup = cgroupCPUInit()
+	procs := defaultGOMAXPROCS()
+	if n, ok := atoi32(gogetenv("GOMAXPROCS")); ok && n > 0 {
+		procs = n
+		maxprocs.custom.Store(true)
+	}
+	return procs
+}
+
+type updateMaxProcsGState struct {
+	lock  mutex
+	g     *g
+	idle  atomic.Bool
+	procs int32 // protected by lock
+}
+
+var updateMaxProcsG updateMaxProcsGState
+
+// start the updateMaxProcsG goroutine
+func init() {
+	if maxprocs.cgroup && debug.updatemaxprocs != 0 && !maxprocs.custom.Load() {
+		go updateMaxProcsGoroutine()
+	}
+}
+
+// updateMaxProcsGoroutine applies the default GOMAXPROCS computed by
+// sysmon, which cannot stop the world itself.
+func updateMaxProcsGoroutine() {
+	updateMaxProcsG.g = getg()
+	lockInit(&updateMaxProcsG.lock, lockRankUpdateMaxProcsG)
+	for {
+		lock(&updateMaxProcsG.lock)
+		if updateMaxProcsG.idle.Load() {
+			throw("updateMaxProcsG: phase error")
+		}
+		updateMaxProcsG.idle.Store(true)
+		goparkunlock(&updateMaxProcsG.lock, waitReasonUpdateGOMAXPROCSIdle, traceBlockSystemGoroutine, 1)
+		// This goroutine is explicitly resumed by sysmon.
+
+		lock(&updateMaxProcsG.lock)
+		procs := updateMaxProcsG.procs
+		unlock(&updateMaxProcsG.lock)
+
+		stopTheWorldGC(stwGOMAXPROCS)
+		// GOMAXPROCS may have been called since sysmon checked.
+		if !maxprocs.custom.Load() {
+			// newprocs will be processed by startTheWorld.
+			newprocs = procs
+		}
+		startTheWorldGC()
+	}
+}
+
+// sysmonUpdateGOMAXPROCS checks if the default GOMAXPROCS has changed,
+// and if so wakes updateMaxProcsG to apply it.
+func sysmonUpdateGOMAXPROCS(now int64) {
+	if !maxprocs.cgroup || debug.updatemaxprocs == 0 || maxprocs.custom.Load() || now-maxprocs.lastUpdate < maxprocsUpdatePeriod {
+		return
+	}
+	maxprocs.lastUpdate = now
+	procs := defaultGOMAXPROCS()
+	lock(&sched.lock)
+	cur := gomaxprocs
+	unlock(&sched.lock)
+	if procs == cur || !updateMaxProcsG.idle.Load() {
+		return
+	}
+	lock(&updateMaxProcsG.lock)
+	updateMaxProcsG.idle.Store(false)
+	updateMaxProcsG.procs = procs
+	var list gList
+	list.push(updateMaxProcsG.g)
+	injectglist(&list)
+	unlock(&updateMaxProcsG.lock)
+}
@@ -433,6 +433,18 @@ func initMetrics() {
 					in.sysStats.gcMiscSys + in.sysStats.otherSys
 			},
 		},
+		"/sched/cpu-limit:cpus": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindFloat64
+				out.scalar = maxprocs.cpuLimit.Load()
+			},
+		},
+		"/sched/gomaxprocs/default:threads": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = uint64(maxprocs.defaultProcs.Load())
+			},
+		},
 		"/sched/gomaxprocs:threads": {
 			compute: func(_ *statAggregate, out *metricValue) {
 				out.kind = metricKindUint64
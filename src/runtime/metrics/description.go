@@ -399,6 +399,21 @@ var allDesc = []Description{
 		Description: "All memory mapped by the Go runtime into the current process as read-write. Note that this does not include memory mapped by code called via cgo or via the syscall package. Sum of all metrics in /memory/classes.",
 		Kind:        KindUint64,
 	},
+	{
+		Name: "/sched/cpu-limit:cpus",
+		Description: "The CPU bandwidth limit of the cgroup (container) of the process, " +
+			"from which the default GOMAXPROCS is computed, or 0 if there is none. " +
+			"Currently only available on Linux.",
+		Kind: KindFloat64,
+	},
+	{
+		Name: "/sched/gomaxprocs/default:threads",
+		Description: "The default runtime.GOMAXPROCS setting: the number of CPUs, " +
+			"lowered to /sched/cpu-limit:cpus rounded up with a minimum of 2, if set. " +
+			"/sched/gomaxprocs:threads differs from it if GOMAXPROCS was set by " +
+			"the environment variable or runtime.GOMAXPROCS.",
+		Kind: KindUint64,
+	},
 	{
 		Name:        "/sched/gomaxprocs:threads",
 		Description: "The current runtime.GOMAXPROCS setting, or the number of operating system threads that can execute user-level Go code simultaneously.",
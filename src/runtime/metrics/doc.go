@@ -230,6 +230,10 @@ Below is the full list of supported metrics, ordered lexicographically.
 	/gc/stack/starting-size:bytes
 		The stack size of new goroutines.
 
+	/godebug/non-default-behavior/asynctimerchan:events
+		The number of non-default behaviors executed by the time package
+		due to a non-default GODEBUG=asynctimerchan=... setting.
+
 	/godebug/non-default-behavior/execerrdot:events
 		The number of non-default behaviors executed by the os/exec
 		package due to a non-default GODEBUG=execerrdot=... setting.
@@ -259,6 +263,10 @@ Below is the full list of supported metrics, ordered lexicographically.
 		package due to a non-default GODEBUG=httplaxcontentlength=...
 		setting.
 
+	/godebug/non-default-behavior/httpmuxgo121:events
+		The number of non-default behaviors executed by the net/http
+		package due to a non-default GODEBUG=httpmuxgo121=... setting.
+
 	/godebug/non-default-behavior/installgoroot:events
 		The number of non-default behaviors executed by the go/build
 		package due to a non-default GODEBUG=installgoroot=... setting.
@@ -382,6 +390,17 @@ Below is the full list of supported metrics, ordered lexicographically.
 		by code called via cgo or via the syscall package. Sum of all
 		metrics in /memory/classes.
 
+	/sched/cpu-limit:cpus
+		The CPU bandwidth limit of the cgroup (container) of the
+		process, from which the default GOMAXPROCS is computed, or 0 if
+		there is none. Currently only available on Linux.
+
+	/sched/gomaxprocs/default:threads
+		The default runtime.GOMAXPROCS setting: the number of CPUs,
+		lowered to /sched/cpu-limit:cpus rounded up with a minimum of 2,
+		if set. /sched/gomaxprocs:threads differs from it if GOMAXPROCS
+		was set by the environment variable or runtime.GOMAXPROCS.
+
 	/sched/gomaxprocs:threads
 		The current runtime.GOMAXPROCS setting, or the number of
 		operating system threads that can execute user-level Go code
@@ -314,6 +314,14 @@ func TestReadMetricsConsistency(t *testing.T) {
 			totalScan.want += samples[i].Value.Uint64()
 		case "/gc/scan/total:bytes":
 			totalScan.got = samples[i].Value.Uint64()
+		case "/sched/cpu-limit:cpus":
+			if got := samples[i].Value.Float64(); got < 0 {
+				t.Errorf("negative CPU limit: %v", got)
+			}
+		case "/sched/gomaxprocs/default:threads":
+			if got, max := samples[i].Value.Uint64(), uint64(runtime.NumCPU()); got < 1 || got > max {
+				t.Errorf("default gomaxprocs out of range: got %d, want in [1, %d]", got, max)
+			}
 		case "/sched/gomaxprocs:threads":
 			if got, want := samples[i].Value.Uint64(), uint64(runtime.GOMAXPROCS(-1)); got != want {
 				t.Errorf("gomaxprocs doesn't match runtime.GOMAXPROCS: got %d, want %d", got, want)
@@ -202,6 +202,19 @@ func runfinq() {
 			for i := fb.cnt; i > 0; i-- {
 				f := &fb.fin[i-1]
 
+				if f.fint == nil {
+					// A cleanup queued by sweep, which
+					// takes no arguments.
+					fn := *(*func())(unsafe.Pointer(&f.fn))
+					fingStatus.Or(fingRunningFinalizer)
+					fn()
+					fingStatus.And(^fingRunningFinalizer)
+
+					f.fn = nil
+					atomic.Store(&fb.cnt, i-1)
+					continue
+				}
+
 				var regs abi.RegArgs
 				// The args may be passed in registers or on stack. Even for
 				// the register case, we still need the spill slots.
@@ -221,9 +234,6 @@ func runfinq() {
 					framecap = framesz
 				}
 
-				if f.fint == nil {
-					throw("missing type in runfinq")
-				}
 				r := frame
 				if argRegs > 0 {
 					r = unsafe.Pointer(&regs.Ints)
@@ -309,6 +319,9 @@ func isGoPointerWithoutSpan(p unsafe.Pointer) bool {
 //
 // SetFinalizer(obj, nil) clears any finalizer associated with obj.
 //
+// New Go code should consider using AddCleanup instead, which is much
+// less error-prone than SetFinalizer.
+//
 // The argument obj must be a pointer to an object allocated by calling
 // new, by taking the address of a composite literal, or by taking the
 // address of a local variable.
@@ -351,10 +351,10 @@ type workType struct {
 	//
 	// nStackRoots == len(stackRoots), but we have nStackRoots for
 	// consistency.
-	nDataRoots, nBSSRoots, nSpanRoots, nStackRoots int
+	nDataRoots, nBSSRoots, nSpanRoots, nProfLabelRoots, nStackRoots int
 
 	// Base indexes of each root type. Set by gcMarkRootPrepare.
-	baseData, baseBSS, baseSpans, baseStacks, baseEnd uint32
+	baseData, baseBSS, baseSpans, baseProfLabels, baseStacks, baseEnd uint32
 
 	// stackRoots is a snapshot of all of the Gs that existed
 	// before the beginning of concurrent marking. The backing
@@ -391,6 +391,22 @@ type workType struct {
 	// explicit user call.
 	userForced bool
 
+	// goroutineLeak is the state of goroutine leak detection. See
+	// mgcleak.go.
+	goroutineLeak struct {
+		// pending requests leak detection in the next GC cycle.
+		pending atomic.Bool
+
+		// enabled indicates that the current cycle is detecting
+		// leaked goroutines. It is set during sweep termination
+		// and cleared once the leaked goroutines are known.
+		enabled bool
+
+		// done is the last GC cycle that detected leaked
+		// goroutines.
+		done atomic.Uint32
+	}
+
 	// initialHeapLive is the value of gcController.heapLive at the
 	// beginning of this GC cycle.
 	initialHeapLive uint64
@@ -728,6 +744,10 @@ func gcStart(trigger gcTrigger) {
 
 	gcBgMarkPrepare() // Must happen before assist enable.
 	gcMarkRootPrepare()
+	if work.goroutineLeak.pending.Load() {
+		work.goroutineLeak.pending.Store(false)
+		gcLeakPrepare()
+	}
 
 	// Mark all active tinyalloc blocks. Since we're
 	// allocating from these, they need to be black like
@@ -867,6 +887,16 @@ top:
 		goto top
 	}
 
+	if work.goroutineLeak.enabled {
+		// Marking is out of work, but goroutine leak detection
+		// has stacks left to scan. Scan those that may be woken
+		// up, or all of them once the leaked goroutines are
+		// known, and keep going.
+		semrelease(&worldsema)
+		gcLeakScan()
+		goto top
+	}
+
 	// There was no global work, no local work, and no Ps
 	// communicated work since we took markDoneSema. Therefore
 	// there are no grey objects and no more objects can be
@@ -1209,6 +1239,15 @@ func gcMarkTermination() {
 		mheap_.enableMetadataHugePages()
 	}
 
+	// Let the unique package know that this cycle may have
+	// cleared weak pointers in its maps.
+	if uniqueMapCleanup != nil {
+		select {
+		case uniqueMapCleanup <- struct{}{}:
+		default:
+		}
+	}
+
 	semrelease(&worldsema)
 	semrelease(&gcsema)
 	// Careful: another GC cycle may start now.
@@ -1478,7 +1517,7 @@ func gcMark(startTime int64) {
 
 	// Check that there's no marking work remaining.
 	if work.full != 0 || work.markrootNext < work.markrootJobs {
-		print("runtime: full=", hex(work.full), " next=", work.markrootNext, " jobs=", work.markrootJobs, " nDataRoots=", work.nDataRoots, " nBSSRoots=", work.nBSSRoots, " nSpanRoots=", work.nSpanRoots, " nStackRoots=", work.nStackRoots, "\n")
+		print("runtime: full=", hex(work.full), " next=", work.markrootNext, " jobs=", work.markrootJobs, " nDataRoots=", work.nDataRoots, " nBSSRoots=", work.nBSSRoots, " nSpanRoots=", work.nSpanRoots, " nProfLabelRoots=", work.nProfLabelRoots, " nStackRoots=", work.nStackRoots, "\n")
 		panic("non-empty mark queue after concurrent mark")
 	}
 
@@ -1661,6 +1700,23 @@ func sync_runtime_registerPoolCleanup(f func()) {
 	poolcleanup = f
 }
 
+// uniqueMapCleanup is notified at the end of each GC cycle
+// so that the unique package can drop map entries whose
+// weak pointers were cleared.
+var uniqueMapCleanup chan struct{}
+
+//go:linkname unique_runtime_registerUniqueMapCleanup unique.runtime_registerUniqueMapCleanup
+func unique_runtime_registerUniqueMapCleanup(f func()) {
+	// Start the goroutine in the runtime so it's counted as a system goroutine.
+	uniqueMapCleanup = make(chan struct{}, 1)
+	go func(cleanup func()) {
+		for {
+			<-uniqueMapCleanup
+			cleanup()
+		}
+	}(f)
+}
+
 //go:linkname boring_registerCache crypto/internal/boring/bcache.registerCache
 func boring_registerCache(p unsafe.Pointer) {
 	boringCaches = append(boringCaches, p)
@@ -1782,7 +1838,7 @@ func gcTestIsReachable(ptrs ...unsafe.Pointer) (mask uint64) {
 		s := (*specialReachable)(mheap_.specialReachableAlloc.alloc())
 		unlock(&mheap_.speciallock)
 		s.special.kind = _KindSpecialReachable
-		if !addspecial(p, &s.special) {
+		if !addspecial(p, &s.special, false) {
 			throw("already have a reachable special (duplicate pointer?)")
 		}
 		specials[i] = s
@@ -0,0 +1,282 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Goroutine leak detection.
+//
+// A goroutine leaks when it blocks on a channel or a semaphore (such as
+// the one of a sync.Mutex or a sync.WaitGroup) that no other goroutine
+// can reach anymore: nothing can ever wake it up, and it holds on to its
+// stack and everything reachable from it forever.
+//
+// The GC finds such goroutines on request. During sweep termination,
+// gcLeakPrepare picks the candidates, the user goroutines blocked on a
+// channel or semaphore, and hides what they are blocked on from the
+// GC: markroot skips their stacks, and their g, which references the
+// channels they are blocked on through g.waiting, is marked without
+// being queued for scanning. Likewise for the sudogs in the semaphore
+// table, which reference the addresses goroutines are blocked on in
+// semacquire.
+//
+// Marking then proceeds from the remaining roots. Each time it runs out
+// of work, gcMarkDone calls gcLeakScan, which scans the candidates that
+// may still be woken up: those blocked on a channel or address that has
+// been marked, and those that have been woken up since. Scanning them
+// may mark what other candidates are blocked on. Once no more
+// candidates can be woken up, the remaining ones have leaked. gcLeakScan
+// records them in g.leakState and scans them too, along with the
+// semaphore table, since they still use their memory, and marking
+// completes as usual.
+//
+// Detection is conservative. A goroutine is only reported if what it is
+// blocked on is reachable from leaked goroutines alone, but some leaked
+// goroutines may be missed. For example, mutators that operate on a
+// channel during the cycle shade the sudogs of the goroutines blocked
+// on it, and through them the channel, and a small object such as a
+// sync.Mutex may share its memory block with reachable objects.
+
+package runtime
+
+import (
+	"runtime/internal/atomic"
+	"unsafe"
+)
+
+// Values for g.leakState.
+const (
+	leakNone      uint8 = iota // not a candidate for leak detection
+	leakCandidate              // blocked; stack and g not scanned yet
+	leakReachable              // blocked on a marked address; not scanned yet
+	leakLeaked                 // leaked as of the last detection
+)
+
+// goroutineLeakGC runs a GC cycle that detects leaked goroutines, and
+// returns once they are known.
+//
+//go:linkname goroutineLeakGC runtime/pprof.runtime_goroutineLeakGC
+func goroutineLeakGC() {
+	// See GC for how cycles are started. Another cycle may start
+	// before ours and miss the request, in which case the request
+	// is served by the next one.
+	for {
+		n := work.cycles.Load()
+		gcWaitOnMark(n)
+		work.goroutineLeak.pending.Store(true)
+		gcStart(gcTrigger{kind: gcTriggerCycle, n: n + 1})
+		gcWaitOnMark(n + 1)
+		if work.goroutineLeak.done.Load() > n {
+			return
+		}
+	}
+}
+
+// gcLeakPrepare starts goroutine leak detection in the current GC cycle.
+//
+// The world must be stopped, after gcMarkRootPrepare.
+func gcLeakPrepare() {
+	assertWorldStopped()
+
+	work.goroutineLeak.enabled = true
+	forEachG(func(gp *g) {
+		gp.leakState = leakNone
+		gp.leakWoken = false
+		if isLeakCandidate(gp) && gcLeakHide(uintptr(unsafe.Pointer(gp))) {
+			gp.leakState = leakCandidate
+		}
+	})
+	for i := range semtable {
+		gcLeakForEachSemaWaiter(semtable[i].root.treap, func(s *sudog) {
+			gcLeakHide(uintptr(unsafe.Pointer(s)))
+		})
+	}
+}
+
+// isLeakCandidate reports whether gp is a user goroutine blocked on a
+// channel or a semaphore.
+func isLeakCandidate(gp *g) bool {
+	if readgstatus(gp) != _Gwaiting || isSystemGoroutine(gp, false) {
+		return false
+	}
+	switch gp.waitreason {
+	case waitReasonChanReceive, waitReasonChanSend, waitReasonSelect,
+		waitReasonSynctestChanReceive, waitReasonSynctestChanSend, waitReasonSynctestSelect,
+		waitReasonChanReceiveNilChan, waitReasonChanSendNilChan, waitReasonSelectNoCases,
+		waitReasonSemacquire, waitReasonSyncMutexLock, waitReasonSyncRWMutexRLock, waitReasonSyncRWMutexLock,
+		waitReasonSyncWaitGroupWait:
+		return true
+	}
+	return false
+}
+
+// gcLeakHide marks the heap object at p without queuing it for
+// scanning, so that the objects it references are only marked once it
+// is queued with gcw.put. It reports whether it marked the object.
+func gcLeakHide(p uintptr) bool {
+	s := spanOfHeap(p)
+	if s == nil {
+		return false
+	}
+	mbits := s.markBitsForIndex(s.objIndex(p))
+	if mbits.isMarked() {
+		return false
+	}
+	mbits.setMarked()
+	arena, pageIdx, pageMask := pageIndexOf(s.base())
+	if arena.pageMarks[pageIdx]&pageMask == 0 {
+		atomic.Or8(&arena.pageMarks[pageIdx], pageMask)
+	}
+	return true
+}
+
+// gcLeakReveal queues the object at p, which gcLeakHide may have
+// marked, for scanning.
+func gcLeakReveal(p uintptr, gcw *gcWork) {
+	obj, span, objIndex := findObject(p, 0, 0)
+	if obj == 0 {
+		return
+	}
+	if span.markBitsForIndex(objIndex).isMarked() {
+		gcw.put(obj)
+	} else {
+		greyobject(obj, 0, 0, span, gcw, objIndex)
+	}
+}
+
+// gcLeakMarked reports whether the object containing p may be
+// reachable: it is marked, or it is not in the heap.
+func gcLeakMarked(p unsafe.Pointer) bool {
+	s := spanOfHeap(uintptr(p))
+	if s == nil {
+		return true
+	}
+	return s.markBitsForIndex(s.objIndex(uintptr(p))).isMarked()
+}
+
+// gcLeakScan is called by gcMarkDone when marking runs out of work
+// during leak detection. It scans the candidates that may still be
+// woken up. If there are none, the remaining candidates have leaked:
+// it records and scans them, and ends detection.
+//
+// The caller must hold work.markDoneSema, and must not hold worldsema.
+func gcLeakScan() {
+	systemstack(func() {
+		// Mark the user stack as preemptible, as in gcMarkDone,
+		// so that stack scans can proceed.
+		userG := getg().m.curg
+		casGToWaiting(userG, _Grunning, waitReasonGCMarkTermination)
+
+		// Goroutines blocked in semacquire are not linked from
+		// their g. Find those blocked on a marked address.
+		gcw := &getg().m.p.ptr().gcw
+		for i := range semtable {
+			root := &semtable[i].root
+			lockWithRank(&root.lock, lockRankRoot)
+			gcLeakForEachSemaWaiter(root.treap, func(s *sudog) {
+				if gp := s.g; gp != nil && gp.leakState == leakCandidate && gcLeakMarked(s.elem) {
+					gp.leakState = leakReachable
+				}
+			})
+			unlock(&root.lock)
+		}
+
+		scanned := false
+		for _, gp := range work.stackRoots {
+			if gp.leakState != leakNone && gcLeakScanG(gp, gcw, false) {
+				scanned = true
+			}
+		}
+		if !scanned {
+			for _, gp := range work.stackRoots {
+				if gp.leakState != leakNone {
+					gcLeakScanG(gp, gcw, true)
+				}
+			}
+			for i := range semtable {
+				root := &semtable[i].root
+				lockWithRank(&root.lock, lockRankRoot)
+				gcLeakForEachSemaWaiter(root.treap, func(s *sudog) {
+					gcLeakReveal(uintptr(unsafe.Pointer(s)), gcw)
+				})
+				unlock(&root.lock)
+			}
+			work.goroutineLeak.enabled = false
+			work.goroutineLeak.done.Store(work.cycles.Load())
+		}
+		// Make the new work visible to gcMarkDone.
+		gcw.dispose()
+
+		casgstatus(userG, _Gwaiting, _Grunning)
+	})
+}
+
+// gcLeakForEachSemaWaiter calls f for each sudog in the semaphore treap
+// rooted at s. The world must be stopped, or the treap's root locked.
+func gcLeakForEachSemaWaiter(s *sudog, f func(*sudog)) {
+	if s == nil {
+		return
+	}
+	for t := s; t != nil; t = t.waitlink {
+		f(t)
+	}
+	gcLeakForEachSemaWaiter(s.prev, f)
+	gcLeakForEachSemaWaiter(s.next, f)
+}
+
+// gcLeakScanG scans the stack of candidate gp and queues its g for
+// scanning, unless gp is still blocked on unmarked objects. If leaked
+// is true, it scans gp regardless and records it as leaked if it is
+// still blocked. It reports whether gp may be woken up.
+//
+// Must run on the system stack, with the user goroutine preemptible.
+//
+//go:systemstack
+func gcLeakScanG(gp *g, gcw *gcWork, leaked bool) bool {
+	stopped := suspendG(gp)
+	reachable := stopped.dead || !gcLeakBlocked(gp)
+	if reachable || leaked {
+		if stopped.dead {
+			gp.gcscandone = true
+		} else {
+			if gp.gcscandone {
+				throw("g already scanned")
+			}
+			gcController.stackScanWork.Add(scanstack(gp, gcw))
+			gp.gcscandone = true
+		}
+		gcLeakReveal(uintptr(unsafe.Pointer(gp)), gcw)
+		gp.leakState = leakNone
+		if !reachable {
+			gp.leakState = leakLeaked
+		}
+	}
+	if !stopped.dead {
+		resumeG(stopped)
+	}
+	return reachable
+}
+
+// gcLeakBlocked reports whether candidate gp, which must be suspended,
+// is still blocked on objects that have not been marked.
+func gcLeakBlocked(gp *g) bool {
+	if gp.leakState != leakCandidate || gp.leakWoken || readgstatus(gp)&^_Gscan != _Gwaiting {
+		return false
+	}
+	switch gp.waitreason {
+	case waitReasonChanReceive, waitReasonChanSend, waitReasonSelect,
+		waitReasonSynctestChanReceive, waitReasonSynctestChanSend, waitReasonSynctestSelect:
+		for sg := gp.waiting; sg != nil; sg = sg.waitlink {
+			if gcLeakMarked(unsafe.Pointer(sg.c)) {
+				return false
+			}
+		}
+		return true
+	case waitReasonChanReceiveNilChan, waitReasonChanSendNilChan, waitReasonSelectNoCases:
+		// Blocked forever.
+		return true
+	case waitReasonSemacquire, waitReasonSyncMutexLock, waitReasonSyncRWMutexRLock, waitReasonSyncRWMutexLock,
+		waitReasonSyncWaitGroupWait:
+		// Checked by gcLeakScan.
+		return true
+	}
+	return false
+}
@@ -96,6 +96,13 @@ func gcMarkRootPrepare() {
 	mheap_.markArenas = mheap_.allArenas[:len(mheap_.allArenas):len(mheap_.allArenas)]
 	work.nSpanRoots = len(mheap_.markArenas) * (pagesPerArena / pagesPerSpanRoot)
 
+	// Scan the profiler labels recorded in profiling buckets.
+	//
+	// Break up the work into ranges of the bucket hash table.
+	// Buckets recording labels after this point are dealt with
+	// in markrootProfLabels.
+	work.nProfLabelRoots = profLabelsRoots()
+
 	// Scan stacks.
 	//
 	// Gs may be created after this point, but it's okay that we
@@ -106,13 +113,14 @@ func gcMarkRootPrepare() {
 	work.nStackRoots = len(work.stackRoots)
 
 	work.markrootNext = 0
-	work.markrootJobs = uint32(fixedRootCount + work.nDataRoots + work.nBSSRoots + work.nSpanRoots + work.nStackRoots)
+	work.markrootJobs = uint32(fixedRootCount + work.nDataRoots + work.nBSSRoots + work.nSpanRoots + work.nProfLabelRoots + work.nStackRoots)
 
 	// Calculate base indexes of each root type
 	work.baseData = uint32(fixedRootCount)
 	work.baseBSS = work.baseData + uint32(work.nDataRoots)
 	work.baseSpans = work.baseBSS + uint32(work.nBSSRoots)
-	work.baseStacks = work.baseSpans + uint32(work.nSpanRoots)
+	work.baseProfLabels = work.baseSpans + uint32(work.nSpanRoots)
+	work.baseStacks = work.baseProfLabels + uint32(work.nProfLabelRoots)
 	work.baseEnd = work.baseStacks + uint32(work.nStackRoots)
 }
 
@@ -188,10 +196,13 @@ func markroot(gcw *gcWork, i uint32, flushBgCredit bool) int64 {
 		// stackfree.
 		systemstack(markrootFreeGStacks)
 
-	case work.baseSpans <= i && i < work.baseStacks:
+	case work.baseSpans <= i && i < work.baseProfLabels:
 		// mark mspan.specials
 		markrootSpans(gcw, int(i-work.baseSpans))
 
+	case work.baseProfLabels <= i && i < work.baseStacks:
+		markrootProfLabels(gcw, int(i-work.baseProfLabels))
+
 	default:
 		// the rest is scanning goroutine stacks
 		workCounter = &gcController.stackScanWork
@@ -201,6 +212,11 @@ func markroot(gcw *gcWork, i uint32, flushBgCredit bool) int64 {
 			throw("markroot: bad index")
 		}
 		gp := work.stackRoots[i-work.baseStacks]
+		if work.goroutineLeak.enabled && gp.leakState != leakNone {
+			// Goroutine leak detection scans gp later,
+			// once its reachability is known.
+			break
+		}
 
 		// remember when we've first observed the G blocked
 		// needed only to output in traceback
@@ -330,6 +346,13 @@ func markrootSpans(gcw *gcWork, shard int) {
 	// 2) Finalizer specials (which are not in the garbage
 	// collected heap) are roots. In practice, this means the fn
 	// field must be scanned.
+	//
+	// Objects with weak handles have only one invariant related
+	// to this function: weak handle specials (which are not in the
+	// garbage collected heap) are roots. In practice, this means
+	// the handle field must be scanned. Note that the value the
+	// handle pointer referenced does *not* need to be scanned,
+	// because the value is the weakly-referenced object itself.
 	sg := mheap_.sweepgen
 
 	// Find the arena and page index into that arena for this shard.
@@ -375,24 +398,33 @@ func markrootSpans(gcw *gcWork, shard int) {
 			// removed from the list while we're traversing it.
 			lock(&s.speciallock)
 			for sp := s.specials; sp != nil; sp = sp.next {
-				if sp.kind != _KindSpecialFinalizer {
-					continue
-				}
-				// don't mark finalized object, but scan it so we
-				// retain everything it points to.
-				spf := (*specialfinalizer)(unsafe.Pointer(sp))
-				// A finalizer can be set for an inner byte of an object, find object beginning.
-				p := s.base() + uintptr(spf.special.offset)/s.elemsize*s.elemsize
-
-				// Mark everything that can be reached from
-				// the object (but *not* the object itself or
-				// we'll never collect it).
-				if !s.spanclass.noscan() {
-					scanobject(p, gcw)
-				}
+				switch sp.kind {
+				case _KindSpecialFinalizer:
+					// don't mark finalized object, but scan it so we
+					// retain everything it points to.
+					spf := (*specialfinalizer)(unsafe.Pointer(sp))
+					// A finalizer can be set for an inner byte of an object, find object beginning.
+					p := s.base() + uintptr(spf.special.offset)/s.elemsize*s.elemsize
+
+					// Mark everything that can be reached from
+					// the object (but *not* the object itself or
+					// we'll never collect it).
+					if !s.spanclass.noscan() {
+						scanobject(p, gcw)
+					}
 
-				// The special itself is a root.
-				scanblock(uintptr(unsafe.Pointer(&spf.fn)), goarch.PtrSize, &oneptrmask[0], gcw, nil)
+					// The special itself is a root.
+					scanblock(uintptr(unsafe.Pointer(&spf.fn)), goarch.PtrSize, &oneptrmask[0], gcw, nil)
+				case _KindSpecialWeakHandle:
+					// The special itself is a root.
+					spw := (*specialWeakHandle)(unsafe.Pointer(sp))
+					scanblock(uintptr(unsafe.Pointer(&spw.handle)), goarch.PtrSize, &oneptrmask[0], gcw, nil)
+				case _KindSpecialCleanup:
+					// The cleanup is a root, but unlike a
+					// finalizer, the object is not.
+					spc := (*specialCleanup)(unsafe.Pointer(sp))
+					scanblock(uintptr(unsafe.Pointer(&spc.fn)), goarch.PtrSize, &oneptrmask[0], gcw, nil)
+				}
 			}
 			unlock(&s.speciallock)
 		}
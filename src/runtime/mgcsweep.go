@@ -566,12 +566,15 @@ func (sl *sweepLocked) sweep(preserve bool) bool {
 				}
 			}
 			// Pass 2: queue all finalizers _or_ handle profile record.
+			// Weak handles are cleared even if the object is kept
+			// alive for a finalizer, so that weak pointers become nil
+			// before any finalizer can resurrect the object.
 			for siter.valid() && uintptr(siter.s.offset) < endOffset {
 				// Find the exact byte for which the special was setup
 				// (as opposed to object beginning).
 				special := siter.s
 				p := s.base() + uintptr(special.offset)
-				if special.kind == _KindSpecialFinalizer || !hasFin {
+				if special.kind == _KindSpecialFinalizer || special.kind == _KindSpecialWeakHandle || !hasFin {
 					siter.unlinkAndNext()
 					freeSpecial(special, unsafe.Pointer(p), size)
 				} else {
@@ -41,7 +41,7 @@ const ranks = `
 # Sysmon
 NONE
 < sysmon
-< scavenge, forcegc;
+< scavenge, forcegc, updateMaxProcsG;
 
 # Defer
 NONE < defer;
@@ -52,22 +52,26 @@ NONE <
   assistQueue,
   sweep;
 
+NONE < timerSend;
+
 # Scheduler, timers, netpoll
 NONE < pollDesc, cpuprof;
+scavenge, sweep, timerSend < hchan;
 assistQueue,
   cpuprof,
   forcegc,
+  hchan,
   pollDesc, # pollDesc can interact with timers, which can lock sched.
   scavenge,
   sweep,
-  sweepWaiters
+  sweepWaiters,
+  updateMaxProcsG
 < sched;
 sched < allg, allp;
-allp < timers;
-timers < netpollInit;
+hchan, pollDesc < timers;
+timers, timerSend < timer < netpollInit;
 
 # Channels
-scavenge, sweep < hchan;
 NONE < notifyList;
 hchan, notifyList < sudog;
 
@@ -83,6 +87,9 @@ NONE
 < itab
 < reflectOffs;
 
+# Synctest
+hchan, root, timers, timer, notifyList, reflectOffs < synctest;
+
 # User arena state
 NONE < userArenaState;
 
@@ -99,10 +106,11 @@ traceBuf < traceStrings;
 
 # Malloc
 allg,
+  allp,
   hchan,
   notifyList,
   reflectOffs,
-  timers,
+  timer,
   traceStrings,
   userArenaState
 # Above MALLOC are things that can allocate memory.
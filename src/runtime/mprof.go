@@ -9,6 +9,7 @@ package runtime
 
 import (
 	"internal/abi"
+	"internal/goarch"
 	"runtime/internal/atomic"
 	"runtime/internal/sys"
 	"unsafe"
@@ -28,7 +29,9 @@ var (
 )
 
 // All memory allocations are local and do not escape outside of the profiler.
-// The profiler is forbidden from referring to garbage-collected memory.
+// The profiler is forbidden from referring to garbage-collected memory,
+// except for the profiler labels recorded in buckets, which the garbage
+// collector scans as roots; see markrootProfLabels.
 
 const (
 	// profile types
@@ -60,7 +63,8 @@ type bucketType int
 // None of the fields in this bucket header are modified after
 // creation, including its next and allnext links.
 //
-// No heap pointers.
+// No heap pointers, except for labels, which markrootProfLabels
+// keeps alive.
 type bucket struct {
 	_       sys.NotInHeap
 	next    *bucket
@@ -69,6 +73,55 @@ type bucket struct {
 	hash    uintptr
 	size    uintptr
 	nstk    uintptr
+	labels  unsafe.Pointer // *profLabelMap of the goroutine, or nil; see runtime_setProfLabel
+}
+
+// profLabel and profLabelMap mirror the layout of runtime/pprof.label
+// and runtime/pprof.labelMap, the profiler labels set by runtime/pprof.
+// The labels are sorted by key, and keys are unique, so equal label
+// sets have equal lists. TestProfLabelLayout checks that the layouts
+// match.
+type profLabel struct {
+	key   string
+	value string
+}
+
+type profLabelMap struct {
+	list []profLabel
+}
+
+// profLabelsHash mixes the contents of the profiler labels into h.
+func profLabelsHash(labels unsafe.Pointer, h uintptr) uintptr {
+	if labels == nil {
+		return h
+	}
+	for i := range (*profLabelMap)(labels).list {
+		l := &(*profLabelMap)(labels).list[i]
+		h = strhash(unsafe.Pointer(&l.key), h)
+		h = strhash(unsafe.Pointer(&l.value), h)
+	}
+	return h
+}
+
+// profLabelsEqual reports whether the profiler labels x and y
+// hold the same labels.
+func profLabelsEqual(x, y unsafe.Pointer) bool {
+	if x == y {
+		return true
+	}
+	if x == nil || y == nil {
+		return false
+	}
+	xl, yl := (*profLabelMap)(x).list, (*profLabelMap)(y).list
+	if len(xl) != len(yl) {
+		return false
+	}
+	for i := range xl {
+		if xl[i] != yl[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // A memRecord is the bucket data for a bucket of type memProfile,
@@ -255,8 +308,10 @@ func (b *bucket) bp() *blockRecord {
 	return (*blockRecord)(data)
 }
 
-// Return the bucket for stk[0:nstk], allocating new bucket if needed.
-func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket {
+// Return the bucket for stk[0:nstk] and labels, allocating new bucket if needed.
+// Buckets are keyed by the contents of labels, so that the goroutines of
+// separate pprof.Do calls with equal labels share buckets.
+func stkbucket(typ bucketType, size uintptr, stk []uintptr, labels unsafe.Pointer, alloc bool) *bucket {
 	bh := (*buckhashArray)(buckhash.Load())
 	if bh == nil {
 		lock(&profInsertLock)
@@ -283,6 +338,10 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	h += size
 	h += h << 10
 	h ^= h >> 6
+	// hash in labels
+	h = profLabelsHash(labels, h)
+	h += h << 10
+	h ^= h >> 6
 	// finalize
 	h += h << 3
 	h ^= h >> 11
@@ -290,7 +349,7 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	i := int(h % buckHashSize)
 	// first check optimistically, without the lock
 	for b := (*bucket)(bh[i].Load()); b != nil; b = b.next {
-		if b.typ == typ && b.hash == h && b.size == size && eqslice(b.stk(), stk) {
+		if b.typ == typ && b.hash == h && b.size == size && eqslice(b.stk(), stk) && profLabelsEqual(b.labels, labels) {
 			return b
 		}
 	}
@@ -302,7 +361,7 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	lock(&profInsertLock)
 	// check again under the insertion lock
 	for b := (*bucket)(bh[i].Load()); b != nil; b = b.next {
-		if b.typ == typ && b.hash == h && b.size == size && eqslice(b.stk(), stk) {
+		if b.typ == typ && b.hash == h && b.size == size && eqslice(b.stk(), stk) && profLabelsEqual(b.labels, labels) {
 			unlock(&profInsertLock)
 			return b
 		}
@@ -313,6 +372,10 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	copy(b.stk(), stk)
 	b.hash = h
 	b.size = size
+	b.labels = labels
+	if labels != nil {
+		profLabelsRecorded.Store(true)
+	}
 
 	var allnext *atomic.UnsafePointer
 	if typ == memProfile {
@@ -333,6 +396,46 @@ func stkbucket(typ bucketType, size uintptr, stk []uintptr, alloc bool) *bucket
 	return b
 }
 
+// profLabelsRecorded is set once a bucket records profiler labels.
+// Until then, the garbage collector has no labels to scan in buckets.
+var profLabelsRecorded atomic.Bool
+
+// profLabelsRootSlots is the number of buckhash slots whose buckets
+// are scanned by one profiler labels root job.
+const profLabelsRootSlots = 4096
+
+// profLabelsRoots returns the number of profiler labels root jobs.
+func profLabelsRoots() int {
+	if !profLabelsRecorded.Load() {
+		return 0
+	}
+	return (buckHashSize + profLabelsRootSlots - 1) / profLabelsRootSlots
+}
+
+// markrootProfLabels marks the profiler labels recorded in the buckets
+// of shard shard of buckhash. Buckets are not in the heap and are never
+// freed, so this keeps one copy of each distinct label set seen with a
+// sampled event alive.
+//
+// Buckets added after this scan record labels that are reachable from
+// the goroutine that set them until it changes its labels, which shades
+// them, so they survive the current cycle.
+func markrootProfLabels(gcw *gcWork, shard int) {
+	bh := (*buckhashArray)(buckhash.Load())
+	if bh == nil {
+		return
+	}
+	start := shard * profLabelsRootSlots
+	end := min(start+profLabelsRootSlots, buckHashSize)
+	for i := start; i < end; i++ {
+		for b := (*bucket)(bh[i].Load()); b != nil; b = b.next {
+			if b.labels != nil {
+				scanblock(uintptr(unsafe.Pointer(&b.labels)), goarch.PtrSize, &oneptrmask[0], gcw, nil)
+			}
+		}
+	}
+}
+
 func eqslice(x, y []uintptr) bool {
 	if len(x) != len(y) {
 		return false
@@ -424,7 +527,7 @@ func mProf_Malloc(p unsafe.Pointer, size uintptr) {
 
 	index := (mProfCycle.read() + 2) % uint32(len(memRecord{}.future))
 
-	b := stkbucket(memProfile, size, stk[:nstk], true)
+	b := stkbucket(memProfile, size, stk[:nstk], curgLabels(), true)
 	mp := b.mp()
 	mpc := &mp.future[index]
 
@@ -442,6 +545,15 @@ func mProf_Malloc(p unsafe.Pointer, size uintptr) {
 	})
 }
 
+// curgLabels returns the profiler labels of the user goroutine running
+// on the current M, to record with a profiling event.
+func curgLabels() unsafe.Pointer {
+	if gp := getg().m.curg; gp != nil {
+		return gp.labels
+	}
+	return nil
+}
+
 // Called when freeing a profiled block.
 func mProf_Free(b *bucket, size uintptr) {
 	index := (mProfCycle.read() + 1) % uint32(len(memRecord{}.future))
@@ -509,7 +621,7 @@ func saveblockevent(cycles, rate int64, skip int, which bucketType) {
 	} else {
 		nstk = gcallers(gp.m.curg, skip, stk[:])
 	}
-	b := stkbucket(which, 0, stk[:nstk], true)
+	b := stkbucket(which, 0, stk[:nstk], curgLabels(), true)
 	bp := b.bp()
 
 	lock(&profBlockLock)
@@ -595,9 +707,9 @@ func (r *StackRecord) Stack() []uintptr {
 // at the beginning of main).
 var MemProfileRate int = 512 * 1024
 
-// disableMemoryProfiling is set by the linker if runtime.MemProfile
-// is not used and the link type guarantees nobody else could use it
-// elsewhere.
+// disableMemoryProfiling is set by the linker if memProfileWithLabels,
+// which MemProfile and runtime/pprof use, is not used and the link type
+// guarantees nobody else could use it elsewhere.
 var disableMemoryProfiling bool
 
 // A MemProfileRecord describes the live objects allocated
@@ -645,10 +757,31 @@ func (r *MemProfileRecord) Stack() []uintptr {
 // collector performs sweeping, the profile only accounts for allocations
 // that have had a chance to be freed by the garbage collector.
 //
+// The profile may have several records with the same stack, for
+// allocations made by goroutines with different profiler labels
+// (see runtime/pprof.Do).
+//
 // Most clients should use the runtime/pprof package or
 // the testing package's -test.memprofile flag instead
 // of calling MemProfile directly.
 func MemProfile(p []MemProfileRecord, inuseZero bool) (n int, ok bool) {
+	return memProfileWithLabels(p, nil, inuseZero)
+}
+
+//go:linkname runtime_memProfileWithLabels runtime/pprof.runtime_memProfileWithLabels
+func runtime_memProfileWithLabels(p []MemProfileRecord, labels []unsafe.Pointer, inuseZero bool) (n int, ok bool) {
+	return memProfileWithLabels(p, labels, inuseZero)
+}
+
+// labels may be nil. If labels is non-nil, it must have the same length as p.
+//
+// The linker disables memory profiling if this function is unreachable;
+// see disableMemoryProfiling.
+func memProfileWithLabels(p []MemProfileRecord, labels []unsafe.Pointer, inuseZero bool) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
+
 	cycle := mProfCycle.read()
 	// If we're between mProf_NextCycle and mProf_Flush, take care
 	// of flushing to the active profile so we only have to look
@@ -695,6 +828,9 @@ func MemProfile(p []MemProfileRecord, inuseZero bool) (n int, ok bool) {
 			mp := b.mp()
 			if inuseZero || mp.active.alloc_bytes != mp.active.free_bytes {
 				record(&p[idx], b)
+				if labels != nil {
+					labels[idx] = b.labels
+				}
 				idx++
 			}
 		}
@@ -747,10 +883,28 @@ type BlockProfileRecord struct {
 // If len(p) >= n, BlockProfile copies the profile into p and returns n, true.
 // If len(p) < n, BlockProfile does not change p and returns n, false.
 //
+// The profile may have several records with the same stack, for
+// events of goroutines with different profiler labels
+// (see runtime/pprof.Do).
+//
 // Most clients should use the runtime/pprof package or
 // the testing package's -test.blockprofile flag instead
 // of calling BlockProfile directly.
 func BlockProfile(p []BlockProfileRecord) (n int, ok bool) {
+	return blockProfileWithLabels(p, nil)
+}
+
+//go:linkname runtime_blockProfileWithLabels runtime/pprof.runtime_blockProfileWithLabels
+func runtime_blockProfileWithLabels(p []BlockProfileRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	return blockProfileWithLabels(p, labels)
+}
+
+// labels may be nil. If labels is non-nil, it must have the same length as p.
+func blockProfileWithLabels(p []BlockProfileRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
+
 	lock(&profBlockLock)
 	head := (*bucket)(bbuckets.Load())
 	for b := head; b != nil; b = b.allnext {
@@ -782,6 +936,10 @@ func BlockProfile(p []BlockProfileRecord) (n int, ok bool) {
 				r.Stack0[i] = 0
 			}
 			p = p[1:]
+			if labels != nil {
+				labels[0] = b.labels
+				labels = labels[1:]
+			}
 		}
 	}
 	unlock(&profBlockLock)
@@ -792,9 +950,27 @@ func BlockProfile(p []BlockProfileRecord) (n int, ok bool) {
 // If len(p) >= n, MutexProfile copies the profile into p and returns n, true.
 // Otherwise, MutexProfile does not change p, and returns n, false.
 //
+// The profile may have several records with the same stack, for
+// events of goroutines with different profiler labels
+// (see runtime/pprof.Do).
+//
 // Most clients should use the runtime/pprof package
 // instead of calling MutexProfile directly.
 func MutexProfile(p []BlockProfileRecord) (n int, ok bool) {
+	return mutexProfileWithLabels(p, nil)
+}
+
+//go:linkname runtime_mutexProfileWithLabels runtime/pprof.runtime_mutexProfileWithLabels
+func runtime_mutexProfileWithLabels(p []BlockProfileRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	return mutexProfileWithLabels(p, labels)
+}
+
+// labels may be nil. If labels is non-nil, it must have the same length as p.
+func mutexProfileWithLabels(p []BlockProfileRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
+
 	lock(&profBlockLock)
 	head := (*bucket)(xbuckets.Load())
 	for b := head; b != nil; b = b.allnext {
@@ -812,6 +988,10 @@ func MutexProfile(p []BlockProfileRecord) (n int, ok bool) {
 				r.Stack0[i] = 0
 			}
 			p = p[1:]
+			if labels != nil {
+				labels[0] = b.labels
+				labels = labels[1:]
+			}
 		}
 	}
 	unlock(&profBlockLock)
@@ -1162,6 +1342,69 @@ func goroutineProfileWithLabelsSync(p []StackRecord, labels []unsafe.Pointer) (n
 	return n, ok
 }
 
+// goroutineLeakProfileWithLabels returns the stacks of the goroutines
+// found leaked by the last goroutine leak detection (see mgcleak.go)
+// that are still blocked. Each stack ends with the pc of the go
+// statement that created the goroutine, even if the rest of the stack
+// is truncated.
+//
+// labels may be nil. If labels is non-nil, it must have the same length as p.
+//
+//go:linkname goroutineLeakProfileWithLabels runtime/pprof.runtime_goroutineLeakProfileWithLabels
+func goroutineLeakProfileWithLabels(p []StackRecord, labels []unsafe.Pointer) (n int, ok bool) {
+	if labels != nil && len(labels) != len(p) {
+		labels = nil
+	}
+
+	isLeaked := func(gp *g) bool {
+		return gp.leakState == leakLeaked && readgstatus(gp) == _Gwaiting
+	}
+
+	stopTheWorld(stwGoroutineProfile)
+
+	// World is stopped, no locking required.
+	forEachGRace(func(gp *g) {
+		if isLeaked(gp) {
+			n++
+		}
+	})
+
+	if n <= len(p) {
+		ok = true
+		r, lbl := p, labels
+		forEachGRace(func(gp *g) {
+			if !isLeaked(gp) || len(r) == 0 {
+				return
+			}
+			// See goroutineProfileWithLabelsSync. Unlike saveg, leave
+			// room for the creation site, so that it is recorded even
+			// if the stack is truncated.
+			systemstack(func() {
+				var u unwinder
+				u.initAt(^uintptr(0), ^uintptr(0), 0, gp, unwindSilentErrors)
+				stk := r[0].Stack0[:]
+				n := tracebackPCs(&u, 0, stk[:len(stk)-1])
+				stk[n] = gp.gopc
+				if n+1 < len(stk) {
+					stk[n+1] = 0
+				}
+			})
+			if labels != nil {
+				lbl[0] = gp.labels
+				lbl = lbl[1:]
+			}
+			r = r[1:]
+		})
+	}
+
+	if raceenabled {
+		raceacquire(unsafe.Pointer(&labelSync))
+	}
+
+	startTheWorld()
+	return n, ok
+}
+
 // GoroutineProfile returns n, the number of records in the active goroutine stack profile.
 // If len(p) >= n, GoroutineProfile copies the profile into p and returns n, true.
 // If len(p) < n, GoroutineProfile does not change p and returns n, false.
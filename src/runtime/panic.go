@@ -296,6 +296,15 @@ func deferproc(fn func()) {
 	// been set and must not be clobbered.
 }
 
+var rangeExitError = error(errorString("range function continued iteration after exit"))
+
+// panicrangeexit is called by the code generated for a range-over-func
+// loop when the range function calls the loop body after the body
+// has returned false or after the range function has returned.
+func panicrangeexit() {
+	panic(rangeExitError)
+}
+
 // deferrangefunc is called by functions that are about to
 // execute a range-over-function loop in which the loop body
 // may execute a defer statement. That defer needs to add to
@@ -314,9 +323,9 @@ func deferproc(fn func()) {
 // but an atomic list hanging off:
 //
 //	g._defer => d4 -> d3 -> drangefunc -> d2 -> d1 -> nil
-//                              | .head
-//                              |
-//                              +--> dY -> dX -> nil
+//	                        | .head
+//	                        |
+//	                        +--> dY -> dX -> nil
 //
 // with each -> indicating a d.link pointer, and where drangefunc
 // has the d.rangefunc = true bit set.
@@ -341,9 +350,9 @@ func deferproc(fn func()) {
 // That is, deferconvert changes this list:
 //
 //	g._defer => drangefunc -> d2 -> d1 -> nil
-//                  | .head
-//                  |
-//                  +--> dY -> dX -> nil
+//	            | .head
+//	            |
+//	            +--> dY -> dX -> nil
 //
 // into this list:
 //
@@ -1011,6 +1020,11 @@ func sync_fatal(s string) {
 func throw(s string) {
 	// Everything throw does should be recursively nosplit so it
 	// can be called even when it's unsafe to grow the stack.
+	if gp := getg(); gp.m.throwing == throwTypeNone {
+		// Set early so that the message goes to the crash output
+		// as well; see writeErrData.
+		gp.m.throwing = throwTypeRuntime
+	}
 	systemstack(func() {
 		print("fatal error: ", s, "\n")
 	})
@@ -1030,6 +1044,11 @@ func throw(s string) {
 func fatal(s string) {
 	// Everything fatal does should be recursively nosplit so it
 	// can be called even when it's unsafe to grow the stack.
+	if gp := getg(); gp.m.throwing == throwTypeNone {
+		// Set early so that the message goes to the crash output
+		// as well; see writeErrData.
+		gp.m.throwing = throwTypeUser
+	}
 	systemstack(func() {
 		print("fatal error: ", s, "\n")
 	})
@@ -99,12 +99,13 @@ import (
 //
 // Each Profile has a unique name. A few profiles are predefined:
 //
-//	goroutine    - stack traces of all current goroutines
-//	heap         - a sampling of memory allocations of live objects
-//	allocs       - a sampling of all past memory allocations
-//	threadcreate - stack traces that led to the creation of new OS threads
-//	block        - stack traces that led to blocking on synchronization primitives
-//	mutex        - stack traces of holders of contended mutexes
+//	goroutine     - stack traces of all current goroutines
+//	goroutineleak - stack traces of goroutines blocked forever
+//	heap          - a sampling of memory allocations of live objects
+//	allocs        - a sampling of all past memory allocations
+//	threadcreate  - stack traces that led to the creation of new OS threads
+//	block         - stack traces that led to blocking on synchronization primitives
+//	mutex         - stack traces of holders of contended mutexes
 //
 // These predefined profiles maintain themselves and panic on an explicit
 // Add or Remove method call.
@@ -126,6 +127,16 @@ import (
 // pprof display to -alloc_space, the total number of bytes allocated since
 // the program began (including garbage-collected bytes).
 //
+// The goroutineleak profile reports the goroutines that have leaked:
+// they are blocked on channels, or on locks and other synchronization
+// primitives of package sync, that no goroutine that could run again
+// can reach, so they can never be woken up. Writing the profile runs a
+// garbage collection that finds them; Count reports the number of
+// leaked goroutines found by the last such collection. The stack trace
+// of each leaked goroutine ends with the go statement that created it.
+// Detection errs on the side of missing leaked goroutines, rather than
+// reporting goroutines that could still be woken up.
+//
 // The CPU profile is not available as a Profile. It has a special API,
 // the StartCPUProfile and StopCPUProfile functions, because it streams
 // output to a writer during profiling.
@@ -149,6 +160,12 @@ var goroutineProfile = &Profile{
 	write: writeGoroutine,
 }
 
+var goroutineLeakProfile = &Profile{
+	name:  "goroutineleak",
+	count: countGoroutineLeak,
+	write: writeGoroutineLeak,
+}
+
 var threadcreateProfile = &Profile{
 	name:  "threadcreate",
 	count: countThreadCreate,
@@ -184,12 +201,13 @@ func lockProfiles() {
 	if profiles.m == nil {
 		// Initial built-in profiles.
 		profiles.m = map[string]*Profile{
-			"goroutine":    goroutineProfile,
-			"threadcreate": threadcreateProfile,
-			"heap":         heapProfile,
-			"allocs":       allocsProfile,
-			"block":        blockProfile,
-			"mutex":        mutexProfile,
+			"goroutine":     goroutineProfile,
+			"goroutineleak": goroutineLeakProfile,
+			"threadcreate":  threadcreateProfile,
+			"heap":          heapProfile,
+			"allocs":        allocsProfile,
+			"block":         blockProfile,
+			"mutex":         mutexProfile,
 		}
 	}
 }
@@ -371,7 +389,8 @@ type countProfile interface {
 // as the pprof-proto format output. Translations from cycle count to time duration
 // are done because The proto expects count and time (nanoseconds) instead of count
 // and the number of cycles for block, contention profiles.
-func printCountCycleProfile(w io.Writer, countName, cycleName string, records []runtime.BlockProfileRecord) error {
+// labels may be nil. If labels is non-nil, it holds the labels of each record.
+func printCountCycleProfile(w io.Writer, countName, cycleName string, records []runtime.BlockProfileRecord, labels []unsafe.Pointer) error {
 	// Output profile in protobuf form.
 	b := newProfileBuilder(w)
 	b.pbValueType(tagProfile_PeriodType, countName, "count")
@@ -383,13 +402,21 @@ func printCountCycleProfile(w io.Writer, countName, cycleName string, records []
 
 	values := []int64{0, 0}
 	var locs []uint64
-	for _, r := range records {
+	for i, r := range records {
 		values[0] = r.Count
 		values[1] = int64(float64(r.Cycles) / cpuGHz)
 		// For count profiles, all stack addresses are
 		// return PCs, which is what appendLocsForStack expects.
 		locs = b.appendLocsForStack(locs[:0], r.Stack())
-		b.pbSample(values, locs, nil)
+		var lbls func()
+		if labels != nil && labels[i] != nil {
+			lbls = func() {
+				for _, lbl := range (*labelMap)(labels[i]).list {
+					b.pbLabel(tagSample_Label, lbl.key, lbl.value, 0)
+				}
+			}
+		}
+		b.pbSample(values, locs, lbls)
 	}
 	b.build()
 	return nil
@@ -455,8 +482,8 @@ func printCountProfile(w io.Writer, debug int, name string, p countProfile) erro
 		var labels func()
 		if p.Label(idx) != nil {
 			labels = func() {
-				for k, v := range *p.Label(idx) {
-					b.pbLabel(tagSample_Label, k, v, 0)
+				for _, lbl := range p.Label(idx).list {
+					b.pbLabel(tagSample_Label, lbl.key, lbl.value, 0)
 				}
 			}
 		}
@@ -554,25 +581,28 @@ func writeHeapInternal(w io.Writer, debug int, defaultSampleType string) error {
 	// and also try again if we're very unlucky.
 	// The loop should only execute one iteration in the common case.
 	var p []runtime.MemProfileRecord
-	n, ok := runtime.MemProfile(nil, true)
+	var labels []unsafe.Pointer
+	n, ok := runtime_memProfileWithLabels(nil, nil, true)
 	for {
 		// Allocate room for a slightly bigger profile,
 		// in case a few more entries have been added
 		// since the call to MemProfile.
 		p = make([]runtime.MemProfileRecord, n+50)
-		n, ok = runtime.MemProfile(p, true)
+		labels = make([]unsafe.Pointer, n+50)
+		n, ok = runtime_memProfileWithLabels(p, labels, true)
 		if ok {
 			p = p[0:n]
+			labels = labels[0:n]
 			break
 		}
 		// Profile grew; try again.
 	}
 
 	if debug == 0 {
-		return writeHeapProto(w, p, int64(runtime.MemProfileRate), defaultSampleType)
+		return writeHeapProto(w, p, labels, int64(runtime.MemProfileRate), defaultSampleType)
 	}
 
-	sort.Slice(p, func(i, j int) bool { return p[i].InUseBytes() > p[j].InUseBytes() })
+	sort.Sort(&memRecordsByInUse{p, labels})
 
 	b := bufio.NewWriter(w)
 	tw := tabwriter.NewWriter(b, 1, 8, 1, '\t', 0)
@@ -618,6 +648,7 @@ func writeHeapInternal(w io.Writer, debug int, defaultSampleType string) error {
 			fmt.Fprintf(w, " %#x", pc)
 		}
 		fmt.Fprintf(w, "\n")
+		printLabels(w, (*labelMap)(labels[i]))
 		printStackRecord(w, r.Stack(), false)
 	}
 
@@ -662,6 +693,31 @@ func writeHeapInternal(w io.Writer, debug int, defaultSampleType string) error {
 	return b.Flush()
 }
 
+// runtime_memProfileWithLabels is defined in runtime/mprof.go.
+func runtime_memProfileWithLabels(p []runtime.MemProfileRecord, labels []unsafe.Pointer, inuseZero bool) (n int, ok bool)
+
+// memRecordsByInUse sorts heap profile records and their labels with the most
+// bytes in use first.
+type memRecordsByInUse struct {
+	p      []runtime.MemProfileRecord
+	labels []unsafe.Pointer
+}
+
+func (x *memRecordsByInUse) Len() int           { return len(x.p) }
+func (x *memRecordsByInUse) Less(i, j int) bool { return x.p[i].InUseBytes() > x.p[j].InUseBytes() }
+func (x *memRecordsByInUse) Swap(i, j int) {
+	x.p[i], x.p[j] = x.p[j], x.p[i]
+	x.labels[i], x.labels[j] = x.labels[j], x.labels[i]
+}
+
+// printLabels prints the labels of a record of a legacy text profile,
+// if any.
+func printLabels(w io.Writer, lbls *labelMap) {
+	if lbls != nil {
+		fmt.Fprintf(w, "# labels: %s\n", lbls.String())
+	}
+}
+
 // countThreadCreate returns the size of the current ThreadCreateProfile.
 func countThreadCreate() int {
 	n, _ := runtime.ThreadCreateProfile(nil)
@@ -694,6 +750,26 @@ func writeGoroutine(w io.Writer, debug int) error {
 	return writeRuntimeProfile(w, debug, "goroutine", runtime_goroutineProfileWithLabels)
 }
 
+// countGoroutineLeak returns the number of goroutines found leaked by
+// the last goroutine leak detection.
+func countGoroutineLeak() int {
+	n, _ := runtime_goroutineLeakProfileWithLabels(nil, nil)
+	return n
+}
+
+// runtime_goroutineLeakGC is defined in runtime/mgcleak.go.
+func runtime_goroutineLeakGC()
+
+// runtime_goroutineLeakProfileWithLabels is defined in runtime/mprof.go.
+func runtime_goroutineLeakProfileWithLabels(p []runtime.StackRecord, labels []unsafe.Pointer) (n int, ok bool)
+
+// writeGoroutineLeak detects leaked goroutines and writes their profile
+// to w.
+func writeGoroutineLeak(w io.Writer, debug int) error {
+	runtime_goroutineLeakGC()
+	return writeRuntimeProfile(w, debug, "goroutineleak", runtime_goroutineLeakProfileWithLabels)
+}
+
 func writeGoroutineStacks(w io.Writer) error {
 	// We don't know how big the buffer needs to be to collect
 	// all the goroutines. Start with 1 MB and try a few times, doubling each time.
@@ -851,33 +927,42 @@ func countMutex() int {
 	return n
 }
 
+// runtime_blockProfileWithLabels is defined in runtime/mprof.go.
+func runtime_blockProfileWithLabels(p []runtime.BlockProfileRecord, labels []unsafe.Pointer) (n int, ok bool)
+
+// runtime_mutexProfileWithLabels is defined in runtime/mprof.go.
+func runtime_mutexProfileWithLabels(p []runtime.BlockProfileRecord, labels []unsafe.Pointer) (n int, ok bool)
+
 // writeBlock writes the current blocking profile to w.
 func writeBlock(w io.Writer, debug int) error {
-	return writeProfileInternal(w, debug, "contention", runtime.BlockProfile)
+	return writeProfileInternal(w, debug, "contention", runtime_blockProfileWithLabels)
 }
 
 // writeMutex writes the current mutex profile to w.
 func writeMutex(w io.Writer, debug int) error {
-	return writeProfileInternal(w, debug, "mutex", runtime.MutexProfile)
+	return writeProfileInternal(w, debug, "mutex", runtime_mutexProfileWithLabels)
 }
 
 // writeProfileInternal writes the current blocking or mutex profile depending on the passed parameters.
-func writeProfileInternal(w io.Writer, debug int, name string, runtimeProfile func([]runtime.BlockProfileRecord) (int, bool)) error {
+func writeProfileInternal(w io.Writer, debug int, name string, runtimeProfile func([]runtime.BlockProfileRecord, []unsafe.Pointer) (int, bool)) error {
 	var p []runtime.BlockProfileRecord
-	n, ok := runtimeProfile(nil)
+	var labels []unsafe.Pointer
+	n, ok := runtimeProfile(nil, nil)
 	for {
 		p = make([]runtime.BlockProfileRecord, n+50)
-		n, ok = runtimeProfile(p)
+		labels = make([]unsafe.Pointer, n+50)
+		n, ok = runtimeProfile(p, labels)
 		if ok {
 			p = p[:n]
+			labels = labels[:n]
 			break
 		}
 	}
 
-	sort.Slice(p, func(i, j int) bool { return p[i].Cycles > p[j].Cycles })
+	sort.Sort(&blockRecordsByCycles{p, labels})
 
 	if debug <= 0 {
-		return printCountCycleProfile(w, "contentions", "delay", p)
+		return printCountCycleProfile(w, "contentions", "delay", p, labels)
 	}
 
 	b := bufio.NewWriter(w)
@@ -897,6 +982,7 @@ func writeProfileInternal(w io.Writer, debug int, name string, runtimeProfile fu
 		}
 		fmt.Fprint(w, "\n")
 		if debug > 0 {
+			printLabels(w, (*labelMap)(labels[i]))
 			printStackRecord(w, r.Stack(), true)
 		}
 	}
@@ -907,4 +993,18 @@ func writeProfileInternal(w io.Writer, debug int, name string, runtimeProfile fu
 	return b.Flush()
 }
 
+// blockRecordsByCycles sorts block or mutex profile records and their labels
+// with the most cycles first.
+type blockRecordsByCycles struct {
+	p      []runtime.BlockProfileRecord
+	labels []unsafe.Pointer
+}
+
+func (x *blockRecordsByCycles) Len() int           { return len(x.p) }
+func (x *blockRecordsByCycles) Less(i, j int) bool { return x.p[i].Cycles > x.p[j].Cycles }
+func (x *blockRecordsByCycles) Swap(i, j int) {
+	x.p[i], x.p[j] = x.p[j], x.p[i]
+	x.labels[i], x.labels[j] = x.labels[j], x.labels[i]
+}
+
 func runtime_cyclesPerSecond() int64
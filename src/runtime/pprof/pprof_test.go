@@ -27,6 +27,7 @@ import (
 	"testing"
 	"time"
 	_ "unsafe"
+	"weak"
 )
 
 func cpuHogger(f func(x int) int, y *int, dur time.Duration) {
@@ -1373,6 +1374,158 @@ func TestMutexProfileRateAdjust(t *testing.T) {
 	}
 }
 
+var labeledMemSink *Obj32
+
+func allocateLabeled1K() {
+	for i := 0; i < 32; i++ {
+		obj := &Obj32{link: labeledMemSink}
+		labeledMemSink = obj
+	}
+}
+
+func TestMemoryProfilerLabels(t *testing.T) {
+	oldRate := runtime.MemProfileRate
+	runtime.MemProfileRate = 1
+	defer func() {
+		runtime.MemProfileRate = oldRate
+	}()
+
+	// Allocating twice with equal label sets must not split the profile.
+	for _, tenant := range []string{"a", "b", "a"} {
+		Do(context.Background(), Labels("tenant", tenant), func(context.Context) {
+			allocateLabeled1K()
+		})
+	}
+
+	runtime.GC() // materialize stats
+
+	t.Run("debug=1", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Lookup("heap").WriteTo(&buf, 1); err != nil {
+			t.Fatalf("failed to write heap profile: %v", err)
+		}
+		for _, tenant := range []string{"a", "b"} {
+			re := `# labels: \{"tenant":"` + tenant + `"\}
+#	0x[0-9,a-f]+	runtime/pprof\.allocateLabeled1K\+`
+			if !regexp.MustCompile(re).Match(buf.Bytes()) {
+				t.Errorf("The entry did not match:\n%v\n\nProfile:\n%v\n", re, buf.String())
+			}
+		}
+	})
+
+	t.Run("proto", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Lookup("allocs").WriteTo(&buf, 0); err != nil {
+			t.Fatalf("failed to write allocs profile: %v", err)
+		}
+		p, err := profile.Parse(&buf)
+		if err != nil {
+			t.Fatalf("failed to parse allocs profile: %v", err)
+		}
+
+		// Count the labeled samples of allocateLabeled1K per tenant.
+		samples := make(map[string]int)
+		for _, s := range p.Sample {
+			if stk := stacks(&profile.Profile{Sample: []*profile.Sample{s}}); !containsStack(stk, []string{"runtime/pprof.allocateLabeled1K"}) {
+				continue
+			}
+			tenants := s.Label["tenant"]
+			if len(tenants) != 1 {
+				t.Errorf("sample of allocateLabeled1K has labels %v, want one tenant", s.Label)
+				continue
+			}
+			samples[tenants[0]]++
+		}
+		for _, tenant := range []string{"a", "b"} {
+			if samples[tenant] != 1 {
+				t.Errorf("got %d samples of allocateLabeled1K for tenant %q, want 1\n\nProfile:\n%v", samples[tenant], tenant, p)
+			}
+		}
+	})
+}
+
+func TestProfilerLabelsCollected(t *testing.T) {
+	// Labels that were never recorded with a sampled event must not be
+	// kept alive by the profiler.
+	oldRate := runtime.MemProfileRate
+	runtime.MemProfileRate = 0
+	defer func() {
+		runtime.MemProfileRate = oldRate
+	}()
+
+	collected := make(chan bool, 1)
+	Do(context.Background(), Labels("request", "collected"), func(context.Context) {
+		l := (*labelMap)(runtime_getProfLabel())
+		runtime.SetFinalizer(l, func(*labelMap) { collected <- true })
+	})
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Errorf("labels set with Do were not collected")
+}
+
+func TestBlockMutexProfileLabels(t *testing.T) {
+	oldRate := runtime.SetMutexProfileFraction(1)
+	defer runtime.SetMutexProfileFraction(oldRate)
+	runtime.SetBlockProfileRate(1)
+	defer runtime.SetBlockProfileRate(0)
+
+	ctx := context.Background()
+	Do(ctx, Labels("blocker", "chan"), func(context.Context) {
+		blockChanRecv(t)
+	})
+	Do(ctx, Labels("blocker", "mutex"), func(context.Context) {
+		blockMutex(t)
+	})
+
+	for _, tc := range []struct {
+		profile string
+		stk     []string
+		label   string
+	}{
+		{"block", []string{"runtime.chanrecv1", "runtime/pprof.blockChanRecv"}, "chan"},
+		{"mutex", []string{"sync.(*Mutex).Unlock", "runtime/pprof.blockMutex.func1"}, "mutex"},
+	} {
+		t.Run(tc.profile, func(t *testing.T) {
+			var buf bytes.Buffer
+			Lookup(tc.profile).WriteTo(&buf, 0)
+			p, err := profile.Parse(&buf)
+			if err != nil {
+				t.Fatalf("failed to parse profile: %v", err)
+			}
+			found := false
+			for _, s := range p.Sample {
+				var stk []string
+				for _, loc := range s.Location {
+					for _, line := range loc.Line {
+						stk = append(stk, line.Function.Name)
+					}
+				}
+				// Other tests may have left unlabeled samples with
+				// the same stack.
+				if got := s.Label["blocker"]; len(got) == 1 && got[0] == tc.label && containsStack([][]string{stk}, tc.stk) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("no sample with stack %v and label blocker=%s in profile:\n%v", tc.stk, tc.label, p)
+			}
+
+			buf.Reset()
+			Lookup(tc.profile).WriteTo(&buf, 1)
+			want := `# labels: {"blocker":"` + tc.label + `"}`
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("debug=1 profile does not contain %q:\n%s", want, buf.String())
+			}
+		})
+	}
+}
+
 func func1(c chan int) { <-c }
 func func2(c chan int) { <-c }
 func func3(c chan int) { <-c }
@@ -1426,7 +1579,7 @@ func TestGoroutineCounts(t *testing.T) {
 	goroutineProf.WriteTo(&w, 1)
 	prof := w.String()
 
-	labels := labelMap{"label": "value"}
+	labels := labelMap{Labels("label", "value")}
 	labelStr := "\n# labels: " + labels.String()
 	if !containsInOrder(prof, "\n50 @ ", "\n44 @", labelStr,
 		"\n40 @", "\n36 @", labelStr, "\n10 @", "\n9 @", labelStr, "\n1 @") {
@@ -1462,6 +1615,150 @@ func TestGoroutineCounts(t *testing.T) {
 	time.Sleep(10 * time.Millisecond) // let goroutines exit
 }
 
+// leakable holds what the leaking goroutines of TestGoroutineLeakProfile
+// block on. The test only holds weak pointers to it, so that the
+// goroutines leak, and releases them through the weak pointers once the
+// profile is checked.
+type leakable struct {
+	c1, c2, c3 chan int
+	// Not a sync.Mutex, which the tiny allocator may place next to
+	// reachable objects.
+	mu sync.RWMutex
+	wg sync.WaitGroup
+}
+
+//go:noinline
+func leakChanRecv(l *leakable, done *sync.WaitGroup) {
+	defer done.Done()
+	<-l.c1
+}
+
+//go:noinline
+func leakSelect(l *leakable, done *sync.WaitGroup) {
+	defer done.Done()
+	select {
+	case <-l.c2:
+	case l.c3 <- 1:
+	}
+}
+
+//go:noinline
+func leakMutex(l *leakable, done *sync.WaitGroup) {
+	defer done.Done()
+	l.mu.Lock()
+	l.mu.Lock()
+}
+
+//go:noinline
+func leakWaitGroup(l *leakable, done *sync.WaitGroup) {
+	defer done.Done()
+	l.wg.Wait()
+}
+
+//go:noinline
+func waitChanRecv(c chan int, done *sync.WaitGroup) {
+	defer done.Done()
+	<-c
+}
+
+//go:noinline
+func waitMutex(mu *sync.Mutex, done *sync.WaitGroup) {
+	defer done.Done()
+	mu.Lock()
+	mu.Unlock()
+}
+
+//go:noinline
+func waitRelay(in, out chan int, done *sync.WaitGroup) {
+	defer done.Done()
+	out <- <-in
+}
+
+func TestGoroutineLeakProfile(t *testing.T) {
+	c := make(chan int)
+	mu := new(sync.Mutex)
+	mu.Lock()
+	var done sync.WaitGroup
+	var leaks []weak.Pointer[leakable]
+	for i := 0; i < 2; i++ {
+		l := &leakable{c1: make(chan int), c2: make(chan int), c3: make(chan int)}
+		l.wg.Add(1)
+		leaks = append(leaks, weak.Make(l))
+		done.Add(8)
+		go leakChanRecv(l, &done)
+		go leakSelect(l, &done)
+		go leakMutex(l, &done)
+		go leakWaitGroup(l, &done)
+		go waitChanRecv(c, &done)
+		go waitMutex(mu, &done)
+		// Only reachable through another blocked goroutine,
+		// which may be woken up.
+		relay := make(chan int)
+		go waitChanRecv(relay, &done)
+		go waitRelay(c, relay, &done)
+	}
+
+	// Release all goroutines, including the leaked ones, so that
+	// they don't disturb later tests.
+	defer func() {
+		close(c)
+		mu.Unlock()
+		for _, w := range leaks {
+			if l := w.Value(); l != nil {
+				close(l.c1)
+				close(l.c2)
+				l.mu.Unlock()
+				l.wg.Done()
+			}
+		}
+		done.Wait()
+	}()
+
+	// Wait for all goroutines to block.
+	leaked := []string{"leakChanRecv", "leakSelect", "leakMutex", "leakWaitGroup"}
+	var prof string
+	for i := 0; ; i++ {
+		var w bytes.Buffer
+		if err := Lookup("goroutineleak").WriteTo(&w, 1); err != nil {
+			t.Fatal(err)
+		}
+		prof = w.String()
+		if Lookup("goroutineleak").Count() >= 2*len(leaked) || i == 100 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, fn := range leaked {
+		if !strings.Contains(prof, "runtime/pprof."+fn+"+") {
+			t.Errorf("goroutineleak profile does not contain %s:\n%s", fn, prof)
+		}
+	}
+	if !strings.Contains(prof, "runtime/pprof.TestGoroutineLeakProfile+") {
+		t.Errorf("goroutineleak profile does not contain creation stacks:\n%s", prof)
+	}
+	for _, fn := range []string{"waitChanRecv", "waitMutex", "waitRelay"} {
+		if strings.Contains(prof, "runtime/pprof."+fn+"+") {
+			t.Errorf("goroutineleak profile contains %s, which is not leaked:\n%s", fn, prof)
+		}
+	}
+	if n := Lookup("goroutineleak").Count(); n < 2*len(leaked) {
+		t.Errorf("goroutineleak profile count is %d, want at least %d:\n%s", n, 2*len(leaked), prof)
+	}
+
+	var w bytes.Buffer
+	if err := Lookup("goroutineleak").WriteTo(&w, 0); err != nil {
+		t.Fatal(err)
+	}
+	p, err := profile.Parse(&w)
+	if err != nil {
+		t.Fatalf("error parsing protobuf profile: %v", err)
+	}
+	if err := p.CheckValid(); err != nil {
+		t.Errorf("protobuf profile is invalid: %v", err)
+	}
+}
+
 func containsInOrder(s string, all ...string) bool {
 	for _, t := range all {
 		var ok bool
@@ -9,10 +9,13 @@ import (
 	"math"
 	"runtime"
 	"strings"
+	"unsafe"
 )
 
 // writeHeapProto writes the current heap profile in protobuf format to w.
-func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, rate int64, defaultSampleType string) error {
+// labels may be nil. If labels is non-nil, it holds the labels of each
+// record of p.
+func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, labels []unsafe.Pointer, rate int64, defaultSampleType string) error {
 	b := newProfileBuilder(w)
 	b.pbValueType(tagProfile_PeriodType, "space", "bytes")
 	b.pb.int64Opt(tagProfile_Period, rate)
@@ -26,7 +29,7 @@ func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, rate int64, defau
 
 	values := []int64{0, 0, 0, 0}
 	var locs []uint64
-	for _, r := range p {
+	for i, r := range p {
 		hideRuntime := true
 		for tries := 0; tries < 2; tries++ {
 			stk := r.Stack()
@@ -56,10 +59,19 @@ func writeHeapProto(w io.Writer, p []runtime.MemProfileRecord, rate int64, defau
 		if r.AllocObjects > 0 {
 			blockSize = r.AllocBytes / r.AllocObjects
 		}
+		var lbls *labelMap
+		if labels != nil {
+			lbls = (*labelMap)(labels[i])
+		}
 		b.pbSample(values, locs, func() {
 			if blockSize != 0 {
 				b.pbLabel(tagSample_Label, "bytes", "", blockSize)
 			}
+			if lbls != nil {
+				for _, lbl := range lbls.list {
+					b.pbLabel(tagSample_Label, lbl.key, lbl.value, 0)
+				}
+			}
 		})
 	}
 	b.build()
@@ -0,0 +1,40 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime_test
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"testing"
+)
+
+// TestProfLabelLayout checks that the runtime's copy of the types of
+// runtime/pprof label sets, which profiling buckets use to compare
+// labels, matches runtime/pprof.
+func TestProfLabelLayout(t *testing.T) {
+	ctx := context.Background()
+	pprof.Do(ctx, pprof.Labels("b", "2", "a", "1", "c", ""), func(ctx context.Context) {
+		want := [][2]string{{"a", "1"}, {"b", "2"}, {"c", ""}}
+		if got := runtime.ProfLabels(); !reflect.DeepEqual(got, want) {
+			t.Errorf("got labels %q, want %q", got, want)
+		}
+
+		// An equal label set from another Do call compares equal.
+		equal, sameHash := runtime.ProfLabelsEqual(func() {
+			pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels("a", "1", "c", "", "b", "2")))
+		})
+		if !equal || !sameHash {
+			t.Errorf("equal label sets: got equal=%v, same hash=%v; want true, true", equal, sameHash)
+		}
+		equal, _ = runtime.ProfLabelsEqual(func() {
+			pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels("c", "3")))
+		})
+		if equal {
+			t.Errorf("different label sets compare equal")
+		}
+	})
+}
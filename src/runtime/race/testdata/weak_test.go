@@ -0,0 +1,72 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package race_test
+
+import (
+	"runtime"
+	"testing"
+	"weak"
+)
+
+type weakObj struct {
+	p *int // contains a pointer so the object is not in a tiny block
+	x int
+}
+
+func TestNoRaceWeakValue(t *testing.T) {
+	c := make(chan weak.Pointer[weakObj])
+	done := make(chan bool)
+	o := new(weakObj)
+	go func() {
+		wp := <-c
+		if v := wp.Value(); v != nil {
+			v.x = 2
+		}
+		done <- true
+	}()
+	o.x = 1
+	c <- weak.Make(o)
+	<-done
+	_ = o.x
+	runtime.KeepAlive(o)
+}
+
+func TestNoRaceWeakReclaimed(t *testing.T) {
+	c := make(chan bool)
+	var wp weak.Pointer[weakObj]
+	go func() {
+		o := new(weakObj)
+		o.x = 1
+		wp = weak.Make(o)
+		c <- true
+	}()
+	<-c
+	runtime.GC()
+	runtime.GC()
+	// The object may have been reclaimed and its memory reused.
+	// Neither must be reported as a race.
+	for i := 0; i < 100; i++ {
+		o := new(weakObj)
+		o.x = i
+	}
+	if v := wp.Value(); v != nil {
+		_ = v.x
+	}
+}
+
+func TestRaceWeakValue(t *testing.T) {
+	c := make(chan bool)
+	o := new(weakObj)
+	wp := weak.Make(o)
+	go func() {
+		if v := wp.Value(); v != nil {
+			v.x = 2
+		}
+		c <- true
+	}()
+	o.x = 1
+	<-c
+	runtime.KeepAlive(o)
+}
@@ -9,6 +9,7 @@ import (
 	"flag"
 	"fmt"
 	"internal/abi"
+	"internal/goexperiment"
 	"internal/testenv"
 	"os"
 	"os/exec"
@@ -171,6 +172,9 @@ func testGdbPython(t *testing.T, cgo bool) {
 	if cgo {
 		testenv.MustHaveCGO(t)
 	}
+	if goexperiment.SwissMap {
+		t.Skip("runtime-gdb.py cannot print Swiss table maps")
+	}
 
 	checkGdbEnvironment(t)
 	t.Parallel()
@@ -326,6 +326,8 @@ var debug struct {
 	harddecommit       int32
 	adaptivestackstart int32
 	tracefpunwindoff   int32
+	containermaxprocs  int32
+	updatemaxprocs     int32
 
 	// debug.malloc is used as a combined debug check
 	// in the malloc function and should be set
@@ -361,6 +363,8 @@ var dbgvars = []*dbgVar{
 	{name: "harddecommit", value: &debug.harddecommit},
 	{name: "adaptivestackstart", value: &debug.adaptivestackstart},
 	{name: "tracefpunwindoff", value: &debug.tracefpunwindoff},
+	{name: "containermaxprocs", value: &debug.containermaxprocs, def: 1},
+	{name: "updatemaxprocs", value: &debug.updatemaxprocs, def: 1},
 	{name: "panicnil", atomic: &debug.panicnil},
 }
 
@@ -13,153 +13,230 @@ import (
 	"unsafe"
 )
 
-// Package time knows the layout of this structure.
-// If this struct changes, adjust ../time/sleep.go:/runtimeTimer.
+// A timer is a potentially repeating trigger for calling t.f(t.arg, t.seq).
+// Timers are allocated by client code, often as part of other data structures.
+// Each P has a heap of pointers to timers that it manages.
+//
+// A timer is expected to be used by only one client goroutine at a time,
+// but there will be concurrent access by the P managing that timer.
+// Timer accesses are protected by the lock t.mu, with a snapshot of
+// t's state bits published in t.astate to enable certain fast paths to make
+// decisions about a timer without acquiring the lock.
 type timer struct {
-	// If this timer is on a heap, which P's heap it is on.
-	// puintptr rather than *p to match uintptr in the versions
-	// of this struct defined in other packages.
-	pp puintptr
+	// mu protects reads and writes to all fields, with exceptions noted below.
+	mu mutex
+
+	astate atomic.Uint8 // atomic copy of state bits at last unlock
+	state  uint8        // state bits
+	isChan bool         // timer has a channel; immutable; can be read without lock
+	isFake bool         // timer is using fake time; immutable; can be read without lock
+
+	// isSending is used to handle races between running a
+	// channel timer and stopping or resetting the timer.
+	// It is used only for channel timers (t.isChan == true).
+	// It is not used for tickers.
+	// The value is incremented when about to send a value on the channel,
+	// and decremented after sending the value.
+	// The stop/reset code uses this to detect whether it
+	// stopped the channel send.
+	//
+	// isSending is incremented only when t.mu is held.
+	// isSending is decremented only when t.sendLock is held.
+	// isSending is read only when both t.mu and t.sendLock are held.
+	isSending atomic.Int32
+
+	blocked uint32 // number of goroutines blocked on timer's channel
 
 	// Timer wakes up at when, and then at when+period, ... (period > 0 only)
-	// each time calling f(arg, now) in the timer goroutine, so f must be
+	// each time calling f(arg, seq, delay) in the timer goroutine, so f must be
 	// a well-behaved function and not block.
 	//
-	// when must be positive on an active timer.
+	// The arg and seq are client-specified opaque arguments passed back to f.
+	// When used from netpoll, arg and seq have meanings defined by netpoll
+	// and are completely opaque to this code; in that context, seq is a sequence
+	// number to recognize and squelch stale function invocations.
+	// When used from package time, arg is a channel (for After, NewTicker)
+	// or the function to call (for AfterFunc) and seq is unused (0).
+	//
+	// Package time does not know about seq, but if this is a channel timer (t.isChan == true),
+	// this file uses t.seq as a sequence number to recognize and squelch
+	// sends that correspond to an earlier (stale) timer configuration,
+	// similar to its use in netpoll. In this usage (that is, when t.isChan == true),
+	// writes to seq are protected by both t.mu and t.sendLock,
+	// so reads are allowed when holding either of the two mutexes.
+	//
+	// The delay argument is nanotime() - t.when, meaning the delay in ns between
+	// when the timer should have gone off and now. Normally that amount is
+	// small enough not to matter, but for channel timers that are fed lazily,
+	// the delay can be arbitrarily long; package time subtracts it out to make
+	// it look like the send happened earlier than it actually did.
+	// (No one looked at the channel since then, or the send would have
+	// not happened so late, so no one can tell the difference.)
 	when   int64
 	period int64
-	f      func(any, uintptr)
+	f      func(arg any, seq uintptr, delay int64)
 	arg    any
 	seq    uintptr
 
-	// What to set the when field to in timerModifiedXX status.
-	nextwhen int64
+	// If non-nil, the timers containing t.
+	ts *timers
 
-	// The status field holds one of the values below.
-	status atomic.Uint32
+	// sendLock protects sends on the timer's channel.
+	// It is only used for channel timers (t.isChan == true).
+	sendLock mutex
 }
 
-// Code outside this file has to be careful in using a timer value.
-//
-// The pp, status, and nextwhen fields may only be used by code in this file.
-//
-// Code that creates a new timer value can set the when, period, f,
-// arg, and seq fields.
-// A new timer value may be passed to addtimer (called by time.startTimer).
-// After doing that no fields may be touched.
-//
-// An active timer (one that has been passed to addtimer) may be
-// passed to deltimer (time.stopTimer), after which it is no longer an
-// active timer. It is an inactive timer.
-// In an inactive timer the period, f, arg, and seq fields may be modified,
-// but not the when field.
-// It's OK to just drop an inactive timer and let the GC collect it.
-// It's not OK to pass an inactive timer to addtimer.
-// Only newly allocated timer values may be passed to addtimer.
-//
-// An active timer may be passed to modtimer. No fields may be touched.
-// It remains an active timer.
-//
-// An inactive timer may be passed to resettimer to turn into an
-// active timer with an updated when field.
-// It's OK to pass a newly allocated timer value to resettimer.
-//
-// Timer operations are addtimer, deltimer, modtimer, resettimer,
-// cleantimers, adjusttimers, and runtimer.
-//
-// We don't permit calling addtimer/deltimer/modtimer/resettimer simultaneously,
-// but adjusttimers and runtimer can be called at the same time as any of those.
-//
-// Active timers live in heaps attached to P, in the timers field.
-// Inactive timers live there too temporarily, until they are removed.
-//
-// addtimer:
-//   timerNoStatus   -> timerWaiting
-//   anything else   -> panic: invalid value
-// deltimer:
-//   timerWaiting         -> timerModifying -> timerDeleted
-//   timerModifiedEarlier -> timerModifying -> timerDeleted
-//   timerModifiedLater   -> timerModifying -> timerDeleted
-//   timerNoStatus        -> do nothing
-//   timerDeleted         -> do nothing
-//   timerRemoving        -> do nothing
-//   timerRemoved         -> do nothing
-//   timerRunning         -> wait until status changes
-//   timerMoving          -> wait until status changes
-//   timerModifying       -> wait until status changes
-// modtimer:
-//   timerWaiting    -> timerModifying -> timerModifiedXX
-//   timerModifiedXX -> timerModifying -> timerModifiedYY
-//   timerNoStatus   -> timerModifying -> timerWaiting
-//   timerRemoved    -> timerModifying -> timerWaiting
-//   timerDeleted    -> timerModifying -> timerModifiedXX
-//   timerRunning    -> wait until status changes
-//   timerMoving     -> wait until status changes
-//   timerRemoving   -> wait until status changes
-//   timerModifying  -> wait until status changes
-// cleantimers (looks in P's timer heap):
-//   timerDeleted    -> timerRemoving -> timerRemoved
-//   timerModifiedXX -> timerMoving -> timerWaiting
-// adjusttimers (looks in P's timer heap):
-//   timerDeleted    -> timerRemoving -> timerRemoved
-//   timerModifiedXX -> timerMoving -> timerWaiting
-// runtimer (looks in P's timer heap):
-//   timerNoStatus   -> panic: uninitialized timer
-//   timerWaiting    -> timerWaiting or
-//   timerWaiting    -> timerRunning -> timerNoStatus or
-//   timerWaiting    -> timerRunning -> timerWaiting
-//   timerModifying  -> wait until status changes
-//   timerModifiedXX -> timerMoving -> timerWaiting
-//   timerDeleted    -> timerRemoving -> timerRemoved
-//   timerRunning    -> panic: concurrent runtimer calls
-//   timerRemoved    -> panic: inconsistent timer heap
-//   timerRemoving   -> panic: inconsistent timer heap
-//   timerMoving     -> panic: inconsistent timer heap
-
-// Values for the timer status field.
+// init initializes a newly allocated timer t.
+// Any code that allocates a timer must call t.init before using it.
+// The arg and f can be set during init, or they can be nil in init
+// and set by a future call to t.modify.
+func (t *timer) init(f func(arg any, seq uintptr, delay int64), arg any) {
+	lockInit(&t.mu, lockRankTimer)
+	t.f = f
+	t.arg = arg
+}
+
+// A timers is a per-P set of timers, or the set of fake-time timers
+// of a synctest bubble.
+type timers struct {
+	// mu protects timers; timers are per-P, but the scheduler can
+	// access the timers of another P, so we have to lock.
+	mu mutex
+
+	// heap is the set of timers, ordered by heap[i].when.
+	// Must hold lock to access.
+	heap []timerWhen
+
+	// len is an atomic copy of len(heap).
+	len atomic.Uint32
+
+	// zombies is the number of timers in the heap
+	// that are marked for removal.
+	zombies atomic.Int32
+
+	// raceCtx is the race context used while executing timer functions.
+	raceCtx uintptr
+
+	// minWhenHeap is the minimum heap[i].when value (= heap[0].when).
+	// The wakeTime method uses minWhenHeap and minWhenModified
+	// to determine the next wake time.
+	// If minWhenHeap = 0, it means there are no timers in the heap.
+	minWhenHeap atomic.Int64
+
+	// minWhenModified is a lower bound on the minimum
+	// heap[i].when over timers with the timerModified bit set.
+	// If minWhenModified = 0, it means there are no timerModified timers in the heap.
+	minWhenModified atomic.Int64
+
+	// syncGroup is the synctest bubble whose fake-time timers these are,
+	// or nil for the timers of a P.
+	syncGroup *synctestGroup
+}
+
+type timerWhen struct {
+	timer *timer
+	when  int64
+}
+
+func (ts *timers) lock() {
+	lock(&ts.mu)
+}
+
+func (ts *timers) unlock() {
+	// Update atomic copy of len(ts.heap).
+	// We only update at unlock so that the len is always
+	// the most recent unlocked length, not an ephemeral length.
+	// This matters if we lock ts, delete the only timer from the heap,
+	// add it back, and unlock. We want ts.len.Load to return 1 the
+	// entire time, never 0. This is important for pidleput deciding
+	// whether ts is empty.
+	ts.len.Store(uint32(len(ts.heap)))
+
+	unlock(&ts.mu)
+}
+
+// Timer state field.
 const (
-	// Timer has no status set yet.
-	timerNoStatus = iota
-
-	// Waiting for timer to fire.
-	// The timer is in some P's heap.
-	timerWaiting
-
-	// Running the timer function.
-	// A timer will only have this status briefly.
-	timerRunning
-
-	// The timer is deleted and should be removed.
-	// It should not be run, but it is still in some P's heap.
-	timerDeleted
-
-	// The timer is being removed.
-	// The timer will only have this status briefly.
-	timerRemoving
-
-	// The timer has been stopped.
-	// It is not in any P's heap.
-	timerRemoved
-
-	// The timer is being modified.
-	// The timer will only have this status briefly.
-	timerModifying
-
-	// The timer has been modified to an earlier time.
-	// The new when value is in the nextwhen field.
-	// The timer is in some P's heap, possibly in the wrong place.
-	timerModifiedEarlier
-
-	// The timer has been modified to the same or a later time.
-	// The new when value is in the nextwhen field.
-	// The timer is in some P's heap, possibly in the wrong place.
-	timerModifiedLater
-
-	// The timer has been modified and is being moved.
-	// The timer will only have this status briefly.
-	timerMoving
+	// timerHeaped is set when the timer is stored in some P's heap.
+	timerHeaped uint8 = 1 << iota
+
+	// timerModified is set when t.when has been modified
+	// but the heap's heap[i].when entry still needs to be updated.
+	// That change waits until the heap in which
+	// the timer appears can be locked and rearranged.
+	// timerModified is only set when timerHeaped is also set.
+	timerModified
+
+	// timerZombie is set when the timer has been stopped
+	// but is still present in some P's heap.
+	// Only set when timerHeaped is also set.
+	// It is possible for timerModified and timerZombie to both
+	// be set, meaning that the timer was modified and then stopped.
+	// A timer sending to a channel may be placed in timerZombie
+	// to take it out of the heap even though the timer is not stopped,
+	// as long as nothing is reading from the channel.
+	timerZombie
 )
 
+// lock locks the timer, allowing reading or writing any of the timer fields.
+func (t *timer) lock() {
+	lock(&t.mu)
+}
+
+// unlock updates t.astate and unlocks the timer.
+func (t *timer) unlock() {
+	// Let heap fast paths know whether heap[i].when is accurate.
+	// Also let maybeRunChan know whether channel is in heap.
+	t.astate.Store(t.state)
+	unlock(&t.mu)
+}
+
+// hchan returns the channel in t.arg.
+// t must be a timer with a channel.
+func (t *timer) hchan() *hchan {
+	if !t.isChan {
+		badTimer()
+	}
+	// Note: t.arg is a chan time.Time,
+	// and runtime cannot refer to that type,
+	// so we cannot use a type assertion.
+	return (*hchan)(efaceOf(&t.arg).data)
+}
+
+// updateHeap updates t as directed by t.state, updating t.state
+// and returning a bool indicating whether the state (and ts.heap[0].when) changed.
+// The caller must hold t's lock, or the world can be stopped instead.
+// The timer set t.ts must be non-nil and locked, t must be t.ts.heap[0], and updateHeap
+// takes care of moving t within the timers heap to preserve the heap invariants.
+// If ts == nil, then t must not be in a heap (or is in a heap that is
+// temporarily not maintaining its invariant, such as during timers.adjust).
+func (t *timer) updateHeap() (updated bool) {
+	assertWorldStoppedOrLockHeld(&t.mu)
+	ts := t.ts
+	if ts == nil || t != ts.heap[0].timer {
+		badTimer()
+	}
+	assertLockHeld(&ts.mu)
+	if t.state&timerZombie != 0 {
+		// Take timer out of heap.
+		t.state &^= timerHeaped | timerZombie | timerModified
+		ts.zombies.Add(-1)
+		ts.deleteMin()
+		return true
+	}
+
+	if t.state&timerModified != 0 {
+		// Update ts.heap[0].when and move within heap.
+		t.state &^= timerModified
+		ts.heap[0].when = t.when
+		ts.siftDown(0)
+		ts.updateMinWhenHeap()
+		return true
+	}
+
+	return false
+}
+
 // maxWhen is the maximum value for timer's when field.
 const maxWhen = 1<<63 - 1
 
@@ -172,6 +249,30 @@ const verifyTimers = false
 
 // time.now is implemented in assembly.
 
+// time_runtimeNow returns the current time,
+// or the fake time of the synctest bubble of the calling goroutine.
+//
+//go:linkname time_runtimeNow time.runtimeNow
+func time_runtimeNow() (sec int64, nsec int32, mono int64) {
+	if sg := getg().syncGroup; sg != nil {
+		sec = sg.now / (1000 * 1000 * 1000)
+		nsec = int32(sg.now % (1000 * 1000 * 1000))
+		return sec, nsec, sg.now
+	}
+	return time_now()
+}
+
+// time_runtimeNano returns the current value of the runtime clock,
+// or the fake time of the synctest bubble of the calling goroutine.
+//
+//go:linkname time_runtimeNano time.runtimeNano
+func time_runtimeNano() int64 {
+	if sg := getg().syncGroup; sg != nil {
+		return sg.now
+	}
+	return nanotime()
+}
+
 // timeSleep puts the current goroutine to sleep for at least ns nanoseconds.
 //
 //go:linkname timeSleep time.Sleep
@@ -184,259 +285,204 @@ func timeSleep(ns int64) {
 	t := gp.timer
 	if t == nil {
 		t = new(timer)
+		t.init(goroutineReady, gp)
+		if gp.syncGroup != nil {
+			t.isFake = true
+		}
 		gp.timer = t
 	}
-	t.f = goroutineReady
-	t.arg = gp
-	t.nextwhen = nanotime() + ns
-	if t.nextwhen < 0 { // check for overflow.
-		t.nextwhen = maxWhen
+	when := time_runtimeNano() + ns
+	if when < 0 { // check for overflow.
+		when = maxWhen
+	}
+	gp.sleepWhen = when
+	if t.isFake {
+		// Call timer.reset in this goroutine, since it's the one in a synctest bubble.
+		// The timer cannot run before the goroutine is parked,
+		// since fake time does not advance until the bubble is idle.
+		resetForSleep(gp, nil)
+		gopark(nil, nil, waitReasonSleep, traceBlockSleep, 1)
+	} else {
+		gopark(resetForSleep, nil, waitReasonSleep, traceBlockSleep, 1)
 	}
-	gopark(resetForSleep, unsafe.Pointer(t), waitReasonSleep, traceBlockSleep, 1)
 }
 
 // resetForSleep is called after the goroutine is parked for timeSleep.
-// We can't call resettimer in timeSleep itself because if this is a short
+// We can't call timer.reset in timeSleep itself because if this is a short
 // sleep and there are many goroutines then the P can wind up running the
 // timer function, goroutineReady, before the goroutine has been parked.
-func resetForSleep(gp *g, ut unsafe.Pointer) bool {
-	t := (*timer)(ut)
-	resettimer(t, t.nextwhen)
+func resetForSleep(gp *g, _ unsafe.Pointer) bool {
+	gp.timer.reset(gp.sleepWhen, 0)
 	return true
 }
 
-// startTimer adds t to the timer heap.
+// A timeTimer is a runtime-allocated time.Timer or time.Ticker
+// with the additional runtime state following it.
+// The runtime state is inaccessible to package time.
+type timeTimer struct {
+	c    unsafe.Pointer // <-chan time.Time
+	init bool
+	timer
+}
+
+// newTimer allocates and returns a new time.Timer or time.Ticker (same layout)
+// with the given parameters.
 //
-//go:linkname startTimer time.startTimer
-func startTimer(t *timer) {
+//go:linkname newTimer time.newTimer
+func newTimer(when, period int64, f func(arg any, seq uintptr, delay int64), arg any, c *hchan) *timeTimer {
+	t := new(timeTimer)
+	t.timer.init(nil, nil)
 	if raceenabled {
-		racerelease(unsafe.Pointer(t))
+		racerelease(unsafe.Pointer(&t.timer))
 	}
-	addtimer(t)
+	if c != nil {
+		lockInit(&t.sendLock, lockRankTimerSend)
+		t.isChan = true
+		c.timer = &t.timer
+		if c.dataqsiz == 0 {
+			throw("invalid timer channel: no capacity")
+		}
+	}
+	if getg().syncGroup != nil {
+		t.isFake = true
+	}
+	t.modify(when, period, f, arg, 0)
+	t.init = true
+	return t
 }
 
 // stopTimer stops a timer.
 // It reports whether t was stopped before being run.
 //
 //go:linkname stopTimer time.stopTimer
-func stopTimer(t *timer) bool {
-	return deltimer(t)
+func stopTimer(t *timeTimer) bool {
+	if t.isFake && getg().syncGroup == nil {
+		panic("stop of synctest timer from outside bubble")
+	}
+	return t.stop()
 }
 
-// resetTimer resets an inactive timer, adding it to the heap.
+// resetTimer resets an inactive timer, adding it to the timer heap.
 //
 // Reports whether the timer was modified before it was run.
 //
 //go:linkname resetTimer time.resetTimer
-func resetTimer(t *timer, when int64) bool {
+func resetTimer(t *timeTimer, when, period int64) bool {
 	if raceenabled {
-		racerelease(unsafe.Pointer(t))
+		racerelease(unsafe.Pointer(&t.timer))
 	}
-	return resettimer(t, when)
-}
-
-// modTimer modifies an existing timer.
-//
-//go:linkname modTimer time.modTimer
-func modTimer(t *timer, when, period int64, f func(any, uintptr), arg any, seq uintptr) {
-	modtimer(t, when, period, f, arg, seq)
+	if t.isFake && getg().syncGroup == nil {
+		panic("reset of synctest timer from outside bubble")
+	}
+	return t.reset(when, period)
 }
 
 // Go runtime.
 
 // Ready the goroutine arg.
-func goroutineReady(arg any, seq uintptr) {
+func goroutineReady(arg any, _ uintptr, _ int64) {
 	goready(arg.(*g), 0)
 }
 
-// Note: this changes some unsynchronized operations to synchronized operations
-// addtimer adds a timer to the current P.
-// This should only be called with a newly created timer.
-// That avoids the risk of changing the when field of a timer in some P's heap,
-// which could cause the heap to become unsorted.
-func addtimer(t *timer) {
-	// when must be positive. A negative value will cause runtimer to
-	// overflow during its delta calculation and never expire other runtime
-	// timers. Zero will cause checkTimers to fail to notice the timer.
-	if t.when <= 0 {
-		throw("timer when must be positive")
-	}
-	if t.period < 0 {
-		throw("timer period must be non-negative")
-	}
-	if t.status.Load() != timerNoStatus {
-		throw("addtimer called with initialized timer")
-	}
-	t.status.Store(timerWaiting)
-
-	when := t.when
-
-	// Disable preemption while using pp to avoid changing another P's heap.
-	mp := acquirem()
-
-	pp := getg().m.p.ptr()
-	lock(&pp.timersLock)
-	cleantimers(pp)
-	doaddtimer(pp, t)
-	unlock(&pp.timersLock)
-
-	wakeNetPoller(when)
-
-	releasem(mp)
-}
-
-// doaddtimer adds t to the current P's heap.
-// The caller must have locked the timers for pp.
-func doaddtimer(pp *p, t *timer) {
+// addHeap adds t to the timers heap.
+// The caller must hold ts.lock or the world must be stopped.
+// The caller must also have checked that t belongs in the heap.
+// Callers that are not sure can call t.maybeAdd instead,
+// but note that maybeAdd has different locking requirements.
+func (ts *timers) addHeap(t *timer) {
+	assertWorldStoppedOrLockHeld(&ts.mu)
 	// Timers rely on the network poller, so make sure the poller
 	// has started.
 	if netpollInited.Load() == 0 {
 		netpollGenericInit()
 	}
 
-	if t.pp != 0 {
-		throw("doaddtimer: P already set in timer")
+	if t.ts != nil {
+		throw("ts set in timer")
 	}
-	t.pp.set(pp)
-	i := len(pp.timers)
-	pp.timers = append(pp.timers, t)
-	siftupTimer(pp.timers, i)
-	if t == pp.timers[0] {
-		pp.timer0When.Store(t.when)
+	t.ts = ts
+	ts.heap = append(ts.heap, timerWhen{t, t.when})
+	ts.siftUp(len(ts.heap) - 1)
+	if t == ts.heap[0].timer {
+		ts.updateMinWhenHeap()
 	}
-	pp.numTimers.Add(1)
 }
 
-// deltimer deletes the timer t. It may be on some other P, so we can't
-// actually remove it from the timers heap. We can only mark it as deleted.
+// stop stops the timer t. It may be on some other P, so we can't
+// actually remove it from the timers heap. We can only mark it as stopped.
 // It will be removed in due course by the P whose heap it is on.
-// Reports whether the timer was removed before it was run.
-func deltimer(t *timer) bool {
-	for {
-		switch s := t.status.Load(); s {
-		case timerWaiting, timerModifiedLater:
-			// Prevent preemption while the timer is in timerModifying.
-			// This could lead to a self-deadlock. See #38070.
-			mp := acquirem()
-			if t.status.CompareAndSwap(s, timerModifying) {
-				// Must fetch t.pp before changing status,
-				// as cleantimers in another goroutine
-				// can clear t.pp of a timerDeleted timer.
-				tpp := t.pp.ptr()
-				if !t.status.CompareAndSwap(timerModifying, timerDeleted) {
-					badTimer()
-				}
-				releasem(mp)
-				tpp.deletedTimers.Add(1)
-				// Timer was not yet run.
-				return true
-			} else {
-				releasem(mp)
-			}
-		case timerModifiedEarlier:
-			// Prevent preemption while the timer is in timerModifying.
-			// This could lead to a self-deadlock. See #38070.
-			mp := acquirem()
-			if t.status.CompareAndSwap(s, timerModifying) {
-				// Must fetch t.pp before setting status
-				// to timerDeleted.
-				tpp := t.pp.ptr()
-				if !t.status.CompareAndSwap(timerModifying, timerDeleted) {
-					badTimer()
-				}
-				releasem(mp)
-				tpp.deletedTimers.Add(1)
-				// Timer was not yet run.
-				return true
-			} else {
-				releasem(mp)
-			}
-		case timerDeleted, timerRemoving, timerRemoved:
-			// Timer was already run.
-			return false
-		case timerRunning, timerMoving:
-			// The timer is being run or moved, by a different P.
-			// Wait for it to complete.
-			osyield()
-		case timerNoStatus:
-			// Removing timer that was never added or
-			// has already been run. Also see issue 21874.
-			return false
-		case timerModifying:
-			// Simultaneous calls to deltimer and modtimer.
-			// Wait for the other call to complete.
-			osyield()
-		default:
-			badTimer()
+// Reports whether the timer was stopped before it was run.
+func (t *timer) stop() bool {
+	if t.isChan {
+		lock(&t.sendLock)
+	}
+
+	t.lock()
+	if t.state&timerHeaped != 0 {
+		t.state |= timerModified
+		if t.state&timerZombie == 0 {
+			t.state |= timerZombie
+			t.ts.zombies.Add(1)
+		}
+	}
+	pending := t.when > 0
+	t.when = 0
+
+	if t.isChan {
+		// Stop any future sends with stale values.
+		// See timer.unlockAndRun.
+		t.seq++
+
+		// If there is currently a send in progress,
+		// incrementing seq is going to prevent that
+		// send from actually happening. That means
+		// that we should return true: the timer was
+		// stopped, even though t.when may be zero.
+		if t.period == 0 && t.isSending.Load() > 0 {
+			pending = true
+		}
+	}
+	t.unlock()
+	if t.isChan {
+		unlock(&t.sendLock)
+		if timerchandrain(t.hchan()) {
+			pending = true
 		}
 	}
-}
 
-// dodeltimer removes timer i from the current P's heap.
-// We are locked on the P when this is called.
-// It returns the smallest changed index in pp.timers.
-// The caller must have locked the timers for pp.
-func dodeltimer(pp *p, i int) int {
-	if t := pp.timers[i]; t.pp.ptr() != pp {
-		throw("dodeltimer: wrong P")
-	} else {
-		t.pp = 0
-	}
-	last := len(pp.timers) - 1
-	if i != last {
-		pp.timers[i] = pp.timers[last]
-	}
-	pp.timers[last] = nil
-	pp.timers = pp.timers[:last]
-	smallestChanged := i
-	if i != last {
-		// Moving to i may have moved the last timer to a new parent,
-		// so sift up to preserve the heap guarantee.
-		smallestChanged = siftupTimer(pp.timers, i)
-		siftdownTimer(pp.timers, i)
-	}
-	if i == 0 {
-		updateTimer0When(pp)
-	}
-	n := pp.numTimers.Add(-1)
-	if n == 0 {
-		// If there are no timers, then clearly none are modified.
-		pp.timerModifiedEarliest.Store(0)
-	}
-	return smallestChanged
+	return pending
 }
 
-// dodeltimer0 removes timer 0 from the current P's heap.
-// We are locked on the P when this is called.
-// It reports whether it saw no problems due to races.
-// The caller must have locked the timers for pp.
-func dodeltimer0(pp *p) {
-	if t := pp.timers[0]; t.pp.ptr() != pp {
-		throw("dodeltimer0: wrong P")
-	} else {
-		t.pp = 0
+// deleteMin removes timer 0 from ts.
+// ts must be locked.
+func (ts *timers) deleteMin() {
+	assertLockHeld(&ts.mu)
+	t := ts.heap[0].timer
+	if t.ts != ts {
+		throw("wrong timers")
 	}
-	last := len(pp.timers) - 1
+	t.ts = nil
+	last := len(ts.heap) - 1
 	if last > 0 {
-		pp.timers[0] = pp.timers[last]
+		ts.heap[0] = ts.heap[last]
 	}
-	pp.timers[last] = nil
-	pp.timers = pp.timers[:last]
+	ts.heap[last] = timerWhen{}
+	ts.heap = ts.heap[:last]
 	if last > 0 {
-		siftdownTimer(pp.timers, 0)
+		ts.siftDown(0)
 	}
-	updateTimer0When(pp)
-	n := pp.numTimers.Add(-1)
-	if n == 0 {
-		// If there are no timers, then clearly none are modified.
-		pp.timerModifiedEarliest.Store(0)
+	ts.updateMinWhenHeap()
+	if last == 0 {
+		// If there are no timers, then clearly there are no timerModified timers.
+		ts.minWhenModified.Store(0)
 	}
 }
 
-// modtimer modifies an existing timer.
+// modify modifies an existing timer.
 // This is called by the netpoll code or time.Ticker.Reset or time.Timer.Reset.
 // Reports whether the timer was modified before it was run.
-func modtimer(t *timer, when, period int64, f func(any, uintptr), arg any, seq uintptr) bool {
+// If f == nil, then t.f, t.arg, and t.seq are not modified.
+func (t *timer) modify(when, period int64, f func(arg any, seq uintptr, delay int64), arg any, seq uintptr) bool {
 	if when <= 0 {
 		throw("timer when must be positive")
 	}
@@ -444,125 +490,157 @@ func modtimer(t *timer, when, period int64, f func(any, uintptr), arg any, seq u
 		throw("timer period must be non-negative")
 	}
 
-	status := uint32(timerNoStatus)
-	wasRemoved := false
-	var pending bool
-	var mp *m
-loop:
-	for {
-		switch status = t.status.Load(); status {
-		case timerWaiting, timerModifiedEarlier, timerModifiedLater:
-			// Prevent preemption while the timer is in timerModifying.
-			// This could lead to a self-deadlock. See #38070.
-			mp = acquirem()
-			if t.status.CompareAndSwap(status, timerModifying) {
-				pending = true // timer not yet run
-				break loop
-			}
-			releasem(mp)
-		case timerNoStatus, timerRemoved:
-			// Prevent preemption while the timer is in timerModifying.
-			// This could lead to a self-deadlock. See #38070.
-			mp = acquirem()
-
-			// Timer was already run and t is no longer in a heap.
-			// Act like addtimer.
-			if t.status.CompareAndSwap(status, timerModifying) {
-				wasRemoved = true
-				pending = false // timer already run or stopped
-				break loop
-			}
-			releasem(mp)
-		case timerDeleted:
-			// Prevent preemption while the timer is in timerModifying.
-			// This could lead to a self-deadlock. See #38070.
-			mp = acquirem()
-			if t.status.CompareAndSwap(status, timerModifying) {
-				t.pp.ptr().deletedTimers.Add(-1)
-				pending = false // timer already stopped
-				break loop
-			}
-			releasem(mp)
-		case timerRunning, timerRemoving, timerMoving:
-			// The timer is being run or moved, by a different P.
-			// Wait for it to complete.
-			osyield()
-		case timerModifying:
-			// Multiple simultaneous calls to modtimer.
-			// Wait for the other call to complete.
-			osyield()
-		default:
-			badTimer()
-		}
+	if t.isChan {
+		lock(&t.sendLock)
 	}
 
+	t.lock()
+	oldPeriod := t.period
 	t.period = period
-	t.f = f
-	t.arg = arg
-	t.seq = seq
-
-	if wasRemoved {
-		t.when = when
-		pp := getg().m.p.ptr()
-		lock(&pp.timersLock)
-		doaddtimer(pp, t)
-		unlock(&pp.timersLock)
-		if !t.status.CompareAndSwap(timerModifying, timerWaiting) {
-			badTimer()
+	if f != nil {
+		t.f = f
+		t.arg = arg
+		t.seq = seq
+	}
+
+	wake := false
+	pending := t.when > 0
+	t.when = when
+	if t.state&timerHeaped != 0 {
+		t.state |= timerModified
+		if t.state&timerZombie != 0 {
+			// In the heap but marked for removal (by a Stop).
+			// Unmark it, since it has been Reset and will be running again.
+			t.ts.zombies.Add(-1)
+			t.state &^= timerZombie
 		}
-		releasem(mp)
-		wakeNetPoller(when)
-	} else {
-		// The timer is in some other P's heap, so we can't change
-		// the when field. If we did, the other P's heap would
-		// be out of order. So we put the new when value in the
-		// nextwhen field, and let the other P set the when field
-		// when it is prepared to resort the heap.
-		t.nextwhen = when
-
-		newStatus := uint32(timerModifiedLater)
-		if when < t.when {
-			newStatus = timerModifiedEarlier
+		// The corresponding heap[i].when is updated later.
+		// See comment in type timer above and in timers.adjust below.
+		if min := t.ts.minWhenModified.Load(); min == 0 || when < min {
+			wake = !t.isFake
+			// Force timerModified bit out to t.astate before updating t.minWhenModified,
+			// to synchronize with t.ts.adjust. See comment in adjust.
+			t.astate.Store(t.state)
+			t.ts.updateMinWhenModified(when)
 		}
+	}
 
-		tpp := t.pp.ptr()
+	add := t.needsAdd()
 
-		if newStatus == timerModifiedEarlier {
-			updateTimerModifiedEarliest(tpp, when)
-		}
+	if t.isChan {
+		// Stop any future sends with stale values.
+		// See timer.unlockAndRun.
+		t.seq++
 
-		// Set the new status of the timer.
-		if !t.status.CompareAndSwap(timerModifying, newStatus) {
-			badTimer()
+		// If there is currently a send in progress,
+		// incrementing seq is going to prevent that
+		// send from actually happening. That means
+		// that we should return true: the timer was
+		// stopped, even though t.when may be zero.
+		if oldPeriod == 0 && t.isSending.Load() > 0 {
+			pending = true
 		}
-		releasem(mp)
-
-		// If the new status is earlier, wake up the poller.
-		if newStatus == timerModifiedEarlier {
-			wakeNetPoller(when)
+	}
+	t.unlock()
+	if t.isChan {
+		if timerchandrain(t.hchan()) {
+			pending = true
 		}
+		unlock(&t.sendLock)
+	}
+
+	if add {
+		t.maybeAdd()
+	}
+	if wake {
+		wakeNetPoller(when)
 	}
 
 	return pending
 }
 
-// resettimer resets the time when a timer should fire.
+// needsAdd reports whether t needs to be added to a timers heap.
+// t must be locked.
+func (t *timer) needsAdd() bool {
+	assertLockHeld(&t.mu)
+	return t.state&timerHeaped == 0 && t.when > 0 && (!t.isChan || t.isFake || t.blocked > 0)
+}
+
+// maybeAdd adds t to the local timers heap if it needs to be in a heap.
+// The caller must not hold t's lock nor any timers heap lock.
+// The caller probably just unlocked t, but that lock must be dropped
+// in order to acquire a ts.lock, to avoid lock inversions.
+// (timers.adjust holds ts.lock while acquiring each t's lock,
+// so we cannot hold any t's lock while acquiring ts.lock).
+//
+// Strictly speaking it *might* be okay to hold t.lock and
+// acquire ts.lock at the same time, because we know that
+// t is not in any ts.heap, so nothing holding a ts.lock would
+// be acquiring the t.lock at the same time, meaning there
+// isn't a possible deadlock. But it is easier and safer not to be
+// too clever and respect the static ordering.
+// (If we don't, we have to change the static lock checking of t and ts.)
+//
+// Concurrent calls to time.Timer.Reset or blockTimerChan
+// may result in concurrent calls to t.maybeAdd,
+// so we cannot assume that t is not in a heap on entry to t.maybeAdd.
+func (t *timer) maybeAdd() {
+	// Note: Not holding any locks on entry to t.maybeAdd,
+	// so the current g can be rescheduled to a different M and P
+	// at any time, including between the ts := assignment and the
+	// call to ts.lock. If a reschedule happened then, we would be
+	// adding t to some other P's timers, perhaps even a P that the scheduler
+	// has marked as idle with no timers, in which case the timer could
+	// go unnoticed until long after t.when.
+	// Calling acquirem instead of using getg().m makes sure that
+	// we end up locking and inserting into the current P's timers.
+	mp := acquirem()
+	var ts *timers
+	if t.isFake {
+		sg := getg().syncGroup
+		if sg == nil {
+			throw("invalid timer: fake time but no syncgroup")
+		}
+		ts = &sg.timers
+	} else {
+		ts = &mp.p.ptr().timers
+	}
+	ts.lock()
+	ts.cleanHead()
+	t.lock()
+	when := int64(0)
+	wake := false
+	if t.needsAdd() {
+		t.state |= timerHeaped
+		when = t.when
+		wakeTime := ts.wakeTime()
+		wake = !t.isFake && (wakeTime == 0 || when < wakeTime)
+		ts.addHeap(t)
+	}
+	t.unlock()
+	ts.unlock()
+	releasem(mp)
+	if wake {
+		wakeNetPoller(when)
+	}
+}
+
+// reset resets the time when a timer should fire.
 // If used for an inactive timer, the timer will become active.
-// This should be called instead of addtimer if the timer value has been,
-// or may have been, used previously.
-// Reports whether the timer was modified before it was run.
-func resettimer(t *timer, when int64) bool {
-	return modtimer(t, when, t.period, t.f, t.arg, t.seq)
+// Reports whether the timer was active and was stopped.
+func (t *timer) reset(when, period int64) bool {
+	return t.modify(when, period, nil, nil, 0)
 }
 
-// cleantimers cleans up the head of the timer queue. This speeds up
+// cleanHead cleans up the head of the timer queue. This speeds up
 // programs that create and delete timers; leaving them in the heap
-// slows down addtimer. Reports whether no timer problems were found.
-// The caller must have locked the timers for pp.
-func cleantimers(pp *p) {
+// slows down heap operations.
+// The caller must have locked ts.
+func (ts *timers) cleanHead() {
+	assertLockHeld(&ts.mu)
 	gp := getg()
 	for {
-		if len(pp.timers) == 0 {
+		if len(ts.heap) == 0 {
 			return
 		}
 
@@ -574,453 +652,502 @@ func cleantimers(pp *p) {
 			return
 		}
 
-		t := pp.timers[0]
-		if t.pp.ptr() != pp {
-			throw("cleantimers: bad p")
-		}
-		switch s := t.status.Load(); s {
-		case timerDeleted:
-			if !t.status.CompareAndSwap(s, timerRemoving) {
-				continue
-			}
-			dodeltimer0(pp)
-			if !t.status.CompareAndSwap(timerRemoving, timerRemoved) {
-				badTimer()
-			}
-			pp.deletedTimers.Add(-1)
-		case timerModifiedEarlier, timerModifiedLater:
-			if !t.status.CompareAndSwap(s, timerMoving) {
-				continue
+		// Delete zombies from tail of heap. It requires no heap adjustments at all,
+		// and doing so increases the chances that when we swap out a zombie
+		// in heap[0] for the tail of the heap, we'll get a non-zombie timer,
+		// shortening this loop.
+		n := len(ts.heap)
+		if t := ts.heap[n-1].timer; t.astate.Load()&timerZombie != 0 {
+			t.lock()
+			if t.state&timerZombie != 0 {
+				t.state &^= timerHeaped | timerZombie | timerModified
+				t.ts = nil
+				ts.zombies.Add(-1)
+				ts.heap[n-1] = timerWhen{}
+				ts.heap = ts.heap[:n-1]
 			}
-			// Now we can change the when field.
-			t.when = t.nextwhen
-			// Move t to the right position.
-			dodeltimer0(pp)
-			doaddtimer(pp, t)
-			if !t.status.CompareAndSwap(timerMoving, timerWaiting) {
-				badTimer()
-			}
-		default:
+			t.unlock()
+			continue
+		}
+
+		t := ts.heap[0].timer
+		if t.ts != ts {
+			throw("bad ts")
+		}
+
+		if t.astate.Load()&(timerModified|timerZombie) == 0 {
+			// Fast path: head of timers does not need adjustment.
+			return
+		}
+
+		t.lock()
+		updated := t.updateHeap()
+		t.unlock()
+		if !updated {
 			// Head of timers does not need adjustment.
 			return
 		}
 	}
 }
 
-// moveTimers moves a slice of timers to pp. The slice has been taken
-// from a different P.
-// This is currently called when the world is stopped, but the caller
-// is expected to have locked the timers for pp.
-func moveTimers(pp *p, timers []*timer) {
-	for _, t := range timers {
-	loop:
-		for {
-			switch s := t.status.Load(); s {
-			case timerWaiting:
-				if !t.status.CompareAndSwap(s, timerMoving) {
-					continue
-				}
-				t.pp = 0
-				doaddtimer(pp, t)
-				if !t.status.CompareAndSwap(timerMoving, timerWaiting) {
-					badTimer()
-				}
-				break loop
-			case timerModifiedEarlier, timerModifiedLater:
-				if !t.status.CompareAndSwap(s, timerMoving) {
-					continue
-				}
-				t.when = t.nextwhen
-				t.pp = 0
-				doaddtimer(pp, t)
-				if !t.status.CompareAndSwap(timerMoving, timerWaiting) {
-					badTimer()
-				}
-				break loop
-			case timerDeleted:
-				if !t.status.CompareAndSwap(s, timerRemoved) {
-					continue
-				}
-				t.pp = 0
-				// We no longer need this timer in the heap.
-				break loop
-			case timerModifying:
-				// Loop until the modification is complete.
-				osyield()
-			case timerNoStatus, timerRemoved:
-				// We should not see these status values in a timers heap.
-				badTimer()
-			case timerRunning, timerRemoving, timerMoving:
-				// Some other P thinks it owns this timer,
-				// which should not happen.
-				badTimer()
-			default:
-				badTimer()
+// take moves any timers from src into ts
+// and then clears the timer state from src,
+// because src is being destroyed.
+// The caller must not have locked either timers.
+// For now this is only called when the world is stopped.
+func (ts *timers) take(src *timers) {
+	assertWorldStopped()
+	if len(src.heap) > 0 {
+		// The world is stopped, so we ignore the locking of ts and src here.
+		// That would introduce a sched < timers lock ordering,
+		// which we'd rather avoid in the static ranking.
+		for _, tw := range src.heap {
+			t := tw.timer
+			t.ts = nil
+			if t.state&timerZombie != 0 {
+				t.state &^= timerHeaped | timerZombie | timerModified
+			} else {
+				t.state &^= timerModified
+				ts.addHeap(t)
 			}
 		}
+		src.heap = nil
+		src.zombies.Store(0)
+		src.minWhenHeap.Store(0)
+		src.minWhenModified.Store(0)
+		src.len.Store(0)
+		ts.len.Store(uint32(len(ts.heap)))
 	}
 }
 
-// adjusttimers looks through the timers in the current P's heap for
+// adjust looks through the timers in ts.heap for
 // any timers that have been modified to run earlier, and puts them in
 // the correct place in the heap. While looking for those timers,
 // it also moves timers that have been modified to run later,
-// and removes deleted timers. The caller must have locked the timers for pp.
-func adjusttimers(pp *p, now int64) {
-	// If we haven't yet reached the time of the first timerModifiedEarlier
+// and removes deleted timers. The caller must have locked ts.
+func (ts *timers) adjust(now int64, force bool) {
+	assertLockHeld(&ts.mu)
+	// If we haven't yet reached the time of the earliest modified
 	// timer, don't do anything. This speeds up programs that adjust
 	// a lot of timers back and forth if the timers rarely expire.
 	// We'll postpone looking through all the adjusted timers until
 	// one would actually expire.
-	first := pp.timerModifiedEarliest.Load()
-	if first == 0 || first > now {
-		if verifyTimers {
-			verifyTimerHeap(pp)
+	if !force {
+		first := ts.minWhenModified.Load()
+		if first == 0 || first > now {
+			if verifyTimers {
+				ts.verify()
+			}
+			return
 		}
-		return
 	}
 
-	// We are going to clear all timerModifiedEarlier timers.
-	pp.timerModifiedEarliest.Store(0)
+	// minWhenModified is a lower bound on the earliest t.when
+	// among the timerModified timers. We want to make it more precise:
+	// we are going to scan the heap and clean out all the timerModified bits,
+	// at which point minWhenModified can be set to 0 (indicating none at all).
+	//
+	// Other P's can be calling ts.wakeTime concurrently, and we'd like to
+	// keep ts.wakeTime returning an accurate value throughout this entire process.
+	//
+	// Setting minWhenModified = 0 *before* the scan could make wakeTime
+	// return an incorrect value: if minWhenModified < minWhenHeap, then clearing
+	// it to 0 will make wakeTime return minWhenHeap (too late) until the scan finishes.
+	// To avoid that, we want to set minWhenModified to 0 *after* the scan.
+	//
+	// Setting minWhenModified = 0 *after* the scan could result in missing
+	// concurrent timer modifications in other goroutines; those will lock
+	// the specific timer, set the timerModified bit, and set t.when.
+	// To avoid that, we want to set minWhenModified to 0 *before* the scan.
+	//
+	// The way out of this dilemma is to preserve wakeTime a different way.
+	// wakeTime is min(minWhenHeap, minWhenModified), and minWhenHeap
+	// is protected by ts.lock, which we hold, so we can modify it however we like
+	// in service of keeping wakeTime accurate.
+	//
+	// So we can:
+	//
+	//	1. Set minWhenHeap = min(minWhenHeap, minWhenModified)
+	//	2. Set minWhenModified = 0
+	//	   (Other goroutines may modify timers and update minWhenModified now.)
+	//	3. Scan timers
+	//	4. Set minWhenHeap = heap[0].when
+	//
+	// That order preserves a correct value of wakeTime throughout the entire
+	// operation:
+	// Step 1 “locks in” an accurate wakeTime even with minWhenModified cleared.
+	// Step 2 makes sure concurrent t.when updates are not lost during the scan.
+	// Step 3 processes all modified timer values, justifying minWhenModified = 0.
+	// Step 4 corrects minWhenHeap to a precise value.
+	//
+	// The wakeTime method implementation reads minWhenModified *before* minWhenHeap,
+	// so that if the minWhenModified is observed to be 0, that means the minWhenHeap that
+	// follows will include the information that was zeroed out of it.
+	//
+	// Step 3 does an atomic load of t.astate to decide whether a timer
+	// needs a full lock. To make sure that we still observe any timer
+	// update already in progress during Steps 1+2, t.modify sets
+	// timerModified in t.astate *before* calling t.updateMinWhenModified.
+	// That ensures that the overwrite in Step 2 cannot lose an update:
+	// if it does overwrite an update, Step 3 will see the timerModified
+	// and do a full lock.
+	ts.minWhenHeap.Store(ts.wakeTime())
+	ts.minWhenModified.Store(0)
+
+	changed := false
+	for i := 0; i < len(ts.heap); i++ {
+		tw := &ts.heap[i]
+		t := tw.timer
+		if t.ts != ts {
+			throw("bad ts")
+		}
 
-	var moved []*timer
-	for i := 0; i < len(pp.timers); i++ {
-		t := pp.timers[i]
-		if t.pp.ptr() != pp {
-			throw("adjusttimers: bad p")
+		if t.astate.Load()&(timerModified|timerZombie) == 0 {
+			// Does not need adjustment.
+			continue
 		}
-		switch s := t.status.Load(); s {
-		case timerDeleted:
-			if t.status.CompareAndSwap(s, timerRemoving) {
-				changed := dodeltimer(pp, i)
-				if !t.status.CompareAndSwap(timerRemoving, timerRemoved) {
-					badTimer()
-				}
-				pp.deletedTimers.Add(-1)
-				// Go back to the earliest changed heap entry.
-				// "- 1" because the loop will add 1.
-				i = changed - 1
-			}
-		case timerModifiedEarlier, timerModifiedLater:
-			if t.status.CompareAndSwap(s, timerMoving) {
-				// Now we can change the when field.
-				t.when = t.nextwhen
-				// Take t off the heap, and hold onto it.
-				// We don't add it back yet because the
-				// heap manipulation could cause our
-				// loop to skip some other timer.
-				changed := dodeltimer(pp, i)
-				moved = append(moved, t)
-				// Go back to the earliest changed heap entry.
-				// "- 1" because the loop will add 1.
-				i = changed - 1
-			}
-		case timerNoStatus, timerRunning, timerRemoving, timerRemoved, timerMoving:
+
+		t.lock()
+		switch {
+		case t.state&timerHeaped == 0:
 			badTimer()
-		case timerWaiting:
-			// OK, nothing to do.
-		case timerModifying:
-			// Check again after modification is complete.
-			osyield()
+
+		case t.state&timerZombie != 0:
+			ts.zombies.Add(-1)
+			t.state &^= timerHeaped | timerZombie | timerModified
+			n := len(ts.heap)
+			ts.heap[i] = ts.heap[n-1]
+			ts.heap[n-1] = timerWhen{}
+			ts.heap = ts.heap[:n-1]
+			t.ts = nil
 			i--
-		default:
-			badTimer()
+			changed = true
+
+		case t.state&timerModified != 0:
+			tw.when = t.when
+			t.state &^= timerModified
+			changed = true
 		}
+		t.unlock()
 	}
 
-	if len(moved) > 0 {
-		addAdjustedTimers(pp, moved)
+	if changed {
+		ts.initHeap()
 	}
+	ts.updateMinWhenHeap()
 
 	if verifyTimers {
-		verifyTimerHeap(pp)
-	}
-}
-
-// addAdjustedTimers adds any timers we adjusted in adjusttimers
-// back to the timer heap.
-func addAdjustedTimers(pp *p, moved []*timer) {
-	for _, t := range moved {
-		doaddtimer(pp, t)
-		if !t.status.CompareAndSwap(timerMoving, timerWaiting) {
-			badTimer()
-		}
+		ts.verify()
 	}
 }
 
-// nobarrierWakeTime looks at P's timers and returns the time when we
+// wakeTime looks at ts's timers and returns the time when we
 // should wake up the netpoller. It returns 0 if there are no timers.
-// This function is invoked when dropping a P, and must run without
+// This function is invoked when dropping a P, so it must run without
 // any write barriers.
 //
 //go:nowritebarrierrec
-func nobarrierWakeTime(pp *p) int64 {
-	next := pp.timer0When.Load()
-	nextAdj := pp.timerModifiedEarliest.Load()
-	if next == 0 || (nextAdj != 0 && nextAdj < next) {
-		next = nextAdj
+func (ts *timers) wakeTime() int64 {
+	// Note that the order of these two loads matters:
+	// adjust updates minWhenHeap to make it safe to clear minWhenModified.
+	// We read minWhenHeap after reading minWhenModified so that
+	// if we see a cleared minWhenModified, we are guaranteed to see
+	// the updated minWhenHeap.
+	nextWhen := ts.minWhenModified.Load()
+	when := ts.minWhenHeap.Load()
+	if when == 0 || (nextWhen != 0 && nextWhen < when) {
+		when = nextWhen
 	}
-	return next
+	return when
+}
+
+// check runs any timers in ts that are ready.
+// If now is not 0 it is the current time.
+// It returns the passed time or the current time if now was passed as 0.
+// and the time when the next timer should run or 0 if there is no next timer,
+// and reports whether it ran any timers.
+// If the time when the next timer should run is not 0,
+// it is always larger than the returned time.
+// We pass now in and out to avoid extra calls of nanotime.
+//
+//go:yeswritebarrierrec
+func (ts *timers) check(now int64) (rnow, pollUntil int64, ran bool) {
+	// If it's not yet time for the first timer, or the first adjusted
+	// timer, then there is nothing to do.
+	next := ts.wakeTime()
+	if next == 0 {
+		// No timers to run or adjust.
+		return now, 0, false
+	}
+
+	if now == 0 {
+		now = nanotime()
+	}
+
+	// If this is the local P, and there are a lot of deleted timers,
+	// clear them out. We only do this for the local P to reduce
+	// lock contention on timersLock.
+	zombies := ts.zombies.Load()
+	if zombies < 0 {
+		badTimer()
+	}
+	force := ts == &getg().m.p.ptr().timers && int(zombies) > int(ts.len.Load())/4
+
+	if now < next && !force {
+		// Next timer is not ready to run, and we don't need to clear deleted timers.
+		return now, next, false
+	}
+
+	ts.lock()
+	if len(ts.heap) > 0 {
+		ts.adjust(now, force)
+		for len(ts.heap) > 0 {
+			// Note that runtimer may temporarily unlock ts.
+			if tw := ts.run(now); tw != 0 {
+				if tw > 0 {
+					pollUntil = tw
+				}
+				break
+			}
+			ran = true
+		}
+	}
+	ts.unlock()
+
+	return now, pollUntil, ran
 }
 
-// runtimer examines the first timer in timers. If it is ready based on now,
+// run examines the first timer in ts. If it is ready based on now,
 // it runs the timer and removes or updates it.
 // Returns 0 if it ran a timer, -1 if there are no more timers, or the time
 // when the first timer should run.
-// The caller must have locked the timers for pp.
-// If a timer is run, this will temporarily unlock the timers.
+// The caller must have locked ts.
+// If a timer is run, this will temporarily unlock ts.
 //
 //go:systemstack
-func runtimer(pp *p, now int64) int64 {
-	for {
-		t := pp.timers[0]
-		if t.pp.ptr() != pp {
-			throw("runtimer: bad p")
-		}
-		switch s := t.status.Load(); s {
-		case timerWaiting:
-			if t.when > now {
-				// Not ready to run.
-				return t.when
-			}
+func (ts *timers) run(now int64) int64 {
+	assertLockHeld(&ts.mu)
+Redo:
+	if len(ts.heap) == 0 {
+		return -1
+	}
+	tw := ts.heap[0]
+	t := tw.timer
+	if t.ts != ts {
+		throw("bad ts")
+	}
 
-			if !t.status.CompareAndSwap(s, timerRunning) {
-				continue
-			}
-			// Note that runOneTimer may temporarily unlock
-			// pp.timersLock.
-			runOneTimer(pp, t, now)
-			return 0
-
-		case timerDeleted:
-			if !t.status.CompareAndSwap(s, timerRemoving) {
-				continue
-			}
-			dodeltimer0(pp)
-			if !t.status.CompareAndSwap(timerRemoving, timerRemoved) {
-				badTimer()
-			}
-			pp.deletedTimers.Add(-1)
-			if len(pp.timers) == 0 {
-				return -1
-			}
+	if t.astate.Load()&(timerModified|timerZombie) == 0 && tw.when > now {
+		// Fast path: not ready to run.
+		return tw.when
+	}
 
-		case timerModifiedEarlier, timerModifiedLater:
-			if !t.status.CompareAndSwap(s, timerMoving) {
-				continue
-			}
-			t.when = t.nextwhen
-			dodeltimer0(pp)
-			doaddtimer(pp, t)
-			if !t.status.CompareAndSwap(timerMoving, timerWaiting) {
-				badTimer()
-			}
+	t.lock()
+	if t.updateHeap() {
+		t.unlock()
+		goto Redo
+	}
 
-		case timerModifying:
-			// Wait for modification to complete.
-			osyield()
+	if t.state&timerHeaped == 0 || t.state&timerModified != 0 {
+		badTimer()
+	}
 
-		case timerNoStatus, timerRemoved:
-			// Should not see a new or inactive timer on the heap.
-			badTimer()
-		case timerRunning, timerRemoving, timerMoving:
-			// These should only be set when timers are locked,
-			// and we didn't do it.
-			badTimer()
-		default:
-			badTimer()
-		}
+	if t.when > now {
+		// Not ready to run.
+		t.unlock()
+		return t.when
 	}
+
+	t.unlockAndRun(now)
+	assertLockHeld(&ts.mu) // t is unlocked now, but not ts
+	return 0
 }
 
-// runOneTimer runs a single timer.
-// The caller must have locked the timers for pp.
-// This will temporarily unlock the timers while running the timer function.
+// unlockAndRun unlocks and runs the timer t (which must be locked).
+// If t is in a timer set (t.ts != nil), the caller must also have locked the timer set,
+// and this call will temporarily unlock the timer set while running the timer function.
+// unlockAndRun returns with t unlocked and t.ts (re-)locked.
 //
 //go:systemstack
-func runOneTimer(pp *p, t *timer, now int64) {
+func (t *timer) unlockAndRun(now int64) {
+	assertLockHeld(&t.mu)
+	if t.ts != nil {
+		assertLockHeld(&t.ts.mu)
+	}
 	if raceenabled {
-		ppcur := getg().m.p.ptr()
-		if ppcur.timerRaceCtx == 0 {
-			ppcur.timerRaceCtx = racegostart(abi.FuncPCABIInternal(runtimer) + sys.PCQuantum)
+		// Note that we are running on a system stack,
+		// so there is no chance of getg().m being reassigned
+		// out from under us while this function executes.
+		tsLocal := &getg().m.p.ptr().timers
+		if tsLocal.raceCtx == 0 {
+			tsLocal.raceCtx = racegostart(abi.FuncPCABIInternal((*timers).run) + sys.PCQuantum)
 		}
-		raceacquirectx(ppcur.timerRaceCtx, unsafe.Pointer(t))
+		raceacquirectx(tsLocal.raceCtx, unsafe.Pointer(t))
+	}
+
+	if t.state&(timerModified|timerZombie) != 0 {
+		badTimer()
 	}
 
 	f := t.f
 	arg := t.arg
 	seq := t.seq
-
+	var next int64
+	delay := now - t.when
 	if t.period > 0 {
 		// Leave in heap but adjust next time to fire.
-		delta := t.when - now
-		t.when += t.period * (1 + -delta/t.period)
-		if t.when < 0 { // check for overflow.
-			t.when = maxWhen
-		}
-		siftdownTimer(pp.timers, 0)
-		if !t.status.CompareAndSwap(timerRunning, timerWaiting) {
-			badTimer()
+		next = t.when + t.period*(1+delay/t.period)
+		if next < 0 { // check for overflow.
+			next = maxWhen
 		}
-		updateTimer0When(pp)
 	} else {
-		// Remove from heap.
-		dodeltimer0(pp)
-		if !t.status.CompareAndSwap(timerRunning, timerNoStatus) {
-			badTimer()
+		next = 0
+	}
+	ts := t.ts
+	t.when = next
+	if t.state&timerHeaped != 0 {
+		t.state |= timerModified
+		if next == 0 {
+			t.state |= timerZombie
+			t.ts.zombies.Add(1)
 		}
+		t.updateHeap()
 	}
 
+	if t.isChan && t.period == 0 {
+		// Tell Stop/Reset that we are sending a value.
+		if t.isSending.Add(1) < 0 {
+			throw("too many concurrent timer firings")
+		}
+	}
+
+	t.unlock()
+
 	if raceenabled {
 		// Temporarily use the current P's racectx for g0.
 		gp := getg()
 		if gp.racectx != 0 {
-			throw("runOneTimer: unexpected racectx")
+			throw("unexpected racectx")
 		}
-		gp.racectx = gp.m.p.ptr().timerRaceCtx
+		gp.racectx = gp.m.p.ptr().timers.raceCtx
 	}
 
-	unlock(&pp.timersLock)
-
-	f(arg, seq)
-
-	lock(&pp.timersLock)
+	if ts != nil {
+		ts.unlock()
+	}
 
-	if raceenabled {
+	if ts != nil && ts.syncGroup != nil {
+		// Temporarily use the timer's synctest group for the G running this timer,
+		// so that the timer function runs as part of the bubble.
 		gp := getg()
-		gp.racectx = 0
+		if gp.syncGroup != nil {
+			throw("unexpected syncgroup set")
+		}
+		gp.syncGroup = ts.syncGroup
+		ts.syncGroup.changegstatus(gp, _Gdead, _Grunning)
 	}
-}
 
-// clearDeletedTimers removes all deleted timers from the P's timer heap.
-// This is used to avoid clogging up the heap if the program
-// starts a lot of long-running timers and then stops them.
-// For example, this can happen via context.WithTimeout.
-//
-// This is the only function that walks through the entire timer heap,
-// other than moveTimers which only runs when the world is stopped.
-//
-// The caller must have locked the timers for pp.
-func clearDeletedTimers(pp *p) {
-	// We are going to clear all timerModifiedEarlier timers.
-	// Do this now in case new ones show up while we are looping.
-	pp.timerModifiedEarliest.Store(0)
-
-	cdel := int32(0)
-	to := 0
-	changedHeap := false
-	timers := pp.timers
-nextTimer:
-	for _, t := range timers {
-		for {
-			switch s := t.status.Load(); s {
-			case timerWaiting:
-				if changedHeap {
-					timers[to] = t
-					siftupTimer(timers, to)
-				}
-				to++
-				continue nextTimer
-			case timerModifiedEarlier, timerModifiedLater:
-				if t.status.CompareAndSwap(s, timerMoving) {
-					t.when = t.nextwhen
-					timers[to] = t
-					siftupTimer(timers, to)
-					to++
-					changedHeap = true
-					if !t.status.CompareAndSwap(timerMoving, timerWaiting) {
-						badTimer()
-					}
-					continue nextTimer
-				}
-			case timerDeleted:
-				if t.status.CompareAndSwap(s, timerRemoving) {
-					t.pp = 0
-					cdel++
-					if !t.status.CompareAndSwap(timerRemoving, timerRemoved) {
-						badTimer()
-					}
-					changedHeap = true
-					continue nextTimer
-				}
-			case timerModifying:
-				// Loop until modification complete.
-				osyield()
-			case timerNoStatus, timerRemoved:
-				// We should not see these status values in a timer heap.
-				badTimer()
-			case timerRunning, timerRemoving, timerMoving:
-				// Some other P thinks it owns this timer,
-				// which should not happen.
-				badTimer()
-			default:
-				badTimer()
+	if t.isChan {
+		// For a timer channel, we want to make sure that no stale sends
+		// happen after a t.stop or t.modify, but we cannot hold t.mu
+		// during the actual send (which f does) due to lock ordering.
+		// It can happen that we are holding t's lock above, we decide
+		// it's time to send a time value (by calling f), grab the parameters,
+		// unlock above, and then a t.stop or t.modify changes the timer
+		// and returns. At that point, the send needs not to happen after all.
+		// The way we arrange for it not to happen is that t.stop and t.modify
+		// both increment t.seq while holding both t.mu and t.sendLock.
+		// We copied the seq value above while holding t.mu.
+		// Now we can acquire t.sendLock (which will be held across the send)
+		// and double-check that t.seq is still the seq value we saw above.
+		// If not, the timer has been updated and we should skip the send.
+		// We skip the send by reassigning f to a no-op function.
+		//
+		// The isSending field tells t.stop or t.modify that we have
+		// started to send the value. That lets them correctly return
+		// true meaning that no value was sent.
+		lock(&t.sendLock)
+
+		if t.period == 0 {
+			// We are committed to possibly sending a value
+			// based on seq, so no need to keep telling
+			// stop/modify that we are sending.
+			if t.isSending.Add(-1) < 0 {
+				throw("mismatched isSending updates")
 			}
 		}
+
+		if t.seq != seq {
+			f = func(any, uintptr, int64) {}
+		}
 	}
 
-	// Set remaining slots in timers slice to nil,
-	// so that the timer values can be garbage collected.
-	for i := to; i < len(timers); i++ {
-		timers[i] = nil
+	f(arg, seq, delay)
+
+	if t.isChan {
+		unlock(&t.sendLock)
 	}
 
-	pp.deletedTimers.Add(-cdel)
-	pp.numTimers.Add(-cdel)
+	if ts != nil && ts.syncGroup != nil {
+		gp := getg()
+		ts.syncGroup.changegstatus(gp, _Grunning, _Gdead)
+		gp.syncGroup = nil
+	}
 
-	timers = timers[:to]
-	pp.timers = timers
-	updateTimer0When(pp)
+	if ts != nil {
+		ts.lock()
+	}
 
-	if verifyTimers {
-		verifyTimerHeap(pp)
+	if raceenabled {
+		gp := getg()
+		gp.racectx = 0
 	}
 }
 
-// verifyTimerHeap verifies that the timer heap is in a valid state.
+// verify verifies that the timer heap is in a valid state.
 // This is only for debugging, and is only called if verifyTimers is true.
-// The caller must have locked the timers.
-func verifyTimerHeap(pp *p) {
-	for i, t := range pp.timers {
+// The caller must have locked ts.
+func (ts *timers) verify() {
+	assertLockHeld(&ts.mu)
+	for i, tw := range ts.heap {
 		if i == 0 {
 			// First timer has no parent.
 			continue
 		}
 
-		// The heap is 4-ary. See siftupTimer and siftdownTimer.
-		p := (i - 1) / 4
-		if t.when < pp.timers[p].when {
-			print("bad timer heap at ", i, ": ", p, ": ", pp.timers[p].when, ", ", i, ": ", t.when, "\n")
+		// The heap is timerHeapN-ary. See siftUp and siftDown.
+		p := int(uint(i-1) / timerHeapN)
+		if tw.when < ts.heap[p].when {
+			print("bad timer heap at ", i, ": ", p, ": ", ts.heap[p].when, ", ", i, ": ", tw.when, "\n")
 			throw("bad timer heap")
 		}
 	}
-	if numTimers := int(pp.numTimers.Load()); len(pp.timers) != numTimers {
-		println("timer heap len", len(pp.timers), "!= numTimers", numTimers)
+	if n := int(ts.len.Load()); len(ts.heap) != n {
+		println("timer heap len", len(ts.heap), "!= atomic len", n)
 		throw("bad timer heap len")
 	}
 }
 
-// updateTimer0When sets the P's timer0When field.
-// The caller must have locked the timers for pp.
-func updateTimer0When(pp *p) {
-	if len(pp.timers) == 0 {
-		pp.timer0When.Store(0)
+// updateMinWhenHeap sets ts.minWhenHeap to ts.heap[0].when.
+// The caller must have locked ts or the world must be stopped.
+func (ts *timers) updateMinWhenHeap() {
+	assertWorldStoppedOrLockHeld(&ts.mu)
+	if len(ts.heap) == 0 {
+		ts.minWhenHeap.Store(0)
 	} else {
-		pp.timer0When.Store(pp.timers[0].when)
+		ts.minWhenHeap.Store(ts.heap[0].when)
 	}
 }
 
-// updateTimerModifiedEarliest updates the recorded nextwhen field of the
-// earlier timerModifiedEarier value.
-// The timers for pp will not be locked.
-func updateTimerModifiedEarliest(pp *p, nextwhen int64) {
+// updateMinWhenModified updates ts.minWhenModified to be <= when.
+// ts need not be (and usually is not) locked.
+func (ts *timers) updateMinWhenModified(when int64) {
 	for {
-		old := pp.timerModifiedEarliest.Load()
-		if old != 0 && int64(old) < nextwhen {
+		old := ts.minWhenModified.Load()
+		if old != 0 && old < when {
 			return
 		}
-
-		if pp.timerModifiedEarliest.CompareAndSwap(old, nextwhen) {
+		if ts.minWhenModified.CompareAndSwap(old, when) {
 			return
 		}
 	}
@@ -1041,14 +1168,8 @@ func timeSleepUntil() int64 {
 			continue
 		}
 
-		w := pp.timer0When.Load()
-		if w != 0 && w < next {
-			next = w
-		}
-
-		w = pp.timerModifiedEarliest.Load()
-		if w != 0 && w < next {
-			next = w
+		if w := pp.timers.wakeTime(); w != 0 {
+			next = min(next, w)
 		}
 	}
 	unlock(&allpLock)
@@ -1056,6 +1177,8 @@ func timeSleepUntil() int64 {
 	return next
 }
 
+const timerHeapN = 4
+
 // Heap maintenance algorithms.
 // These algorithms check for slice index errors manually.
 // Slice index error can happen if the program is using racy
@@ -1064,74 +1187,77 @@ func timeSleepUntil() int64 {
 // "panic holding locks" message. Instead, we panic while not
 // holding a lock.
 
-// siftupTimer puts the timer at position i in the right place
+// siftUp puts the timer at position i in the right place
 // in the heap by moving it up toward the top of the heap.
-// It returns the smallest changed index.
-func siftupTimer(t []*timer, i int) int {
-	if i >= len(t) {
+func (ts *timers) siftUp(i int) {
+	heap := ts.heap
+	if i >= len(heap) {
 		badTimer()
 	}
-	when := t[i].when
+	tw := heap[i]
+	when := tw.when
 	if when <= 0 {
 		badTimer()
 	}
-	tmp := t[i]
 	for i > 0 {
-		p := (i - 1) / 4 // parent
-		if when >= t[p].when {
+		p := int(uint(i-1) / timerHeapN) // parent
+		if when >= heap[p].when {
 			break
 		}
-		t[i] = t[p]
+		heap[i] = heap[p]
 		i = p
 	}
-	if tmp != t[i] {
-		t[i] = tmp
-	}
-	return i
+	heap[i] = tw
 }
 
-// siftdownTimer puts the timer at position i in the right place
+// siftDown puts the timer at position i in the right place
 // in the heap by moving it down toward the bottom of the heap.
-func siftdownTimer(t []*timer, i int) {
-	n := len(t)
+func (ts *timers) siftDown(i int) {
+	heap := ts.heap
+	n := len(heap)
 	if i >= n {
 		badTimer()
 	}
-	when := t[i].when
+	if i*timerHeapN+1 >= n {
+		return
+	}
+	tw := heap[i]
+	when := tw.when
 	if when <= 0 {
 		badTimer()
 	}
-	tmp := t[i]
 	for {
-		c := i*4 + 1 // left child
-		c3 := c + 2  // mid child
-		if c >= n {
+		leftChild := i*timerHeapN + 1
+		if leftChild >= n {
 			break
 		}
-		w := t[c].when
-		if c+1 < n && t[c+1].when < w {
-			w = t[c+1].when
-			c++
-		}
-		if c3 < n {
-			w3 := t[c3].when
-			if c3+1 < n && t[c3+1].when < w3 {
-				w3 = t[c3+1].when
-				c3++
-			}
-			if w3 < w {
-				w = w3
-				c = c3
+		w := when
+		c := -1
+		for j, tw := range heap[leftChild:min(leftChild+timerHeapN, n)] {
+			if tw.when < w {
+				w = tw.when
+				c = leftChild + j
 			}
 		}
-		if w >= when {
+		if c < 0 {
 			break
 		}
-		t[i] = t[c]
+		heap[i] = heap[c]
 		i = c
 	}
-	if tmp != t[i] {
-		t[i] = tmp
+	heap[i] = tw
+}
+
+// initHeap reestablishes the heap order in the slice ts.heap.
+// It takes O(n) time for n=len(ts.heap), not the O(n log n) of n repeated add operations.
+func (ts *timers) initHeap() {
+	// Last possible element that needs sifting down is parent of last element;
+	// last element is len(t)-1; parent of last element is (len(t)-1-1)/timerHeapN.
+	if len(ts.heap) <= 1 {
+		return
+	}
+	for i := int(uint(len(ts.heap)-1-1) / timerHeapN); i >= 0; i-- {
+		ts.siftDown(i)
 	}
 }
 
@@ -1142,3 +1268,126 @@ func siftdownTimer(t []*timer, i int) {
 func badTimer() {
 	throw("timer data corruption")
 }
+
+// Timer channels.
+
+// Unless GODEBUG=asynctimerchan=1 is set, package time passes the
+// channel of a time.Timer or time.Ticker to newTimer, making it a
+// channel timer (t.isChan). A channel timer is only kept in a timers
+// heap while some goroutine is blocked receiving from its channel,
+// so that an unreferenced timer or ticker can be garbage collected
+// even if it was never stopped. When no goroutine is blocked, a
+// receive runs the timer on demand instead (see maybeRunChan).
+//
+// The exception is a channel timer created in a synctest bubble
+// (t.isFake), which always stays in the timers heap of its bubble,
+// so that the bubble knows when to advance its fake clock.
+//
+// Channel timers still use a channel with a 1-element buffer, but
+// present it to users as unbuffered: len and cap report 0, and stop
+// and modify drain the buffer, so that no value from before a Stop or
+// Reset can be received after it.
+
+// maybeRunChan checks whether the timer needs to run
+// to send a value to its associated channel. If so, it does.
+// The timer must not be locked.
+func (t *timer) maybeRunChan() {
+	if t.isFake {
+		t.lock()
+		var timerGroup *synctestGroup
+		if t.ts != nil {
+			timerGroup = t.ts.syncGroup
+		}
+		t.unlock()
+		sg := getg().syncGroup
+		if sg == nil {
+			panic(plainError("synctest timer accessed from outside bubble"))
+		}
+		if timerGroup != nil && sg != timerGroup {
+			panic(plainError("timer moved between synctest bubbles"))
+		}
+		// No need to do anything here.
+		// synctest.Run will run the timer when it advances its fake clock.
+		return
+	}
+	if t.astate.Load()&timerHeaped != 0 {
+		// If the timer is in the heap, the ordinary timer code
+		// is in charge of sending when appropriate.
+		return
+	}
+
+	t.lock()
+	now := nanotime()
+	if t.state&timerHeaped != 0 || t.when == 0 || t.when > now {
+		// Timer in the heap, or not running at all, or not triggered.
+		t.unlock()
+		return
+	}
+	systemstack(func() {
+		t.unlockAndRun(now)
+	})
+}
+
+// blockTimerChan is called when a channel op has decided to block on c.
+// The caller holds the channel lock for c and possibly other channels.
+// blockTimerChan makes sure that c is in a timer heap,
+// adding it if needed.
+func blockTimerChan(c *hchan) {
+	t := c.timer
+	if t.isFake {
+		// Fake timers are always in the heap of their bubble.
+		return
+	}
+	t.lock()
+	if !t.isChan {
+		badTimer()
+	}
+
+	t.blocked++
+
+	// If this is the first enqueue after a recent dequeue,
+	// the timer may still be in the heap but marked as a zombie.
+	// Unmark it in this case, if the timer is still pending.
+	if t.state&timerHeaped != 0 && t.state&timerZombie != 0 && t.when > 0 {
+		t.state &^= timerZombie
+		t.ts.zombies.Add(-1)
+	}
+
+	// t.maybeAdd must be called with t unlocked,
+	// because it needs to lock t.ts before t.
+	// Then it will do nothing if t.needsAdd(state) is false.
+	// Check that now before the unlock,
+	// avoiding the extra lock-lock-unlock-unlock
+	// inside maybeAdd when t does not need to be added.
+	add := t.needsAdd()
+	t.unlock()
+	if add {
+		t.maybeAdd()
+	}
+}
+
+// unblockTimerChan is called when a channel op that was blocked on c
+// is no longer blocked. Every call to blockTimerChan must be paired with
+// a call to unblockTimerChan.
+// The caller holds the channel lock for c and possibly other channels.
+// unblockTimerChan removes c from the timer heap when nothing is
+// blocked on it anymore.
+func unblockTimerChan(c *hchan) {
+	t := c.timer
+	if t.isFake {
+		return
+	}
+	t.lock()
+	if !t.isChan || t.blocked == 0 {
+		badTimer()
+	}
+	t.blocked--
+	if t.blocked == 0 && t.state&timerHeaped != 0 && t.state&timerZombie == 0 {
+		// Last goroutine that was blocked on this timer.
+		// Mark for removal from heap but do not clear t.when,
+		// so that we know what time it is still meant to trigger.
+		t.state |= timerZombie
+		t.ts.zombies.Add(1)
+	}
+	t.unlock()
+}
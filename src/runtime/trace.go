@@ -106,6 +106,7 @@ const (
 	traceBlockDebugCall                        = traceEvGoBlock
 	traceBlockUntilGCEnds                      = traceEvGoBlock
 	traceBlockSleep                            = traceEvGoSleep
+	traceBlockSynctest                         = traceEvGoBlock
 )
 
 const (
@@ -127,6 +128,8 @@ const (
 	traceBytesPerNumber = 10
 	// Shift of the number of arguments in the first event byte.
 	traceArgCountShift = 6
+	// Header at the start of the trace and of every generation.
+	traceHeader = "go 1.21 trace\x00\x00\x00"
 )
 
 // trace is global tracing context.
@@ -326,6 +329,29 @@ func StartTrace() error {
 	// here.)
 	atomicstorep(unsafe.Pointer(&trace.cpuLogWrite), unsafe.Pointer(profBuf))
 
+	trace.headerWritten = false
+	trace.footerWritten = false
+	traceStartGeneration(mp, stackID)
+
+	unlock(&trace.bufLock)
+
+	unlock(&sched.sysmonlock)
+
+	// Record the current state of HeapGoal to avoid information loss in trace.
+	traceHeapGoal()
+
+	startTheWorldGC()
+	return nil
+}
+
+// traceStartGeneration emits the events describing the state of the world at
+// the start of a generation of the trace (see traceAdvance), resets the
+// string and sequence state, and enables tracing. stackID is used as the
+// stack of all initial traceEvGoCreate events.
+//
+// The world must be stopped, trace.bufLock and sched.sysmonlock must be held,
+// and mp.trace.startingTrace must be set so that these events are not dropped.
+func traceStartGeneration(mp *m, stackID uint64) {
 	// World is stopped, no need to lock.
 	forEachGRace(func(gp *g) {
 		status := readgstatus(gp)
@@ -376,8 +402,6 @@ func StartTrace() error {
 	trace.startTime = traceClockNow()
 	trace.startTicks = cputicks()
 	trace.startNanotime = nanotime()
-	trace.headerWritten = false
-	trace.footerWritten = false
 
 	// string to id mapping
 	//  0 : reserved for an empty string
@@ -395,16 +419,6 @@ func StartTrace() error {
 		trace.markWorkerLabels[i], bufp = traceString(bufp, pid, label)
 	}
 	traceReleaseBuffer(mp, pid)
-
-	unlock(&trace.bufLock)
-
-	unlock(&sched.sysmonlock)
-
-	// Record the current state of HeapGoal to avoid information loss in trace.
-	traceHeapGoal()
-
-	startTheWorldGC()
-	return nil
 }
 
 // StopTrace stops tracing, if it was previously enabled.
@@ -433,6 +447,58 @@ func StopTrace() {
 	trace.cpuLogRead.close()
 	traceReadCPU()
 
+	traceQueueBuffers()
+	traceSetEndTime()
+
+	trace.enabled = false
+	trace.shutdown = true
+	unlock(&trace.bufLock)
+
+	unlock(&sched.sysmonlock)
+
+	startTheWorldGC()
+
+	// The world is started but we've set trace.shutdown, so new tracing can't start.
+	// Wait for the trace reader to flush pending buffers and stop.
+	semacquire(&trace.shutdownSema)
+	if raceenabled {
+		raceacquire(unsafe.Pointer(&trace.shutdownSema))
+	}
+
+	systemstack(func() {
+		// The lock protects us from races with StartTrace/StopTrace because they do stop-the-world.
+		lock(&trace.lock)
+		for _, p := range allp[:cap(allp)] {
+			if p.trace.buf != 0 {
+				throw("trace: non-empty trace buffer in proc")
+			}
+		}
+		if trace.buf != 0 {
+			throw("trace: non-empty global trace buffer")
+		}
+		if trace.fullHead != 0 || trace.fullTail != 0 {
+			throw("trace: non-empty full trace buffer")
+		}
+		if trace.reading != 0 || trace.reader.Load() != nil {
+			throw("trace: reading after shutdown")
+		}
+		for trace.empty != 0 {
+			buf := trace.empty
+			trace.empty = buf.ptr().link
+			sysFree(unsafe.Pointer(buf), unsafe.Sizeof(*buf.ptr()), &memstats.other_sys)
+		}
+		trace.strings = nil
+		trace.shutdown = false
+		trace.cpuLogRead = nil
+		unlock(&trace.lock)
+	})
+}
+
+// traceQueueBuffers moves all non-empty per-P, global and CPU sample
+// buffers to the full queue.
+//
+// The world must be stopped and trace.bufLock must be held.
+func traceQueueBuffers() {
 	// Loop over all allocated Ps because dead Ps may still have
 	// trace buffers.
 	for _, p := range allp[:cap(allp)] {
@@ -456,7 +522,10 @@ func StopTrace() {
 			traceFullQueue(buf)
 		}
 	}
+}
 
+// traceSetEndTime records the end time of the current generation of the trace.
+func traceSetEndTime() {
 	// Wait for startNanotime != endNanotime. On Windows the default interval between
 	// system clock ticks is typically between 1 and 15 milliseconds, which may not
 	// have passed since the trace started. Without nanotime moving forward, trace
@@ -472,49 +541,73 @@ func StopTrace() {
 		}
 		osyield()
 	}
+}
 
-	trace.enabled = false
-	trace.shutdown = true
-	unlock(&trace.bufLock)
-
-	unlock(&sched.sysmonlock)
-
-	startTheWorldGC()
+// traceAdvance ends the current generation of the trace and starts a new one.
+// Each generation is a complete trace on its own: it starts with the trace
+// header and the state of every goroutine, and ends with the timer frequency
+// and the stack table. This lets a reader keep only the most recent
+// generations of a long-running trace and still produce a valid trace from
+// them, see runtime/trace.FlightRecorder.
+//
+// traceAdvance does nothing if tracing is not enabled.
+//
+//go:linkname trace_traceAdvance runtime/trace.traceAdvance
+func trace_traceAdvance() {
+	// See the comments in StartTrace.
+	stopTheWorldGC(stwTraceAdvance)
+	lock(&sched.sysmonlock)
+	lock(&trace.bufLock)
 
-	// The world is started but we've set trace.shutdown, so new tracing can't start.
-	// Wait for the trace reader to flush pending buffers and stop.
-	semacquire(&trace.shutdownSema)
-	if raceenabled {
-		raceacquire(unsafe.Pointer(&trace.shutdownSema))
+	if !trace.enabled || trace.shutdown {
+		unlock(&trace.bufLock)
+		unlock(&sched.sysmonlock)
+		startTheWorldGC()
+		return
 	}
 
+	// End the current generation. Every other P is already stopped.
+	mp := getg().m
+	traceGoSched()
+	traceProcStop(mp.p.ptr())
+	traceReadCPU()
+
+	// Drop any event until the new generation starts, such as the
+	// allocations performed while writing the footer.
+	trace.enabled = false
+	traceQueueBuffers()
+	traceSetEndTime()
 	systemstack(func() {
-		// The lock protects us from races with StartTrace/StopTrace because they do stop-the-world.
-		lock(&trace.lock)
-		for _, p := range allp[:cap(allp)] {
-			if p.trace.buf != 0 {
-				throw("trace: non-empty trace buffer in proc")
-			}
-		}
-		if trace.buf != 0 {
-			throw("trace: non-empty global trace buffer")
-		}
-		if trace.fullHead != 0 || trace.fullTail != 0 {
-			throw("trace: non-empty full trace buffer")
-		}
-		if trace.reading != 0 || trace.reader.Load() != nil {
-			throw("trace: reading after shutdown")
-		}
-		for trace.empty != 0 {
-			buf := trace.empty
-			trace.empty = buf.ptr().link
-			sysFree(unsafe.Pointer(buf), unsafe.Sizeof(*buf.ptr()), &memstats.other_sys)
+		if raceenabled {
+			// See the comment in readTrace0.
+			getg().racectx = getg().m.curg.racectx
+			defer func() { getg().racectx = 0 }()
 		}
-		trace.strings = nil
-		trace.shutdown = false
-		trace.cpuLogRead = nil
+		bufp := traceFooter()
+
+		// Queue the header of the next generation in a buffer of its own,
+		// so that the reader returns it as a separate chunk right after
+		// the footer.
+		bufp = traceFlush(bufp, 0)
+		buf := bufp.ptr()
+		buf.pos = copy(buf.arr[:], traceHeader)
+		lock(&trace.lock)
+		traceFullQueue(bufp)
 		unlock(&trace.lock)
 	})
+
+	stkBuf := make([]uintptr, traceStackSize)
+	stackID := traceStackID(mp, stkBuf, 2)
+	mp.trace.startingTrace = true
+	traceStartGeneration(mp, stackID)
+
+	unlock(&trace.bufLock)
+	unlock(&sched.sysmonlock)
+
+	// See the comment in StartTrace.
+	traceHeapGoal()
+
+	startTheWorldGC()
 }
 
 // ReadTrace returns the next chunk of binary tracing data, blocking until data
@@ -603,7 +696,7 @@ func readTrace0() (buf []byte, park bool) {
 	if !trace.headerWritten {
 		trace.headerWritten = true
 		unlock(&trace.lock)
-		return []byte("go 1.21 trace\x00\x00\x00"), false
+		return []byte(traceHeader), false
 	}
 	// Wait for new data.
 	if trace.fullHead == 0 && !trace.shutdown {
@@ -626,22 +719,11 @@ newFull:
 	// Write footer with timer frequency.
 	if !trace.footerWritten {
 		trace.footerWritten = true
-		freq := (float64(trace.endTicks-trace.startTicks) / traceTimeDiv) / (float64(trace.endNanotime-trace.startNanotime) / 1e9)
-		if freq <= 0 {
-			throw("trace: ReadTrace got invalid frequency")
-		}
 		unlock(&trace.lock)
 
-		// Write frequency event.
-		bufp := traceFlush(0, 0)
-		buf := bufp.ptr()
-		buf.byte(traceEvFrequency | 0<<traceArgCountShift)
-		buf.varint(uint64(freq))
-
-		// Dump stack table.
 		// This will emit a bunch of full buffers, we will pick them up
 		// on the next iteration.
-		bufp = trace.stackTab.dump(bufp)
+		bufp := traceFooter()
 
 		// Flush final buffer.
 		lock(&trace.lock)
@@ -667,6 +749,30 @@ newFull:
 	return nil, false
 }
 
+// traceFooter writes the footer of the current generation of the trace: the
+// timer frequency, followed by the stack table, which is reset. It returns the
+// last buffer it wrote to, which the caller must queue.
+//
+// This must run on the system stack because it writes trace buffers, and
+// must be called without trace.lock held.
+//
+//go:systemstack
+func traceFooter() traceBufPtr {
+	freq := (float64(trace.endTicks-trace.startTicks) / traceTimeDiv) / (float64(trace.endNanotime-trace.startNanotime) / 1e9)
+	if freq <= 0 {
+		throw("trace: ReadTrace got invalid frequency")
+	}
+
+	// Write frequency event.
+	bufp := traceFlush(0, 0)
+	buf := bufp.ptr()
+	buf.byte(traceEvFrequency | 0<<traceArgCountShift)
+	buf.varint(uint64(freq))
+
+	// Dump stack table.
+	return trace.stackTab.dump(bufp)
+}
+
 // traceReader returns the trace reader that should be woken up, if any.
 // Callers should first check that trace.enabled or trace.shutdown is set.
 //
@@ -1504,9 +1610,9 @@ func traceGCDone() {
 }
 
 func traceSTWStart(reason stwReason) {
-	// Don't trace if this STW is for trace start/stop, since traceEnabled
-	// switches during a STW.
-	if reason == stwStartTrace || reason == stwStopTrace {
+	// Don't trace if this STW is for trace start/stop/advance, since
+	// traceEnabled switches during a STW.
+	if reason == stwStartTrace || reason == stwStopTrace || reason == stwTraceAdvance {
 		return
 	}
 	getg().m.trace.tracedSTWStart = true
@@ -0,0 +1,20 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import "time"
+
+// SetGenerationPeriod sets the interval between the generations of a trace
+// written by Start, and returns a function that restores the previous one.
+func SetGenerationPeriod(d time.Duration) (restore func()) {
+	old := generationPeriod
+	generationPeriod = d
+	return func() { generationPeriod = old }
+}
+
+// Period returns the interval between the generations started by fr.
+func (fr *FlightRecorder) Period() time.Duration {
+	return fr.period
+}
@@ -0,0 +1,272 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// FlightRecorderConfig is the configuration of a [FlightRecorder].
+type FlightRecorderConfig struct {
+	// MinAge is a lower bound on the age of the trace data kept by the
+	// flight recorder: a snapshot covers at least the last MinAge of
+	// execution, unless that would exceed MaxBytes. Older data is
+	// discarded promptly, but a snapshot may still include some of it.
+	//
+	// If MinAge is zero, a default of 10 seconds is used.
+	MinAge time.Duration
+
+	// MaxBytes is an upper bound on the size of the trace data kept by
+	// the flight recorder, and takes precedence over MinAge. The most
+	// recent part of the trace is always kept, even if it alone exceeds
+	// MaxBytes.
+	//
+	// If MaxBytes is zero, a default of 10 MiB is used.
+	MaxBytes uint64
+}
+
+// A FlightRecorder records an execution trace of the program into an
+// in-memory window covering its recent past, which can be written out on
+// demand with [FlightRecorder.WriteTo]. This makes it possible to capture
+// what led up to a rare event, such as a latency spike, without writing a
+// trace of the whole execution.
+//
+// The window is made of generations, self-contained parts of the trace that
+// the runtime produces periodically while the flight recorder is enabled,
+// each of which can be parsed on its own. The flight recorder discards the
+// oldest generations as new ones are produced, according to its
+// [FlightRecorderConfig].
+//
+// Only one of a FlightRecorder and [Start] may be active at a time.
+type FlightRecorder struct {
+	minAge   time.Duration
+	maxBytes uint64
+	period   time.Duration // interval between generations
+
+	// The following fields are protected by tracing.
+	enabled     bool
+	kick        chan struct{} // requests a new generation early
+	stopAdvance func()        // stops starting generations
+	rdDone      chan struct{} // closed when the reader exits
+
+	// advanceMu serializes the starts of new generations.
+	advanceMu sync.Mutex
+	advances  uint64 // number of generations started by traceAdvance
+
+	mu   sync.Mutex
+	cond sync.Cond // signaled when a generation completes or the reader exits
+	gens []generation
+	size uint64     // total size of gens
+	cur  generation // generation being read
+	seen uint64     // number of generation boundaries read
+	eof  bool       // the reader has exited
+}
+
+// minGenerationPeriod is the shortest interval between the generations
+// started periodically by a flight recorder, however small its MinAge.
+const minGenerationPeriod = 100 * time.Millisecond
+
+// A generation is the data of a self-contained part of the trace.
+type generation struct {
+	start time.Time // when its first chunk was read
+	data  [][]byte
+	size  uint64
+}
+
+// NewFlightRecorder creates a new flight recorder with the given
+// configuration. The flight recorder must be started with
+// [FlightRecorder.Start] before it records anything.
+func NewFlightRecorder(cfg FlightRecorderConfig) *FlightRecorder {
+	fr := &FlightRecorder{
+		minAge:   cfg.MinAge,
+		maxBytes: cfg.MaxBytes,
+	}
+	if fr.minAge <= 0 {
+		fr.minAge = 10 * time.Second
+	}
+	if fr.maxBytes == 0 {
+		fr.maxBytes = 10 << 20
+	}
+	// Start generations often enough that the window does not exceed
+	// MinAge by much, and at least once a second, but no more than ten
+	// times a second: each one stops the world briefly and repeats the
+	// state of all goroutines.
+	fr.period = min(max(fr.minAge/4, minGenerationPeriod), time.Second)
+	fr.cond.L = &fr.mu
+	return fr
+}
+
+// Start starts recording the execution trace into the flight recorder's
+// window. Start returns an error if the flight recorder or tracing is
+// already enabled.
+func (fr *FlightRecorder) Start() error {
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	if fr.enabled {
+		return errors.New("trace: flight recorder already enabled")
+	}
+	if err := runtime.StartTrace(); err != nil {
+		return err
+	}
+	fr.enabled = true
+	fr.kick = make(chan struct{}, 1)
+	fr.rdDone = make(chan struct{})
+	fr.advances = 0
+	fr.mu.Lock()
+	fr.gens, fr.size, fr.cur, fr.seen, fr.eof = nil, 0, generation{}, 0, false
+	fr.mu.Unlock()
+	go fr.read()
+	fr.stopAdvance = startAdvancing(fr.period, fr.kick, func() { fr.startGeneration() })
+
+	tracing.enabled.Store(true)
+	tracing.flightRecorder = true
+	return nil
+}
+
+// Stop stops the flight recorder and discards its window. It does nothing
+// if the flight recorder is not enabled.
+func (fr *FlightRecorder) Stop() {
+	tracing.Lock()
+	defer tracing.Unlock()
+
+	if !fr.enabled {
+		return
+	}
+	fr.enabled = false
+	tracing.enabled.Store(false)
+	tracing.flightRecorder = false
+
+	// Stop starting generations before stopping the trace, so that a
+	// generation of a later trace cannot be started.
+	fr.stopAdvance()
+	runtime.StopTrace()
+	<-fr.rdDone
+
+	fr.mu.Lock()
+	fr.gens, fr.size, fr.cur = nil, 0, generation{}
+	fr.mu.Unlock()
+}
+
+// Enabled reports whether the flight recorder is enabled.
+func (fr *FlightRecorder) Enabled() bool {
+	tracing.Lock()
+	defer tracing.Unlock()
+	return fr.enabled
+}
+
+// WriteTo writes a snapshot of the flight recorder's window to w, as a
+// trace that can be read by go tool trace. The snapshot ends at the time
+// of the call. WriteTo returns the number of bytes written and any error
+// encountered, including if the flight recorder is not enabled.
+func (fr *FlightRecorder) WriteTo(w io.Writer) (n int64, err error) {
+	tracing.Lock()
+	if !fr.enabled {
+		tracing.Unlock()
+		return 0, errors.New("trace: flight recorder not enabled")
+	}
+	// End the current generation so the snapshot includes everything
+	// up to now, and wait for the reader to complete it.
+	target := fr.startGeneration()
+	tracing.Unlock()
+
+	fr.mu.Lock()
+	for fr.seen < target && !fr.eof {
+		fr.cond.Wait()
+	}
+	gens := append([]generation(nil), fr.gens...)
+	fr.mu.Unlock()
+
+	for _, g := range gens {
+		for _, b := range g.data {
+			m, err := w.Write(b)
+			n += int64(m)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// startGeneration ends the current generation of the trace and starts a new
+// one. It returns the number of generation boundaries the reader will have
+// seen once it reads the new generation.
+func (fr *FlightRecorder) startGeneration() uint64 {
+	fr.advanceMu.Lock()
+	defer fr.advanceMu.Unlock()
+	traceAdvance()
+	fr.advances++
+	return fr.advances
+}
+
+// read reads the trace into generations, and discards the generations that
+// have fallen out of the window.
+func (fr *FlightRecorder) read() {
+	defer close(fr.rdDone)
+
+	for {
+		data := runtime.ReadTrace()
+		if data == nil {
+			break
+		}
+		now := time.Now()
+
+		fr.mu.Lock()
+		// Every generation starts with a chunk holding the trace header.
+		if bytes.HasPrefix(data, []byte("go 1.")) && fr.cur.size > 0 {
+			fr.complete(now)
+		}
+		if fr.cur.size == 0 {
+			fr.cur.start = now
+		}
+		fr.cur.data = append(fr.cur.data, bytes.Clone(data))
+		fr.cur.size += uint64(len(data))
+		full := fr.cur.size > fr.maxBytes/4
+		fr.mu.Unlock()
+
+		if full {
+			// Keep generations small compared to the window, so it
+			// does not lose much when discarding the oldest one.
+			select {
+			case fr.kick <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	fr.mu.Lock()
+	fr.eof = true
+	fr.cond.Broadcast()
+	fr.mu.Unlock()
+}
+
+// complete adds the current generation to the window, and discards the
+// oldest generations that are no longer needed to cover MinAge or that do
+// not fit in MaxBytes. fr.mu must be held.
+func (fr *FlightRecorder) complete(now time.Time) {
+	fr.gens = append(fr.gens, fr.cur)
+	fr.size += fr.cur.size
+	fr.cur = generation{}
+	fr.seen++
+
+	for len(fr.gens) > 1 {
+		if fr.size <= fr.maxBytes && now.Sub(fr.gens[1].start) < fr.minAge {
+			break
+		}
+		fr.size -= fr.gens[0].size
+		fr.gens[0] = generation{}
+		fr.gens = fr.gens[1:]
+	}
+	fr.cond.Broadcast()
+}
+
+// traceAdvance is implemented in runtime/trace.go.
+func traceAdvance()
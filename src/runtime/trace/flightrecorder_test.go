@@ -0,0 +1,189 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"internal/trace"
+	. "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: time.Minute})
+	if fr.Enabled() {
+		t.Fatal("flight recorder enabled before Start")
+	}
+	if _, err := fr.WriteTo(new(bytes.Buffer)); err == nil {
+		t.Fatal("WriteTo succeeded on a flight recorder that is not enabled")
+	}
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer fr.Stop()
+	if !fr.Enabled() || !IsEnabled() {
+		t.Fatal("flight recorder not enabled after Start")
+	}
+	if err := fr.Start(); err == nil {
+		t.Fatal("second Start succeeded")
+	}
+	if err := Start(new(bytes.Buffer)); err == nil {
+		t.Fatal("Start succeeded while the flight recorder is enabled")
+	}
+	Stop() // Must not stop the flight recorder.
+
+	// Produce a few generations of annotated activity.
+	ctx, task := NewTask(context.Background(), "flight")
+	for i := 0; i < 3; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < 4; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				WithRegion(ctx, "work", func() {
+					Log(ctx, "key", "value")
+				})
+			}()
+		}
+		wg.Wait()
+		var buf bytes.Buffer
+		if _, err := fr.WriteTo(&buf); err != nil {
+			t.Fatalf("WriteTo failed: %v", err)
+		}
+	}
+	task.End()
+
+	var buf bytes.Buffer
+	n, err := fr.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo returned %d, wrote %d bytes", n, buf.Len())
+	}
+	saveTrace(t, &buf, "TestFlightRecorder")
+	if gens := bytes.Count(buf.Bytes(), []byte("go 1.")); gens < 4 {
+		t.Errorf("snapshot has %d generations, want at least 4", gens)
+	}
+	events, _ := parseTrace(t, &buf)
+	logs, tasks := 0, 0
+	for _, ev := range events {
+		switch ev.Type {
+		case trace.EvUserLog:
+			if ev.SArgs[0] == "key" && ev.SArgs[1] == "value" {
+				logs++
+			}
+		case trace.EvUserTaskCreate, trace.EvUserTaskEnd:
+			tasks++
+		}
+	}
+	if logs != 12 {
+		t.Errorf("snapshot has %d log events, want 12", logs)
+	}
+	if tasks != 2 {
+		t.Errorf("snapshot has %d task events, want 2", tasks)
+	}
+
+	fr.Stop()
+	if fr.Enabled() || IsEnabled() {
+		t.Fatal("flight recorder enabled after Stop")
+	}
+	if _, err := fr.WriteTo(new(bytes.Buffer)); err == nil {
+		t.Fatal("WriteTo succeeded on a stopped flight recorder")
+	}
+}
+
+func TestFlightRecorderWindow(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: 100 * time.Millisecond})
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer fr.Stop()
+
+	ctx := context.Background()
+	Log(ctx, "window", "old")
+	time.Sleep(time.Second)
+	Log(ctx, "window", "new")
+
+	var buf bytes.Buffer
+	if _, err := fr.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	saveTrace(t, &buf, "TestFlightRecorderWindow")
+	events, _ := parseTrace(t, &buf)
+	found := map[string]bool{}
+	for _, ev := range events {
+		if ev.Type == trace.EvUserLog && ev.SArgs[0] == "window" {
+			found[ev.SArgs[1]] = true
+		}
+	}
+	if found["old"] {
+		t.Errorf("snapshot includes an event older than MinAge")
+	}
+	if !found["new"] {
+		t.Errorf("snapshot is missing the most recent event")
+	}
+}
+
+func TestFlightRecorderPeriod(t *testing.T) {
+	for _, tt := range []struct {
+		minAge, want time.Duration
+	}{
+		{0, time.Second}, // default MinAge of 10s
+		{time.Minute, time.Second},
+		{2 * time.Second, 500 * time.Millisecond},
+		{time.Second, 250 * time.Millisecond},
+		{400 * time.Millisecond, 100 * time.Millisecond},
+		{40 * time.Millisecond, 100 * time.Millisecond},
+		{time.Nanosecond, 100 * time.Millisecond},
+	} {
+		fr := NewFlightRecorder(FlightRecorderConfig{MinAge: tt.minAge})
+		if got := fr.Period(); got != tt.want {
+			t.Errorf("MinAge %v: got generation period %v, want %v", tt.minAge, got, tt.want)
+		}
+	}
+}
+
+func TestFlightRecorderSmallMinAge(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := NewFlightRecorder(FlightRecorderConfig{MinAge: 40 * time.Millisecond})
+	if err := fr.Start(); err != nil {
+		t.Fatalf("failed to start flight recorder: %v", err)
+	}
+	defer fr.Stop()
+
+	ctx := context.Background()
+	time.Sleep(250 * time.Millisecond)
+	Log(ctx, "window", "new")
+
+	var buf bytes.Buffer
+	if _, err := fr.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	events, _ := parseTrace(t, &buf)
+	found := false
+	for _, ev := range events {
+		if ev.Type == trace.EvUserLog && ev.SArgs[0] == "window" && ev.SArgs[1] == "new" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("snapshot is missing the most recent event")
+	}
+}
@@ -0,0 +1,464 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"fmt"
+	"internal/trace"
+	"iter"
+	"strings"
+	"time"
+)
+
+// EventKind indicates the kind of an [Event], and which of its accessor
+// methods can be used.
+type EventKind uint8
+
+const (
+	EventBad EventKind = iota
+
+	// EventSync marks the start of a generation of the trace. Events
+	// before it and after it are consistent with each other.
+	EventSync
+
+	// EventMetric is a sample of a runtime metric, see [Event.Metric].
+	EventMetric
+
+	// EventLabel attaches a label to a goroutine, see [Event.Label].
+	EventLabel
+
+	// EventStackSample is a CPU profile sample of the stack of the
+	// goroutine or P that the event refers to.
+	EventStackSample
+
+	// EventRangeBegin and EventRangeEnd mark the start and end of a
+	// runtime activity such as a GC phase, see [Event.Range].
+	EventRangeBegin
+	EventRangeEnd
+
+	// EventTaskBegin and EventTaskEnd mark the start and end of a task
+	// created with runtime/trace.NewTask, see [Event.Task].
+	EventTaskBegin
+	EventTaskEnd
+
+	// EventRegionBegin and EventRegionEnd mark the start and end of a
+	// region in a goroutine, see [Event.Region].
+	EventRegionBegin
+	EventRegionEnd
+
+	// EventLog is a message logged with runtime/trace.Log, see
+	// [Event.Log].
+	EventLog
+
+	// EventStateTransition is a change of the state of a goroutine or a P,
+	// see [Event.StateTransition].
+	EventStateTransition
+)
+
+var eventKindStrings = [...]string{
+	EventBad:             "Bad",
+	EventSync:            "Sync",
+	EventMetric:          "Metric",
+	EventLabel:           "Label",
+	EventStackSample:     "StackSample",
+	EventRangeBegin:      "RangeBegin",
+	EventRangeEnd:        "RangeEnd",
+	EventTaskBegin:       "TaskBegin",
+	EventTaskEnd:         "TaskEnd",
+	EventRegionBegin:     "RegionBegin",
+	EventRegionEnd:       "RegionEnd",
+	EventLog:             "Log",
+	EventStateTransition: "StateTransition",
+}
+
+func (k EventKind) String() string {
+	if int(k) < len(eventKindStrings) {
+		return eventKindStrings[k]
+	}
+	return eventKindStrings[EventBad]
+}
+
+// Time is a timestamp in a trace, in nanoseconds since the start of the
+// trace.
+type Time int64
+
+// Sub returns the duration t-t0.
+func (t Time) Sub(t0 Time) time.Duration {
+	return time.Duration(t - t0)
+}
+
+// GoID is the ID of a goroutine.
+type GoID int64
+
+// NoGoroutine indicates that an event is not associated with a goroutine.
+const NoGoroutine GoID = -1
+
+// ProcID is the ID of a P.
+type ProcID int64
+
+// NoProc indicates that an event is not associated with a P.
+const NoProc ProcID = -1
+
+// TaskID is the ID of a task.
+type TaskID uint64
+
+// NoTask indicates that an event is not associated with a task.
+const NoTask TaskID = 0
+
+// A Stack is a stack trace recorded in the trace.
+type Stack struct {
+	frames []*trace.Frame
+}
+
+// NoStack is the empty stack, for events without one.
+var NoStack = Stack{}
+
+// A StackFrame is a frame of a stack trace.
+type StackFrame struct {
+	PC   uint64
+	Func string
+	File string
+	Line uint64
+}
+
+// Frames returns an iterator over the frames of s, from the innermost
+// to the outermost.
+func (s Stack) Frames() iter.Seq[StackFrame] {
+	return func(yield func(StackFrame) bool) {
+		for _, f := range s.frames {
+			if !yield(StackFrame{PC: f.PC, Func: f.Fn, File: f.File, Line: uint64(f.Line)}) {
+				return
+			}
+		}
+	}
+}
+
+// Metric is a sample of a runtime metric. Its name is that of the
+// corresponding runtime/metrics metric.
+type Metric struct {
+	Name  string
+	Value uint64
+}
+
+// Label is a label attached to a resource, such as the kind of GC work
+// a goroutine does.
+type Label struct {
+	Label    string
+	Resource ResourceID
+}
+
+// Range describes a runtime activity spanning an interval of time.
+type Range struct {
+	// Name is the name of the activity, such as "GC concurrent mark phase".
+	Name string
+
+	// Scope is the resource doing the activity, or has kind ResourceNone
+	// if the activity is global.
+	Scope ResourceID
+}
+
+// Task describes a task created with runtime/trace.NewTask.
+type Task struct {
+	ID     TaskID
+	Parent TaskID // NoTask for a task without a parent
+	Type   string // empty if the start of the task is not in the trace
+}
+
+// Region describes a region started with runtime/trace.StartRegion or
+// runtime/trace.WithRegion.
+type Region struct {
+	Task TaskID
+	Type string
+}
+
+// Log is a message logged with runtime/trace.Log.
+type Log struct {
+	Task     TaskID
+	Category string
+	Message  string
+}
+
+// ResourceKind is the kind of a resource whose state is tracked.
+type ResourceKind uint8
+
+const (
+	ResourceNone ResourceKind = iota
+	ResourceGoroutine
+	ResourceProc
+)
+
+func (k ResourceKind) String() string {
+	switch k {
+	case ResourceGoroutine:
+		return "Goroutine"
+	case ResourceProc:
+		return "Proc"
+	}
+	return "None"
+}
+
+// ResourceID identifies a goroutine or a P.
+type ResourceID struct {
+	Kind ResourceKind
+	id   int64
+}
+
+func goroutineResource(id GoID) ResourceID {
+	return ResourceID{Kind: ResourceGoroutine, id: int64(id)}
+}
+
+func procResource(id ProcID) ResourceID {
+	return ResourceID{Kind: ResourceProc, id: int64(id)}
+}
+
+// Goroutine returns the goroutine ID of r. It panics if r is not a
+// goroutine.
+func (r ResourceID) Goroutine() GoID {
+	if r.Kind != ResourceGoroutine {
+		panic(fmt.Sprintf("attempted to get GoID from %s resource", r.Kind))
+	}
+	return GoID(r.id)
+}
+
+// Proc returns the P ID of r. It panics if r is not a P.
+func (r ResourceID) Proc() ProcID {
+	if r.Kind != ResourceProc {
+		panic(fmt.Sprintf("attempted to get ProcID from %s resource", r.Kind))
+	}
+	return ProcID(r.id)
+}
+
+func (r ResourceID) String() string {
+	if r.Kind == ResourceNone {
+		return r.Kind.String()
+	}
+	return fmt.Sprintf("%s(%d)", r.Kind, r.id)
+}
+
+// GoState is the state of a goroutine.
+type GoState uint8
+
+const (
+	GoUndetermined GoState = iota // state unknown, at the start of the trace
+	GoNotExist
+	GoRunnable
+	GoRunning
+	GoWaiting
+	GoSyscall
+)
+
+var goStateStrings = [...]string{
+	GoUndetermined: "Undetermined",
+	GoNotExist:     "NotExist",
+	GoRunnable:     "Runnable",
+	GoRunning:      "Running",
+	GoWaiting:      "Waiting",
+	GoSyscall:      "Syscall",
+}
+
+func (s GoState) String() string {
+	if int(s) < len(goStateStrings) {
+		return goStateStrings[s]
+	}
+	return "Bad"
+}
+
+// ProcState is the state of a P.
+type ProcState uint8
+
+const (
+	ProcUndetermined ProcState = iota
+	ProcRunning
+	ProcIdle
+)
+
+func (s ProcState) String() string {
+	switch s {
+	case ProcUndetermined:
+		return "Undetermined"
+	case ProcRunning:
+		return "Running"
+	case ProcIdle:
+		return "Idle"
+	}
+	return "Bad"
+}
+
+// StateTransition describes a change of the state of a goroutine or a P.
+type StateTransition struct {
+	Resource ResourceID
+
+	// Reason is a short description of why a goroutine stopped running,
+	// such as "chan receive" or "preempted", or empty.
+	Reason string
+
+	// Stack is the stack of the goroutine when it stopped running, or,
+	// for a goroutine that was just created, the stack it starts with.
+	// It is NoStack if unknown.
+	Stack Stack
+
+	from, to uint8
+}
+
+// Goroutine returns the old and new states of the goroutine. It panics if
+// the transition is not for a goroutine.
+func (d StateTransition) Goroutine() (from, to GoState) {
+	if d.Resource.Kind != ResourceGoroutine {
+		panic("Goroutine called on non-Goroutine state transition")
+	}
+	return GoState(d.from), GoState(d.to)
+}
+
+// Proc returns the old and new states of the P. It panics if the
+// transition is not for a P.
+func (d StateTransition) Proc() (from, to ProcState) {
+	if d.Resource.Kind != ResourceProc {
+		panic("Proc called on non-Proc state transition")
+	}
+	return ProcState(d.from), ProcState(d.to)
+}
+
+func goTransition(id GoID, from, to GoState, reason string, stk Stack) StateTransition {
+	return StateTransition{Resource: goroutineResource(id), Reason: reason, Stack: stk, from: uint8(from), to: uint8(to)}
+}
+
+func procTransition(id ProcID, from, to ProcState) StateTransition {
+	return StateTransition{Resource: procResource(id), from: uint8(from), to: uint8(to)}
+}
+
+// An Event is a single event in a trace.
+type Event struct {
+	kind EventKind
+	ts   Time
+	g    GoID
+	p    ProcID
+	stk  Stack
+
+	// Kind-specific data. Only the field for the kind is set.
+	metric     Metric
+	label      Label
+	rng        Range
+	task       Task
+	region     Region
+	log        Log
+	transition StateTransition
+}
+
+// Kind returns the kind of the event.
+func (e Event) Kind() EventKind {
+	return e.kind
+}
+
+// Time returns the time of the event.
+func (e Event) Time() Time {
+	return e.ts
+}
+
+// Goroutine returns the goroutine that emitted the event, or NoGoroutine.
+func (e Event) Goroutine() GoID {
+	return e.g
+}
+
+// Proc returns the P that emitted the event, or NoProc.
+func (e Event) Proc() ProcID {
+	return e.p
+}
+
+// Stack returns the stack of the goroutine when it emitted the event, or
+// NoStack.
+func (e Event) Stack() Stack {
+	return e.stk
+}
+
+// Metric returns the metric sample of an EventMetric event. It panics for
+// other kinds of events.
+func (e Event) Metric() Metric {
+	e.mustBe("Metric", EventMetric)
+	return e.metric
+}
+
+// Label returns the label of an EventLabel event. It panics for other
+// kinds of events.
+func (e Event) Label() Label {
+	e.mustBe("Label", EventLabel)
+	return e.label
+}
+
+// Range returns the activity of an EventRangeBegin or EventRangeEnd event.
+// It panics for other kinds of events.
+func (e Event) Range() Range {
+	e.mustBe("Range", EventRangeBegin, EventRangeEnd)
+	return e.rng
+}
+
+// Task returns the task of an EventTaskBegin or EventTaskEnd event. It
+// panics for other kinds of events.
+func (e Event) Task() Task {
+	e.mustBe("Task", EventTaskBegin, EventTaskEnd)
+	return e.task
+}
+
+// Region returns the region of an EventRegionBegin or EventRegionEnd
+// event. It panics for other kinds of events.
+func (e Event) Region() Region {
+	e.mustBe("Region", EventRegionBegin, EventRegionEnd)
+	return e.region
+}
+
+// Log returns the message of an EventLog event. It panics for other kinds
+// of events.
+func (e Event) Log() Log {
+	e.mustBe("Log", EventLog)
+	return e.log
+}
+
+// StateTransition returns the state transition of an EventStateTransition
+// event. It panics for other kinds of events.
+func (e Event) StateTransition() StateTransition {
+	e.mustBe("StateTransition", EventStateTransition)
+	return e.transition
+}
+
+func (e Event) mustBe(method string, kinds ...EventKind) {
+	for _, k := range kinds {
+		if e.kind == k {
+			return
+		}
+	}
+	panic(fmt.Sprintf("%s called on %s event", method, e.kind))
+}
+
+// String returns a description of the event for debugging.
+func (e Event) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Time=%d P=%d G=%d %s", e.ts, e.p, e.g, e.kind)
+	switch e.kind {
+	case EventMetric:
+		fmt.Fprintf(&sb, " Name=%q Value=%d", e.metric.Name, e.metric.Value)
+	case EventLabel:
+		fmt.Fprintf(&sb, " Label=%q Resource=%v", e.label.Label, e.label.Resource)
+	case EventRangeBegin, EventRangeEnd:
+		fmt.Fprintf(&sb, " Name=%q Scope=%v", e.rng.Name, e.rng.Scope)
+	case EventTaskBegin, EventTaskEnd:
+		fmt.Fprintf(&sb, " ID=%d Parent=%d Type=%q", e.task.ID, e.task.Parent, e.task.Type)
+	case EventRegionBegin, EventRegionEnd:
+		fmt.Fprintf(&sb, " Task=%d Type=%q", e.region.Task, e.region.Type)
+	case EventLog:
+		fmt.Fprintf(&sb, " Task=%d Category=%q Message=%q", e.log.Task, e.log.Category, e.log.Message)
+	case EventStateTransition:
+		d := e.transition
+		var from, to fmt.Stringer
+		if d.Resource.Kind == ResourceGoroutine {
+			from, to = d.Goroutine()
+		} else {
+			from, to = d.Proc()
+		}
+		fmt.Fprintf(&sb, " Resource=%v %v->%v", d.Resource, from, to)
+		if d.Reason != "" {
+			fmt.Fprintf(&sb, " Reason=%q", d.Reason)
+		}
+	}
+	return sb.String()
+}
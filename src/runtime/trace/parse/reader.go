@@ -0,0 +1,284 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package parse decodes execution traces written by package runtime/trace,
+// by go test -trace, or by a runtime/trace.FlightRecorder.
+//
+// A [Reader] turns a trace into a stream of typed [Event] values:
+// state transitions of goroutines and Ps, ranges of runtime activity such
+// as garbage collection, user tasks, regions and logs, runtime metrics, and
+// CPU profile samples. The runtime writes a trace as a sequence of
+// self-contained generations, and the Reader holds only one of them in
+// memory at a time, so arbitrarily long traces can be processed.
+//
+// Events are delivered in time order, except that stack samples may
+// slightly precede the last events of the previous generation, since the
+// runtime records them with some delay.
+package parse
+
+import (
+	"fmt"
+	"internal/trace"
+	"io"
+)
+
+// A Reader reads the events of an execution trace, in order.
+type Reader struct {
+	gr *trace.GenerationReader
+
+	// The generation being read.
+	gen      trace.ParseResult
+	i        int // index of the next event of gen to convert
+	prefix   int // index of the first P start of gen
+	initial  map[uint64]GoState
+	blocking map[*trace.Event]bool // system calls that block
+	pending  []Event               // converted events not yet returned
+
+	// State kept across generations.
+	tasks   map[uint64]string // type of active tasks
+	stwName string            // name of the current stop-the-world range
+}
+
+// NewReader returns a Reader reading the trace from r. It reads the first
+// generation of the trace, and returns an error if the trace is malformed
+// or unsupported.
+func NewReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{
+		gr:    trace.NewGenerationReader(r),
+		tasks: make(map[uint64]string),
+	}
+	if err := rd.nextGeneration(); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return rd, nil
+}
+
+// ReadEvent returns the next event of the trace. It returns io.EOF at the
+// end of the trace.
+func (r *Reader) ReadEvent() (Event, error) {
+	for len(r.pending) == 0 {
+		if r.i == len(r.gen.Events) {
+			if err := r.nextGeneration(); err != nil {
+				return Event{}, err
+			}
+			continue
+		}
+		r.convert(r.gen.Events[r.i])
+		r.i++
+	}
+	e := r.pending[0]
+	r.pending = r.pending[1:]
+	return e, nil
+}
+
+// nextGeneration reads the next generation of the trace, and queues an
+// EventSync for it.
+func (r *Reader) nextGeneration() error {
+	gen, err := r.gr.Next()
+	if err != nil {
+		return err
+	}
+	if ver := r.gr.Version(); ver < 1021 {
+		return fmt.Errorf("unsupported trace version %d.%d, want 1.21 or later", ver/1000, ver%1000)
+	}
+	r.gen, r.i = gen, 0
+	r.scan()
+	var ts Time
+	if len(gen.Events) > 0 {
+		ts = Time(gen.Events[0].Ts)
+	}
+	r.pending = append(r.pending, Event{kind: EventSync, ts: ts, g: NoGoroutine, p: NoProc})
+	return nil
+}
+
+// scan prepares the conversion of the events of a generation.
+//
+// A generation starts with events describing the goroutines that exist at
+// that point, until the first P starts. These are folded into a single
+// transition from GoUndetermined for each goroutine.
+//
+// The trace records when a goroutine enters a system call, and only later
+// whether the call blocks. Calls that don't block are reported as
+// transitions to and from GoSyscall at the same time.
+func (r *Reader) scan() {
+	r.prefix = len(r.gen.Events)
+	r.initial = make(map[uint64]GoState)
+	r.blocking = make(map[*trace.Event]bool)
+	inSyscall := make(map[uint64]*trace.Event)
+	for i, ev := range r.gen.Events {
+		if i < r.prefix {
+			switch ev.Type {
+			case trace.EvProcStart:
+				r.prefix = i
+			case trace.EvGoCreate:
+				r.initial[ev.Args[0]] = GoRunnable
+			case trace.EvGoWaiting:
+				r.initial[ev.G] = GoWaiting
+			case trace.EvGoInSyscall:
+				r.initial[ev.G] = GoSyscall
+			}
+		}
+		if ev.Type == trace.EvCPUSample {
+			continue
+		}
+		if call, ok := inSyscall[ev.G]; ok {
+			r.blocking[call] = ev.Type == trace.EvGoSysBlock
+			delete(inSyscall, ev.G)
+		}
+		if ev.Type == trace.EvGoSysCall {
+			inSyscall[ev.G] = ev
+		}
+	}
+}
+
+// blockReasons are the reasons for goroutines to stop running, by event
+// type.
+var blockReasons = [...]string{
+	trace.EvGoStop:        "forever",
+	trace.EvGoSched:       "yield",
+	trace.EvGoPreempt:     "preempted",
+	trace.EvGoSleep:       "sleep",
+	trace.EvGoBlock:       "blocked",
+	trace.EvGoBlockSend:   "chan send",
+	trace.EvGoBlockRecv:   "chan receive",
+	trace.EvGoBlockSelect: "select",
+	trace.EvGoBlockSync:   "sync",
+	trace.EvGoBlockCond:   "sync.(*Cond).Wait",
+	trace.EvGoBlockNet:    "network",
+	trace.EvGoBlockGC:     "GC mark assist wait for work",
+}
+
+// convert queues the events corresponding to ev.
+func (r *Reader) convert(ev *trace.Event) {
+	e := Event{ts: Time(ev.Ts), g: GoID(ev.G), p: ProcID(ev.P), stk: Stack{ev.Stk}}
+	if ev.G == 0 {
+		e.g = NoGoroutine
+	}
+	if ev.P < 0 || ev.P >= trace.FakeP {
+		e.p = NoProc
+	}
+	g := GoID(ev.G)
+	transition := func(id GoID, from, to GoState, reason string, stk Stack) {
+		e.kind = EventStateTransition
+		e.transition = goTransition(id, from, to, reason, stk)
+		r.pending = append(r.pending, e)
+	}
+	rangeEvent := func(kind EventKind, name string, scope ResourceID) {
+		e.kind = kind
+		e.rng = Range{Name: name, Scope: scope}
+		r.pending = append(r.pending, e)
+	}
+	metric := func(name string, value uint64) {
+		e.kind = EventMetric
+		e.metric = Metric{Name: name, Value: value}
+		r.pending = append(r.pending, e)
+	}
+
+	inPrefix := r.i < r.prefix
+	switch ev.Type {
+	case trace.EvProcStart:
+		e.kind = EventStateTransition
+		e.transition = procTransition(e.p, ProcIdle, ProcRunning)
+		r.pending = append(r.pending, e)
+	case trace.EvProcStop:
+		e.kind = EventStateTransition
+		e.transition = procTransition(e.p, ProcRunning, ProcIdle)
+		r.pending = append(r.pending, e)
+	case trace.EvGomaxprocs:
+		metric("/sched/gomaxprocs:threads", ev.Args[0])
+	case trace.EvHeapAlloc:
+		metric("/memory/classes/heap/objects:bytes", ev.Args[0])
+	case trace.EvHeapGoal:
+		metric("/gc/heap/goal:bytes", ev.Args[0])
+	case trace.EvGCStart:
+		rangeEvent(EventRangeBegin, "GC concurrent mark phase", ResourceID{})
+	case trace.EvGCDone:
+		rangeEvent(EventRangeEnd, "GC concurrent mark phase", ResourceID{})
+	case trace.EvSTWStart:
+		r.stwName = "stop-the-world (" + ev.SArgs[0] + ")"
+		rangeEvent(EventRangeBegin, r.stwName, ResourceID{})
+	case trace.EvSTWDone:
+		rangeEvent(EventRangeEnd, r.stwName, ResourceID{})
+	case trace.EvGCSweepStart:
+		rangeEvent(EventRangeBegin, "GC incremental sweep", procResource(e.p))
+	case trace.EvGCSweepDone:
+		rangeEvent(EventRangeEnd, "GC incremental sweep", procResource(e.p))
+	case trace.EvGCMarkAssistStart:
+		rangeEvent(EventRangeBegin, "GC mark assist", goroutineResource(g))
+	case trace.EvGCMarkAssistDone:
+		rangeEvent(EventRangeEnd, "GC mark assist", goroutineResource(g))
+	case trace.EvGoCreate:
+		id := GoID(ev.Args[0])
+		if inPrefix {
+			e.stk = NoStack
+			transition(id, GoUndetermined, r.initial[ev.Args[0]], "", NoStack)
+			break
+		}
+		transition(id, GoNotExist, GoRunnable, "", Stack{r.gen.Stacks[ev.Args[1]]})
+	case trace.EvGoWaiting, trace.EvGoInSyscall:
+		if inPrefix {
+			break
+		}
+		// A goroutine of a new extra M, used by a C thread calling Go.
+		to := GoWaiting
+		if ev.Type == trace.EvGoInSyscall {
+			to = GoSyscall
+		}
+		transition(g, GoRunnable, to, "", NoStack)
+	case trace.EvGoStartLabel:
+		e.kind = EventLabel
+		e.label = Label{Label: ev.SArgs[0], Resource: goroutineResource(g)}
+		r.pending = append(r.pending, e)
+		fallthrough
+	case trace.EvGoStart:
+		transition(g, GoRunnable, GoRunning, "", NoStack)
+	case trace.EvGoEnd:
+		transition(g, GoRunning, GoNotExist, "", NoStack)
+	case trace.EvGoSched, trace.EvGoPreempt:
+		transition(g, GoRunning, GoRunnable, blockReasons[ev.Type], e.stk)
+	case trace.EvGoStop, trace.EvGoSleep, trace.EvGoBlock, trace.EvGoBlockSend,
+		trace.EvGoBlockRecv, trace.EvGoBlockSelect, trace.EvGoBlockSync,
+		trace.EvGoBlockCond, trace.EvGoBlockNet, trace.EvGoBlockGC:
+		transition(g, GoRunning, GoWaiting, blockReasons[ev.Type], e.stk)
+	case trace.EvGoUnblock:
+		transition(GoID(ev.Args[0]), GoWaiting, GoRunnable, "", NoStack)
+	case trace.EvGoSysCall:
+		transition(g, GoRunning, GoSyscall, "", e.stk)
+		if !r.blocking[ev] {
+			transition(g, GoSyscall, GoRunning, "", NoStack)
+		}
+	case trace.EvGoSysBlock:
+		// Reported at the start of the system call.
+	case trace.EvGoSysExit:
+		transition(g, GoSyscall, GoRunnable, "", NoStack)
+	case trace.EvUserTaskCreate:
+		r.tasks[ev.Args[0]] = ev.SArgs[0]
+		e.kind = EventTaskBegin
+		e.task = Task{ID: TaskID(ev.Args[0]), Parent: TaskID(ev.Args[1]), Type: ev.SArgs[0]}
+		r.pending = append(r.pending, e)
+	case trace.EvUserTaskEnd:
+		typ := r.tasks[ev.Args[0]]
+		delete(r.tasks, ev.Args[0])
+		e.kind = EventTaskEnd
+		e.task = Task{ID: TaskID(ev.Args[0]), Type: typ}
+		r.pending = append(r.pending, e)
+	case trace.EvUserRegion:
+		e.kind = EventRegionBegin
+		if ev.Args[1] == 1 {
+			e.kind = EventRegionEnd
+		}
+		e.region = Region{Task: TaskID(ev.Args[0]), Type: ev.SArgs[0]}
+		r.pending = append(r.pending, e)
+	case trace.EvUserLog:
+		e.kind = EventLog
+		e.log = Log{Task: TaskID(ev.Args[0]), Category: ev.SArgs[0], Message: ev.SArgs[1]}
+		r.pending = append(r.pending, e)
+	case trace.EvCPUSample:
+		e.kind = EventStackSample
+		r.pending = append(r.pending, e)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"runtime/trace"
+	. "runtime/trace/parse"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// work produces a variety of trace events.
+func work(t *testing.T) {
+	ctx, task := trace.NewTask(context.Background(), "work")
+	defer task.End()
+
+	var wg sync.WaitGroup
+	ch := make(chan int)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			trace.WithRegion(ctx, "recv", func() {
+				<-ch
+			})
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	for i := 0; i < 4; i++ {
+		ch <- i
+	}
+	wg.Wait()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	go func() {
+		time.Sleep(time.Millisecond)
+		w.Write([]byte("x"))
+	}()
+	var b [1]byte
+	r.Read(b[:])
+
+	runtime.GC()
+	trace.Log(ctx, "category", "message")
+}
+
+// checkTrace reads the whole trace in data and verifies that its events
+// are consistent. It returns the events.
+func checkTrace(t *testing.T, data []byte) []Event {
+	t.Helper()
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	gstates := make(map[GoID]GoState)
+	pstates := make(map[ProcID]ProcState)
+	var events []Event
+	var last Time
+	for {
+		e, err := r.ReadEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadEvent: %v", err)
+		}
+		events = append(events, e)
+		if e.Kind() != EventStackSample {
+			if e.Time() < last {
+				t.Errorf("event out of order: %v after time %d", e, last)
+			}
+			last = e.Time()
+		}
+		if e.Kind() != EventStateTransition {
+			continue
+		}
+		st := e.StateTransition()
+		switch st.Resource.Kind {
+		case ResourceGoroutine:
+			id := st.Resource.Goroutine()
+			from, to := st.Goroutine()
+			if cur, ok := gstates[id]; ok && cur != from {
+				t.Errorf("goroutine %d is %v, got transition %v", id, cur, e)
+			} else if !ok && from != GoUndetermined && from != GoNotExist {
+				t.Errorf("first transition of goroutine %d is %v", id, e)
+			}
+			gstates[id] = to
+		case ResourceProc:
+			id := st.Resource.Proc()
+			from, to := st.Proc()
+			if cur, ok := pstates[id]; ok && cur != from {
+				t.Errorf("P %d is %v, got transition %v", id, cur, e)
+			}
+			pstates[id] = to
+		default:
+			t.Errorf("transition for bad resource: %v", e)
+		}
+	}
+	return events
+}
+
+func count(events []Event, f func(e Event) bool) int {
+	n := 0
+	for _, e := range events {
+		if f(e) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestReader(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Fatal(err)
+	}
+	work(t)
+	trace.Stop()
+
+	events := checkTrace(t, buf.Bytes())
+	if n := count(events, func(e Event) bool { return e.Kind() == EventSync }); n < 1 {
+		t.Errorf("got %d sync events, want at least 1", n)
+	}
+	if n := count(events, func(e Event) bool {
+		return e.Kind() == EventTaskBegin && e.Task().Type == "work" ||
+			e.Kind() == EventTaskEnd && e.Task().Type == "work"
+	}); n != 2 {
+		t.Errorf("got %d task events, want 2", n)
+	}
+	if n := count(events, func(e Event) bool {
+		return (e.Kind() == EventRegionBegin || e.Kind() == EventRegionEnd) && e.Region().Type == "recv"
+	}); n != 8 {
+		t.Errorf("got %d region events, want 8", n)
+	}
+	if n := count(events, func(e Event) bool {
+		return e.Kind() == EventLog && e.Log() == Log{Task: e.Log().Task, Category: "category", Message: "message"}
+	}); n != 1 {
+		t.Errorf("got %d log events, want 1", n)
+	}
+	if n := count(events, func(e Event) bool {
+		return e.Kind() == EventRangeBegin && e.Range().Name == "GC concurrent mark phase"
+	}); n < 1 {
+		t.Errorf("got %d GC ranges, want at least 1", n)
+	}
+	if n := count(events, func(e Event) bool {
+		if e.Kind() != EventStateTransition || e.StateTransition().Resource.Kind != ResourceGoroutine {
+			return false
+		}
+		from, to := e.StateTransition().Goroutine()
+		return from == GoRunning && to == GoWaiting && e.StateTransition().Reason == "chan receive"
+	}); n < 1 {
+		t.Errorf("got %d goroutines blocking on chan receive, want at least 1", n)
+	}
+
+	// Blocking events have the stack of the goroutine.
+	found := false
+	for _, e := range events {
+		if e.Kind() != EventStateTransition || e.StateTransition().Reason != "chan receive" {
+			continue
+		}
+		for f := range e.StateTransition().Stack.Frames() {
+			if strings.HasSuffix(f.Func, "parse_test.work.func1.1") {
+				found = true
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no chan receive transition with the stack of work")
+	}
+}
+
+func TestReaderGenerations(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	fr := trace.NewFlightRecorder(trace.FlightRecorderConfig{MinAge: time.Minute})
+	if err := fr.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer fr.Stop()
+	var buf bytes.Buffer
+	for i := 0; i < 3; i++ {
+		work(t)
+		buf.Reset()
+		if _, err := fr.WriteTo(&buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := checkTrace(t, buf.Bytes())
+	if n := count(events, func(e Event) bool { return e.Kind() == EventSync }); n < 3 {
+		t.Errorf("got %d sync events, want at least 3", n)
+	}
+	if n := count(events, func(e Event) bool { return e.Kind() == EventLog }); n != 3 {
+		t.Errorf("got %d log events, want 3", n)
+	}
+}
+
+func TestReaderBadTrace(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"not a trace",
+		"go 1.21 trace\x00\x00\x00",
+		"go 1.5 trace\x00\x00\x00\x00",
+	} {
+		if _, err := NewReader(strings.NewReader(data)); err == nil {
+			t.Errorf("NewReader(%q) succeeded, want error", data)
+		}
+	}
+}
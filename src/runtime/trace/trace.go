@@ -113,10 +113,14 @@ import (
 	"runtime"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // Start enables tracing for the current program.
 // While tracing, the trace will be buffered and written to w.
+// The trace is written as a sequence of generations, a new one starting
+// every second, each of which can be parsed on its own. This lets readers,
+// such as package [runtime/trace/parse], process a long trace incrementally.
 // Start returns an error if tracing is already enabled.
 func Start(w io.Writer) error {
 	tracing.Lock()
@@ -134,21 +138,62 @@ func Start(w io.Writer) error {
 			w.Write(data)
 		}
 	}()
+	tracing.stopAdvance = startAdvancing(generationPeriod, nil, traceAdvance)
 	tracing.enabled.Store(true)
 	return nil
 }
 
 // Stop stops the current tracing, if any.
 // Stop only returns after all the writes for the trace have completed.
+// Stop does not stop a [FlightRecorder].
 func Stop() {
 	tracing.Lock()
 	defer tracing.Unlock()
+	if tracing.flightRecorder {
+		return
+	}
 	tracing.enabled.Store(false)
 
+	if tracing.stopAdvance != nil {
+		tracing.stopAdvance()
+		tracing.stopAdvance = nil
+	}
 	runtime.StopTrace()
 }
 
+// generationPeriod is the interval between the generations of a trace
+// written by Start. Each generation can be parsed on its own, so readers
+// don't need to hold a whole long trace in memory. Tests change it.
+var generationPeriod = time.Second
+
+// startAdvancing starts a goroutine that starts a new generation of the
+// trace by calling advance every period, and whenever kick is ready. It
+// returns a function that stops the goroutine and waits for it to exit.
+func startAdvancing(period time.Duration, kick <-chan struct{}, advance func()) (stop func()) {
+	stopc, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(period)
+		defer t.Stop()
+		for {
+			select {
+			case <-stopc:
+				return
+			case <-t.C:
+			case <-kick:
+			}
+			advance()
+		}
+	}()
+	return func() {
+		close(stopc)
+		<-done
+	}
+}
+
 var tracing struct {
-	sync.Mutex // gate mutators (Start, Stop)
-	enabled    atomic.Bool
+	sync.Mutex     // gate mutators (Start, Stop, FlightRecorder)
+	enabled        atomic.Bool
+	flightRecorder bool   // tracing was enabled by a FlightRecorder
+	stopAdvance    func() // stops starting generations, see Start
 }
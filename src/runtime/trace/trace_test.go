@@ -18,6 +18,7 @@ import (
 	"runtime"
 	"runtime/pprof"
 	. "runtime/trace"
+	"runtime/trace/parse"
 	"strconv"
 	"strings"
 	"sync"
@@ -123,6 +124,93 @@ func TestTraceDoubleStart(t *testing.T) {
 	Stop()
 }
 
+// TestTraceGenerations checks that a trace written by Start is divided into
+// generations that a reader can process one at a time, without reading
+// the rest of the trace.
+func TestTraceGenerations(t *testing.T) {
+	if IsEnabled() {
+		t.Skip("skipping because -test.trace is set")
+	}
+	defer SetGenerationPeriod(10 * time.Millisecond)()
+	buf := new(bytes.Buffer)
+	if err := Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < 10; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				time.Sleep(time.Millisecond)
+			}()
+		}
+		wg.Wait()
+		time.Sleep(5 * time.Millisecond)
+	}
+	Stop()
+	saveTrace(t, buf, "TestTraceGenerations")
+	data := buf.Bytes()
+
+	// Each generation starts with the trace header.
+	header := data[:16]
+	var ends []int
+	for off := len(header); ; {
+		i := bytes.Index(data[off:], header)
+		if i < 0 {
+			break
+		}
+		off += i
+		ends = append(ends, off)
+		off += len(header)
+	}
+	ends = append(ends, len(data))
+	if len(ends) < 3 {
+		t.Fatalf("trace has %d generations, want at least 3", len(ends))
+	}
+
+	// By the time the reader returns the first event of a generation,
+	// it must not have read much past the end of that generation.
+	const slack = 4 << 10 // buffered by the reader
+	cr := &countingReader{r: bytes.NewReader(data)}
+	r, err := parse.NewReader(cr)
+	if err != nil {
+		t.Fatalf("failed to read trace: %v", err)
+	}
+	gen := 0
+	for {
+		e, err := r.ReadEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read trace: %v", err)
+		}
+		if e.Kind() != parse.EventSync {
+			continue
+		}
+		if gen < len(ends) && cr.n > int64(ends[gen]+slack) {
+			t.Errorf("reader consumed %d bytes before returning generation %d, which ends at %d", cr.n, gen, ends[gen])
+		}
+		gen++
+	}
+	if gen != len(ends) {
+		t.Errorf("reader returned %d generations, want %d", gen, len(ends))
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
 func TestTrace(t *testing.T) {
 	if IsEnabled() {
 		t.Skip("skipping because -test.trace is set")
@@ -6,8 +6,10 @@
 
 package runtime
 
-import "unsafe"
-
+// writeErr writes b to standard error and, if crashing, to the
+// SetCrashOutput fd.
 func writeErr(b []byte) {
-	write(2, unsafe.Pointer(&b[0]), int32(len(b)))
+	if len(b) > 0 {
+		writeErrData(&b[0], int32(len(b)))
+	}
 }
@@ -0,0 +1,122 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strings_test
+
+import (
+	"internal/testenv"
+	. "strings"
+	"testing"
+)
+
+func collect(seq func(func(string) bool)) []string {
+	var a []string
+	for s := range seq {
+		a = append(a, s)
+	}
+	return a
+}
+
+func TestSplitSeq(t *testing.T) {
+	for _, tt := range splittests {
+		if tt.n >= 0 {
+			continue // SplitSeq has no limit
+		}
+		a := collect(SplitSeq(tt.s, tt.sep))
+		if !eq(a, tt.a) {
+			t.Errorf("SplitSeq(%q, %q) = %v; want %v", tt.s, tt.sep, a, tt.a)
+		}
+	}
+}
+
+func TestFieldsSeq(t *testing.T) {
+	for _, tt := range fieldstests {
+		a := collect(FieldsSeq(tt.s))
+		if !eq(a, tt.a) {
+			t.Errorf("FieldsSeq(%q) = %v; want %v", tt.s, a, tt.a)
+		}
+	}
+}
+
+var linesTests = []struct {
+	s string
+	a []string
+}{
+	{"", nil},
+	{"\n", []string{"\n"}},
+	{"abc", []string{"abc"}},
+	{"abc\n", []string{"abc\n"}},
+	{"abc\ndef", []string{"abc\n", "def"}},
+	{"abc\n\ndef\n", []string{"abc\n", "\n", "def\n"}},
+	{"a\r\nb\r\n", []string{"a\r\n", "b\r\n"}},
+}
+
+func TestLines(t *testing.T) {
+	for _, tt := range linesTests {
+		a := collect(Lines(tt.s))
+		if !eq(a, tt.a) {
+			t.Errorf("Lines(%q) = %q; want %q", tt.s, a, tt.a)
+		}
+	}
+}
+
+func TestSeqStop(t *testing.T) {
+	// Stopping early must not yield further values.
+	var got []string
+	for s := range SplitSeq("a,b,c,d", ",") {
+		got = append(got, s)
+		if s == "b" {
+			break
+		}
+	}
+	if !eq(got, []string{"a", "b"}) {
+		t.Errorf("SplitSeq stopped early = %v; want [a b]", got)
+	}
+}
+
+func TestSeqAllocs(t *testing.T) {
+	testenv.SkipIfOptimizationOff(t)
+
+	const s = "the quick\tbrown fox\njumps over\nthe lazy dog\n"
+	tests := []struct {
+		name string
+		f    func() int
+	}{
+		{"SplitSeq", func() (n int) {
+			for range SplitSeq(s, " ") {
+				n++
+			}
+			return n
+		}},
+		{"SplitSeqEmpty", func() (n int) {
+			for range SplitSeq(s, "") {
+				n++
+			}
+			return n
+		}},
+		{"FieldsSeq", func() (n int) {
+			for range FieldsSeq(s) {
+				n++
+			}
+			return n
+		}},
+		{"Lines", func() (n int) {
+			for range Lines(s) {
+				n++
+			}
+			return n
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if n := tt.f(); n == 0 {
+				t.Fatalf("%s yielded no values", tt.name)
+			}
+			allocs := testing.AllocsPerRun(100, func() { tt.f() })
+			if allocs != 0 {
+				t.Errorf("ranging over %s allocated %v times; want 0", tt.name, allocs)
+			}
+		})
+	}
+}
@@ -352,3 +352,9 @@ TEXT ·libc_unlinkat_trampoline(SB),NOSPLIT,$0-0
 TEXT ·libc_openat_trampoline(SB),NOSPLIT,$0-0
 	CALL	libc_openat(SB)
 	RET
+TEXT ·libc_mkdirat_trampoline(SB),NOSPLIT,$0-0
+	CALL	libc_mkdirat(SB)
+	RET
+TEXT ·libc_readlinkat_trampoline(SB),NOSPLIT,$0-0
+	CALL	libc_readlinkat(SB)
+	RET
@@ -0,0 +1,72 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package synctest provides support for testing concurrent code.
+//
+// The [Run] function starts a goroutine in an isolated "bubble".
+// Goroutines in a bubble use a fake clock, which advances only when
+// every goroutine in the bubble is blocked. Tests of code that
+// sleeps, waits on timers, or times out run instantly and
+// deterministically in a bubble, without real delays or a
+// replaceable clock.
+//
+// The [Wait] function blocks until every other goroutine in the
+// bubble is blocked, letting a test observe the state of the bubble
+// once all activity in it has settled.
+package synctest
+
+import (
+	"internal/synctest"
+)
+
+// Run executes f in a new goroutine.
+//
+// The new goroutine and any goroutines transitively started by it form
+// an isolated "bubble".
+// Run waits for all goroutines in the bubble to exit before returning.
+//
+// Goroutines in the bubble use a fake clock for time.Now, time.Sleep,
+// and the timers and tickers of package time.
+// The initial time is midnight UTC 2000-01-01.
+//
+// Time advances when every goroutine in the bubble is durably blocked,
+// to the time of the next pending timer.
+// For example, a call to time.Sleep blocks until all other
+// goroutines are durably blocked and returns after the bubble's clock has
+// advanced. See [Wait] for the definition of durably blocked.
+//
+// If every goroutine in the bubble is durably blocked and there are
+// no timers scheduled, Run panics.
+//
+// Channels, time.Timers, and time.Tickers created within the bubble
+// are associated with it. Operating on a bubbled channel, timer, or ticker
+// from outside the bubble panics.
+//
+// Run panics if called from within a bubble.
+func Run(f func()) {
+	synctest.Run(f)
+}
+
+// Wait blocks until every goroutine within the current bubble,
+// other than the current goroutine, is durably blocked.
+// It panics if called from a non-bubbled goroutine,
+// or if two goroutines in the same bubble call Wait at the same time.
+//
+// A goroutine is durably blocked if it can only be unblocked by another
+// goroutine in its bubble, or by the bubble's clock advancing.
+// The following operations durably block a goroutine:
+//   - a send or receive on a channel created within the bubble
+//   - a select statement where every case is a channel created within the bubble
+//   - sync.Cond.Wait
+//   - sync.WaitGroup.Wait
+//   - time.Sleep
+//
+// Other blocking operations are not durable, since they may be
+// unblocked by events outside the bubble. For example, a goroutine
+// blocked on a sync.Mutex, in a system call, or reading from a network
+// connection is not durably blocked, even if the lock is held by or the
+// connection is connected to another goroutine in the same bubble.
+func Wait() {
+	synctest.Wait()
+}
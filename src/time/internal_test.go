@@ -36,25 +36,19 @@ func disablePlatformSources() (undo func()) {
 var Interrupt = interrupt
 var DaysIn = daysIn
 
-func empty(arg any, seq uintptr) {}
+func empty(arg any, seq uintptr, delta int64) {}
 
-// Test that a runtimeTimer with a period that would overflow when on
+// Test that a runtime timer with a period that would overflow when on
 // expiration does not throw or cause other timers to hang.
 //
 // This test has to be in internal_test.go since it fiddles with
 // unexported data structures.
 func CheckRuntimeTimerPeriodOverflow() {
-	// We manually create a runtimeTimer with huge period, but that expires
+	// We manually create a runtime timer with huge period, but that expires
 	// immediately. The public Timer interface would require waiting for
 	// the entire period before the first update.
-	r := &runtimeTimer{
-		when:   runtimeNano(),
-		period: 1<<63 - 1,
-		f:      empty,
-		arg:    nil,
-	}
-	startTimer(r)
-	defer stopTimer(r)
+	t := newTimer(runtimeNano(), 1<<63-1, empty, nil, nil)
+	defer t.Stop()
 
 	// If this test fails, we will either throw (when siftdownTimer detects
 	// bad when on update), or other timers will hang (if the timer in a
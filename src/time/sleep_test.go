@@ -633,6 +633,122 @@ func TestAdjustTimers(t *testing.T) {
 	}
 }
 
+// Test that timers and tickers that are not stopped are collected
+// once they are no longer referenced.
+func TestTimerGC(t *testing.T) {
+	run := func(t *testing.T, what string, f func()) {
+		t.Helper()
+		t.Run(what, func(t *testing.T) {
+			t.Helper()
+			const N = 1e4
+			var stats runtime.MemStats
+			runtime.GC()
+			runtime.GC()
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			before := int64(stats.Mallocs - stats.Frees)
+
+			for j := 0; j < N; j++ {
+				f()
+			}
+
+			runtime.GC()
+			runtime.GC()
+			runtime.GC()
+			runtime.ReadMemStats(&stats)
+			after := int64(stats.Mallocs - stats.Frees)
+
+			// Allow some slack, but inuse >= N means at least 1 allocation per iteration.
+			inuse := after - before
+			if inuse >= N {
+				t.Errorf("%s did not get GC'ed: %d allocations", what, inuse)
+			}
+		})
+	}
+
+	run(t, "After", func() { After(Hour) })
+	run(t, "Tick", func() { Tick(Hour) })
+	run(t, "NewTimer", func() { NewTimer(Hour) })
+	run(t, "NewTicker", func() { NewTicker(Hour) })
+	run(t, "NewTimerStop", func() { NewTimer(Hour).Stop() })
+	run(t, "NewTickerStop", func() { NewTicker(Hour).Stop() })
+}
+
+// Test that Stop and Reset on a timer channel that has expired but not
+// been received from leave no stale value, unless asynctimerchan=1.
+func TestTimerChanStale(t *testing.T) {
+	for _, async := range []bool{false, true} {
+		t.Run(fmt.Sprintf("asynctimerchan=%v", async), func(t *testing.T) {
+			if async {
+				t.Setenv("GODEBUG", "asynctimerchan=1")
+			}
+			// expire waits until the timer or ticker with channel c
+			// has expired. A timer channel is only filled when received
+			// from unless asynctimerchan=1, in which case wait for the value.
+			expire := func(c <-chan Time) {
+				Sleep(10 * Millisecond)
+				for async && len(c) == 0 {
+					Sleep(Millisecond)
+				}
+			}
+			readable := func(c <-chan Time) bool {
+				select {
+				case <-c:
+					return true
+				default:
+					return false
+				}
+			}
+
+			tm := NewTimer(Millisecond)
+			wantCap := 0
+			if async {
+				wantCap = 1
+			}
+			if got := cap(tm.C); got != wantCap {
+				t.Errorf("cap(Timer.C) = %d, want %d", got, wantCap)
+			}
+			expire(tm.C)
+			if got := tm.Stop(); got == async {
+				t.Errorf("Stop of expired timer = %v, want %v", got, !async)
+			}
+			if got := readable(tm.C); got != async {
+				t.Errorf("receive after Stop = %v, want %v", got, async)
+			}
+
+			tm = NewTimer(Millisecond)
+			expire(tm.C)
+			if got := tm.Reset(Hour); got == async {
+				t.Errorf("Reset of expired timer = %v, want %v", got, !async)
+			}
+			if got := readable(tm.C); got != async {
+				t.Errorf("receive after Reset = %v, want %v", got, async)
+			}
+			tm.Stop()
+
+			tk := NewTicker(Millisecond)
+			if got := cap(tk.C); got != wantCap {
+				t.Errorf("cap(Ticker.C) = %d, want %d", got, wantCap)
+			}
+			expire(tk.C)
+			tk.Reset(Hour)
+			if got := readable(tk.C); got != async {
+				t.Errorf("receive after Ticker.Reset = %v, want %v", got, async)
+			}
+			tk.Stop()
+
+			// A receive without Stop or Reset still sees the value,
+			// sent as of the expiration time.
+			start := Now()
+			tm = NewTimer(Millisecond)
+			expire(tm.C)
+			if v := <-tm.C; v.Before(start) || v.After(start.Add(5*Second)) {
+				t.Errorf("received time %v, want shortly after %v", v, start)
+			}
+		})
+	}
+}
+
 // Benchmark timer latency when the thread that creates the timer is busy with
 // other work and the timers must be serviced by other threads.
 // https://golang.org/issue/38860
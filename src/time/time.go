@@ -1108,11 +1108,23 @@ func daysSinceEpoch(year int) uint64 {
 }
 
 // Provided by package runtime.
+//
+// now returns the current real time. Now uses runtimeNow instead,
+// which returns the fake clock of a synctest bubble when appropriate.
 func now() (sec int64, nsec int32, mono int64)
 
+// runtimeNow returns the current time.
+// When called within a synctest.Run bubble, it returns the bubble's fake clock.
+// Provided by package runtime.
+//
+//go:linkname runtimeNow
+func runtimeNow() (sec int64, nsec int32, mono int64)
+
 // runtimeNano returns the current value of the runtime clock in nanoseconds.
+// When called within a synctest.Run bubble, it returns the bubble's fake clock.
+// Provided by package runtime.
 //
-//go:linkname runtimeNano runtime.nanotime
+//go:linkname runtimeNano
 func runtimeNano() int64
 
 // Monotonic times are reported as offsets from startNano.
@@ -1125,7 +1137,7 @@ var startNano int64 = runtimeNano() - 1
 
 // Now returns the current local time.
 func Now() Time {
-	sec, nsec, mono := now()
+	sec, nsec, mono := runtimeNow()
 	mono -= startNano
 	sec += unixToInternal - minWall
 	if uint64(sec)>>33 != 0 {
@@ -0,0 +1,109 @@
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+The weak package is a package for managing weak pointers.
+
+Weak pointers are pointers that explicitly do not keep a value live and
+must be queried for a regular Go pointer.
+The result of such a query may be observed as nil at any point after a
+weakly-pointed-to object becomes eligible for reclamation by the garbage
+collector.
+More specifically, weak pointers become nil as soon as the garbage collector
+identifies that the object is unreachable, before it is made reachable
+again by a finalizer.
+In terms of the C# language, these semantics are roughly equivalent to the
+semantics of "short" weak references.
+In terms of the Java language, these semantics are roughly equivalent to the
+semantics of the WeakReference type.
+
+# Caches and canonicalization maps
+
+The primary use-cases for weak pointers are for implementing caches,
+canonicalization maps (like the unique package), and for tying together
+the lifetimes of separate values.
+
+A typical cache maps keys to weak pointers and, on lookup, calls
+[Pointer.Value] to recover the value.
+If Value returns nil, the value has been reclaimed and the entry
+may be dropped from the cache.
+Unlike a cache built with [runtime.SetFinalizer], such a cache never
+resurrects the values it holds, and it does not delay their reclamation
+by a garbage collection cycle.
+
+# Finalizers
+
+If an object has a finalizer, weak pointers to that object become nil
+as soon as the finalizer is queued to run, before the finalizer is
+able to make the object reachable again.
+Weak pointers created after the finalizer runs refer to a new identity
+for the object and do not compare equal to weak pointers created before.
+*/
+package weak
+
+import (
+	"internal/abi"
+	"runtime"
+	"unsafe"
+)
+
+// Pointer is a weak pointer to a value of type T.
+//
+// Just like regular pointers, Pointer may reference any part of an
+// object, such as the field of a struct or an element of an array.
+// Objects that are only pointed to by weak pointers are not considered
+// reachable and once the object becomes unreachable [Pointer.Value]
+// may return nil.
+//
+// Two Pointer values always compare equal if the pointers from which they were
+// created compare equal. This property is retained even after the
+// object referenced by the pointer used to create a weak reference is
+// reclaimed.
+// If multiple weak pointers are made to different offsets within the same object
+// (for example, pointers to different fields of the same struct), those pointers
+// will not compare equal.
+// If a weak pointer is created from an object that becomes unreachable, but is
+// then resurrected due to a finalizer, that weak pointer will not compare equal
+// with weak pointers created after the resurrection.
+//
+// Calling [Make] with a nil pointer returns a weak pointer whose [Pointer.Value]
+// always returns nil. The zero value of a Pointer behaves as if it were created
+// by passing nil to [Make] and compares equal with such pointers.
+type Pointer[T any] struct {
+	_ [0]*T // ensure that Pointer[T] values of different T are not convertible
+	u unsafe.Pointer
+}
+
+// Make creates a weak pointer from a pointer to some value of type T.
+func Make[T any](ptr *T) Pointer[T] {
+	// Explicitly force ptr to escape to the heap.
+	ptr = abi.Escape(ptr)
+
+	var u unsafe.Pointer
+	if ptr != nil {
+		u = runtime_registerWeakPointer(unsafe.Pointer(ptr))
+	}
+	runtime.KeepAlive(ptr)
+	return Pointer[T]{u: u}
+}
+
+// Value returns the original pointer used to create the weak pointer.
+// It returns nil if the value pointed to by the original pointer was reclaimed by
+// the garbage collector.
+// If a weak pointer points to an object with a finalizer, then Value will
+// return nil as soon as the object's finalizer is queued for execution.
+func (p Pointer[T]) Value() *T {
+	if p.u == nil {
+		return nil
+	}
+	return (*T)(runtime_makeStrongFromWeak(p.u))
+}
+
+// Implemented in runtime.
+
+//go:linkname runtime_registerWeakPointer
+func runtime_registerWeakPointer(unsafe.Pointer) unsafe.Pointer
+
+//go:linkname runtime_makeStrongFromWeak
+func runtime_makeStrongFromWeak(unsafe.Pointer) unsafe.Pointer
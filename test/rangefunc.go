@@ -0,0 +1,264 @@
+// run
+
+// Copyright 2023 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Test the 'for range' construct ranging over functions.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Seq[T any] func(yield func(T) bool)
+
+type Seq2[K, V any] func(yield func(K, V) bool)
+
+// count returns an iterator over 0, 1, ..., n-1.
+func count(n int) Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+// pairs returns an iterator over the index-value pairs of s.
+func pairs(s []string) Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		for i, v := range s {
+			if !yield(i, v) {
+				return
+			}
+		}
+	}
+}
+
+// times returns an iterator that yields no values, n times.
+func times(n int) func(func() bool) {
+	return func(yield func() bool) {
+		for i := 0; i < n; i++ {
+			if !yield() {
+				return
+			}
+		}
+	}
+}
+
+// badCount ignores the result of yield.
+func badCount(n int) Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			yield(i)
+		}
+	}
+}
+
+func expect(got, want string) {
+	if got != want {
+		panic(fmt.Sprintf("got %q, want %q", got, want))
+	}
+}
+
+func testBasic() {
+	var b strings.Builder
+	for i := range count(4) {
+		fmt.Fprint(&b, i)
+	}
+	expect(b.String(), "0123")
+
+	b.Reset()
+	for i, v := range pairs([]string{"a", "b", "c"}) {
+		fmt.Fprint(&b, i, v)
+	}
+	expect(b.String(), "0a1b2c")
+
+	b.Reset()
+	for range times(3) {
+		b.WriteString("x")
+	}
+	expect(b.String(), "xxx")
+
+	// Assignment form.
+	var i int
+	var s string
+	for i, s = range pairs([]string{"x", "y"}) {
+	}
+	expect(fmt.Sprint(i, s), "1y")
+}
+
+func testBreakContinue() {
+	var b strings.Builder
+	for i := range count(10) {
+		if i%2 == 0 {
+			continue
+		}
+		if i > 6 {
+			break
+		}
+		fmt.Fprint(&b, i)
+	}
+	expect(b.String(), "135")
+}
+
+func testLabeled() {
+	var b strings.Builder
+outer:
+	for i := range count(5) {
+		for j := range count(5) {
+			if j > i {
+				continue outer
+			}
+			if i == 3 {
+				break outer
+			}
+			fmt.Fprintf(&b, "%d%d;", i, j)
+		}
+	}
+	expect(b.String(), "00;10;11;20;21;22;")
+
+	// Branches out of a range-over-func loop to an ordinary loop.
+	b.Reset()
+loop:
+	for i := 0; i < 4; i++ {
+		for j := range count(4) {
+			if j == 2 {
+				continue loop
+			}
+			if i == 2 {
+				break loop
+			}
+			fmt.Fprintf(&b, "%d%d;", i, j)
+		}
+	}
+	expect(b.String(), "00;01;10;11;")
+
+	// Branches out of an ordinary loop inside a range-over-func loop.
+	b.Reset()
+L:
+	for i := range count(4) {
+		for j := 0; j < 4; j++ {
+			switch {
+			case j > i:
+				continue L
+			case i == 3:
+				break L
+			}
+			fmt.Fprintf(&b, "%d%d;", i, j)
+		}
+	}
+	expect(b.String(), "00;10;11;20;21;22;")
+}
+
+func find(s []string, x string) (int, bool) {
+	for i, v := range pairs(s) {
+		if v == x {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func findNested(x int) (r int) {
+	for i := range count(10) {
+		for j := range count(10) {
+			if i*j == x {
+				return i*10 + j
+			}
+		}
+	}
+	return -1
+}
+
+func bareReturn() (n int) {
+	for i := range count(10) {
+		n = i
+		if i == 3 {
+			return
+		}
+	}
+	return 100
+}
+
+func testReturn() {
+	expect(fmt.Sprint(find([]string{"a", "b", "c"}, "b")), "1 true")
+	expect(fmt.Sprint(find([]string{"a", "b", "c"}, "d")), "-1 false")
+	expect(fmt.Sprint(findNested(12)), "26")
+	expect(fmt.Sprint(findNested(1000)), "-1")
+	expect(fmt.Sprint(bareReturn()), "3")
+}
+
+func testGoto() {
+	var b strings.Builder
+	for i := range count(10) {
+		if i == 3 {
+			goto done
+		}
+		fmt.Fprint(&b, i)
+	}
+	b.WriteString("unreachable")
+done:
+	expect(b.String(), "012")
+}
+
+func deferOrder() (s string) {
+	var b strings.Builder
+	defer func() {
+		s = b.String()
+	}()
+	for i := range count(3) {
+		defer fmt.Fprint(&b, i)
+	}
+	b.WriteString("body;")
+	return
+}
+
+func testDefer() {
+	expect(deferOrder(), "body;210")
+}
+
+func testLoopVar() {
+	var fs []func() int
+	for i := range count(3) {
+		fs = append(fs, func() int { return i })
+	}
+	var b strings.Builder
+	for _, f := range fs {
+		fmt.Fprint(&b, f())
+	}
+	expect(b.String(), "012")
+}
+
+func testExitCheck() {
+	defer func() {
+		r := recover()
+		if r == nil {
+			panic("missing panic for iterator that ignores yield result")
+		}
+		err, ok := r.(error)
+		if !ok || !strings.Contains(err.Error(), "range function continued iteration") {
+			panic(fmt.Sprintf("unexpected panic %v", r))
+		}
+	}()
+	for i := range badCount(3) {
+		if i == 1 {
+			break
+		}
+	}
+}
+
+func main() {
+	testBasic()
+	testBreakContinue()
+	testLabeled()
+	testReturn()
+	testGoto()
+	testDefer()
+	testLoopVar()
+	testExitCheck()
+}